@@ -17,10 +17,24 @@ limitations under the License.
 package main
 
 import (
+	"context"
+	"crypto/x509"
+	"errors"
 	"flag"
+	"fmt"
+	"net/http"
 	"os"
+	"path"
+	"sort"
+	"strings"
+	"time"
 
 	certmanager "github.com/cert-manager/cert-manager/pkg/apis/certmanager/v1"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
 
 	// Import all Kubernetes client auth plugins (e.g. Azure, GCP, OIDC, etc.)
 	// to ensure that exec-entrypoint and run can make use of them.
@@ -28,16 +42,24 @@ import (
 
 	"k8s.io/apimachinery/pkg/runtime"
 	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	"k8s.io/apimachinery/pkg/util/validation"
 	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/leaderelection"
 	"k8s.io/utils/clock"
 	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/cache"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/event"
 	"sigs.k8s.io/controller-runtime/pkg/healthz"
 	"sigs.k8s.io/controller-runtime/pkg/log/zap"
+	"sigs.k8s.io/controller-runtime/pkg/metrics/filters"
 	metricsserver "sigs.k8s.io/controller-runtime/pkg/metrics/server"
 	"sigs.k8s.io/controller-runtime/pkg/webhook"
 
 	awspcacertmanageriov1beta1 "github.com/cert-manager/aws-privateca-issuer/pkg/api/v1beta1"
+	awspca "github.com/cert-manager/aws-privateca-issuer/pkg/aws"
 	"github.com/cert-manager/aws-privateca-issuer/pkg/controllers"
+	"github.com/cert-manager/aws-privateca-issuer/pkg/webhooks"
 	// +kubebuilder:scaffold:imports
 )
 
@@ -54,68 +76,302 @@ func init() {
 }
 
 func main() {
+	if len(os.Args) > 1 && os.Args[1] == validateCommand {
+		os.Exit(runValidate(os.Args[2:], os.Stdin, os.Stdout, os.Stderr))
+	}
+
 	var metricsAddr string
+	var secureMetrics bool
+	var debugEndpoint bool
 	var enableLeaderElection bool
 	var probeAddr string
 	var disableApprovedCheck bool
+	var pendingRequeueInterval time.Duration
+	var maxRequeueBackoff time.Duration
+	var requeueJitter time.Duration
+	var minPollInterval time.Duration
+	var maxPollInterval time.Duration
+	var issuanceTimeout time.Duration
+	var caHealthCheckInterval time.Duration
+	var namespace string
+	var clusterResourceNamespace string
+	var otlpEndpoint string
+	var certificateArnAnnotation string
+	var enableWebhooks bool
+	var logFormat string
+	var awsRetryMaxAttempts int
+	var awsMaxIdleConns int
+	var awsIdleConnTimeout time.Duration
+	var awsMaxConnsPerHost int
+	var awsRetryMaxBackoff time.Duration
+	var maxConcurrentReconciles int
+	var defaultRegion string
+	var awsCABundle string
+	var leaderElection leaderElectionConfig
+	var gracefulShutdownTimeout time.Duration
+	var issuanceRateLimit float64
+	var userAgentSuffix string
+	var enableIssuer bool
+	var enableClusterIssuer bool
+	var failureThreshold int
+	var allowedCAArns string
+	defaultTags := tagsFlag{}
+	var awsCallTimeout time.Duration
+	var issuanceQuotaThreshold int
+	var issuanceQuotaWindow time.Duration
+	var issuerFinalizer bool
+	var caEventsQueueURL string
 
-	flag.StringVar(&metricsAddr, "metrics-bind-address", ":8080", "The address the metric endpoint binds to.")
+	flag.StringVar(&metricsAddr, "metrics-bind-address", ":8080", "The address the metric endpoint binds to. Serving metrics is disabled if 0.")
+	flag.BoolVar(&secureMetrics, "metrics-secure", false,
+		"Serve metrics over HTTPS, only to clients authenticated with a token that is authorized to get the /metrics non-resource URL.")
+	flag.BoolVar(&debugEndpoint, "enable-debug-endpoint", false,
+		"Serve the provisioners and AWS clients cached for issuers as JSON at /debug/provisioners of the metrics endpoint. Requires -metrics-secure.")
 	flag.StringVar(&probeAddr, "health-probe-bind-address", ":8081", "The address the probe endpoint binds to.")
 	flag.BoolVar(&enableLeaderElection, "leader-elect", false,
 		"Enable leader election for controller manager. "+
 			"Enabling this will ensure there is only one active controller manager.")
+	flag.BoolVar(&enableLeaderElection, "enable-leader-election", false,
+		"Alias of --leader-elect.")
+	flag.StringVar(&leaderElection.namespace, "leader-election-namespace", "",
+		"The namespace of the leader election lease. The namespace of the controller is used if not set.")
+	flag.DurationVar(&leaderElection.leaseDuration, "leader-election-lease-duration", 15*time.Second,
+		"How long replicas wait before taking over the lease of a leader that stopped renewing it.")
+	flag.DurationVar(&leaderElection.renewDeadline, "leader-election-renew-deadline", 10*time.Second,
+		"How long the leader retries renewing its lease before it stops leading. Must be less than the lease duration.")
+	flag.DurationVar(&leaderElection.retryPeriod, "leader-election-retry-period", 2*time.Second,
+		"How long replicas wait between attempts to acquire or renew the lease. The renew deadline must be greater than 1.2 times it.")
+	flag.DurationVar(&gracefulShutdownTimeout, "graceful-shutdown-timeout", 30*time.Second,
+		"How long in-flight reconciles may continue after SIGTERM, e.g. to record a certificate PCA is issuing, before the controller exits.")
 	flag.BoolVar(&disableApprovedCheck, "disable-approved-check", false,
 		"Disables waiting for CertificateRequests to have an approved condition before signing.")
+	flag.DurationVar(&pendingRequeueInterval, "pending-requeue-interval", 5*time.Second,
+		"The delay before first retrying to retrieve a certificate that is still being issued by PCA. It doubles with every further attempt.")
+	flag.DurationVar(&maxRequeueBackoff, "max-requeue-backoff", time.Minute,
+		"The maximum delay between attempts to retrieve a certificate that is still being issued by PCA.")
+	flag.DurationVar(&minPollInterval, "min-poll-interval", 0,
+		"The minimum delay between attempts to retrieve a certificate PCA is still issuing, including jitter. Not bounded if 0.")
+	flag.DurationVar(&maxPollInterval, "max-poll-interval", 0,
+		"The maximum delay between attempts to retrieve a certificate PCA is still issuing, including jitter. Not bounded if 0.")
+	flag.DurationVar(&issuanceTimeout, "issuance-timeout", 0,
+		"How long after its creation a CertificateRequest may wait for PCA to issue its certificate before it is marked Failed. Waits indefinitely if 0.")
+	flag.DurationVar(&requeueJitter, "requeue-jitter", 5*time.Second,
+		"A random delay of up to this long is added to every requeue, spreading retries to PCA when many certificates renew at once. Set to 0 to disable.")
+	flag.IntVar(&failureThreshold, "failure-threshold", 1,
+		"The number of consecutive failed attempts to request or retrieve a certificate from PCA after which a CertificateRequest is marked Failed. Earlier failures are retried with the requeue backoff.")
+	flag.DurationVar(&caHealthCheckInterval, "ca-health-check-interval", 0,
+		"How often to verify that the CAs of issuers are reachable and ACTIVE. The check is disabled if 0.")
+	flag.StringVar(&otlpEndpoint, "otlp-endpoint", "",
+		"The OTLP/HTTP URL to export traces to, e.g. http://otel-collector:4318. "+
+			"The OTEL_EXPORTER_OTLP_ENDPOINT environment variable is used if not set, and tracing is disabled if neither is.")
+	flag.StringVar(&certificateArnAnnotation, "certificate-arn-annotation", awspca.CertificateArnAnnotation,
+		"The annotation CertificateRequests record the ARN of their certificate in, e.g. to use a key per issuer deployment. "+
+			"The default annotation is still read for requests signed before it was changed.")
+	flag.BoolVar(&enableWebhooks, "enable-webhooks", false,
+		"Serve the validating admission webhooks for AWSPCAIssuers and AWSPCAClusterIssuers on port 9443. "+
+			"Requires a serving certificate in the webhook server's cert directory.")
+	flag.StringVar(&logFormat, "log-format", "",
+		"The format of the logs, json or console. Takes precedence over --zap-encoder if set.")
+	flag.IntVar(&awsRetryMaxAttempts, "aws-retry-max-attempts", 0,
+		"The maximum number of attempts of each ACM PCA API call, including the first, before the reconcile fails. The SDK default of 3 is used if 0.")
+	flag.DurationVar(&awsRetryMaxBackoff, "aws-retry-max-backoff", 0,
+		"The maximum delay between retries of an ACM PCA API call. The SDK default of 20s is used if 0.")
+	flag.IntVar(&awsMaxIdleConns, "aws-max-idle-conns", 0,
+		"The maximum number of idle connections to AWS kept open for reuse, in total and per host. The SDK default of 100 in total and 10 per host is used if 0.")
+	flag.DurationVar(&awsIdleConnTimeout, "aws-idle-conn-timeout", 0,
+		"How long an idle connection to AWS is kept open for reuse. The SDK default of 90s is used if 0.")
+	flag.IntVar(&awsMaxConnsPerHost, "aws-max-conns-per-host", 0,
+		"The maximum number of connections to each AWS endpoint, including those in use. Not limited if 0.")
+	flag.DurationVar(&awsCallTimeout, "aws-call-timeout", time.Minute,
+		"How long requesting or retrieving a certificate from PCA may take, including SDK retries, before the CertificateRequest is requeued. "+
+			"Retrieving may take longer by the certificateWaitTimeout of the issuer. Unlimited if 0.")
+	flag.IntVar(&maxConcurrentReconciles, "max-concurrent-reconciles", 1,
+		"The number of CertificateRequests that are reconciled in parallel.")
+	flag.Float64Var(&issuanceRateLimit, "issuance-rate-limit", 0,
+		"The number of certificates per second each issuer may request from PCA, unless overridden by the aws-privateca-issuer/issuance-rate-limit annotation of the issuer. Unlimited if 0.")
+	flag.IntVar(&issuanceQuotaThreshold, "issuance-quota-threshold", 0,
+		"The number of certificates issued for an issuer within -issuance-quota-window at which a Warning event is emitted and its IssuanceQuota condition becomes True. Disabled if 0.")
+	flag.DurationVar(&issuanceQuotaWindow, "issuance-quota-window", 24*time.Hour,
+		"The period over which issuances are counted against -issuance-quota-threshold.")
+	flag.StringVar(&defaultRegion, "default-region", "",
+		"The AWS region of issuers that do not specify one. The AWS_REGION environment variable is used if not set.")
+	flag.StringVar(&awsCABundle, "aws-ca-bundle", "",
+		"Path to a PEM file of CA certificates trusted in addition to the system roots when connecting to AWS, e.g. of a TLS intercepting proxy. "+
+			"Requests to AWS use the proxy set by the HTTPS_PROXY and NO_PROXY environment variables.")
+	flag.StringVar(&userAgentSuffix, "user-agent-suffix", "",
+		"A product name added with the controller version to the User-Agent of PCA calls, e.g. to tell fleets apart in CloudTrail.")
+	flag.StringVar(&clusterResourceNamespace, "cluster-resource-namespace", "",
+		"The namespace of the credentials Secrets of AWSPCAClusterIssuers whose secretRef does not name one. The namespace of the controller is used if not set.")
+	flag.StringVar(&namespace, "namespace", "",
+		"Only watch CertificateRequests and AWSPCAIssuers in this namespace. AWSPCAClusterIssuers are not handled if set.")
+	flag.BoolVar(&enableIssuer, "enable-issuer", true,
+		"Serve AWSPCAIssuers and the CertificateRequests referencing them.")
+	flag.BoolVar(&enableClusterIssuer, "enable-cluster-issuer", true,
+		"Serve AWSPCAClusterIssuers and the CertificateRequests referencing them. Implied false if -namespace is set.")
+	flag.StringVar(&allowedCAArns, "allowed-ca-arns", "",
+		"Comma separated CA ARNs, or glob patterns of them, that AWSPCAClusterIssuers may reference. All CAs are allowed if empty.")
+	flag.Var(defaultTags, "default-tags",
+		"A key=value tag applied to the CA of every issuer in addition to its own tags, which take precedence. May be repeated or hold comma separated tags.")
+	flag.BoolVar(&issuerFinalizer, "issuer-finalizer", false,
+		"Add a finalizer to issuers that cleans up the state cached for them once they are deleted, and removes their tags from the CA if they have the aws-privateca-issuer/untag-on-delete annotation.")
+	flag.StringVar(&caEventsQueueURL, "ca-events-queue-url", "",
+		"The URL of an SQS queue receiving EventBridge events of ACM PCA. Issuers are reconciled when the state of their CA changes. Disabled if empty.")
 
 	opts := zap.Options{
 		Development: false,
 	}
 	opts.BindFlags(flag.CommandLine)
 	flag.Parse()
+	if err := setLogFormat(&opts, logFormat); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
 
 	ctrl.SetLogger(zap.New(zap.UseFlagOptions(&opts)))
 
-	mgr, err := ctrl.NewManager(ctrl.GetConfigOrDie(), ctrl.Options{
-		Scheme: scheme,
-		Metrics: metricsserver.Options{
-			BindAddress: metricsAddr,
-		},
+	if errs := validation.IsQualifiedName(certificateArnAnnotation); len(errs) > 0 {
+		setupLog.Error(fmt.Errorf("%s", strings.Join(errs, "; ")), "invalid certificate-arn-annotation", "annotation", certificateArnAnnotation)
+		os.Exit(1)
+	}
+
+	enableClusterIssuer = enableClusterIssuer && namespace == ""
+	if !enableIssuer && !enableClusterIssuer {
+		setupLog.Error(errors.New("no issuer kind is enabled"), "invalid enable-issuer and enable-cluster-issuer")
+		os.Exit(1)
+	}
+
+	if debugEndpoint && !secureMetrics {
+		setupLog.Error(errors.New("the debug endpoint is only served to authenticated clients"), "invalid enable-debug-endpoint without metrics-secure")
+		os.Exit(1)
+	}
+
+	leaderElection.enabled = enableLeaderElection
+	mgrOpts := ctrl.Options{
+		Scheme:  scheme,
+		Cache:   cacheOptions(namespace),
+		Metrics: metricsOptions(metricsAddr, secureMetrics, debugEndpoint),
 		WebhookServer: webhook.NewServer(webhook.Options{
 			Port: 9443,
 		}),
 		HealthProbeBindAddress: probeAddr,
-		LeaderElection:         enableLeaderElection,
-		LeaderElectionID:       "b858308c.awspca.cert-manager.io",
-	})
+	}
+	if err := leaderElection.apply(&mgrOpts); err != nil {
+		setupLog.Error(err, "invalid leader election configuration")
+		os.Exit(1)
+	}
+	if err := validatePollIntervals(minPollInterval, maxPollInterval); err != nil {
+		setupLog.Error(err, "invalid min-poll-interval or max-poll-interval")
+		os.Exit(1)
+	}
+	if issuanceTimeout < 0 {
+		setupLog.Error(fmt.Errorf("issuance timeout %s must not be negative", issuanceTimeout), "invalid issuance-timeout")
+		os.Exit(1)
+	}
+	if err := setGracefulShutdownTimeout(&mgrOpts, gracefulShutdownTimeout); err != nil {
+		setupLog.Error(err, "invalid graceful-shutdown-timeout")
+		os.Exit(1)
+	}
+
+	caBundle, err := loadCABundle(awsCABundle)
+	if err != nil {
+		setupLog.Error(err, "invalid aws-ca-bundle", "path", awsCABundle)
+		os.Exit(1)
+	}
+
+	allowedCAArnPatterns, err := parseAllowedCAArns(allowedCAArns)
+	if err != nil {
+		setupLog.Error(err, "invalid allowed-ca-arns")
+		os.Exit(1)
+	}
+
+	if err := awspca.ValidateTags(defaultTags); err != nil {
+		setupLog.Error(err, "invalid default-tags")
+		os.Exit(1)
+	}
+
+	shutdownTracing, err := setupTracing(context.Background(), otlpEndpoint)
+	if err != nil {
+		setupLog.Error(err, "unable to set up tracing")
+		os.Exit(1)
+	}
+	defer func() {
+		if err := shutdownTracing(context.Background()); err != nil {
+			setupLog.Error(err, "problem shutting down tracing")
+		}
+	}()
+
+	mgr, err := ctrl.NewManager(ctrl.GetConfigOrDie(), mgrOpts)
 	if err != nil {
 		setupLog.Error(err, "unable to start manager")
 		os.Exit(1)
 	}
 
+	var caHealthChecker *controllers.CAHealthChecker
+	if caHealthCheckInterval > 0 {
+		caHealthChecker = &controllers.CAHealthChecker{
+			Client:    mgr.GetClient(),
+			Log:       ctrl.Log.WithName("controllers").WithName("CAHealth"),
+			Recorder:  mgr.GetEventRecorderFor("awspcaissuer-controller"),
+			Interval:  caHealthCheckInterval,
+			Namespace: namespace,
+		}
+		if err := mgr.Add(caHealthChecker); err != nil {
+			setupLog.Error(err, "unable to set up CA health check")
+			os.Exit(1)
+		}
+	}
+
+	issuanceLimiters := &controllers.IssuanceLimiters{}
+	issuanceCounters := &controllers.IssuanceCounters{}
+	caCommonNames := &controllers.CACommonNames{}
 	genericIssuerController := &controllers.GenericIssuerReconciler{
 		Client:            mgr.GetClient(),
 		Log:               ctrl.Log.WithName("controllers").WithName("GenericIssuer"),
 		Scheme:            mgr.GetScheme(),
 		Recorder:          mgr.GetEventRecorderFor("awspcaissuer-controller"),
 		GetCallerIdentity: true,
+		CheckCAStatus:     true,
+		CAHealth:          caHealthChecker,
+		IssuanceLimiters:  issuanceLimiters,
+		IssuanceCounters:  issuanceCounters,
+		CACommonNames:     caCommonNames,
+
+		CertificateArnAnnotation: certificateArnAnnotation,
+		RetryMaxAttempts:         awsRetryMaxAttempts,
+		RetryMaxBackoff:          awsRetryMaxBackoff,
+		MaxIdleConns:             awsMaxIdleConns,
+		IdleConnTimeout:          awsIdleConnTimeout,
+		MaxConnsPerHost:          awsMaxConnsPerHost,
+		DefaultRegion:            defaultRegion,
+		CABundle:                 caBundle,
+		ClusterResourceNamespace: resolveClusterResourceNamespace(clusterResourceNamespace, serviceAccountNamespaceFile),
+		UserAgentSuffix:          userAgentSuffix,
+		AllowedCAArns:            allowedCAArnPatterns,
+		DefaultTags:              defaultTags,
+		Finalize:                 issuerFinalizer,
 	}
-	if err = (&controllers.AWSPCAIssuerReconciler{
-		Client:            mgr.GetClient(),
-		Log:               ctrl.Log.WithName("controllers").WithName("AWSPCAIssuer"),
-		Scheme:            mgr.GetScheme(),
-		GenericController: genericIssuerController,
-	}).SetupWithManager(mgr); err != nil {
-		setupLog.Error(err, "unable to create controller", "controller", "AWSPCAIssuer")
-		os.Exit(1)
+	if caEventsQueueURL != "" {
+		cfg, err := genericIssuerController.ControllerConfig(context.Background())
+		if err != nil {
+			setupLog.Error(err, "unable to load AWS config for the CA events queue")
+			os.Exit(1)
+		}
+		queue, err := awspca.NewSQSQueue(cfg, caEventsQueueURL)
+		if err != nil {
+			setupLog.Error(err, "invalid ca-events-queue-url")
+			os.Exit(1)
+		}
+		genericIssuerController.CAStateWatcher = newCAStateWatcher(mgr.GetClient(), queue, namespace, caHealthChecker, enableIssuer, enableClusterIssuer)
+		if err := mgr.Add(genericIssuerController.CAStateWatcher); err != nil {
+			setupLog.Error(err, "unable to set up CA state watcher")
+			os.Exit(1)
+		}
 	}
-	if err = (&controllers.AWSPCAClusterIssuerReconciler{
-		Client:            mgr.GetClient(),
-		Log:               ctrl.Log.WithName("controllers").WithName("AWSPCAClusterIssuer"),
-		Scheme:            mgr.GetScheme(),
-		GenericController: genericIssuerController,
-	}).SetupWithManager(mgr); err != nil {
-		setupLog.Error(err, "unable to create controller", "controller", "AWSPCAClusterIssuer")
-		os.Exit(1)
+	for _, c := range issuerControllers(genericIssuerController, enableIssuer, enableClusterIssuer) {
+		if err = c.reconciler.SetupWithManager(mgr); err != nil {
+			setupLog.Error(err, "unable to create controller", "controller", c.name)
+			os.Exit(1)
+		}
 	}
 	if err = (&controllers.CertificateRequestReconciler{
 		Client:   mgr.GetClient(),
@@ -123,12 +379,38 @@ func main() {
 		Scheme:   mgr.GetScheme(),
 		Recorder: mgr.GetEventRecorderFor("awspcaissuer-controller"),
 
-		Clock:                  clock.RealClock{},
-		CheckApprovedCondition: !disableApprovedCheck,
+		Clock:                    clock.RealClock{},
+		CheckApprovedCondition:   !disableApprovedCheck,
+		PendingRequeueInterval:   pendingRequeueInterval,
+		MaxRequeueBackoff:        maxRequeueBackoff,
+		RequeueJitter:            requeueJitter,
+		MinPollInterval:          minPollInterval,
+		MaxPollInterval:          maxPollInterval,
+		IssuanceTimeout:          issuanceTimeout,
+		DisableIssuers:           !enableIssuer,
+		DisableClusterIssuers:    !enableClusterIssuer,
+		CertificateArnAnnotation: certificateArnAnnotation,
+		MaxConcurrentReconciles:  maxConcurrentReconciles,
+		Provisioners:             genericIssuerController,
+		ShutdownTimeout:          gracefulShutdownTimeout,
+		IssuanceRateLimit:        issuanceRateLimit,
+		IssuanceLimiters:         issuanceLimiters,
+		FailureThreshold:         failureThreshold,
+		CallTimeout:              awsCallTimeout,
+		IssuanceQuotaThreshold:   issuanceQuotaThreshold,
+		IssuanceQuotaWindow:      issuanceQuotaWindow,
+		IssuanceCounters:         issuanceCounters,
+		CACommonNames:            caCommonNames,
 	}).SetupWithManager(mgr); err != nil {
 		setupLog.Error(err, "unable to create controller", "controller", "CertificateRequest")
 		os.Exit(1)
 	}
+	if enableWebhooks {
+		if err = (&webhooks.IssuerValidator{}).SetupWithManager(mgr); err != nil {
+			setupLog.Error(err, "unable to create webhook", "webhook", "AWSPCAIssuer")
+			os.Exit(1)
+		}
+	}
 	// +kubebuilder:scaffold:builder
 
 	if err := mgr.AddHealthzCheck("health", healthz.Ping); err != nil {
@@ -139,6 +421,12 @@ func main() {
 		setupLog.Error(err, "unable to set up ready check")
 		os.Exit(1)
 	}
+	if caHealthChecker != nil {
+		if err := mgr.AddReadyzCheck("ca-health", caHealthChecker.Check); err != nil {
+			setupLog.Error(err, "unable to set up CA health check")
+			os.Exit(1)
+		}
+	}
 
 	setupLog.Info("starting manager")
 	if err := mgr.Start(ctrl.SetupSignalHandler()); err != nil {
@@ -146,3 +434,272 @@ func main() {
 		os.Exit(1)
 	}
 }
+
+// issuerController is a controller of an issuer kind
+type issuerController struct {
+	name       string
+	reconciler interface {
+		SetupWithManager(mgr ctrl.Manager) error
+	}
+}
+
+// issuerControllers returns the controllers of the enabled issuer kinds, which
+// share the client and scheme of generic
+func issuerControllers(generic *controllers.GenericIssuerReconciler, enableIssuer, enableClusterIssuer bool) []issuerController {
+	var issuerControllers []issuerController
+	if enableIssuer {
+		issuerControllers = append(issuerControllers, issuerController{
+			name: "AWSPCAIssuer",
+			reconciler: &controllers.AWSPCAIssuerReconciler{
+				Client:            generic.Client,
+				Log:               ctrl.Log.WithName("controllers").WithName("AWSPCAIssuer"),
+				Scheme:            generic.Scheme,
+				GenericController: generic,
+			},
+		})
+	}
+	if enableClusterIssuer {
+		issuerControllers = append(issuerControllers, issuerController{
+			name: "AWSPCAClusterIssuer",
+			reconciler: &controllers.AWSPCAClusterIssuerReconciler{
+				Client:            generic.Client,
+				Log:               ctrl.Log.WithName("controllers").WithName("AWSPCAClusterIssuer"),
+				Scheme:            generic.Scheme,
+				GenericController: generic,
+			},
+		})
+	}
+	return issuerControllers
+}
+
+// newCAStateWatcher returns a watcher of the CA state changes received from
+// queue, with a channel for each enabled issuer kind
+func newCAStateWatcher(c client.Client, queue *awspca.SQSQueue, namespace string, caHealth *controllers.CAHealthChecker, enableIssuer, enableClusterIssuer bool) *controllers.CAStateWatcher {
+	watcher := &controllers.CAStateWatcher{
+		Client:    c,
+		Log:       ctrl.Log.WithName("controllers").WithName("CAStateWatcher"),
+		Queue:     queue,
+		Namespace: namespace,
+		CAHealth:  caHealth,
+	}
+	if enableIssuer {
+		watcher.Issuers = make(chan event.GenericEvent)
+	}
+	if enableClusterIssuer {
+		watcher.ClusterIssuers = make(chan event.GenericEvent)
+	}
+	return watcher
+}
+
+// metricsOptions serves metrics on bindAddress. Secure metrics are served over
+// HTTPS with a self-signed certificate, and requests are authenticated with
+// TokenReviews and authorized with SubjectAccessReviews. The debug endpoint of
+// the cached provisioners is served next to the metrics if debug is set.
+func metricsOptions(bindAddress string, secure, debug bool) metricsserver.Options {
+	opts := metricsserver.Options{
+		BindAddress:   bindAddress,
+		SecureServing: secure,
+	}
+	if secure {
+		opts.FilterProvider = filters.WithAuthenticationAndAuthorization
+	}
+	if debug {
+		opts.ExtraHandlers = map[string]http.Handler{
+			awspca.CachedProvisionersPath: awspca.CachedProvisionersHandler(),
+		}
+	}
+	return opts
+}
+
+// cacheOptions restricts the cache of the manager to namespace, unless it is
+// empty
+func cacheOptions(namespace string) cache.Options {
+	if namespace == "" {
+		return cache.Options{}
+	}
+	return cache.Options{
+		DefaultNamespaces: map[string]cache.Config{namespace: {}},
+	}
+}
+
+// leaderElectionID is the name of the lease replicas of the controller elect
+// their leader with
+const leaderElectionID = "b858308c.awspca.cert-manager.io"
+
+// leaderElectionConfig configures the election of the single active replica
+// of the controller
+type leaderElectionConfig struct {
+	enabled       bool
+	namespace     string
+	leaseDuration time.Duration
+	renewDeadline time.Duration
+	retryPeriod   time.Duration
+}
+
+// apply sets the leader election options of the manager. The lease timings
+// are checked like the leader elector does, so that invalid flags are rejected
+// at startup rather than when the manager starts.
+func (c leaderElectionConfig) apply(opts *ctrl.Options) error {
+	if c.leaseDuration <= c.renewDeadline {
+		return fmt.Errorf("leader election lease duration %s must be greater than the renew deadline %s", c.leaseDuration, c.renewDeadline)
+	}
+	if float64(c.renewDeadline) <= leaderelection.JitterFactor*float64(c.retryPeriod) {
+		return fmt.Errorf("leader election renew deadline %s must be greater than %v times the retry period %s", c.renewDeadline, leaderelection.JitterFactor, c.retryPeriod)
+	}
+
+	opts.LeaderElection = c.enabled
+	opts.LeaderElectionID = leaderElectionID
+	opts.LeaderElectionNamespace = c.namespace
+	opts.LeaseDuration = &c.leaseDuration
+	opts.RenewDeadline = &c.renewDeadline
+	opts.RetryPeriod = &c.retryPeriod
+	return nil
+}
+
+// setGracefulShutdownTimeout sets how long the manager waits for its runnables,
+// and so in-flight reconciles, to stop. Negative timeouts, which the manager
+// treats as waiting forever, are rejected.
+func setGracefulShutdownTimeout(opts *ctrl.Options, timeout time.Duration) error {
+	if timeout < 0 {
+		return fmt.Errorf("graceful shutdown timeout %s must not be negative", timeout)
+	}
+
+	opts.GracefulShutdownTimeout = &timeout
+	return nil
+}
+
+// validatePollIntervals rejects negative poll interval bounds, and a minimum
+// greater than the maximum
+func validatePollIntervals(min, max time.Duration) error {
+	switch {
+	case min < 0 || max < 0:
+		return fmt.Errorf("poll intervals %s and %s must not be negative", min, max)
+	case max > 0 && min > max:
+		return fmt.Errorf("minimum poll interval %s must not be greater than the maximum %s", min, max)
+	}
+	return nil
+}
+
+// setLogFormat selects the encoder of the logger for format. The encoder
+// selected by the zap options is kept if format is empty.
+func setLogFormat(opts *zap.Options, format string) error {
+	switch format {
+	case "":
+	case "json":
+		zap.JSONEncoder()(opts)
+	case "console":
+		zap.ConsoleEncoder()(opts)
+	default:
+		return fmt.Errorf("invalid log format %q, must be json or console", format)
+	}
+	return nil
+}
+
+// serviceAccountNamespaceFile holds the namespace of the pod of the controller
+const serviceAccountNamespaceFile = "/var/run/secrets/kubernetes.io/serviceaccount/namespace"
+
+// defaultClusterResourceNamespace is the cluster resource namespace of a
+// controller running outside of a pod
+const defaultClusterResourceNamespace = "default"
+
+// resolveClusterResourceNamespace returns namespace, or if it is empty the
+// namespace of the controller read from namespaceFile
+func resolveClusterResourceNamespace(namespace, namespaceFile string) string {
+	if namespace != "" {
+		return namespace
+	}
+	if data, err := os.ReadFile(namespaceFile); err == nil {
+		if ns := strings.TrimSpace(string(data)); ns != "" {
+			return ns
+		}
+	}
+	return defaultClusterResourceNamespace
+}
+
+// loadCABundle reads the PEM encoded CA certificates at path. No bundle is
+// loaded if path is empty.
+func loadCABundle(path string) ([]byte, error) {
+	if path == "" {
+		return nil, nil
+	}
+	bundle, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	if !x509.NewCertPool().AppendCertsFromPEM(bundle) {
+		return nil, fmt.Errorf("%s contains no PEM encoded certificates", path)
+	}
+	return bundle, nil
+}
+
+// tagsFlag collects the key=value tags of a repeatable flag
+type tagsFlag map[string]string
+
+func (f tagsFlag) String() string {
+	tags := make([]string, 0, len(f))
+	for key, value := range f {
+		tags = append(tags, key+"="+value)
+	}
+	sort.Strings(tags)
+	return strings.Join(tags, ",")
+}
+
+// Set adds the comma separated key=value tags of value. Tag values cannot
+// contain commas, and a key given twice keeps its last value.
+func (f tagsFlag) Set(value string) error {
+	for _, tag := range strings.Split(value, ",") {
+		tag = strings.TrimSpace(tag)
+		if tag == "" {
+			continue
+		}
+		key, value, ok := strings.Cut(tag, "=")
+		if !ok {
+			return fmt.Errorf("tag %q is not of the form key=value", tag)
+		}
+		f[strings.TrimSpace(key)] = strings.TrimSpace(value)
+	}
+	return nil
+}
+
+// parseAllowedCAArns splits the comma separated CA ARN patterns of the
+// allowed-ca-arns flag, and checks that they are valid glob patterns
+func parseAllowedCAArns(value string) ([]string, error) {
+	var patterns []string
+	for _, pattern := range strings.Split(value, ",") {
+		pattern = strings.TrimSpace(pattern)
+		if pattern == "" {
+			continue
+		}
+		if _, err := path.Match(pattern, ""); err != nil {
+			return nil, fmt.Errorf("%q is not a valid pattern: %w", pattern, err)
+		}
+		patterns = append(patterns, pattern)
+	}
+	return patterns, nil
+}
+
+// setupTracing exports spans over OTLP/HTTP to endpoint, or to the endpoint
+// set by the standard OTEL_EXPORTER_OTLP_* environment variables. Tracing is
+// disabled if neither is set.
+func setupTracing(ctx context.Context, endpoint string) (func(context.Context) error, error) {
+	if endpoint == "" && os.Getenv("OTEL_EXPORTER_OTLP_ENDPOINT") == "" && os.Getenv("OTEL_EXPORTER_OTLP_TRACES_ENDPOINT") == "" {
+		return func(context.Context) error { return nil }, nil
+	}
+
+	var opts []otlptracehttp.Option
+	if endpoint != "" {
+		opts = append(opts, otlptracehttp.WithEndpointURL(endpoint))
+	}
+	exporter, err := otlptracehttp.New(ctx, opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	res, err := resource.Merge(resource.Default(), resource.NewSchemaless(attribute.String("service.name", "aws-privateca-issuer")))
+	if err != nil {
+		return nil, err
+	}
+	provider := sdktrace.NewTracerProvider(sdktrace.WithBatcher(exporter), sdktrace.WithResource(res))
+	otel.SetTracerProvider(provider)
+	return provider.Shutdown, nil
+}
@@ -0,0 +1,388 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/pem"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/cert-manager/aws-privateca-issuer/pkg/controllers"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/cache"
+	"sigs.k8s.io/controller-runtime/pkg/log/zap"
+)
+
+func TestCacheOptions(t *testing.T) {
+	tests := map[string]struct {
+		namespace string
+		expected  cache.Options
+	}{
+		"cluster-scoped": {
+			expected: cache.Options{},
+		},
+		"namespace-scoped": {
+			namespace: "tenant-a",
+			expected: cache.Options{
+				DefaultNamespaces: map[string]cache.Config{"tenant-a": {}},
+			},
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			assert.Equal(t, tc.expected, cacheOptions(tc.namespace))
+		})
+	}
+}
+
+func TestIssuerControllers(t *testing.T) {
+	tests := map[string]struct {
+		enableIssuer        bool
+		enableClusterIssuer bool
+		expected            []string
+	}{
+		"all": {
+			enableIssuer:        true,
+			enableClusterIssuer: true,
+			expected:            []string{"AWSPCAIssuer", "AWSPCAClusterIssuer"},
+		},
+		"issuer-only": {
+			enableIssuer: true,
+			expected:     []string{"AWSPCAIssuer"},
+		},
+		"cluster-issuer-only": {
+			enableClusterIssuer: true,
+			expected:            []string{"AWSPCAClusterIssuer"},
+		},
+		"none": {},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			generic := &controllers.GenericIssuerReconciler{}
+			var names []string
+			for _, c := range issuerControllers(generic, tc.enableIssuer, tc.enableClusterIssuer) {
+				names = append(names, c.name)
+				switch r := c.reconciler.(type) {
+				case *controllers.AWSPCAIssuerReconciler:
+					assert.Equal(t, "AWSPCAIssuer", c.name)
+					assert.Same(t, generic, r.GenericController)
+				case *controllers.AWSPCAClusterIssuerReconciler:
+					assert.Equal(t, "AWSPCAClusterIssuer", c.name)
+					assert.Same(t, generic, r.GenericController)
+				default:
+					t.Errorf("unexpected reconciler %T", r)
+				}
+			}
+			assert.Equal(t, tc.expected, names)
+		})
+	}
+}
+
+func TestSetLogFormat(t *testing.T) {
+	tests := map[string]struct {
+		format        string
+		development   bool
+		expectJSON    bool
+		expectFailure bool
+	}{
+		"json": {
+			format:     "json",
+			expectJSON: true,
+		},
+		"json-overrides-development": {
+			format:      "json",
+			development: true,
+			expectJSON:  true,
+		},
+		"console": {
+			format: "console",
+		},
+		"default-keeps-zap-encoder": {
+			expectJSON: true,
+		},
+		"invalid": {
+			format:        "xml",
+			expectFailure: true,
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			opts := zap.Options{Development: tc.development}
+			err := setLogFormat(&opts, tc.format)
+			if tc.expectFailure {
+				assert.EqualError(t, err, `invalid log format "xml", must be json or console`)
+				return
+			}
+			require.NoError(t, err)
+
+			var buf bytes.Buffer
+			zap.New(zap.UseFlagOptions(&opts), zap.WriteTo(&buf)).
+				WithValues("issuerName", "issuer1", "issuerNamespace", "ns1").
+				Info("Created certificate", "certificateArn", "arn")
+
+			var entry map[string]interface{}
+			if !tc.expectJSON {
+				assert.Error(t, json.Unmarshal(buf.Bytes(), &entry), "expected console output, got %s", buf.String())
+				assert.Contains(t, buf.String(), "Created certificate")
+				return
+			}
+			require.NoError(t, json.Unmarshal(buf.Bytes(), &entry), "expected JSON output, got %s", buf.String())
+			assert.Equal(t, "Created certificate", entry["msg"])
+			assert.Equal(t, "issuer1", entry["issuerName"])
+			assert.Equal(t, "ns1", entry["issuerNamespace"])
+			assert.Equal(t, "arn", entry["certificateArn"])
+		})
+	}
+}
+
+func TestLoadCABundle(t *testing.T) {
+	server := httptest.NewTLSServer(http.NotFoundHandler())
+	defer server.Close()
+	bundle := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw})
+
+	dir := t.TempDir()
+	bundlePath := filepath.Join(dir, "ca.pem")
+	require.NoError(t, os.WriteFile(bundlePath, bundle, 0o600))
+	invalidPath := filepath.Join(dir, "invalid.pem")
+	require.NoError(t, os.WriteFile(invalidPath, []byte("not a certificate"), 0o600))
+
+	loaded, err := loadCABundle(bundlePath)
+	require.NoError(t, err)
+	assert.Equal(t, bundle, loaded)
+
+	loaded, err = loadCABundle("")
+	require.NoError(t, err)
+	assert.Nil(t, loaded)
+
+	_, err = loadCABundle(invalidPath)
+	assert.EqualError(t, err, invalidPath+" contains no PEM encoded certificates")
+
+	_, err = loadCABundle(filepath.Join(dir, "missing.pem"))
+	assert.ErrorIs(t, err, os.ErrNotExist)
+}
+
+func TestResolveClusterResourceNamespace(t *testing.T) {
+	dir := t.TempDir()
+	namespaceFile := filepath.Join(dir, "namespace")
+	require.NoError(t, os.WriteFile(namespaceFile, []byte("aws-privateca-issuer\n"), 0o600))
+	emptyFile := filepath.Join(dir, "empty")
+	require.NoError(t, os.WriteFile(emptyFile, nil, 0o600))
+
+	assert.Equal(t, "cert-manager", resolveClusterResourceNamespace("cert-manager", namespaceFile))
+	assert.Equal(t, "aws-privateca-issuer", resolveClusterResourceNamespace("", namespaceFile))
+	assert.Equal(t, "default", resolveClusterResourceNamespace("", emptyFile))
+	assert.Equal(t, "default", resolveClusterResourceNamespace("", filepath.Join(dir, "missing")))
+}
+
+func TestTagsFlag(t *testing.T) {
+	tags := tagsFlag{}
+	require.NoError(t, tags.Set("managed-by=aws-privateca-issuer"))
+	require.NoError(t, tags.Set(" environment = prod , team=a,"))
+	require.NoError(t, tags.Set("team=b"))
+	require.NoError(t, tags.Set("empty="))
+	assert.Equal(t, tagsFlag{"managed-by": "aws-privateca-issuer", "environment": "prod", "team": "b", "empty": ""}, tags)
+	assert.Equal(t, "empty=,environment=prod,managed-by=aws-privateca-issuer,team=b", tags.String())
+
+	assert.EqualError(t, tags.Set("managed-by"), `tag "managed-by" is not of the form key=value`)
+}
+
+func TestParseAllowedCAArns(t *testing.T) {
+	patterns, err := parseAllowedCAArns(" arn:aws:acm-pca:us-east-1:111122223333:certificate-authority/ca1,arn:aws:acm-pca:*:444455556666:certificate-authority/*,")
+	require.NoError(t, err)
+	assert.Equal(t, []string{
+		"arn:aws:acm-pca:us-east-1:111122223333:certificate-authority/ca1",
+		"arn:aws:acm-pca:*:444455556666:certificate-authority/*",
+	}, patterns)
+
+	patterns, err = parseAllowedCAArns("")
+	require.NoError(t, err)
+	assert.Nil(t, patterns)
+
+	_, err = parseAllowedCAArns("arn:aws:acm-pca:[us-east-1")
+	assert.ErrorContains(t, err, `"arn:aws:acm-pca:[us-east-1" is not a valid pattern`)
+}
+
+func TestLeaderElectionConfig(t *testing.T) {
+	tests := map[string]struct {
+		config        leaderElectionConfig
+		expectedError string
+	}{
+		"defaults": {
+			config: leaderElectionConfig{leaseDuration: 15 * time.Second, renewDeadline: 10 * time.Second, retryPeriod: 2 * time.Second},
+		},
+		"enabled-in-namespace": {
+			config: leaderElectionConfig{
+				enabled:       true,
+				namespace:     "aws-privateca-issuer",
+				leaseDuration: time.Minute,
+				renewDeadline: 40 * time.Second,
+				retryPeriod:   5 * time.Second,
+			},
+		},
+		"failure-renew-deadline-exceeds-lease-duration": {
+			config:        leaderElectionConfig{leaseDuration: 10 * time.Second, renewDeadline: 10 * time.Second, retryPeriod: 2 * time.Second},
+			expectedError: "leader election lease duration 10s must be greater than the renew deadline 10s",
+		},
+		"failure-retry-period-exceeds-renew-deadline": {
+			config:        leaderElectionConfig{leaseDuration: 15 * time.Second, renewDeadline: 10 * time.Second, retryPeriod: 9 * time.Second},
+			expectedError: "leader election renew deadline 10s must be greater than 1.2 times the retry period 9s",
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			var opts ctrl.Options
+			err := tc.config.apply(&opts)
+			if tc.expectedError != "" {
+				assert.EqualError(t, err, tc.expectedError)
+				return
+			}
+			require.NoError(t, err)
+
+			assert.Equal(t, tc.config.enabled, opts.LeaderElection)
+			assert.Equal(t, leaderElectionID, opts.LeaderElectionID)
+			assert.Equal(t, tc.config.namespace, opts.LeaderElectionNamespace)
+			require.NotNil(t, opts.LeaseDuration)
+			assert.Equal(t, tc.config.leaseDuration, *opts.LeaseDuration)
+			require.NotNil(t, opts.RenewDeadline)
+			assert.Equal(t, tc.config.renewDeadline, *opts.RenewDeadline)
+			require.NotNil(t, opts.RetryPeriod)
+			assert.Equal(t, tc.config.retryPeriod, *opts.RetryPeriod)
+		})
+	}
+}
+
+func TestMetricsOptions(t *testing.T) {
+	tests := map[string]struct {
+		bindAddress string
+		secure      bool
+		debug       bool
+	}{
+		"default": {
+			bindAddress: ":8080",
+		},
+		"custom-port": {
+			bindAddress: ":9090",
+		},
+		"secure": {
+			bindAddress: ":8443",
+			secure:      true,
+		},
+		"secure-debug": {
+			bindAddress: ":8443",
+			secure:      true,
+			debug:       true,
+		},
+		"disabled": {
+			bindAddress: "0",
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			opts := metricsOptions(tc.bindAddress, tc.secure, tc.debug)
+			assert.Equal(t, tc.bindAddress, opts.BindAddress)
+			assert.Equal(t, tc.secure, opts.SecureServing)
+			assert.Equal(t, tc.secure, opts.FilterProvider != nil, "expected authn/authz only for secure serving")
+			if tc.debug {
+				assert.Contains(t, opts.ExtraHandlers, "/debug/provisioners")
+			} else {
+				assert.Empty(t, opts.ExtraHandlers)
+			}
+		})
+	}
+}
+
+func TestValidatePollIntervals(t *testing.T) {
+	tests := map[string]struct {
+		min, max      time.Duration
+		expectedError string
+	}{
+		"unbounded": {},
+		"bounded": {
+			min: 5 * time.Second,
+			max: time.Minute,
+		},
+		"min-only": {
+			min: 5 * time.Second,
+		},
+		"failure-min-exceeds-max": {
+			min:           time.Minute,
+			max:           5 * time.Second,
+			expectedError: "minimum poll interval 1m0s must not be greater than the maximum 5s",
+		},
+		"failure-negative": {
+			max:           -time.Second,
+			expectedError: "poll intervals 0s and -1s must not be negative",
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			err := validatePollIntervals(tc.min, tc.max)
+			if tc.expectedError != "" {
+				assert.EqualError(t, err, tc.expectedError)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestSetGracefulShutdownTimeout(t *testing.T) {
+	tests := map[string]struct {
+		timeout       time.Duration
+		expectedError string
+	}{
+		"default": {
+			timeout: 30 * time.Second,
+		},
+		"no-wait": {
+			timeout: 0,
+		},
+		"failure-negative": {
+			timeout:       -time.Second,
+			expectedError: "graceful shutdown timeout -1s must not be negative",
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			var opts ctrl.Options
+			err := setGracefulShutdownTimeout(&opts, tc.timeout)
+			if tc.expectedError != "" {
+				assert.EqualError(t, err, tc.expectedError)
+				assert.Nil(t, opts.GracefulShutdownTimeout)
+				return
+			}
+			require.NoError(t, err)
+			require.NotNil(t, opts.GracefulShutdownTimeout)
+			assert.Equal(t, tc.timeout, *opts.GracefulShutdownTimeout)
+		})
+	}
+}
@@ -3,4 +3,7 @@ package injections
 var (
 	// PlugInVersion is the git version of the cert-manager plugin
 	PlugInVersion string
+
+	// GitCommit is the git commit the cert-manager plugin was built from
+	GitCommit string
 )
@@ -31,12 +31,234 @@ type AWSPCAIssuerSpec struct {
 
 	// Specifies the ARN of the PCA resource
 	Arn string `json:"arn,omitempty"`
+	// Specifies a key of a ConfigMap or Secret holding the ARN of the PCA
+	// resource, instead of Arn
+	// +optional
+	ArnFrom *AWSArnSource `json:"arnFrom,omitempty"`
+	// Specifies the ARNs of CAs to fail over to, in order of priority, when the
+	// CA of Arn is unavailable or cannot issue certificates. Failover CAs may be
+	// in other regions of the same partition.
+	// +optional
+	ArnFailover []string `json:"arnFailover,omitempty"`
+	// Maps regions to the ARNs of CAs in them. CertificateRequests select the
+	// CA of a region with the aws-privateca-issuer/region annotation, and are
+	// issued by the CA of Arn without it.
+	// +optional
+	RegionalArns map[string]string `json:"regionalArns,omitempty"`
 	// Should contain the AWS region if it cannot be inferred
 	// +optional
 	Region string `json:"region,omitempty"`
+	// Specifies a custom https URL to reach PCA at, e.g. an interface VPC
+	// endpoint. Requests are still signed for the region
+	// +optional
+	Endpoint string `json:"endpoint,omitempty"`
+	// Specifies whether to use the FIPS endpoint of PCA in the region. Setting
+	// AWS_USE_FIPS_ENDPOINT=true on the controller has the same effect
+	// +optional
+	UseFIPSEndpoint bool `json:"useFIPSEndpoint,omitempty"`
 	// Needs to be specified if you want to authorize with AWS using an access and secret key
 	// +optional
 	SecretRef AWSCredentialsSecretReference `json:"secretRef,omitempty"`
+	// Specifies a Secret with an X.509 client certificate to obtain
+	// credentials through IAM Roles Anywhere, e.g. outside of EKS. It cannot
+	// be combined with the access key of SecretRef
+	// +optional
+	RolesAnywhere *AWSRolesAnywhere `json:"rolesAnywhere,omitempty"`
+	// Specifies a named profile of the shared AWS config and credentials
+	// files of the controller, e.g. when running it locally. It cannot be
+	// combined with the access key of SecretRef or with RolesAnywhere
+	// +optional
+	Profile string `json:"profile,omitempty"`
+	// Specifies an IAM role to assume before calling PCA, for example when the
+	// CA lives in a different AWS account than the base credentials
+	// +optional
+	AssumeRole *AWSAssumeRole `json:"assumeRole,omitempty"`
+	// Specifies the ARN of the PCA certificate template used to issue certificates.
+	// If omitted, the template is derived from the usages of the CertificateRequest
+	// +optional
+	TemplateArn string `json:"templateArn,omitempty"`
+	// Specifies extensions PCA adds to issued certificates through the
+	// ApiPassthrough of IssueCertificate. The APIPassthrough variant of the
+	// derived template is used, so TemplateArn, if set, must be an
+	// APIPassthrough template
+	// +optional
+	APIPassthrough *AWSPCAAPIPassthrough `json:"apiPassthrough,omitempty"`
+	// Specifies the path length constraint of issued subordinate CA
+	// certificates, selecting the SubordinateCACertificate_PathLen template
+	// of that length. Defaults to 0
+	// +kubebuilder:validation:Minimum=0
+	// +kubebuilder:validation:Maximum=3
+	// +optional
+	PathLength *int32 `json:"pathLength,omitempty"`
+	// Specifies the validity of issued certificates when the CertificateRequest
+	// does not request a duration
+	// +optional
+	DefaultValidity *metav1.Duration `json:"defaultValidity,omitempty"`
+	// Specifies the maximum validity of issued certificates. Longer durations
+	// requested by a CertificateRequest are clamped to this value
+	// +optional
+	MaxValidity *metav1.Duration `json:"maxValidity,omitempty"`
+	// Specifies the unit PCA expresses the validity of issued certificates
+	// in. DAYS, MONTHS and YEARS round the duration to the nearest whole
+	// unit without exceeding maxValidity, while ABSOLUTE and END_DATE set
+	// the exact expiration. If omitted, whole days are expressed in DAYS and
+	// other durations as ABSOLUTE
+	// +kubebuilder:validation:Enum=ABSOLUTE;DAYS;END_DATE;MONTHS;YEARS
+	// +optional
+	ValidityPeriodType string `json:"validityPeriodType,omitempty"`
+	// Specifies tags to apply when issuing certificates. PCA does not tag
+	// individual certificates, so the tags are applied to the CA
+	// +optional
+	Tags map[string]string `json:"tags,omitempty"`
+	// Specifies whether to return the full CA certificate chain up to the
+	// root, as returned by GetCertificateAuthorityCertificate, as the CA of
+	// issued certificates. By default only the root certificate is returned
+	// +optional
+	FullChain bool `json:"fullChain,omitempty"`
+	// Specifies which CA certificates are returned as the CA of issued
+	// certificates: RootOnly returns the root certificate, CAOnly the
+	// issuing CA certificate, and FullChain the issuing CA certificate
+	// followed by its chain up to the root. It takes precedence over
+	// FullChain; if omitted, FullChain selects FullChain and RootOnly otherwise
+	// +kubebuilder:validation:Enum=CAOnly;FullChain;RootOnly
+	// +optional
+	ChainMode string `json:"chainMode,omitempty"`
+	// Specifies which certificates are returned as the issued certificate:
+	// LeafAndIntermediates returns the certificate followed by the
+	// intermediates of its chain, LeafOnly only the certificate, and
+	// LeafAndFullChain the certificate followed by its chain including the
+	// root. Defaults to LeafAndIntermediates
+	// +kubebuilder:validation:Enum=LeafAndFullChain;LeafAndIntermediates;LeafOnly
+	// +optional
+	CertificateChain string `json:"certificateChain,omitempty"`
+	// Specifies how long the CA certificate chain returned with fullChain is
+	// cached. By default it is not cached and every issuance calls
+	// GetCertificateAuthorityCertificate, so a rotated CA is picked up at once
+	// +optional
+	CACertificateCacheTTL *metav1.Duration `json:"caCertificateCacheTTL,omitempty"`
+	// Specifies whether to hold back signing until
+	// GetCertificateAuthorityCertificate returns the chain of the CA, e.g. for
+	// a newly activated subordinate CA, so that no certificates are handed out
+	// without a valid chain. CertificateRequests are requeued meanwhile
+	// +optional
+	WaitForCAChain bool `json:"waitForCAChain,omitempty"`
+	// Specifies how long to poll PCA for a certificate that is still being
+	// issued before requeueing the CertificateRequest. Polling blocks a
+	// reconcile, so by default the CertificateRequest is requeued at once
+	// +optional
+	CertificateWaitTimeout *metav1.Duration `json:"certificateWaitTimeout,omitempty"`
+	// Specifies the domains CertificateRequests may request DNS names in. A
+	// domain allows itself and all of its subdomains, or only its subdomains
+	// if it starts with "*.". Requests for other DNS names are denied. All DNS
+	// names are allowed if empty
+	// +optional
+	AllowedDomains []string `json:"allowedDomains,omitempty"`
+	// Specifies the namespaces CertificateRequests may be created in, e.g. to
+	// restrict an AWSPCAClusterIssuer. All namespaces are allowed if empty
+	// +optional
+	AllowedNamespaces []string `json:"allowedNamespaces,omitempty"`
+	// Specifies the S3 bucket and schedule of audit reports of the CA. Audit
+	// reports are only generated if it is set
+	// +optional
+	AuditReport *AWSPCAAuditReport `json:"auditReport,omitempty"`
+}
+
+// AWSAssumeRole defines the IAM role assumed by the issuer through STS
+type AWSAssumeRole struct {
+	// Specifies the ARN of the IAM role to assume
+	RoleARN string `json:"roleARN"`
+	// Specifies the external ID required by the role's trust policy
+	// +optional
+	ExternalID string `json:"externalID,omitempty"`
+	// Specifies the session name used when assuming the role
+	// +optional
+	SessionName string `json:"sessionName,omitempty"`
+}
+
+// AWSPCAAPIPassthrough defines the extensions PCA adds to the certificates of
+// an issuer, and the attributes overriding their subject
+type AWSPCAAPIPassthrough struct {
+	// Specifies the OIDs of the certificate policies of issued certificates,
+	// e.g. 2.23.140.1.2.1
+	// +optional
+	CertificatePolicies []string `json:"certificatePolicies,omitempty"`
+	// Specifies extensions that PCA does not model, by their OID and value
+	// +optional
+	CustomExtensions []AWSPCACustomExtension `json:"customExtensions,omitempty"`
+	// Specifies attributes of the subject of issued certificates that take
+	// precedence over those of the CSR
+	// +optional
+	Subject *AWSPCASubject `json:"subject,omitempty"`
+}
+
+// AWSPCASubject defines subject attributes of issued certificates. Attributes
+// that are not set are taken from the CSR.
+type AWSPCASubject struct {
+	// Specifies the organization (O), e.g. Example Corp
+	// +kubebuilder:validation:MaxLength=64
+	// +optional
+	Organization string `json:"organization,omitempty"`
+	// Specifies the organizational unit (OU)
+	// +kubebuilder:validation:MaxLength=64
+	// +optional
+	OrganizationalUnit string `json:"organizationalUnit,omitempty"`
+	// Specifies the two letter country code (C), e.g. US
+	// +kubebuilder:validation:Pattern=`^[A-Za-z]{2}$`
+	// +optional
+	Country string `json:"country,omitempty"`
+	// Specifies the state or province (ST)
+	// +kubebuilder:validation:MaxLength=128
+	// +optional
+	State string `json:"state,omitempty"`
+	// Specifies the locality (L), e.g. Seattle
+	// +kubebuilder:validation:MaxLength=128
+	// +optional
+	Locality string `json:"locality,omitempty"`
+}
+
+// AWSPCAAuditReport defines how audit reports of the certificates issued by
+// the CA are generated
+type AWSPCAAuditReport struct {
+	// Specifies the S3 bucket PCA writes audit reports to. Its bucket policy
+	// must allow PCA to write to it
+	S3BucketName string `json:"s3BucketName"`
+	// Specifies the format of audit reports, JSON by default
+	// +kubebuilder:validation:Enum=JSON;CSV
+	// +optional
+	Format string `json:"format,omitempty"`
+	// Specifies how often an audit report is generated. Without an interval,
+	// reports are only generated when the aws-privateca-issuer/audit-report
+	// annotation of the issuer is set to a new value
+	// +optional
+	Interval *metav1.Duration `json:"interval,omitempty"`
+}
+
+// AWSPCAAuditReportStatus describes the last audit report generated for the CA
+type AWSPCAAuditReportStatus struct {
+	// ID is the ID of the audit report
+	ID string `json:"id"`
+	// S3Bucket is the S3 bucket the audit report is written to
+	S3Bucket string `json:"s3Bucket"`
+	// S3Key is the key of the audit report in the S3 bucket
+	S3Key string `json:"s3Key"`
+	// CreatedTime is when the audit report was requested. PCA writes the
+	// report to the bucket asynchronously
+	CreatedTime metav1.Time `json:"createdTime"`
+	// Request is the value of the aws-privateca-issuer/audit-report
+	// annotation when the report was requested
+	// +optional
+	Request string `json:"request,omitempty"`
+}
+
+// AWSPCACustomExtension defines an X.509 extension of issued certificates
+type AWSPCACustomExtension struct {
+	// Specifies the OID of the extension, e.g. 1.3.6.1.4.1.99999.1
+	ObjectIdentifier string `json:"objectIdentifier"`
+	// Specifies the base64 encoded DER value of the extension
+	Value string `json:"value"`
+	// Specifies whether the extension is critical
+	// +optional
+	Critical bool `json:"critical,omitempty"`
 }
 
 // AWSCredentialsSecretReference defines the secret used by the issuer
@@ -48,6 +270,34 @@ type AWSCredentialsSecretReference struct {
 	// Specifies the secret key where the AWS Secret Access Key exists
 	// +optional
 	SecretAccessKeySelector v1.SecretKeySelector `json:"secretAccessKeySelector,omitempty"`
+	// Specifies the secret key where the AWS Session Token of temporary
+	// credentials exists. The AWS_SESSION_TOKEN key is read if it is not set
+	// +optional
+	SessionTokenSelector v1.SecretKeySelector `json:"sessionTokenSelector,omitempty"`
+}
+
+// AWSArnSource selects the ConfigMap or Secret key holding the ARN of a CA.
+// Exactly one of ConfigMapKeyRef and SecretKeyRef must be set.
+type AWSArnSource struct {
+	// +optional
+	ConfigMapKeyRef *AWSKeySelector `json:"configMapKeyRef,omitempty"`
+	// +optional
+	SecretKeyRef *AWSKeySelector `json:"secretKeyRef,omitempty"`
+}
+
+// AWSKeySelector selects a key of a ConfigMap or Secret in a namespace
+type AWSKeySelector struct {
+	Name      string `json:"name"`
+	Namespace string `json:"namespace"`
+	Key       string `json:"key"`
+}
+
+// AWSRolesAnywhere defines the Secret used to obtain credentials through IAM
+// Roles Anywhere. The Secret contains the client certificate and its chain in
+// tls.crt, the private key in tls.key, like the Secrets of cert-manager, as
+// well as the trustAnchorArn, profileArn and roleArn keys
+type AWSRolesAnywhere struct {
+	SecretRef v1.SecretReference `json:"secretRef"`
 }
 
 // AWSPCAIssuerStatus defines the observed state of AWSPCAIssuer
@@ -56,11 +306,147 @@ type AWSPCAIssuerStatus struct {
 	// Important: Run "make" to regenerate code after modifying this file
 
 	Conditions []metav1.Condition `json:"conditions,omitempty"`
+
+	// LastIssuedTime is when a certificate was last issued for a
+	// CertificateRequest of the issuer. It is updated at most once a minute.
+	// +optional
+	LastIssuedTime *metav1.Time `json:"lastIssuedTime,omitempty"`
+
+	// KeyAlgorithm is the algorithm of the key of the CA of the issuer, as
+	// last described by PCA. It is informational only.
+	// +optional
+	KeyAlgorithm string `json:"keyAlgorithm,omitempty"`
+
+	// SigningAlgorithm is the algorithm the CA of the issuer signs
+	// certificates with, as last described by PCA. It is informational only.
+	// +optional
+	SigningAlgorithm string `json:"signingAlgorithm,omitempty"`
+
+	// AuditReport is the last audit report generated for the CA of the issuer
+	// +optional
+	AuditReport *AWSPCAAuditReportStatus `json:"auditReport,omitempty"`
 }
 
 // ConditionTypeReady is the default condition type for the CRs
 const ConditionTypeReady = "Ready"
 
+// ConditionTypeConnected reflects whether the last AWS API call made for an
+// issuer succeeded, independent of whether its spec is valid
+const ConditionTypeConnected = "Connected"
+
+// ConditionTypeIssuanceQuota is True while the number of certificates issued
+// for an issuer approaches the issuance quota threshold of the controller
+const ConditionTypeIssuanceQuota = "IssuanceQuota"
+
+// ConditionReason is the reason of a condition, or of an event, of an issuer,
+// which tooling can match on
+type ConditionReason string
+
+// Reasons of the Ready condition
+const (
+	ReasonVerified                 ConditionReason = "Verified"
+	ReasonValidation               ConditionReason = "Validation"
+	ReasonInvalidArnFrom           ConditionReason = "InvalidArnFrom"
+	ReasonRegionMismatch           ConditionReason = "RegionMismatch"
+	ReasonCANotAllowed             ConditionReason = "CANotAllowed"
+	ReasonError                    ConditionReason = "Error"
+	ReasonNoCredentials            ConditionReason = "NoCredentials"
+	ReasonInvalidCredentialsSecret ConditionReason = "InvalidCredentialsSecret"
+	ReasonTagsOnSharedCA           ConditionReason = "TagsOnSharedCA"
+	ReasonCANotActive              ConditionReason = "CANotActive"
+)
+
+// Reasons of both the Ready and the Connected condition
+const (
+	ReasonExpiredCredentials ConditionReason = "ExpiredCredentials"
+	ReasonCAUnreachable      ConditionReason = "CAUnreachable"
+)
+
+// Reasons of the Connected condition
+const (
+	ReasonConnected ConditionReason = "Connected"
+	ReasonAPIError  ConditionReason = "APIError"
+)
+
+// Reasons of the IssuanceQuota condition
+const (
+	ReasonIssuanceQuotaApproaching ConditionReason = "IssuanceQuotaApproaching"
+	ReasonWithinIssuanceQuota      ConditionReason = "WithinIssuanceQuota"
+)
+
+// Reasons of events of issuers
+const (
+	ReasonUntagged                 ConditionReason = "Untagged"
+	ReasonUntagFailed              ConditionReason = "UntagFailed"
+	ReasonAuditReportCreated       ConditionReason = "AuditReportCreated"
+	ReasonAuditReportFailed        ConditionReason = "AuditReportFailed"
+	ReasonAuditReportNotConfigured ConditionReason = "AuditReportNotConfigured"
+)
+
+// CertificateRequestReason is the reason of the Ready condition, or of an
+// event, of a CertificateRequest for an issuer, which tooling can match on.
+// The controller sets the reasons of cert-manager, e.g. Pending or Failed,
+// otherwise.
+type CertificateRequestReason string
+
+// Reasons of the Ready condition of CertificateRequests
+const (
+	// ReasonDryRunValidated is the reason of CertificateRequests that were
+	// validated by a dry run instead of being issued
+	ReasonDryRunValidated CertificateRequestReason = "DryRunValidated"
+
+	// ReasonWaitingForCAChain is the reason of CertificateRequests that are
+	// held back until PCA returns the chain of the CA of their issuer
+	ReasonWaitingForCAChain CertificateRequestReason = "WaitingForCAChain"
+
+	// ReasonThrottled is the reason of CertificateRequests that are requeued
+	// because PCA throttles requests
+	ReasonThrottled CertificateRequestReason = "Throttled"
+)
+
+// Reasons of the InvalidRequest condition of CertificateRequests that were
+// rejected before PCA was called. Their Ready reason is Failed, as cert-manager
+// only backs off and recreates requests that failed with that reason.
+const (
+	// ReasonDeniedByPolicy is the reason of CertificateRequests that the
+	// policy of their issuer does not allow
+	ReasonDeniedByPolicy CertificateRequestReason = "DeniedByPolicy"
+
+	// ReasonInvalidCSR is the reason of CertificateRequests whose CSR is
+	// malformed or cannot be issued by PCA
+	ReasonInvalidCSR CertificateRequestReason = "InvalidCSR"
+
+	// ReasonUsageNotAllowed is the reason of CertificateRequests asking for a
+	// usage the PCA template of their issuer does not have
+	ReasonUsageNotAllowed CertificateRequestReason = "UsageNotAllowed"
+)
+
+// Reasons of events of CertificateRequests
+const (
+	// ReasonIssuanceTimeout is the reason of the event of CertificateRequests
+	// whose certificate PCA did not issue within the issuance timeout. Their
+	// Ready reason is Failed, as cert-manager only recreates requests that
+	// failed with that reason.
+	ReasonIssuanceTimeout CertificateRequestReason = "IssuanceTimeout"
+
+	// ReasonForceReissue is the reason of the event of CertificateRequests
+	// whose certificate is reissued for a new force reissue nonce
+	ReasonForceReissue CertificateRequestReason = "ForceReissue"
+
+	// ReasonValidityClamped is the reason of the event of CertificateRequests
+	// whose certificate was issued with the maxValidity of their issuer
+	// instead of the longer validity they requested
+	ReasonValidityClamped CertificateRequestReason = "ValidityClamped"
+
+	// ReasonRevoked is the reason of the event of deleted CertificateRequests
+	// whose certificate was revoked
+	ReasonRevoked CertificateRequestReason = "Revoked"
+
+	// ReasonRevocationFailed is the reason of the event of deleted
+	// CertificateRequests whose certificate could not be revoked
+	ReasonRevocationFailed CertificateRequestReason = "RevocationFailed"
+)
+
 // +kubebuilder:object:root=true
 // +kubebuilder:subresource:status
 
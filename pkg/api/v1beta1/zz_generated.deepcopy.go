@@ -26,12 +26,53 @@ import (
 	"k8s.io/apimachinery/pkg/runtime"
 )
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *AWSArnSource) DeepCopyInto(out *AWSArnSource) {
+	*out = *in
+	if in.ConfigMapKeyRef != nil {
+		in, out := &in.ConfigMapKeyRef, &out.ConfigMapKeyRef
+		*out = new(AWSKeySelector)
+		**out = **in
+	}
+	if in.SecretKeyRef != nil {
+		in, out := &in.SecretKeyRef, &out.SecretKeyRef
+		*out = new(AWSKeySelector)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new AWSArnSource.
+func (in *AWSArnSource) DeepCopy() *AWSArnSource {
+	if in == nil {
+		return nil
+	}
+	out := new(AWSArnSource)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *AWSAssumeRole) DeepCopyInto(out *AWSAssumeRole) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new AWSAssumeRole.
+func (in *AWSAssumeRole) DeepCopy() *AWSAssumeRole {
+	if in == nil {
+		return nil
+	}
+	out := new(AWSAssumeRole)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *AWSCredentialsSecretReference) DeepCopyInto(out *AWSCredentialsSecretReference) {
 	*out = *in
 	out.SecretReference = in.SecretReference
 	in.AccessKeyIDSelector.DeepCopyInto(&out.AccessKeyIDSelector)
 	in.SecretAccessKeySelector.DeepCopyInto(&out.SecretAccessKeySelector)
+	in.SessionTokenSelector.DeepCopyInto(&out.SessionTokenSelector)
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new AWSCredentialsSecretReference.
@@ -44,6 +85,82 @@ func (in *AWSCredentialsSecretReference) DeepCopy() *AWSCredentialsSecretReferen
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *AWSKeySelector) DeepCopyInto(out *AWSKeySelector) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new AWSKeySelector.
+func (in *AWSKeySelector) DeepCopy() *AWSKeySelector {
+	if in == nil {
+		return nil
+	}
+	out := new(AWSKeySelector)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *AWSPCAAPIPassthrough) DeepCopyInto(out *AWSPCAAPIPassthrough) {
+	*out = *in
+	if in.CertificatePolicies != nil {
+		in, out := &in.CertificatePolicies, &out.CertificatePolicies
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.CustomExtensions != nil {
+		in, out := &in.CustomExtensions, &out.CustomExtensions
+		*out = make([]AWSPCACustomExtension, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new AWSPCAAPIPassthrough.
+func (in *AWSPCAAPIPassthrough) DeepCopy() *AWSPCAAPIPassthrough {
+	if in == nil {
+		return nil
+	}
+	out := new(AWSPCAAPIPassthrough)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *AWSPCAAuditReport) DeepCopyInto(out *AWSPCAAuditReport) {
+	*out = *in
+	if in.Interval != nil {
+		in, out := &in.Interval, &out.Interval
+		*out = new(v1.Duration)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new AWSPCAAuditReport.
+func (in *AWSPCAAuditReport) DeepCopy() *AWSPCAAuditReport {
+	if in == nil {
+		return nil
+	}
+	out := new(AWSPCAAuditReport)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *AWSPCAAuditReportStatus) DeepCopyInto(out *AWSPCAAuditReportStatus) {
+	*out = *in
+	in.CreatedTime.DeepCopyInto(&out.CreatedTime)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new AWSPCAAuditReportStatus.
+func (in *AWSPCAAuditReportStatus) DeepCopy() *AWSPCAAuditReportStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(AWSPCAAuditReportStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *AWSPCAClusterIssuer) DeepCopyInto(out *AWSPCAClusterIssuer) {
 	*out = *in
@@ -103,6 +220,21 @@ func (in *AWSPCAClusterIssuerList) DeepCopyObject() runtime.Object {
 	return nil
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *AWSPCACustomExtension) DeepCopyInto(out *AWSPCACustomExtension) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new AWSPCACustomExtension.
+func (in *AWSPCACustomExtension) DeepCopy() *AWSPCACustomExtension {
+	if in == nil {
+		return nil
+	}
+	out := new(AWSPCACustomExtension)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *AWSPCAIssuer) DeepCopyInto(out *AWSPCAIssuer) {
 	*out = *in
@@ -165,7 +297,86 @@ func (in *AWSPCAIssuerList) DeepCopyObject() runtime.Object {
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *AWSPCAIssuerSpec) DeepCopyInto(out *AWSPCAIssuerSpec) {
 	*out = *in
+	if in.ArnFrom != nil {
+		in, out := &in.ArnFrom, &out.ArnFrom
+		*out = new(AWSArnSource)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.ArnFailover != nil {
+		in, out := &in.ArnFailover, &out.ArnFailover
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.RegionalArns != nil {
+		in, out := &in.RegionalArns, &out.RegionalArns
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
 	in.SecretRef.DeepCopyInto(&out.SecretRef)
+	if in.RolesAnywhere != nil {
+		in, out := &in.RolesAnywhere, &out.RolesAnywhere
+		*out = new(AWSRolesAnywhere)
+		**out = **in
+	}
+	if in.AssumeRole != nil {
+		in, out := &in.AssumeRole, &out.AssumeRole
+		*out = new(AWSAssumeRole)
+		**out = **in
+	}
+	if in.APIPassthrough != nil {
+		in, out := &in.APIPassthrough, &out.APIPassthrough
+		*out = new(AWSPCAAPIPassthrough)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.PathLength != nil {
+		in, out := &in.PathLength, &out.PathLength
+		*out = new(int32)
+		**out = **in
+	}
+	if in.DefaultValidity != nil {
+		in, out := &in.DefaultValidity, &out.DefaultValidity
+		*out = new(v1.Duration)
+		**out = **in
+	}
+	if in.MaxValidity != nil {
+		in, out := &in.MaxValidity, &out.MaxValidity
+		*out = new(v1.Duration)
+		**out = **in
+	}
+	if in.Tags != nil {
+		in, out := &in.Tags, &out.Tags
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	if in.CACertificateCacheTTL != nil {
+		in, out := &in.CACertificateCacheTTL, &out.CACertificateCacheTTL
+		*out = new(v1.Duration)
+		**out = **in
+	}
+	if in.CertificateWaitTimeout != nil {
+		in, out := &in.CertificateWaitTimeout, &out.CertificateWaitTimeout
+		*out = new(v1.Duration)
+		**out = **in
+	}
+	if in.AllowedDomains != nil {
+		in, out := &in.AllowedDomains, &out.AllowedDomains
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.AllowedNamespaces != nil {
+		in, out := &in.AllowedNamespaces, &out.AllowedNamespaces
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.AuditReport != nil {
+		in, out := &in.AuditReport, &out.AuditReport
+		*out = new(AWSPCAAuditReport)
+		(*in).DeepCopyInto(*out)
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new AWSPCAIssuerSpec.
@@ -188,6 +399,15 @@ func (in *AWSPCAIssuerStatus) DeepCopyInto(out *AWSPCAIssuerStatus) {
 			(*in)[i].DeepCopyInto(&(*out)[i])
 		}
 	}
+	if in.LastIssuedTime != nil {
+		in, out := &in.LastIssuedTime, &out.LastIssuedTime
+		*out = (*in).DeepCopy()
+	}
+	if in.AuditReport != nil {
+		in, out := &in.AuditReport, &out.AuditReport
+		*out = new(AWSPCAAuditReportStatus)
+		(*in).DeepCopyInto(*out)
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new AWSPCAIssuerStatus.
@@ -199,3 +419,34 @@ func (in *AWSPCAIssuerStatus) DeepCopy() *AWSPCAIssuerStatus {
 	in.DeepCopyInto(out)
 	return out
 }
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *AWSPCASubject) DeepCopyInto(out *AWSPCASubject) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new AWSPCASubject.
+func (in *AWSPCASubject) DeepCopy() *AWSPCASubject {
+	if in == nil {
+		return nil
+	}
+	out := new(AWSPCASubject)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *AWSRolesAnywhere) DeepCopyInto(out *AWSRolesAnywhere) {
+	*out = *in
+	out.SecretRef = in.SecretRef
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new AWSRolesAnywhere.
+func (in *AWSRolesAnywhere) DeepCopy() *AWSRolesAnywhere {
+	if in == nil {
+		return nil
+	}
+	out := new(AWSRolesAnywhere)
+	in.DeepCopyInto(out)
+	return out
+}
@@ -0,0 +1,119 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package aws
+
+import (
+	"encoding/json"
+	"net/http"
+	"sort"
+
+	"k8s.io/apimachinery/pkg/types"
+)
+
+// CachedProvisionersPath is the path of CachedProvisionersHandler
+const CachedProvisionersPath = "/debug/provisioners"
+
+// CachedProvisioner describes the cached provisioner and client of an issuer.
+// It holds no credentials: the static credentials of a Secret are only known
+// by the fingerprint of the client key.
+type CachedProvisioner struct {
+	// Issuer is the namespace/name of the issuer, or only the name of a
+	// ClusterIssuer
+	Issuer string `json:"issuer"`
+	// Provisioner reports whether a provisioner is cached, i.e. whether the
+	// issuer can sign CertificateRequests without being reconciled again
+	Provisioner bool `json:"provisioner"`
+	// CAArn is the ARN of the CA of the cached provisioner
+	CAArn string `json:"caArn,omitempty"`
+	// Client is the key of the cached client, if any
+	Client *CachedClientKey `json:"client,omitempty"`
+}
+
+// CachedClientKey is the ClientKey of a cached client without the external ID
+type CachedClientKey struct {
+	Region                 string `json:"region"`
+	Endpoint               string `json:"endpoint,omitempty"`
+	UseFIPSEndpoint        bool   `json:"useFIPSEndpoint,omitempty"`
+	CredentialsFingerprint string `json:"credentialsFingerprint,omitempty"`
+	RoleARN                string `json:"roleArn,omitempty"`
+	SessionName            string `json:"sessionName,omitempty"`
+	Profile                string `json:"profile,omitempty"`
+}
+
+// CachedProvisioners lists the issuers with a cached provisioner or client,
+// sorted by issuer
+func CachedProvisioners() []CachedProvisioner {
+	cached := map[types.NamespacedName]*CachedProvisioner{}
+	entry := func(name types.NamespacedName) *CachedProvisioner {
+		if cached[name] == nil {
+			issuer := name.String()
+			if name.Namespace == "" {
+				issuer = name.Name
+			}
+			cached[name] = &CachedProvisioner{Issuer: issuer}
+		}
+		return cached[name]
+	}
+
+	collection.Range(func(key, value interface{}) bool {
+		e := entry(key.(types.NamespacedName))
+		e.Provisioner = true
+		if p, ok := value.(*PCAProvisioner); ok {
+			e.CAArn = p.arn
+		}
+		return true
+	})
+	clientKeys.Range(func(key, value interface{}) bool {
+		k := value.(ClientKey)
+		if _, ok := clients.Load(k); !ok {
+			return true
+		}
+		entry(key.(types.NamespacedName)).Client = &CachedClientKey{
+			Region:                 k.Region,
+			Endpoint:               k.Endpoint,
+			UseFIPSEndpoint:        k.UseFIPSEndpoint,
+			CredentialsFingerprint: k.CredentialsFingerprint,
+			RoleARN:                k.RoleARN,
+			SessionName:            k.SessionName,
+			Profile:                k.Profile,
+		}
+		return true
+	})
+
+	provisioners := make([]CachedProvisioner, 0, len(cached))
+	for _, e := range cached {
+		provisioners = append(provisioners, *e)
+	}
+	sort.Slice(provisioners, func(i, j int) bool {
+		return provisioners[i].Issuer < provisioners[j].Issuer
+	})
+	return provisioners
+}
+
+// CachedProvisionersHandler serves CachedProvisioners as JSON, e.g. to find out
+// whether an issuer still uses a client built from stale credentials
+func CachedProvisionersHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			w.Header().Set("Allow", http.MethodGet)
+			http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(map[string][]CachedProvisioner{"provisioners": CachedProvisioners()})
+	})
+}
@@ -0,0 +1,92 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package aws
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+func TestCachedProvisionersHandler(t *testing.T) {
+	ClearProvisioners()
+	t.Cleanup(ClearProvisioners)
+
+	issuer := types.NamespacedName{Namespace: "ns1", Name: "issuer1"}
+	clusterIssuer := types.NamespacedName{Name: "clusterissuer1"}
+	StoreProvisioner(issuer, &PCAProvisioner{arn: arn})
+	_, _, err := LoadClient(issuer, ClientKey{
+		Region:                 "us-east-1",
+		CredentialsFingerprint: "fake-fingerprint",
+		RoleARN:                "arn:aws:iam::111122223333:role/pca-signer",
+		ExternalID:             "fake-external-id",
+	}, func() (aws.Config, error) {
+		return aws.Config{Region: "us-east-1"}, nil
+	})
+	require.NoError(t, err)
+	// The provisioner of the ClusterIssuer was dropped, e.g. because its
+	// Secret was updated, but its client is still cached
+	_, _, err = LoadClient(clusterIssuer, ClientKey{Region: "eu-west-1", Profile: "dev"}, func() (aws.Config, error) {
+		return aws.Config{Region: "eu-west-1"}, nil
+	})
+	require.NoError(t, err)
+
+	server := httptest.NewServer(CachedProvisionersHandler())
+	defer server.Close()
+
+	resp, err := http.Get(server.URL + CachedProvisionersPath)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, "application/json", resp.Header.Get("Content-Type"))
+	assert.JSONEq(t, `{"provisioners": [
+		{
+			"issuer": "clusterissuer1",
+			"provisioner": false,
+			"client": {"region": "eu-west-1", "profile": "dev"}
+		},
+		{
+			"issuer": "ns1/issuer1",
+			"provisioner": true,
+			"caArn": "`+arn+`",
+			"client": {
+				"region": "us-east-1",
+				"credentialsFingerprint": "fake-fingerprint",
+				"roleArn": "arn:aws:iam::111122223333:role/pca-signer"
+			}
+		}
+	]}`, string(body))
+	assert.NotContains(t, string(body), "fake-external-id")
+
+	resp, err = http.Post(server.URL+CachedProvisionersPath, "application/json", nil)
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusMethodNotAllowed, resp.StatusCode)
+
+	// Invalidated issuers are no longer listed
+	InvalidateProvisioner(issuer)
+	InvalidateProvisioner(clusterIssuer)
+	assert.Empty(t, CachedProvisioners())
+}
@@ -19,165 +19,1748 @@ package aws
 import (
 	"bytes"
 	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
 	"crypto/md5"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/asn1"
+	"encoding/base64"
 	"encoding/pem"
+	"errors"
 	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"regexp"
+	"slices"
+	"strconv"
 	"strings"
 	"sync"
 	"time"
+	"unicode/utf8"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
+	awsarn "github.com/aws/aws-sdk-go-v2/aws/arn"
 	"github.com/aws/aws-sdk-go-v2/aws/middleware"
+	"github.com/aws/aws-sdk-go-v2/aws/retry"
 	"github.com/aws/aws-sdk-go-v2/service/acmpca"
 	acmpcatypes "github.com/aws/aws-sdk-go-v2/service/acmpca/types"
+	"github.com/aws/smithy-go"
+	smithymiddleware "github.com/aws/smithy-go/middleware"
+	smithyhttp "github.com/aws/smithy-go/transport/http"
 	injections "github.com/cert-manager/aws-privateca-issuer/pkg/api/injections"
+	api "github.com/cert-manager/aws-privateca-issuer/pkg/api/v1beta1"
 	cmapi "github.com/cert-manager/cert-manager/pkg/apis/certmanager/v1"
 	"github.com/go-logr/logr"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/types"
 )
 
 const DEFAULT_DURATION = 30 * 24 * 3600
 
+// SigningAlgorithmAnnotation can be set on a CertificateRequest to override the
+// signing algorithm of the CA
+const SigningAlgorithmAnnotation = "aws-privateca-issuer/signing-algorithm"
+
+// TemplateArnAnnotation can be set on a CertificateRequest to issue its
+// certificate with that PCA template instead of the template of the issuer or
+// the one derived from its usages
+const TemplateArnAnnotation = "aws-privateca-issuer/template-arn"
+
+// CertificateArnAnnotation is set on a CertificateRequest by Sign to record the
+// ARN of the certificate issued by PCA, unless another key is configured with
+// WithCertificateArnAnnotation
+const CertificateArnAnnotation = "aws-privateca-issuer/certificate-arn"
+
+// CAArnAnnotation is set on a CertificateRequest by Sign to record the ARN of
+// the CA that issued the certificate, which differs from the ARN of the issuer
+// after a failover
+const CAArnAnnotation = "aws-privateca-issuer/ca-arn"
+
+// ValidityClampedAnnotation is set on a CertificateRequest by Sign to the
+// validity its certificate was issued with, if the requested validity exceeded
+// the maxValidity of the issuer
+const ValidityClampedAnnotation = "aws-privateca-issuer/validity-clamped"
+
+// DryRunAnnotation can be set to "true" on a CertificateRequest to only
+// validate that it could be signed, without issuing a certificate
+const DryRunAnnotation = "aws-privateca-issuer/dry-run"
+
+// ForceReissueAnnotation can be set on a CertificateRequest to a nonce to have
+// its certificate issued again whenever the nonce changes. The nonce is part of
+// the idempotency token, so PCA issues a new certificate even within the
+// five minute window of the previous request.
+const ForceReissueAnnotation = "aws-privateca-issuer/force-reissue"
+
+// NotAfterAnnotation can be set on a CertificateRequest to an RFC3339 timestamp
+// the certificate must expire at, regardless of when it is issued. It takes
+// precedence over the requested duration, but is still limited by the maximum
+// validity of the issuer.
+const NotAfterAnnotation = "aws-privateca-issuer/not-after"
+
+// RegionAnnotation can be set on a CertificateRequest to have it issued by the
+// CA of that region in the regional ARNs of the issuer, see WithRegionalArns
+const RegionAnnotation = "aws-privateca-issuer/region"
+
+// Chain modes select the CA certificates Get returns as the CA of issued
+// certificates, see WithChainMode
+const (
+	// ChainModeRootOnly returns the root certificate of the chain
+	ChainModeRootOnly = "RootOnly"
+	// ChainModeCAOnly returns the certificate of the issuing CA
+	ChainModeCAOnly = "CAOnly"
+	// ChainModeFullChain returns the certificate of the issuing CA followed
+	// by its chain up to the root, see WithFullChain
+	ChainModeFullChain = "FullChain"
+)
+
+// Certificate chains select the certificates Get returns as the issued
+// certificate, see WithCertificateChain
+const (
+	// CertificateChainLeafAndIntermediates returns the certificate followed by
+	// the intermediates of its chain
+	CertificateChainLeafAndIntermediates = "LeafAndIntermediates"
+	// CertificateChainLeafOnly returns only the certificate
+	CertificateChainLeafOnly = "LeafOnly"
+	// CertificateChainLeafAndFullChain returns the certificate followed by its
+	// chain up to and including the root
+	CertificateChainLeafAndFullChain = "LeafAndFullChain"
+)
+
+var errInvalidSigningAlgorithm = errors.New("invalid signing algorithm")
+
+var errInvalidNotAfter = errors.New("invalid not-after")
+
+var errInvalidTemplateArn = errors.New("invalid template ARN")
+
+var errUnknownRegion = errors.New("issuer has no regional CA for region")
+
+// ErrInvalidRevocationReason is returned by ParseRevocationReason for reasons
+// PCA does not know
+var ErrInvalidRevocationReason = errors.New("invalid revocation reason")
+
+// extendedKeyUsages are only set by the end-entity templates of PCA
+var extendedKeyUsages = map[cmapi.KeyUsage]bool{
+	cmapi.UsageAny:             true,
+	cmapi.UsageServerAuth:      true,
+	cmapi.UsageClientAuth:      true,
+	cmapi.UsageCodeSigning:     true,
+	cmapi.UsageEmailProtection: true,
+	cmapi.UsageSMIME:           true,
+	cmapi.UsageIPsecEndSystem:  true,
+	cmapi.UsageIPsecTunnel:     true,
+	cmapi.UsageIPsecUser:       true,
+	cmapi.UsageTimestamping:    true,
+	cmapi.UsageOCSPSigning:     true,
+	cmapi.UsageMicrosoftSGC:    true,
+	cmapi.UsageNetscapeSGC:     true,
+}
+
+var oidExtensionBasicConstraints = asn1.ObjectIdentifier{2, 5, 29, 19}
+
+// ErrCANotActive is returned by Sign when the CA cannot issue certificates
+var ErrCANotActive = errors.New("certificate authority is not ACTIVE")
+
+// ErrCAChainNotPropagated is returned by Sign while PCA does not return the
+// chain of a subordinate CA yet, and the provisioner waits for it
+var ErrCAChainNotPropagated = errors.New("certificate chain of the CA is not available yet")
+
+// ErrInvalidCSR is returned by Sign when the CSR of a CertificateRequest is
+// malformed, or PCA cannot issue certificates for it
+var ErrInvalidCSR = errors.New("invalid CSR")
+
+// ErrUsageNotAllowed is returned by Sign when a CertificateRequest asks for a
+// usage that the PCA template of its certificate does not have, or that no
+// template supports for the kind of certificate requested
+var ErrUsageNotAllowed = errors.New("usage not allowed by the PCA template")
+
+// maxCSRSize is the largest CSR IssueCertificate accepts, in bytes
+const maxCSRSize = 32 * 1024
+
+// templateExtendedKeyUsages are the extended key usages of the certificates of
+// the PCA templates, by name as of templateName. CA certificates have none.
+// Templates that are not listed, such as the blank templates, take the
+// extended key usages from the CSR or the API passthrough and allow all.
+var templateExtendedKeyUsages = map[string][]cmapi.KeyUsage{
+	"RootCACertificate":              {},
+	"SubordinateCACertificate":       {},
+	"EndEntityCertificate":           {cmapi.UsageServerAuth, cmapi.UsageClientAuth},
+	"EndEntityServerAuthCertificate": {cmapi.UsageServerAuth},
+	"EndEntityClientAuthCertificate": {cmapi.UsageClientAuth},
+	"CodeSigningCertificate":         {cmapi.UsageCodeSigning},
+	"OCSPSigningCertificate":         {cmapi.UsageOCSPSigning},
+}
+
+// supportedRSAKeySizes and supportedECDSACurves are the keys PCA issues
+// certificates for
+var (
+	supportedRSAKeySizes = []int{2048, 3072, 4096}
+	supportedECDSACurves = []elliptic.Curve{elliptic.P256(), elliptic.P384(), elliptic.P521()}
+)
+
+// ErrDeniedByPolicy is returned by Sign when the issuer policy does not allow
+// the namespace, the DNS names or the SPIFFE IDs of the CertificateRequest
+var ErrDeniedByPolicy = errors.New("denied by issuer policy")
+
+// maxPathLength is the longest path length constraint of the subordinate CA
+// templates of PCA
+const maxPathLength = 3
+
+// caStatusCacheTTL is how long Sign relies on a previously described CA status
+const caStatusCacheTTL = time.Minute
+
+var templateArnPattern = regexp.MustCompile(`^arn:[a-z0-9-]+:acm-pca:::template/[A-Za-z0-9_]+/V[0-9]+$`)
+
+// Limits on tags imposed by AWS
+// @see: https://docs.aws.amazon.com/privateca/latest/APIReference/API_Tag.html
+const (
+	maxTags           = 50
+	maxTagKeyLength   = 128
+	maxTagValueLength = 256
+)
+
+// Limits on the ApiPassthrough of IssueCertificate imposed by AWS
+// @see: https://docs.aws.amazon.com/privateca/latest/APIReference/API_Extensions.html
+const (
+	maxCertificatePolicies        = 20
+	maxCustomExtensions           = 20
+	maxObjectIdentifierLength     = 64
+	maxCustomExtensionValueLength = 4096
+)
+
+var objectIdentifierPattern = regexp.MustCompile(`^[0-2]\.([0-9]|[1-3][0-9])(\.(0|[1-9][0-9]*))*$`)
+
+// Limits on the attributes of an ASN1Subject imposed by AWS
+// @see: https://docs.aws.amazon.com/privateca/latest/APIReference/API_ASN1Subject.html
+const (
+	maxSubjectOrganizationLength = 64
+	maxSubjectStateLength        = 128
+)
+
+var countryPattern = regexp.MustCompile(`^[A-Za-z]{2}$`)
+
+const (
+	spiffeScheme      = "spiffe"
+	maxSPIFFEIDLength = 2048
+)
+
+var (
+	spiffeTrustDomainPattern = regexp.MustCompile(`^[a-z0-9._-]+$`)
+	spiffePathSegmentPattern = regexp.MustCompile(`^[A-Za-z0-9._-]+$`)
+)
+
+// fipsRegions are the regions with a FIPS endpoint for PCA
+// @see: https://docs.aws.amazon.com/general/latest/gr/pca.html
+var fipsRegions = map[string]struct{}{
+	"ca-central-1":  {},
+	"ca-west-1":     {},
+	"us-east-1":     {},
+	"us-east-2":     {},
+	"us-west-1":     {},
+	"us-west-2":     {},
+	"us-gov-east-1": {},
+	"us-gov-west-1": {},
+}
+
+var tagPattern = regexp.MustCompile(`^[\p{L}\p{Z}\p{N}_.:/=+\-@]*$`)
+
 var collection = new(sync.Map)
 
-// GenericProvisioner abstracts over the Provisioner type for mocking purposes
-type GenericProvisioner interface {
-	Sign(ctx context.Context, cr *cmapi.CertificateRequest, log logr.Logger) ([]byte, []byte, error)
+// clients caches AWS configs and PCA clients by ClientKey, and clientKeys
+// records the key each issuer last loaded a client for
+var (
+	clients    = new(sync.Map)
+	clientKeys = new(sync.Map)
+)
+
+// ClientKey identifies the AWS configuration a PCA client is built from
+type ClientKey struct {
+	Region          string
+	Endpoint        string
+	UseFIPSEndpoint bool
+	// CredentialsFingerprint changes whenever the static credentials of an
+	// issuer change. It is empty when the default credential chain is used.
+	CredentialsFingerprint string
+	RoleARN                string
+	ExternalID             string
+	SessionName            string
+	// Profile is the shared config profile of the default credential chain.
+	// It is empty when static credentials are used.
+	Profile string
+}
+
+type cachedClient struct {
+	config aws.Config
+	pca    *acmpca.Client
+}
+
+// GenericProvisioner abstracts over the Provisioner type for mocking purposes
+type GenericProvisioner interface {
+	CAStatus(ctx context.Context) (acmpcatypes.CertificateAuthorityStatus, error)
+	Get(ctx context.Context, cr *cmapi.CertificateRequest, certArn string, log logr.Logger) ([]byte, []byte, error)
+	Sign(ctx context.Context, cr *cmapi.CertificateRequest, log logr.Logger) error
+	Revoke(ctx context.Context, cr *cmapi.CertificateRequest, serial string, reason acmpcatypes.RevocationReason, log logr.Logger) error
+}
+
+// acmPCAClient abstracts over the methods used from acmpca.Client
+type acmPCAClient interface {
+	acmpca.GetCertificateAPIClient
+	DescribeCertificateAuthority(ctx context.Context, params *acmpca.DescribeCertificateAuthorityInput, optFns ...func(*acmpca.Options)) (*acmpca.DescribeCertificateAuthorityOutput, error)
+	IssueCertificate(ctx context.Context, params *acmpca.IssueCertificateInput, optFns ...func(*acmpca.Options)) (*acmpca.IssueCertificateOutput, error)
+	TagCertificateAuthority(ctx context.Context, params *acmpca.TagCertificateAuthorityInput, optFns ...func(*acmpca.Options)) (*acmpca.TagCertificateAuthorityOutput, error)
+	UntagCertificateAuthority(ctx context.Context, params *acmpca.UntagCertificateAuthorityInput, optFns ...func(*acmpca.Options)) (*acmpca.UntagCertificateAuthorityOutput, error)
+	GetCertificateAuthorityCertificate(ctx context.Context, params *acmpca.GetCertificateAuthorityCertificateInput, optFns ...func(*acmpca.Options)) (*acmpca.GetCertificateAuthorityCertificateOutput, error)
+	CreateCertificateAuthorityAuditReport(ctx context.Context, params *acmpca.CreateCertificateAuthorityAuditReportInput, optFns ...func(*acmpca.Options)) (*acmpca.CreateCertificateAuthorityAuditReportOutput, error)
+	RevokeCertificate(ctx context.Context, params *acmpca.RevokeCertificateInput, optFns ...func(*acmpca.Options)) (*acmpca.RevokeCertificateOutput, error)
+}
+
+// PCAProvisioner contains logic for issuing PCA certificates
+type PCAProvisioner struct {
+	pcaClient        acmPCAClient
+	clientOptions    []func(*acmpca.Options)
+	arn              string
+	templateArn      string
+	defaultValidity  time.Duration
+	maxValidity      time.Duration
+	validityType     acmpcatypes.ValidityPeriodType
+	pathLength       int
+	tags             map[string]string
+	tagged           bool
+	fullChain        bool
+	chainMode        string
+	certificateChain string
+	waitForCAChain   bool
+	caChainComplete  bool
+	signingAlgorithm *acmpcatypes.SigningAlgorithm
+	clock            func() time.Time
+
+	// failover are the provisioners of the failover CAs, in the order Sign
+	// tries them when this CA is unavailable
+	failoverArns []string
+	failover     []*PCAProvisioner
+
+	// regional are the provisioners of the CAs CertificateRequests select
+	// with the RegionAnnotation, by region
+	regionalArns map[string]string
+	regional     map[string]*PCAProvisioner
+
+	// certificateArnAnnotation is the annotation Sign records the certificate
+	// ARN in. The CertificateArnAnnotation is used if it is empty.
+	certificateArnAnnotation string
+
+	// apiPassthrough holds the extensions added to every issued certificate,
+	// and the attributes overriding the subject of its CSR
+	apiPassthrough *acmpcatypes.ApiPassthrough
+
+	// allowedDomains and allowedNamespaces restrict the CertificateRequests
+	// Sign accepts. Empty lists allow all.
+	allowedDomains    []string
+	allowedNamespaces []string
+
+	// mu guards signingAlgorithm, tagged and caChainComplete, which are set lazily by
+	// concurrent reconciles of CertificateRequests of the same issuer
+	mu sync.Mutex
+
+	// caStatusMu guards the cached CA status, which is also refreshed by
+	// callers of CAStatus outside of reconciles
+	caStatusMu         sync.Mutex
+	caStatus           acmpcatypes.CertificateAuthorityStatus
+	caStatusCheckedAt  time.Time
+	caKeyAlgorithm     acmpcatypes.KeyAlgorithm
+	caSigningAlgorithm acmpcatypes.SigningAlgorithm
+
+	// caChainMu guards the CA certificate chain cached for caChainTTL by
+	// getCAChain. Nothing is cached when caChainTTL is zero.
+	caChainMu        sync.Mutex
+	caChainTTL       time.Duration
+	caChain          []byte
+	caChainFetchedAt time.Time
+
+	// certificateWaitTimeout is how long Get polls PCA for a certificate
+	// that is still being issued, at least certificateWaitMinDelay apart.
+	// Get returns at once if it is zero.
+	certificateWaitTimeout  time.Duration
+	certificateWaitMinDelay time.Duration
+}
+
+// ProvisionerOption configures optional behaviour of a PCAProvisioner
+type ProvisionerOption func(*PCAProvisioner)
+
+// WithTemplateArn makes the provisioner issue certificates with the given
+// template instead of deriving one from the CertificateRequest usages
+func WithTemplateArn(templateArn string) ProvisionerOption {
+	return func(p *PCAProvisioner) {
+		p.templateArn = templateArn
+	}
+}
+
+// WithTags makes the provisioner apply the given tags to the CA before it
+// issues its first certificate
+func WithTags(tags map[string]string) ProvisionerOption {
+	return func(p *PCAProvisioner) {
+		p.tags = tags
+	}
+}
+
+// WithFullChain returns the full CA certificate chain up to the root as the CA
+// of issued certificates, instead of only the root certificate
+func WithFullChain(fullChain bool) ProvisionerOption {
+	return func(p *PCAProvisioner) {
+		p.fullChain = fullChain
+	}
+}
+
+// WithChainMode selects the CA certificates returned as the CA of issued
+// certificates, see ChainModeRootOnly, ChainModeCAOnly and ChainModeFullChain.
+// It takes precedence over WithFullChain.
+func WithChainMode(mode string) ProvisionerOption {
+	return func(p *PCAProvisioner) {
+		p.chainMode = mode
+	}
+}
+
+// WithCertificateChain selects the certificates returned as the issued
+// certificate, see CertificateChainLeafAndIntermediates,
+// CertificateChainLeafOnly and CertificateChainLeafAndFullChain. By default
+// the certificate is followed by the intermediates of its chain.
+func WithCertificateChain(chain string) ProvisionerOption {
+	return func(p *PCAProvisioner) {
+		p.certificateChain = chain
+	}
+}
+
+// WithCertificateWaitTimeout makes Get poll PCA for up to timeout while a
+// certificate is still being issued, instead of returning the
+// RequestInProgressException at once. Nil or non-positive values do not poll.
+func WithCertificateWaitTimeout(timeout *metav1.Duration) ProvisionerOption {
+	return func(p *PCAProvisioner) {
+		if timeout != nil && timeout.Duration > 0 {
+			p.certificateWaitTimeout = timeout.Duration
+		}
+	}
+}
+
+// WithCACertificateCacheTTL makes the provisioner cache the CA certificate
+// chain returned with the full chain for ttl. Nil or non-positive values fetch
+// the chain for every certificate.
+func WithCACertificateCacheTTL(ttl *metav1.Duration) ProvisionerOption {
+	return func(p *PCAProvisioner) {
+		if ttl != nil && ttl.Duration > 0 {
+			p.caChainTTL = ttl.Duration
+		}
+	}
+}
+
+// WithWaitForCAChain makes Sign return ErrCAChainNotPropagated until PCA
+// returns the certificate of the CA and, unless it is a root CA, its chain
+func WithWaitForCAChain(wait bool) ProvisionerOption {
+	return func(p *PCAProvisioner) {
+		p.waitForCAChain = wait
+	}
+}
+
+// WithCertificateArnAnnotation makes the provisioner record the certificate ARN
+// in the annotation key instead of the CertificateArnAnnotation
+func WithCertificateArnAnnotation(key string) ProvisionerOption {
+	return func(p *PCAProvisioner) {
+		p.certificateArnAnnotation = key
+	}
+}
+
+// WithRegionalArns makes the provisioner issue certificates for
+// CertificateRequests with the RegionAnnotation from the CA of that region.
+// arns maps regions to the ARNs of their CAs.
+func WithRegionalArns(arns map[string]string) ProvisionerOption {
+	return func(p *PCAProvisioner) {
+		p.regionalArns = arns
+	}
+}
+
+// WithFailoverArns makes the provisioner fail over to the given CAs, in order,
+// when its CA is unavailable or cannot issue certificates
+func WithFailoverArns(arns []string) ProvisionerOption {
+	return func(p *PCAProvisioner) {
+		p.failoverArns = arns
+	}
+}
+
+// WithAPIPassthrough makes the provisioner add the extensions of passthrough to
+// issued certificates, using the APIPassthrough variant of derived templates.
+// The attributes of its subject override those of the subject of each CSR.
+func WithAPIPassthrough(passthrough *acmpcatypes.ApiPassthrough) ProvisionerOption {
+	return func(p *PCAProvisioner) {
+		p.apiPassthrough = passthrough
+	}
+}
+
+// WithPolicy makes the provisioner deny CertificateRequests from namespaces that
+// are not in allowedNamespaces, or with DNS names outside of allowedDomains
+func WithPolicy(allowedDomains, allowedNamespaces []string) ProvisionerOption {
+	return func(p *PCAProvisioner) {
+		p.allowedDomains = allowedDomains
+		p.allowedNamespaces = allowedNamespaces
+	}
+}
+
+// GetProvisioner gets a provisioner that has previously been stored
+func GetProvisioner(name types.NamespacedName) (GenericProvisioner, bool) {
+	value, exists := collection.Load(name)
+	if !exists {
+		return nil, exists
+	}
+
+	p, exists := value.(GenericProvisioner)
+	return p, exists
+}
+
+// StoreProvisioner stores a provisioner in the cache
+func StoreProvisioner(name types.NamespacedName, provisioner GenericProvisioner) {
+	collection.Store(name, provisioner)
+}
+
+// DeleteProvisioner removes the provisioner of an issuer from the cache, so
+// that CertificateRequests are not signed with it until the issuer has been
+// reconciled again
+func DeleteProvisioner(name types.NamespacedName) {
+	collection.Delete(name)
+}
+
+// InvalidateProvisioner removes the provisioner and the AWS client of an issuer
+// from the cache, e.g. after its credentials expired, so that both are built
+// again from the current credentials
+func InvalidateProvisioner(name types.NamespacedName) {
+	collection.Delete(name)
+	if key, loaded := clientKeys.LoadAndDelete(name); loaded {
+		clients.Delete(key)
+	}
+}
+
+// ClearProvisioners removes all provisioners and AWS clients from the cache
+func ClearProvisioners() {
+	for _, m := range []*sync.Map{collection, clients, clientKeys} {
+		m.Range(func(key, _ interface{}) bool {
+			m.Delete(key)
+			return true
+		})
+	}
+}
+
+// LoadClient returns the AWS config and PCA client cached for key, building
+// them with loadConfig if there are none. When the key of an issuer changes,
+// e.g. because its credentials were updated, the client cached under the
+// previous key is dropped.
+func LoadClient(issuer types.NamespacedName, key ClientKey, loadConfig func() (aws.Config, error)) (aws.Config, *acmpca.Client, error) {
+	if previous, loaded := clientKeys.Swap(issuer, key); loaded && previous.(ClientKey) != key {
+		clients.Delete(previous)
+	}
+
+	if value, ok := clients.Load(key); ok {
+		c := value.(*cachedClient)
+		return c.config, c.pca, nil
+	}
+
+	cfg, err := loadConfig()
+	if err != nil {
+		return aws.Config{}, nil, err
+	}
+
+	value, _ := clients.LoadOrStore(key, &cachedClient{config: cfg, pca: NewClient(cfg, key.clientOptions()...)})
+	c := value.(*cachedClient)
+	return c.config, c.pca, nil
+}
+
+// WithValidity sets the validity used when a CertificateRequest does not
+// request a duration, and the maximum validity a CertificateRequest may request.
+// Nil values keep the provisioner defaults.
+func WithValidity(defaultValidity, maxValidity *metav1.Duration) ProvisionerOption {
+	return func(p *PCAProvisioner) {
+		if defaultValidity != nil {
+			p.defaultValidity = defaultValidity.Duration
+		}
+		if maxValidity != nil {
+			p.maxValidity = maxValidity.Duration
+		}
+	}
+}
+
+// WithPathLength makes the provisioner issue subordinate CA certificates with
+// the path length constraint pathLength, if it is not nil
+func WithPathLength(pathLength *int32) ProvisionerOption {
+	return func(p *PCAProvisioner) {
+		if pathLength != nil {
+			p.pathLength = int(*pathLength)
+		}
+	}
+}
+
+// WithValidityPeriodType makes the provisioner express the validity of issued
+// certificates in periodType instead of choosing between DAYS and ABSOLUTE
+func WithValidityPeriodType(periodType string) ProvisionerOption {
+	return func(p *PCAProvisioner) {
+		p.validityType = acmpcatypes.ValidityPeriodType(periodType)
+	}
+}
+
+// WithClientOptions applies optFns to the PCA client built by NewProvisioner,
+// e.g. WithEndpoint to send requests to localstack or a mock of PCA at
+// http://localhost:4566. Provisioners built with NewProvisionerWithClient use
+// the options of their client instead.
+func WithClientOptions(optFns ...func(*acmpca.Options)) ProvisionerOption {
+	return func(p *PCAProvisioner) {
+		p.clientOptions = append(p.clientOptions, optFns...)
+	}
+}
+
+// clientOptions returns the PCA client options described by the key
+func (k ClientKey) clientOptions() []func(*acmpca.Options) {
+	var optFns []func(*acmpca.Options)
+	if k.Endpoint != "" {
+		optFns = append(optFns, WithEndpoint(k.Endpoint))
+	}
+	if k.UseFIPSEndpoint {
+		optFns = append(optFns, WithFIPSEndpoint())
+	}
+	return optFns
+}
+
+// NewClient returns a new PCA client for config
+func NewClient(config aws.Config, optFns ...func(*acmpca.Options)) *acmpca.Client {
+	optFns = append([]func(*acmpca.Options){acmpca.WithAPIOptions(
+		middleware.AddUserAgentKeyValue("aws-privateca-issuer", injections.PlugInVersion),
+	)}, optFns...)
+	return acmpca.NewFromConfig(config, optFns...)
+}
+
+// WithUserAgent appends the issuer, and suffix with the version of the plugin
+// if suffix is set, to the User-Agent of the PCA calls of a client, so that the
+// calls of each issuer can be told apart in CloudTrail
+func WithUserAgent(suffix string, issuer types.NamespacedName) func(*acmpca.Options) {
+	name := issuer.Name
+	if issuer.Namespace != "" {
+		name = issuer.Namespace + "_" + issuer.Name
+	}
+	apiOptions := []func(*smithymiddleware.Stack) error{middleware.AddUserAgentKeyValue("issuer", name)}
+	if suffix != "" {
+		apiOptions = append(apiOptions, middleware.AddUserAgentKeyValue(suffix, injections.PlugInVersion))
+	}
+	return acmpca.WithAPIOptions(apiOptions...)
+}
+
+// WithEndpoint makes a PCA client send requests to endpoint instead of the
+// regional PCA endpoint. Requests are still signed for the configured region.
+func WithEndpoint(endpoint string) func(*acmpca.Options) {
+	return func(o *acmpca.Options) {
+		o.BaseEndpoint = aws.String(endpoint)
+	}
+}
+
+// WithFIPSEndpoint makes a PCA client use the FIPS endpoint of its region
+func WithFIPSEndpoint() func(*acmpca.Options) {
+	return func(o *acmpca.Options) {
+		o.EndpointOptions.UseFIPSEndpoint = aws.FIPSEndpointStateEnabled
+	}
+}
+
+// FIPSEndpointAvailable reports whether PCA has a FIPS endpoint in region
+func FIPSEndpointAvailable(region string) bool {
+	_, ok := fipsRegions[region]
+	return ok
+}
+
+// regionPartitions maps region prefixes to the AWS partitions they belong to.
+// Regions without a matching prefix are in the aws partition.
+// @see: https://docs.aws.amazon.com/whitepapers/latest/aws-fault-isolation-boundaries/partitions.html
+var regionPartitions = []struct {
+	prefix    string
+	partition string
+}{
+	{prefix: "cn-", partition: "aws-cn"},
+	{prefix: "us-gov-", partition: "aws-us-gov"},
+	{prefix: "us-isob-", partition: "aws-iso-b"},
+	{prefix: "us-iso-", partition: "aws-iso"},
+}
+
+// RegionPartition returns the AWS partition of region, e.g. aws-us-gov for
+// us-gov-west-1
+func RegionPartition(region string) string {
+	for _, p := range regionPartitions {
+		if strings.HasPrefix(region, p.prefix) {
+			return p.partition
+		}
+	}
+	return "aws"
+}
+
+// ParseCertificateAuthorityARN parses the ARN of a PCA certificate authority
+// in any partition, e.g.
+// arn:aws:acm-pca:us-east-1:123456789012:certificate-authority/12345678-1234-1234-1234-123456789012.
+// ARNs of other services or PCA resources, and ARNs without a region, account
+// or CA ID are rejected.
+func ParseCertificateAuthorityARN(arn string) (partition, region, accountID, caID string, err error) {
+	parsed, err := awsarn.Parse(arn)
+	if err != nil {
+		return "", "", "", "", err
+	}
+	id, ok := strings.CutPrefix(parsed.Resource, "certificate-authority/")
+	if parsed.Service != "acm-pca" || !ok {
+		return "", "", "", "", fmt.Errorf("%s is not the ARN of a PCA certificate authority", arn)
+	}
+	switch {
+	case parsed.Partition == "":
+		return "", "", "", "", fmt.Errorf("%s is not the ARN of a PCA certificate authority: no partition", arn)
+	case parsed.Region == "":
+		return "", "", "", "", fmt.Errorf("%s is not the ARN of a PCA certificate authority: no region", arn)
+	case parsed.AccountID == "":
+		return "", "", "", "", fmt.Errorf("%s is not the ARN of a PCA certificate authority: no account ID", arn)
+	case id == "" || strings.Contains(id, "/"):
+		return "", "", "", "", fmt.Errorf("%s is not the ARN of a PCA certificate authority: invalid CA ID %q", arn, id)
+	}
+	return parsed.Partition, parsed.Region, parsed.AccountID, id, nil
+}
+
+// ValidEndpoint reports whether endpoint is an https URL that can be used as
+// a custom PCA endpoint
+func ValidEndpoint(endpoint string) bool {
+	u, err := url.Parse(endpoint)
+	return err == nil && u.Scheme == "https" && u.Host != "" && u.User == nil && u.RawQuery == "" && u.Fragment == ""
+}
+
+// NewProvisioner returns a new PCAProvisioner
+func NewProvisioner(config aws.Config, arn string, opts ...ProvisionerOption) (p *PCAProvisioner) {
+	// The client options are collected first, as the client is needed to
+	// build the provisioner
+	var o PCAProvisioner
+	for _, opt := range opts {
+		opt(&o)
+	}
+	return NewProvisionerWithClient(NewClient(config, o.clientOptions...), arn, opts...)
+}
+
+// NewProvisionerWithClient returns a new PCAProvisioner that uses an existing
+// PCA client
+func NewProvisionerWithClient(client *acmpca.Client, arn string, opts ...ProvisionerOption) (p *PCAProvisioner) {
+	p = newProvisioner(client, arn, opts)
+	for _, failoverArn := range p.failoverArns {
+		f := newProvisioner(clientForArn(client, failoverArn), failoverArn, opts)
+		f.failoverArns = nil
+		f.regionalArns = nil
+		p.failover = append(p.failover, f)
+	}
+	for region, regionalArn := range p.regionalArns {
+		r := newProvisioner(clientForArn(client, regionalArn), regionalArn, opts)
+		r.failoverArns = nil
+		r.regionalArns = nil
+		if p.regional == nil {
+			p.regional = map[string]*PCAProvisioner{}
+		}
+		p.regional[region] = r
+	}
+	return p
+}
+
+func newProvisioner(client acmPCAClient, arn string, opts []ProvisionerOption) *PCAProvisioner {
+	p := &PCAProvisioner{
+		pcaClient: client,
+		arn:       arn,
+	}
+	for _, opt := range opts {
+		opt(p)
+	}
+	return p
+}
+
+// clientForArn returns client, or a copy of it for the region of the CA if
+// that is in another region
+func clientForArn(client *acmpca.Client, caArn string) *acmpca.Client {
+	_, region, _, _, err := ParseCertificateAuthorityARN(caArn)
+	if err != nil || client == nil || region == client.Options().Region {
+		return client
+	}
+	return acmpca.New(client.Options(), func(o *acmpca.Options) {
+		o.Region = region
+	})
+}
+
+// idempotencyToken is limited to 36 ASCII characters, so make a fixed length hash.
+// It is derived from the UID, CSR and force reissue nonce of the request, so
+// retries of Sign within the five minute window PCA honours the token for do
+// not issue a second certificate, while a recreated or force reissued request
+// gets a new one.
+// @see: https://docs.aws.amazon.com/privateca/latest/APIReference/API_IssueCertificate.html
+func idempotencyToken(cr *cmapi.CertificateRequest) string {
+	hash := md5.New()
+	hash.Write([]byte(cr.ObjectMeta.Namespace + "/" + cr.ObjectMeta.Name + "/" + string(cr.ObjectMeta.UID) + "/"))
+	hash.Write(cr.Spec.Request)
+	if nonce := cr.GetAnnotations()[ForceReissueAnnotation]; nonce != "" {
+		hash.Write([]byte("/" + nonce))
+	}
+	return fmt.Sprintf("%x", hash.Sum(nil))
+}
+
+// withCertificateRequest adds the namespace, name and UID of cr to the user
+// agent of a PCA call. IssueCertificate cannot tag certificates, but CloudTrail
+// records the user agent of each call, so the events of a certificate can be
+// traced back to the CertificateRequest that issued it.
+func withCertificateRequest(cr *cmapi.CertificateRequest) func(*acmpca.Options) {
+	return acmpca.WithAPIOptions(
+		middleware.AddUserAgentKeyValue("certificaterequest", cr.Namespace+"_"+cr.Name),
+		middleware.AddUserAgentKeyValue("certificaterequest-uid", string(cr.UID)),
+	)
+}
+
+// Sign takes a certificate request and asks PCA to issue a certificate for it.
+// The ARN of the issued certificate is stored in the certificate ARN annotation
+// of the CertificateRequest; use Get to retrieve the certificate once it is
+// issued. Requests that already have the annotation, or the
+// CertificateArnAnnotation, are not signed again. For dry runs
+// the request is validated against the CA, but no certificate is issued.
+//
+// Invalid CSRs are rejected with ErrInvalidCSR, and requests the policy of the
+// provisioner does not allow with ErrDeniedByPolicy, before PCA is called.
+//
+// When the CA is unavailable or cannot issue certificates, the failover CAs are
+// tried in order, and the CA that issued the certificate is recorded in the
+// CAArnAnnotation. Requests with the RegionAnnotation are issued by the CA of
+// that region instead, without failover.
+func (p *PCAProvisioner) Sign(ctx context.Context, cr *cmapi.CertificateRequest, log logr.Logger) error {
+	if certArn, ok := CertificateArn(cr, p.certificateArnAnnotation); ok {
+		log.Info("Certificate already issued", "certificateArn", certArn)
+		return nil
+	}
+
+	csr, err := parseCSR(cr.Spec.Request)
+	if err != nil {
+		return err
+	}
+	if err := p.checkPolicy(cr, csr); err != nil {
+		return err
+	}
+
+	if region := cr.GetAnnotations()[RegionAnnotation]; region != "" {
+		regional, ok := p.regional[region]
+		if !ok {
+			return fmt.Errorf("%w %q in annotation %s", errUnknownRegion, region, RegionAnnotation)
+		}
+		return regional.sign(ctx, cr, csr, log)
+	}
+
+	err = p.sign(ctx, cr, csr, log)
+	for _, f := range p.failover {
+		if !failoverError(err) {
+			break
+		}
+		log.Info("Certificate authority is unavailable, failing over", "failedArn", p.arn, "arn", f.arn, "error", err.Error())
+		err = f.sign(ctx, cr, csr, log)
+	}
+	return err
+}
+
+// sign asks the CA of the provisioner to issue a certificate for cr, whose CSR
+// was parsed into csr
+func (p *PCAProvisioner) sign(ctx context.Context, cr *cmapi.CertificateRequest, csr *x509.CertificateRequest, log logr.Logger) error {
+	dryRun := DryRun(cr)
+
+	duration, clamped := EffectiveDuration(cr, p.defaultValidity, p.maxValidity)
+
+	// The template of the request takes precedence over the one of the issuer,
+	// and either over the template derived from the usages
+	configuredTempArn, err := templateArnOverride(cr)
+	if err != nil {
+		return err
+	}
+	if configuredTempArn == "" {
+		configuredTempArn = p.templateArn
+	}
+	tempArn := configuredTempArn
+	if tempArn == "" {
+		// cert-manager also requests CA certificates through the CSR
+		spec := cr.Spec
+		spec.IsCA = spec.IsCA || csrRequestsCA(csr)
+		if err := validateUsages(spec); err != nil {
+			return err
+		}
+		tempArn = templateArn(p.arn, spec, p.pathLength)
+		if p.apiPassthrough != nil {
+			tempArn = apiPassthroughTemplateArn(tempArn)
+		}
+	}
+
+	if err := validateTemplateUsages(cr.Spec.Usages, tempArn); err != nil {
+		return err
+	}
+
+	// Consider it a "retry" if we try to sign the same request again
+	token := idempotencyToken(cr)
+
+	now := p.now()
+	certValidity := validity(duration, p.maxValidity, now, p.validityType)
+	notAfter, err := notAfterOverride(cr, now)
+	if err != nil {
+		return err
+	}
+	if !notAfter.IsZero() {
+		duration = notAfter.Sub(now)
+		clamped = p.maxValidity > 0 && duration > p.maxValidity
+		if clamped {
+			duration = p.maxValidity
+		}
+		certValidity = validity(duration, p.maxValidity, now, acmpcatypes.ValidityPeriodTypeEndDate)
+	}
+
+	signingAlgorithm, err := signingAlgorithmOverride(cr)
+	if err != nil {
+		return err
+	}
+
+	if signingAlgorithm == "" {
+		signingAlgorithm, err = getSigningAlgorithm(ctx, p)
+		if err != nil {
+			return err
+		}
+	}
+
+	err = p.checkCAActive(ctx)
+	if err != nil {
+		return err
+	}
+
+	if p.waitForCAChain {
+		if err := p.checkCAChain(ctx); err != nil {
+			return err
+		}
+	}
+
+	if dryRun {
+		log.Info("Dry run, not issuing certificate", "templateArn", tempArn, "signingAlgorithm", signingAlgorithm)
+		return nil
+	}
+
+	err = tagCertificateAuthority(ctx, p)
+	if err != nil {
+		return fmt.Errorf("failed to tag certificate authority: %w", err)
+	}
+
+	issueParams := acmpca.IssueCertificateInput{
+		CertificateAuthorityArn: aws.String(p.arn),
+		SigningAlgorithm:        signingAlgorithm,
+		TemplateArn:             aws.String(tempArn),
+		Csr:                     cr.Spec.Request,
+		Validity:                certValidity,
+		IdempotencyToken:        aws.String(token),
+		ApiPassthrough:          subjectPassthrough(p.apiPassthrough, csr),
+	}
+
+	issueOutput, err := p.pcaClient.IssueCertificate(ctx, &issueParams, withCertificateRequest(cr))
+
+	if err != nil {
+		var invalidArgs *acmpcatypes.InvalidArgsException
+		if configuredTempArn != "" && errors.As(err, &invalidArgs) {
+			return fmt.Errorf("template %s may be incompatible with the requested usages: %w", configuredTempArn, err)
+		}
+		return err
+	}
+
+	metav1.SetMetaDataAnnotation(&cr.ObjectMeta, CertificateArnKey(p.certificateArnAnnotation), *issueOutput.CertificateArn)
+	metav1.SetMetaDataAnnotation(&cr.ObjectMeta, CAArnAnnotation, p.arn)
+	if clamped {
+		metav1.SetMetaDataAnnotation(&cr.ObjectMeta, ValidityClampedAnnotation, duration.String())
+	}
+
+	log.Info("Created certificate", "certificateArn", *issueOutput.CertificateArn, "caArn", p.arn)
+
+	return nil
+}
+
+// Get retrieves the certificate with the given ARN from PCA. While PCA is still
+// issuing the certificate a RequestInProgressException is returned, after
+// polling for the certificate wait timeout if one is set. The certificate is
+// retrieved from the CA recorded in the CAArnAnnotation.
+func (p *PCAProvisioner) Get(ctx context.Context, cr *cmapi.CertificateRequest, certArn string, log logr.Logger) ([]byte, []byte, error) {
+	p = p.issuedBy(cr)
+	getParams := acmpca.GetCertificateInput{
+		CertificateArn:          aws.String(certArn),
+		CertificateAuthorityArn: aws.String(p.arn),
+	}
+
+	getOutput, err := p.getCertificate(ctx, &getParams, cr)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	certPem := []byte(*getOutput.Certificate + "\n")
+	chainPem := []byte(*getOutput.CertificateChain)
+	chainIntCAs, rootCA, err := splitRootCACertificate(chainPem)
+	if err != nil {
+		return nil, nil, err
+	}
+	switch p.certificateChain {
+	case CertificateChainLeafOnly:
+	case CertificateChainLeafAndFullChain:
+		certPem = append(append(certPem, chainIntCAs...), rootCA...)
+	default:
+		certPem = append(certPem, chainIntCAs...)
+	}
+
+	switch p.effectiveChainMode() {
+	case ChainModeCAOnly:
+		caPem, err := issuingCACertificate(chainPem)
+		if err != nil {
+			return nil, nil, err
+		}
+		return certPem, caPem, nil
+	case ChainModeFullChain:
+		caPem, err := p.getCAChain(ctx)
+		if err != nil {
+			return nil, nil, err
+		}
+		return certPem, caPem, nil
+	default:
+		return certPem, rootCA, nil
+	}
+}
+
+// effectiveChainMode returns the chain mode of the provisioner, falling back
+// to FullChain or RootOnly depending on WithFullChain
+func (p *PCAProvisioner) effectiveChainMode() string {
+	switch {
+	case p.chainMode != "":
+		return p.chainMode
+	case p.fullChain:
+		return ChainModeFullChain
+	default:
+		return ChainModeRootOnly
+	}
+}
+
+// Revoke revokes the certificate of cr with the given serial number, formatted
+// as colon separated hex bytes, at the CA recorded in the CAArnAnnotation.
+// Certificates that were already revoked are not an error.
+func (p *PCAProvisioner) Revoke(ctx context.Context, cr *cmapi.CertificateRequest, serial string, reason acmpcatypes.RevocationReason, log logr.Logger) error {
+	p = p.issuedBy(cr)
+	if reason == "" {
+		reason = acmpcatypes.RevocationReasonUnspecified
+	}
+	revokeParams := acmpca.RevokeCertificateInput{
+		CertificateAuthorityArn: aws.String(p.arn),
+		CertificateSerial:       aws.String(serial),
+		RevocationReason:        reason,
+	}
+
+	_, err := p.pcaClient.RevokeCertificate(ctx, &revokeParams, withCertificateRequest(cr))
+	var alreadyProcessed *acmpcatypes.RequestAlreadyProcessedException
+	if errors.As(err, &alreadyProcessed) {
+		log.Info("Certificate was already revoked", "serialNumber", serial, "caArn", p.arn)
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	log.Info("Revoked certificate", "serialNumber", serial, "caArn", p.arn, "reason", reason)
+	return nil
+}
+
+// ParseRevocationReason returns the PCA revocation reason named value, or
+// UNSPECIFIED if value is empty
+func ParseRevocationReason(value string) (acmpcatypes.RevocationReason, error) {
+	if value == "" {
+		return acmpcatypes.RevocationReasonUnspecified, nil
+	}
+	for _, reason := range acmpcatypes.RevocationReason("").Values() {
+		if string(reason) == value {
+			return reason, nil
+		}
+	}
+	return "", fmt.Errorf("%w %q", ErrInvalidRevocationReason, value)
+}
+
+// getCertificate calls GetCertificate, using the CertificateIssued waiter to
+// poll while the certificate is being issued if a certificate wait timeout is
+// set. Other errors are returned at once, and a RequestInProgressException if
+// the certificate was not issued within the timeout.
+func (p *PCAProvisioner) getCertificate(ctx context.Context, params *acmpca.GetCertificateInput, cr *cmapi.CertificateRequest) (*acmpca.GetCertificateOutput, error) {
+	if p.certificateWaitTimeout <= 0 {
+		return p.pcaClient.GetCertificate(ctx, params, withCertificateRequest(cr))
+	}
+
+	var lastErr error
+	waiter := acmpca.NewCertificateIssuedWaiter(p.pcaClient, func(o *acmpca.CertificateIssuedWaiterOptions) {
+		if p.certificateWaitMinDelay > 0 {
+			o.MinDelay = p.certificateWaitMinDelay
+		}
+		o.ClientOptions = append(o.ClientOptions, withCertificateRequest(cr))
+		// The default retries every error until the timeout
+		o.Retryable = func(_ context.Context, _ *acmpca.GetCertificateInput, _ *acmpca.GetCertificateOutput, err error) (bool, error) {
+			lastErr = err
+			var inProgress *acmpcatypes.RequestInProgressException
+			if errors.As(err, &inProgress) {
+				return true, nil
+			}
+			return false, err
+		}
+	})
+
+	output, err := waiter.WaitForOutput(ctx, params, p.certificateWaitTimeout)
+	if err == nil || ctx.Err() != nil {
+		return output, err
+	}
+	// The waiter gives up with a plain error, or cuts off the last call when
+	// the timeout expires
+	var inProgress *acmpcatypes.RequestInProgressException
+	if errors.As(lastErr, &inProgress) || errors.Is(lastErr, context.DeadlineExceeded) {
+		return nil, &acmpcatypes.RequestInProgressException{
+			Message: aws.String(fmt.Sprintf("certificate was not issued within %s", p.certificateWaitTimeout)),
+		}
+	}
+	return nil, err
+}
+
+// getCAChain returns the certificate of the CA followed by its chain up to the
+// root. The chain is fetched at most once per caChainTTL.
+func (p *PCAProvisioner) getCAChain(ctx context.Context) ([]byte, error) {
+	if p.caChainTTL > 0 {
+		p.caChainMu.Lock()
+		caChain, fetchedAt := p.caChain, p.caChainFetchedAt
+		p.caChainMu.Unlock()
+		if caChain != nil && p.now().Sub(fetchedAt) < p.caChainTTL {
+			return slices.Clone(caChain), nil
+		}
+	}
+
+	caOutput, err := p.pcaClient.GetCertificateAuthorityCertificate(ctx, &acmpca.GetCertificateAuthorityCertificateInput{
+		CertificateAuthorityArn: aws.String(p.arn),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// The chain is empty for a root CA
+	caPem := []byte(aws.ToString(caOutput.Certificate) + "\n" + aws.ToString(caOutput.CertificateChain))
+	caChain, rootCA, err := splitRootCACertificate(caPem)
+	if err != nil {
+		return nil, err
+	}
+	caChain = append(caChain, rootCA...)
+
+	if p.caChainTTL > 0 {
+		p.caChainMu.Lock()
+		p.caChain, p.caChainFetchedAt = slices.Clone(caChain), p.now()
+		p.caChainMu.Unlock()
+	}
+
+	return caChain, nil
+}
+
+// issuedBy returns the provisioner of the CA recorded in the CAArnAnnotation of
+// cr, or p if the annotation is not one of its failover or regional CAs
+func (p *PCAProvisioner) issuedBy(cr *cmapi.CertificateRequest) *PCAProvisioner {
+	caArn := cr.GetAnnotations()[CAArnAnnotation]
+	for _, f := range p.failover {
+		if f.arn == caArn {
+			return f
+		}
+	}
+	for _, r := range p.regional {
+		if r.arn == caArn {
+			return r
+		}
+	}
+	return p
+}
+
+// failoverError reports whether Sign should try the next CA after err because
+// the CA is unavailable or cannot issue certificates. Errors caused by the
+// request itself, such as an invalid CSR, are not retried on another CA.
+func failoverError(err error) bool {
+	var (
+		invalidState *acmpcatypes.InvalidStateException
+		notFound     *acmpcatypes.ResourceNotFoundException
+		responseErr  *smithyhttp.ResponseError
+		netErr       net.Error
+	)
+	switch {
+	case err == nil:
+		return false
+	case errors.Is(err, ErrCANotActive), errors.As(err, &invalidState), errors.As(err, &notFound), errors.As(err, &netErr):
+		return true
+	case errors.As(err, &responseErr):
+		return responseErr.HTTPStatusCode() >= http.StatusInternalServerError
+	}
+	return false
+}
+
+// CreateAuditReport requests an audit report of the certificates issued and
+// revoked by the CA in format, JSON if empty. PCA writes the report to the S3
+// bucket asynchronously; the ID and S3 key of the report are returned.
+func (p *PCAProvisioner) CreateAuditReport(ctx context.Context, bucket, format string) (string, string, error) {
+	if format == "" {
+		format = string(acmpcatypes.AuditReportResponseFormatJson)
+	}
+	output, err := p.pcaClient.CreateCertificateAuthorityAuditReport(ctx, &acmpca.CreateCertificateAuthorityAuditReportInput{
+		CertificateAuthorityArn:   aws.String(p.arn),
+		S3BucketName:              aws.String(bucket),
+		AuditReportResponseFormat: acmpcatypes.AuditReportResponseFormat(format),
+	})
+	if err != nil {
+		return "", "", err
+	}
+
+	return aws.ToString(output.AuditReportId), aws.ToString(output.S3Key), nil
+}
+
+// Arn returns the ARN of the CA of the provisioner. It is the ARN resolved from
+// the arnFrom reference of the issuer if it has one.
+func (p *PCAProvisioner) Arn() string {
+	return p.arn
+}
+
+// CAStatus returns the current status of the CA. When it is not ACTIVE but one
+// of the failover CAs is, the issuer can still issue certificates, so ACTIVE is
+// returned.
+func (p *PCAProvisioner) CAStatus(ctx context.Context) (acmpcatypes.CertificateAuthorityStatus, error) {
+	status, err := p.describeCAStatus(ctx)
+	if err == nil && status == acmpcatypes.CertificateAuthorityStatusActive {
+		return status, nil
+	}
+	for _, f := range p.failover {
+		if fStatus, fErr := f.describeCAStatus(ctx); fErr == nil && fStatus == acmpcatypes.CertificateAuthorityStatusActive {
+			return fStatus, nil
+		}
+	}
+	return status, err
+}
+
+// describeCAStatus describes the status of the CA and caches it for checkCAActive
+func (p *PCAProvisioner) describeCAStatus(ctx context.Context) (acmpcatypes.CertificateAuthorityStatus, error) {
+	describeOutput, err := p.pcaClient.DescribeCertificateAuthority(ctx, &acmpca.DescribeCertificateAuthorityInput{
+		CertificateAuthorityArn: aws.String(p.arn),
+	})
+	if err != nil {
+		return "", err
+	}
+
+	ca := describeOutput.CertificateAuthority
+	p.caStatusMu.Lock()
+	p.caStatus, p.caStatusCheckedAt = ca.Status, p.now()
+	if config := ca.CertificateAuthorityConfiguration; config != nil {
+		p.caKeyAlgorithm, p.caSigningAlgorithm = config.KeyAlgorithm, config.SigningAlgorithm
+	}
+	p.caStatusMu.Unlock()
+
+	return ca.Status, nil
+}
+
+// CAAlgorithms returns the key and signing algorithm of the CA. They are
+// recorded when the CA status is described, so a describe within
+// caStatusCacheTTL, e.g. by CAStatus in the same reconcile, is reused.
+func (p *PCAProvisioner) CAAlgorithms(ctx context.Context) (acmpcatypes.KeyAlgorithm, acmpcatypes.SigningAlgorithm, error) {
+	p.caStatusMu.Lock()
+	status, checkedAt := p.caStatus, p.caStatusCheckedAt
+	keyAlgorithm, signingAlgorithm := p.caKeyAlgorithm, p.caSigningAlgorithm
+	p.caStatusMu.Unlock()
+	if status != "" && p.now().Sub(checkedAt) < caStatusCacheTTL {
+		return keyAlgorithm, signingAlgorithm, nil
+	}
+
+	if _, err := p.describeCAStatus(ctx); err != nil {
+		return "", "", err
+	}
+	p.caStatusMu.Lock()
+	defer p.caStatusMu.Unlock()
+	return p.caKeyAlgorithm, p.caSigningAlgorithm, nil
+}
+
+// checkCAActive returns ErrCANotActive unless the CA is ACTIVE. The status is
+// described at most once per caStatusCacheTTL.
+func (p *PCAProvisioner) checkCAActive(ctx context.Context) error {
+	p.caStatusMu.Lock()
+	status, checkedAt := p.caStatus, p.caStatusCheckedAt
+	p.caStatusMu.Unlock()
+
+	if status == "" || p.now().Sub(checkedAt) >= caStatusCacheTTL {
+		var err error
+		status, err = p.describeCAStatus(ctx)
+		if err != nil {
+			return err
+		}
+	}
+
+	if status != acmpcatypes.CertificateAuthorityStatusActive {
+		return fmt.Errorf("%w: status is %s", ErrCANotActive, status)
+	}
+	return nil
+}
+
+// checkCAChain returns ErrCAChainNotPropagated until PCA returns the
+// certificate of the CA and, for a subordinate CA, a non-empty chain. A root CA
+// has no chain, so its certificate suffices. Once complete the chain is not
+// checked again.
+func (p *PCAProvisioner) checkCAChain(ctx context.Context) error {
+	p.mu.Lock()
+	complete := p.caChainComplete
+	p.mu.Unlock()
+	if complete {
+		return nil
+	}
+
+	caOutput, err := p.pcaClient.GetCertificateAuthorityCertificate(ctx, &acmpca.GetCertificateAuthorityCertificateInput{
+		CertificateAuthorityArn: aws.String(p.arn),
+	})
+	if err != nil {
+		return err
+	}
+	block, _ := pem.Decode([]byte(aws.ToString(caOutput.Certificate)))
+	if block == nil {
+		return fmt.Errorf("%w: no CA certificate", ErrCAChainNotPropagated)
+	}
+	caCert, err := x509.ParseCertificate(block.Bytes)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrCAChainNotPropagated, err)
+	}
+	if aws.ToString(caOutput.CertificateChain) == "" && !bytes.Equal(caCert.RawIssuer, caCert.RawSubject) {
+		return fmt.Errorf("%w: the chain of the subordinate CA is empty", ErrCAChainNotPropagated)
+	}
+
+	p.mu.Lock()
+	p.caChainComplete = true
+	p.mu.Unlock()
+	return nil
 }
 
-// acmPCAClient abstracts over the methods used from acmpca.Client
-type acmPCAClient interface {
-	acmpca.GetCertificateAPIClient
-	DescribeCertificateAuthority(ctx context.Context, params *acmpca.DescribeCertificateAuthorityInput, optFns ...func(*acmpca.Options)) (*acmpca.DescribeCertificateAuthorityOutput, error)
-	IssueCertificate(ctx context.Context, params *acmpca.IssueCertificateInput, optFns ...func(*acmpca.Options)) (*acmpca.IssueCertificateOutput, error)
+// getSigningAlgorithm returns the signing algorithm of the CA, describing it
+// only once. Concurrent reconciles may each describe the CA before the first
+// result is cached, which is harmless.
+func getSigningAlgorithm(ctx context.Context, p *PCAProvisioner) (acmpcatypes.SigningAlgorithm, error) {
+	p.mu.Lock()
+	signingAlgorithm := p.signingAlgorithm
+	p.mu.Unlock()
+	if signingAlgorithm != nil {
+		return *signingAlgorithm, nil
+	}
+
+	describeParams := acmpca.DescribeCertificateAuthorityInput{
+		CertificateAuthorityArn: aws.String(p.arn),
+	}
+	describeOutput, err := p.pcaClient.DescribeCertificateAuthority(ctx, &describeParams)
+
+	if err != nil {
+		return "", err
+	}
+
+	signingAlgorithm = &describeOutput.CertificateAuthority.CertificateAuthorityConfiguration.SigningAlgorithm
+	p.mu.Lock()
+	p.signingAlgorithm = signingAlgorithm
+	p.mu.Unlock()
+	return *signingAlgorithm, nil
 }
 
-// PCAProvisioner contains logic for issuing PCA certificates
-type PCAProvisioner struct {
-	pcaClient        acmPCAClient
-	arn              string
-	signingAlgorithm *acmpcatypes.SigningAlgorithm
-	clock            func() time.Time
+// tagCertificateAuthority applies the tags of the provisioner to the CA. PCA has
+// no API to tag issued certificates, so this is the closest equivalent.
+func tagCertificateAuthority(ctx context.Context, p *PCAProvisioner) error {
+	p.mu.Lock()
+	tagged := p.tagged
+	p.mu.Unlock()
+	if tagged || len(p.tags) == 0 {
+		return nil
+	}
+
+	tags := make([]acmpcatypes.Tag, 0, len(p.tags))
+	for key, value := range p.tags {
+		tags = append(tags, acmpcatypes.Tag{Key: aws.String(key), Value: aws.String(value)})
+	}
+
+	_, err := p.pcaClient.TagCertificateAuthority(ctx, &acmpca.TagCertificateAuthorityInput{
+		CertificateAuthorityArn: aws.String(p.arn),
+		Tags:                    tags,
+	})
+	if err != nil {
+		return err
+	}
+
+	p.mu.Lock()
+	p.tagged = true
+	p.mu.Unlock()
+	return nil
 }
 
-// GetProvisioner gets a provisioner that has previously been stored
-func GetProvisioner(name types.NamespacedName) (GenericProvisioner, bool) {
-	value, exists := collection.Load(name)
-	if !exists {
-		return nil, exists
+// UntagCertificateAuthority removes the tags of the provisioner from the CA,
+// e.g. when its issuer is deleted. Tags whose value was changed since are kept.
+func (p *PCAProvisioner) UntagCertificateAuthority(ctx context.Context) error {
+	if len(p.tags) == 0 {
+		return nil
 	}
 
-	p, exists := value.(GenericProvisioner)
-	return p, exists
+	tags := make([]acmpcatypes.Tag, 0, len(p.tags))
+	for key, value := range p.tags {
+		tags = append(tags, acmpcatypes.Tag{Key: aws.String(key), Value: aws.String(value)})
+	}
+
+	_, err := p.pcaClient.UntagCertificateAuthority(ctx, &acmpca.UntagCertificateAuthorityInput{
+		CertificateAuthorityArn: aws.String(p.arn),
+		Tags:                    tags,
+	})
+	if err != nil {
+		return err
+	}
+
+	p.mu.Lock()
+	p.tagged = false
+	p.mu.Unlock()
+	return nil
 }
 
-// StoreProvisioner stores a provisioner in the cache
-func StoreProvisioner(name types.NamespacedName, provisioner GenericProvisioner) {
-	collection.Store(name, provisioner)
+// ValidateTags checks tags against the limits AWS imposes on tag keys and values
+func ValidateTags(tags map[string]string) error {
+	if len(tags) > maxTags {
+		return fmt.Errorf("at most %d tags are allowed, got %d", maxTags, len(tags))
+	}
+	for key, value := range tags {
+		switch {
+		case key == "" || utf8.RuneCountInString(key) > maxTagKeyLength:
+			return fmt.Errorf("tag key %q must be between 1 and %d characters", key, maxTagKeyLength)
+		case strings.HasPrefix(strings.ToLower(key), "aws:"):
+			return fmt.Errorf("tag key %q uses the reserved prefix aws:", key)
+		case !tagPattern.MatchString(key):
+			return fmt.Errorf("tag key %q contains invalid characters", key)
+		case utf8.RuneCountInString(value) > maxTagValueLength:
+			return fmt.Errorf("value of tag %q must be at most %d characters", key, maxTagValueLength)
+		case !tagPattern.MatchString(value):
+			return fmt.Errorf("value of tag %q contains invalid characters", key)
+		}
+	}
+	return nil
 }
 
-// NewProvisioner returns a new PCAProvisioner
-func NewProvisioner(config aws.Config, arn string) (p *PCAProvisioner) {
-	return &PCAProvisioner{
-		pcaClient: acmpca.NewFromConfig(config, acmpca.WithAPIOptions(
-			middleware.AddUserAgentKeyValue("aws-privateca-issuer", injections.PlugInVersion),
-		)),
-		arn: arn,
+// APIPassthrough returns the ApiPassthrough of IssueCertificate for the
+// extensions and subject of an issuer, or nil if it has neither. An error is
+// returned if the OIDs or values of the extensions, or the attributes of the
+// subject, are invalid.
+func APIPassthrough(passthrough *api.AWSPCAAPIPassthrough) (*acmpcatypes.ApiPassthrough, error) {
+	if passthrough == nil || (len(passthrough.CertificatePolicies) == 0 && len(passthrough.CustomExtensions) == 0 && passthrough.Subject == nil) {
+		return nil, nil
+	}
+	if len(passthrough.CertificatePolicies) > maxCertificatePolicies {
+		return nil, fmt.Errorf("at most %d certificate policies are allowed, got %d", maxCertificatePolicies, len(passthrough.CertificatePolicies))
+	}
+	if len(passthrough.CustomExtensions) > maxCustomExtensions {
+		return nil, fmt.Errorf("at most %d custom extensions are allowed, got %d", maxCustomExtensions, len(passthrough.CustomExtensions))
+	}
+
+	extensions := &acmpcatypes.Extensions{}
+	seen := map[string]bool{}
+	for _, oid := range passthrough.CertificatePolicies {
+		if err := ValidateObjectIdentifier(oid); err != nil {
+			return nil, fmt.Errorf("certificate policy: %w", err)
+		}
+		if seen[oid] {
+			return nil, fmt.Errorf("duplicate certificate policy %s", oid)
+		}
+		seen[oid] = true
+		extensions.CertificatePolicies = append(extensions.CertificatePolicies, acmpcatypes.PolicyInformation{CertPolicyId: aws.String(oid)})
+	}
+
+	seen = map[string]bool{}
+	for _, ext := range passthrough.CustomExtensions {
+		if err := ValidateObjectIdentifier(ext.ObjectIdentifier); err != nil {
+			return nil, fmt.Errorf("custom extension: %w", err)
+		}
+		if seen[ext.ObjectIdentifier] {
+			return nil, fmt.Errorf("duplicate custom extension %s", ext.ObjectIdentifier)
+		}
+		seen[ext.ObjectIdentifier] = true
+		if err := ValidateExtensionValue(ext.Value); err != nil {
+			return nil, fmt.Errorf("custom extension %s: %w", ext.ObjectIdentifier, err)
+		}
+		extensions.CustomExtensions = append(extensions.CustomExtensions, acmpcatypes.CustomExtension{
+			ObjectIdentifier: aws.String(ext.ObjectIdentifier),
+			Value:            aws.String(ext.Value),
+			Critical:         aws.Bool(ext.Critical),
+		})
+	}
+
+	result := &acmpcatypes.ApiPassthrough{}
+	if len(extensions.CertificatePolicies) > 0 || len(extensions.CustomExtensions) > 0 {
+		result.Extensions = extensions
+	}
+	if passthrough.Subject != nil {
+		subject, err := subjectOverride(passthrough.Subject)
+		if err != nil {
+			return nil, fmt.Errorf("subject: %w", err)
+		}
+		result.Subject = subject
 	}
+	return result, nil
 }
 
-// idempotencyToken is limited to 64 ASCII characters, so make a fixed length hash.
-// @see: https://docs.aws.amazon.com/AWSEC2/latest/APIReference/Run_Instance_Idempotency.html
-func idempotencyToken(cr *cmapi.CertificateRequest) string {
-	token := []byte(cr.ObjectMeta.Namespace + "/" + cr.ObjectMeta.Name)
-	return fmt.Sprintf("%x", md5.Sum(token))
+// subjectOverride returns the ASN1Subject holding the attributes of subject
+// that are set
+func subjectOverride(subject *api.AWSPCASubject) (*acmpcatypes.ASN1Subject, error) {
+	switch {
+	case subject.Country != "" && !countryPattern.MatchString(subject.Country):
+		return nil, fmt.Errorf("country %q is not a two letter code", subject.Country)
+	case len(subject.Organization) > maxSubjectOrganizationLength:
+		return nil, fmt.Errorf("organization must be at most %d characters", maxSubjectOrganizationLength)
+	case len(subject.OrganizationalUnit) > maxSubjectOrganizationLength:
+		return nil, fmt.Errorf("organizational unit must be at most %d characters", maxSubjectOrganizationLength)
+	case len(subject.State) > maxSubjectStateLength:
+		return nil, fmt.Errorf("state must be at most %d characters", maxSubjectStateLength)
+	case len(subject.Locality) > maxSubjectStateLength:
+		return nil, fmt.Errorf("locality must be at most %d characters", maxSubjectStateLength)
+	}
+
+	override := &acmpcatypes.ASN1Subject{}
+	for _, attr := range []struct {
+		value string
+		field **string
+	}{
+		{subject.Organization, &override.Organization},
+		{subject.OrganizationalUnit, &override.OrganizationalUnit},
+		{subject.Country, &override.Country},
+		{subject.State, &override.State},
+		{subject.Locality, &override.Locality},
+	} {
+		if attr.value != "" {
+			*attr.field = aws.String(attr.value)
+		}
+	}
+	return override, nil
 }
 
-// Sign takes a certificate request and signs it using PCA
-func (p *PCAProvisioner) Sign(ctx context.Context, cr *cmapi.CertificateRequest, log logr.Logger) ([]byte, []byte, error) {
-	block, _ := pem.Decode(cr.Spec.Request)
-	if block == nil {
-		return nil, nil, fmt.Errorf("failed to decode CSR")
+// subjectPassthrough returns passthrough for csr. PCA replaces
+// the whole subject of the CSR with the subject of the passthrough, so the
+// attributes of the CSR subject are merged into a copy of it, the ones of the
+// passthrough taking precedence. Only the first value of each attribute is
+// kept, and attributes ASN1Subject does not model are dropped.
+func subjectPassthrough(passthrough *acmpcatypes.ApiPassthrough, csr *x509.CertificateRequest) *acmpcatypes.ApiPassthrough {
+	if passthrough == nil || passthrough.Subject == nil {
+		return passthrough
 	}
 
-	validityExpiration := int64(p.now().Unix()) + DEFAULT_DURATION
-	if cr.Spec.Duration != nil {
-		validityExpiration = int64(p.now().Unix()) + int64(cr.Spec.Duration.Seconds())
+	optional := func(value string) *string {
+		if value == "" {
+			return nil
+		}
+		return aws.String(value)
+	}
+	first := func(values []string) *string {
+		if len(values) == 0 {
+			return nil
+		}
+		return optional(values[0])
+	}
+	name := csr.Subject
+	subject := acmpcatypes.ASN1Subject{
+		CommonName:         optional(name.CommonName),
+		SerialNumber:       optional(name.SerialNumber),
+		Organization:       first(name.Organization),
+		OrganizationalUnit: first(name.OrganizationalUnit),
+		Country:            first(name.Country),
+		State:              first(name.Province),
+		Locality:           first(name.Locality),
+	}
+	override := passthrough.Subject
+	for _, attr := range []struct {
+		value *string
+		field **string
+	}{
+		{override.Organization, &subject.Organization},
+		{override.OrganizationalUnit, &subject.OrganizationalUnit},
+		{override.Country, &subject.Country},
+		{override.State, &subject.State},
+		{override.Locality, &subject.Locality},
+	} {
+		if attr.value != nil {
+			*attr.field = attr.value
+		}
 	}
 
-	tempArn := templateArn(p.arn, cr.Spec)
+	merged := *passthrough
+	merged.Subject = &subject
+	return &merged
+}
 
-	// Consider it a "retry" if we try to re-create a cert with the same name in the same namespace
-	token := idempotencyToken(cr)
+// ValidateObjectIdentifier checks that oid is a dotted decimal OID that PCA
+// accepts, e.g. 1.3.6.1.4.1.99999.1
+func ValidateObjectIdentifier(oid string) error {
+	if len(oid) > maxObjectIdentifierLength {
+		return fmt.Errorf("OID %q must be at most %d characters", oid, maxObjectIdentifierLength)
+	}
+	if !objectIdentifierPattern.MatchString(oid) {
+		return fmt.Errorf("%q is not a dotted decimal OID", oid)
+	}
+	return nil
+}
 
-	err := getSigningAlgorithm(ctx, p)
+// ValidateExtensionValue checks that value is the base64 encoding of a single
+// DER encoded ASN.1 value
+func ValidateExtensionValue(value string) error {
+	if len(value) > maxCustomExtensionValueLength {
+		return fmt.Errorf("value must be at most %d characters", maxCustomExtensionValueLength)
+	}
+	der, err := base64.StdEncoding.DecodeString(value)
 	if err != nil {
-		return nil, nil, err
+		return fmt.Errorf("value is not base64 encoded: %v", err)
+	}
+	var raw asn1.RawValue
+	rest, err := asn1.Unmarshal(der, &raw)
+	if err != nil {
+		return fmt.Errorf("value is not DER encoded: %v", err)
+	}
+	if len(rest) > 0 {
+		return fmt.Errorf("value has %d bytes of trailing data", len(rest))
+	}
+	return nil
+}
+
+// CertificateArnKey returns key, or the CertificateArnAnnotation if it is empty
+func CertificateArnKey(key string) string {
+	if key == "" {
+		return CertificateArnAnnotation
 	}
+	return key
+}
 
-	issueParams := acmpca.IssueCertificateInput{
-		CertificateAuthorityArn: aws.String(p.arn),
-		SigningAlgorithm:        *p.signingAlgorithm,
-		TemplateArn:             aws.String(tempArn),
-		Csr:                     cr.Spec.Request,
-		Validity: &acmpcatypes.Validity{
-			Type:  acmpcatypes.ValidityPeriodTypeAbsolute,
-			Value: &validityExpiration,
-		},
-		IdempotencyToken: aws.String(token),
+// CertificateArn returns the certificate ARN recorded in the annotation key of
+// cr. Requests signed before the key was configured only have the
+// CertificateArnAnnotation, so it is read if key is absent.
+func CertificateArn(cr *cmapi.CertificateRequest, key string) (string, bool) {
+	if certArn, ok := cr.GetAnnotations()[CertificateArnKey(key)]; ok {
+		return certArn, true
 	}
+	certArn, ok := cr.GetAnnotations()[CertificateArnAnnotation]
+	return certArn, ok
+}
 
-	issueOutput, err := p.pcaClient.IssueCertificate(ctx, &issueParams)
+// DryRun reports whether the DryRunAnnotation of cr asks for a dry run
+func DryRun(cr *cmapi.CertificateRequest) bool {
+	return cr.GetAnnotations()[DryRunAnnotation] == "true"
+}
 
-	if err != nil {
-		return nil, nil, err
+// signingAlgorithmOverride returns the signing algorithm requested through the
+// SigningAlgorithmAnnotation, or an empty value if none was requested
+func signingAlgorithmOverride(cr *cmapi.CertificateRequest) (acmpcatypes.SigningAlgorithm, error) {
+	value, ok := cr.GetAnnotations()[SigningAlgorithmAnnotation]
+	if !ok || value == "" {
+		return "", nil
 	}
 
-	getParams := acmpca.GetCertificateInput{
-		CertificateArn:          aws.String(*issueOutput.CertificateArn),
-		CertificateAuthorityArn: aws.String(p.arn),
+	for _, algorithm := range acmpcatypes.SigningAlgorithm("").Values() {
+		if string(algorithm) == value {
+			return algorithm, nil
+		}
 	}
 
-	log.Info("Created certificate with arn: " + *issueOutput.CertificateArn)
+	return "", fmt.Errorf("%w %q in annotation %s", errInvalidSigningAlgorithm, value, SigningAlgorithmAnnotation)
+}
+
+// templateArnOverride returns the template requested through the
+// TemplateArnAnnotation, or "" if none was requested
+func templateArnOverride(cr *cmapi.CertificateRequest) (string, error) {
+	value, ok := cr.GetAnnotations()[TemplateArnAnnotation]
+	if !ok || value == "" {
+		return "", nil
+	}
+	if !ValidTemplateArn(value) {
+		return "", fmt.Errorf("%w %q in annotation %s", errInvalidTemplateArn, value, TemplateArnAnnotation)
+	}
+	return value, nil
+}
 
-	waiter := acmpca.NewCertificateIssuedWaiter(p.pcaClient)
-	err = waiter.Wait(ctx, &getParams, 5*time.Minute)
-	if err != nil {
-		return nil, nil, err
+// notAfterOverride returns the expiration requested through the
+// NotAfterAnnotation, or the zero time if none was requested. Expirations that
+// are not after now are rejected.
+func notAfterOverride(cr *cmapi.CertificateRequest, now time.Time) (time.Time, error) {
+	value, ok := cr.GetAnnotations()[NotAfterAnnotation]
+	if !ok || value == "" {
+		return time.Time{}, nil
 	}
 
-	getOutput, err := p.pcaClient.GetCertificate(ctx, &getParams)
+	notAfter, err := time.Parse(time.RFC3339, value)
 	if err != nil {
-		return nil, nil, err
+		return time.Time{}, fmt.Errorf("%w %q in annotation %s: %v", errInvalidNotAfter, value, NotAfterAnnotation, err)
+	}
+	if !notAfter.After(now) {
+		return time.Time{}, fmt.Errorf("%w %q in annotation %s: the date is in the past", errInvalidNotAfter, value, NotAfterAnnotation)
 	}
+	return notAfter, nil
+}
 
-	certPem := []byte(*getOutput.Certificate + "\n")
-	chainPem := []byte(*getOutput.CertificateChain)
-	chainIntCAs, rootCA, err := splitRootCACertificate(chainPem)
-	if err != nil {
-		return nil, nil, err
+// EffectiveDuration returns the validity to request for cr. The requested
+// duration falls back to defaultValidity and then DEFAULT_DURATION, and is
+// clamped to maxValidity when that is set. The second return value reports
+// whether clamping took place.
+func EffectiveDuration(cr *cmapi.CertificateRequest, defaultValidity, maxValidity time.Duration) (time.Duration, bool) {
+	duration := DEFAULT_DURATION * time.Second
+	if defaultValidity > 0 {
+		duration = defaultValidity
+	}
+	if cr.Spec.Duration != nil {
+		duration = cr.Spec.Duration.Duration
 	}
-	certPem = append(certPem, chainIntCAs...)
 
-	return certPem, rootCA, nil
+	if maxValidity > 0 && duration > maxValidity {
+		return maxValidity, true
+	}
+	return duration, false
 }
 
-func getSigningAlgorithm(ctx context.Context, p *PCAProvisioner) error {
-	if p.signingAlgorithm != nil {
-		return nil
+// validity converts a duration into a PCA validity of periodType. DAYS, MONTHS
+// and YEARS are rounded to the nearest whole unit, but at least one, and down
+// if rounding up would exceed maxValidity. If even one unit exceeds
+// maxValidity, the duration is expressed as an ABSOLUTE expiration instead.
+// Without a periodType, whole days are expressed in DAYS, anything else as an
+// ABSOLUTE expiration relative to now.
+func validity(duration, maxValidity time.Duration, now time.Time, periodType acmpcatypes.ValidityPeriodType) *acmpcatypes.Validity {
+	const (
+		day = 24 * time.Hour
+		// months and years vary in length, so the duration is divided by
+		// their average length in the Gregorian calendar
+		year  = 365*day + 6*time.Hour
+		month = year / 12
+	)
+
+	if periodType == "" {
+		periodType = acmpcatypes.ValidityPeriodTypeAbsolute
+		if duration%day == 0 {
+			periodType = acmpcatypes.ValidityPeriodTypeDays
+		}
 	}
 
-	describeParams := acmpca.DescribeCertificateAuthorityInput{
-		CertificateAuthorityArn: aws.String(p.arn),
+	units := map[acmpcatypes.ValidityPeriodType]time.Duration{
+		acmpcatypes.ValidityPeriodTypeDays:   day,
+		acmpcatypes.ValidityPeriodTypeMonths: month,
+		acmpcatypes.ValidityPeriodTypeYears:  year,
+	}
+	if unit, ok := units[periodType]; ok && maxValidity > 0 && unit > maxValidity {
+		periodType = acmpcatypes.ValidityPeriodTypeAbsolute
 	}
-	describeOutput, err := p.pcaClient.DescribeCertificateAuthority(ctx, &describeParams)
 
-	if err != nil {
-		return err
+	var value int64
+	switch periodType {
+	case acmpcatypes.ValidityPeriodTypeDays, acmpcatypes.ValidityPeriodTypeMonths, acmpcatypes.ValidityPeriodTypeYears:
+		value = wholeUnits(duration, units[periodType], maxValidity)
+	case acmpcatypes.ValidityPeriodTypeEndDate:
+		// END_DATE is the expiration in UTC formatted as YYYYMMDDHHMMSS
+		value, _ = strconv.ParseInt(now.Add(duration).UTC().Format("20060102150405"), 10, 64)
+	default:
+		value = now.Unix() + int64(duration.Seconds())
 	}
+	return &acmpcatypes.Validity{
+		Type:  periodType,
+		Value: &value,
+	}
+}
 
-	p.signingAlgorithm = &describeOutput.CertificateAuthority.CertificateAuthorityConfiguration.SigningAlgorithm
-	return nil
+// wholeUnits returns duration in whole units, rounded to the nearest, but at
+// least one. It rounds down instead if rounding up would exceed limit, unless
+// limit is zero.
+func wholeUnits(duration, unit, limit time.Duration) int64 {
+	n := int64((duration + unit/2) / unit)
+	if limit > 0 && time.Duration(n)*unit > limit {
+		n = int64(limit / unit)
+	}
+	return max(n, 1)
 }
 
 func (p *PCAProvisioner) now() time.Time {
@@ -188,12 +1771,220 @@ func (p *PCAProvisioner) now() time.Time {
 	return time.Now()
 }
 
-func templateArn(caArn string, spec cmapi.CertificateRequestSpec) string {
+// ValidPathLength reports whether PCA has a subordinate CA template for
+// pathLength
+func ValidPathLength(pathLength int32) bool {
+	return pathLength >= 0 && pathLength <= maxPathLength
+}
+
+// ValidTemplateArn reports whether arn is a well-formed PCA certificate template ARN
+func ValidTemplateArn(arn string) bool {
+	return templateArnPattern.MatchString(arn)
+}
+
+// validateUsages returns ErrUsageNotAllowed for usages that no template PCA
+// could select for spec supports: CA certificates cannot have extended key usages, and end-entity
+// certificates cannot sign certificates
+func validateUsages(spec cmapi.CertificateRequestSpec) error {
+	for _, usage := range spec.Usages {
+		switch {
+		case spec.IsCA && extendedKeyUsages[usage]:
+			return fmt.Errorf("%w: CA certificates cannot have the extended key usage %q", ErrUsageNotAllowed, usage)
+		case !spec.IsCA && usage == cmapi.UsageCertSign:
+			return fmt.Errorf("%w: the usage %q requires isCA", ErrUsageNotAllowed, usage)
+		}
+	}
+	return nil
+}
+
+// validateTemplateUsages returns ErrUsageNotAllowed if usages contain an
+// extended key usage the template does not have, which IssueCertificate would
+// reject or silently drop. Other key usages are not checked.
+func validateTemplateUsages(usages []cmapi.KeyUsage, templateArn string) error {
+	name := templateName(templateArn)
+	allowed, ok := templateExtendedKeyUsages[name]
+	if !ok {
+		return nil
+	}
+	for _, usage := range usages {
+		if extendedKeyUsages[usage] && !slices.Contains(allowed, usage) {
+			return fmt.Errorf("%w: the template %s does not allow the usage %q", ErrUsageNotAllowed, name, usage)
+		}
+	}
+	return nil
+}
+
+// templateName returns the name of a PCA template ARN without its version,
+// passthrough suffix and path length, e.g. SubordinateCACertificate for
+// arn:aws:acm-pca:::template/SubordinateCACertificate_PathLen0_APIPassthrough/V1
+func templateName(templateArn string) string {
+	_, name, _ := strings.Cut(templateArn, ":template/")
+	name, _, _ = strings.Cut(name, "/")
+	name = strings.TrimSuffix(name, "_APIPassthrough")
+	name = strings.TrimSuffix(name, "_CSRPassthrough")
+	name = strings.TrimSuffix(name, "_APICSRPassthrough")
+	if i := strings.Index(name, "_PathLen"); i >= 0 {
+		name = name[:i]
+	}
+	return name
+}
+
+// parseCSR decodes a PEM encoded CSR and returns ErrInvalidCSR unless its
+// signature verifies and PCA can issue certificates for its key, so that such
+// CSRs do not fail opaquely in IssueCertificate
+func parseCSR(request []byte) (*x509.CertificateRequest, error) {
+	if len(request) > maxCSRSize {
+		return nil, fmt.Errorf("%w: %d bytes exceeds the maximum of %d bytes", ErrInvalidCSR, len(request), maxCSRSize)
+	}
+	block, _ := pem.Decode(request)
+	if block == nil {
+		return nil, fmt.Errorf("%w: failed to decode PEM", ErrInvalidCSR)
+	}
+	csr, err := x509.ParseCertificateRequest(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidCSR, err)
+	}
+	if err := csr.CheckSignature(); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidCSR, err)
+	}
+
+	switch key := csr.PublicKey.(type) {
+	case *rsa.PublicKey:
+		if bits := key.N.BitLen(); !slices.Contains(supportedRSAKeySizes, bits) {
+			return nil, fmt.Errorf("%w: RSA keys must be 2048, 3072 or 4096 bits, got %d", ErrInvalidCSR, bits)
+		}
+	case *ecdsa.PublicKey:
+		if !slices.Contains(supportedECDSACurves, key.Curve) {
+			return nil, fmt.Errorf("%w: ECDSA keys must use the P-256, P-384 or P-521 curve, got %s", ErrInvalidCSR, key.Curve.Params().Name)
+		}
+	default:
+		return nil, fmt.Errorf("%w: unsupported key type %s", ErrInvalidCSR, csr.PublicKeyAlgorithm)
+	}
+	return csr, nil
+}
+
+// checkPolicy returns ErrDeniedByPolicy unless the namespace and all DNS names
+// of cr are allowed by the provisioner. With a policy, the spiffe URIs of the
+// CSR must also be well-formed SPIFFE IDs, at most one of them.
+func (p *PCAProvisioner) checkPolicy(cr *cmapi.CertificateRequest, csr *x509.CertificateRequest) error {
+	if len(p.allowedNamespaces) == 0 && len(p.allowedDomains) == 0 {
+		return nil
+	}
+	if len(p.allowedNamespaces) > 0 && !slices.Contains(p.allowedNamespaces, cr.Namespace) {
+		return fmt.Errorf("%w: namespace %s is not allowed", ErrDeniedByPolicy, cr.Namespace)
+	}
+
+	spiffeIDs := 0
+	for _, uri := range csr.URIs {
+		if uri.Scheme != spiffeScheme {
+			continue
+		}
+		if spiffeIDs++; spiffeIDs > 1 {
+			return fmt.Errorf("%w: an SVID must have exactly one SPIFFE ID", ErrDeniedByPolicy)
+		}
+		if err := validateSPIFFEID(uri); err != nil {
+			return fmt.Errorf("%w: %v", ErrDeniedByPolicy, err)
+		}
+	}
+
+	if len(p.allowedDomains) == 0 {
+		return nil
+	}
+
+	for _, name := range csr.DNSNames {
+		if !domainAllowed(name, p.allowedDomains) {
+			return fmt.Errorf("%w: DNS name %s is not in the allowed domains", ErrDeniedByPolicy, name)
+		}
+	}
+	return nil
+}
+
+// domainAllowed reports whether name is one of the allowed domains or one of
+// their subdomains. Domains starting with "*." only allow their subdomains.
+// A wildcard name is allowed if every name it matches is, e.g. "*.example.com"
+// is allowed by "example.com" and "*.example.com", whereas "*.com" is not.
+func domainAllowed(name string, allowedDomains []string) bool {
+	name = strings.TrimSuffix(strings.ToLower(name), ".")
+	wildcard := strings.HasPrefix(name, "*.")
+	name = strings.TrimPrefix(name, "*.")
+	// Wildcards are only valid as the entire leftmost label
+	if name == "" || strings.Contains(name, "*") {
+		return false
+	}
+
+	for _, domain := range allowedDomains {
+		domain = strings.TrimSuffix(strings.ToLower(domain), ".")
+		subdomainsOnly := strings.HasPrefix(domain, "*.")
+		domain = strings.TrimPrefix(domain, "*.")
+
+		if strings.HasSuffix(name, "."+domain) {
+			return true
+		}
+		if name == domain && (wildcard || !subdomainsOnly) {
+			return true
+		}
+	}
+	return false
+}
+
+// validateSPIFFEID checks that uri is a SPIFFE ID as specified by
+// https://github.com/spiffe/spiffe/blob/main/standards/SPIFFE-ID.md: a trust
+// domain of lowercase letters, digits, dots, dashes and underscores, without
+// user info or port, and a path of non-empty segments other than "." and ".."
+// of letters, digits, dots, dashes and underscores, without query or fragment.
+func validateSPIFFEID(uri *url.URL) error {
+	id := uri.String()
+	switch {
+	case len(id) > maxSPIFFEIDLength:
+		return fmt.Errorf("SPIFFE ID must be at most %d bytes", maxSPIFFEIDLength)
+	case uri.Opaque != "" || uri.Host == "":
+		return fmt.Errorf("SPIFFE ID %q has no trust domain", id)
+	case uri.User != nil || uri.Port() != "":
+		return fmt.Errorf("SPIFFE ID %q must not have user info or a port", id)
+	case uri.RawQuery != "" || uri.ForceQuery || uri.Fragment != "":
+		return fmt.Errorf("SPIFFE ID %q must not have a query or fragment", id)
+	case !spiffeTrustDomainPattern.MatchString(uri.Host):
+		return fmt.Errorf("trust domain of SPIFFE ID %q contains invalid characters", id)
+	}
+
+	path := uri.EscapedPath()
+	if path == "" {
+		return nil
+	}
+	for _, segment := range strings.Split(strings.TrimPrefix(path, "/"), "/") {
+		if segment == "" || segment == "." || segment == ".." || !spiffePathSegmentPattern.MatchString(segment) {
+			return fmt.Errorf("path of SPIFFE ID %q has an invalid segment %q", id, segment)
+		}
+	}
+	return nil
+}
+
+// csrRequestsCA reports whether the basic constraints extension of csr
+// requests a CA certificate
+func csrRequestsCA(csr *x509.CertificateRequest) bool {
+	for _, ext := range csr.Extensions {
+		if !ext.Id.Equal(oidExtensionBasicConstraints) {
+			continue
+		}
+		var constraints struct {
+			IsCA       bool `asn1:"optional"`
+			MaxPathLen int  `asn1:"optional,default:-1"`
+		}
+		if _, err := asn1.Unmarshal(ext.Value, &constraints); err == nil {
+			return constraints.IsCA
+		}
+	}
+	return false
+}
+
+// templateArn returns the PCA template for the usages of spec. CA certificates
+// are issued with the subordinate CA template of pathLength.
+func templateArn(caArn string, spec cmapi.CertificateRequestSpec, pathLength int) string {
 	arn := strings.SplitAfterN(caArn, ":", 3)
 	prefix := arn[0] + arn[1]
 
 	if spec.IsCA {
-		return prefix + "acm-pca:::template/SubordinateCACertificate_PathLen0/V1"
+		return prefix + fmt.Sprintf("acm-pca:::template/SubordinateCACertificate_PathLen%d/V1", pathLength)
 	}
 
 	if len(spec.Usages) == 1 {
@@ -218,6 +2009,50 @@ func templateArn(caArn string, spec cmapi.CertificateRequestSpec) string {
 	return prefix + "acm-pca:::template/BlankEndEntityCertificate_APICSRPassthrough/V1"
 }
 
+// apiPassthroughTemplateArn returns the variant of a PCA template that applies
+// the ApiPassthrough of IssueCertificate. Templates that pass through both the
+// API and CSR extensions are returned as is.
+func apiPassthroughTemplateArn(templateArn string) string {
+	if strings.Contains(templateArn, "Passthrough/") {
+		return templateArn
+	}
+	i := strings.LastIndex(templateArn, "/V")
+	return templateArn[:i] + "_APIPassthrough" + templateArn[i:]
+}
+
+// CertificateSerialNumber returns the serial number of the first certificate in
+// certPem as colon separated hex bytes, e.g. 0a:1b:2c
+func CertificateSerialNumber(certPem []byte) (string, error) {
+	block, _ := pem.Decode(certPem)
+	if block == nil || block.Type != "CERTIFICATE" {
+		return "", fmt.Errorf("failed to read certificate")
+	}
+	cert, err := x509.ParseCertificate(block.Bytes)
+	if err != nil {
+		return "", err
+	}
+
+	serial := cert.SerialNumber.Bytes()
+	if len(serial) == 0 {
+		serial = []byte{0}
+	}
+	parts := make([]string, len(serial))
+	for i, b := range serial {
+		parts[i] = fmt.Sprintf("%02x", b)
+	}
+	return strings.Join(parts, ":"), nil
+}
+
+// issuingCACertificate returns the first certificate of the CA chain PCA
+// returns with a certificate, which is the CA that issued it
+func issuingCACertificate(caCertChainPem []byte) ([]byte, error) {
+	block, _ := pem.Decode(caCertChainPem)
+	if block == nil || block.Type != "CERTIFICATE" {
+		return nil, fmt.Errorf("failed to read certificate")
+	}
+	return pem.EncodeToMemory(block), nil
+}
+
 func splitRootCACertificate(caCertChainPem []byte) ([]byte, []byte, error) {
 	var caChainCerts []byte
 	var rootCACert []byte
@@ -240,3 +2075,43 @@ func splitRootCACertificate(caCertChainPem []byte) ([]byte, []byte, error) {
 	}
 	return caChainCerts, rootCACert, nil
 }
+
+// IsThrottlingError reports whether err means that PCA is throttling requests,
+// e.g. a ThrottlingException or LimitExceededException
+func IsThrottlingError(err error) bool {
+	return retry.ThrottleErrorCode{Codes: retry.DefaultThrottleErrorCodes}.IsErrorThrottle(err) == aws.TrueTernary
+}
+
+// IsExpiredTokenError reports whether err means that the session token of the
+// credentials used for the request has expired
+func IsExpiredTokenError(err error) bool {
+	var apiErr smithy.APIError
+	if !errors.As(err, &apiErr) {
+		return false
+	}
+	switch apiErr.ErrorCode() {
+	case "ExpiredToken", "ExpiredTokenException":
+		return true
+	}
+	return false
+}
+
+// RetryAfter returns the delay requested by the Retry-After header of the
+// response that caused err, if there is one
+func RetryAfter(err error) (time.Duration, bool) {
+	var respErr *smithyhttp.ResponseError
+	if !errors.As(err, &respErr) || respErr.Response == nil || respErr.Response.Response == nil {
+		return 0, false
+	}
+
+	header := respErr.Response.Header.Get("Retry-After")
+	if seconds, err := strconv.Atoi(header); err == nil && seconds >= 0 {
+		return time.Duration(seconds) * time.Second, true
+	}
+	if at, err := http.ParseTime(header); err == nil {
+		if delay := time.Until(at); delay > 0 {
+			return delay, true
+		}
+	}
+	return 0, false
+}
@@ -0,0 +1,245 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package aws
+
+import (
+	"bytes"
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	awsarn "github.com/aws/aws-sdk-go-v2/aws/arn"
+)
+
+const (
+	rolesAnywhereService         = "rolesanywhere"
+	rolesAnywhereSessionDuration = time.Hour
+	rolesAnywhereTimeFormat      = "20060102T150405Z"
+)
+
+// ErrInvalidRolesAnywhere is returned for client certificates, keys or ARNs
+// that cannot be used with IAM Roles Anywhere
+var ErrInvalidRolesAnywhere = errors.New("invalid IAM Roles Anywhere configuration")
+
+// RolesAnywhereProvider retrieves temporary credentials from the CreateSession
+// API of IAM Roles Anywhere, authenticating with an X.509 client certificate.
+// The requests are signed like the aws_signing_helper of Roles Anywhere does.
+type RolesAnywhereProvider struct {
+	// Endpoint overrides the regional Roles Anywhere endpoint
+	Endpoint string
+
+	client         aws.HTTPClient
+	certificate    *x509.Certificate
+	chain          []*x509.Certificate
+	key            crypto.Signer
+	region         string
+	trustAnchorArn string
+	profileArn     string
+	roleArn        string
+	now            func() time.Time
+}
+
+// NewRolesAnywhereProvider returns a provider exchanging the first of
+// certificates, signed by key, for credentials of the role. The remaining
+// certificates are sent as the chain to the trust anchor. The region of the
+// trust anchor selects the Roles Anywhere endpoint.
+func NewRolesAnywhereProvider(client aws.HTTPClient, certificates []*x509.Certificate, key crypto.Signer, trustAnchorArn, profileArn, roleArn string) (*RolesAnywhereProvider, error) {
+	if len(certificates) == 0 {
+		return nil, fmt.Errorf("%w: no client certificate", ErrInvalidRolesAnywhere)
+	}
+	switch key.(type) {
+	case *rsa.PrivateKey, *ecdsa.PrivateKey:
+	default:
+		return nil, fmt.Errorf("%w: unsupported key type %T", ErrInvalidRolesAnywhere, key)
+	}
+	if !publicKeyEqual(certificates[0].PublicKey, key.Public()) {
+		return nil, fmt.Errorf("%w: the key does not match the client certificate", ErrInvalidRolesAnywhere)
+	}
+
+	trustAnchor, err := awsarn.Parse(trustAnchorArn)
+	if err != nil || trustAnchor.Service != rolesAnywhereService {
+		return nil, fmt.Errorf("%w: %q is not the ARN of a trust anchor", ErrInvalidRolesAnywhere, trustAnchorArn)
+	}
+	if _, err := awsarn.Parse(profileArn); err != nil {
+		return nil, fmt.Errorf("%w: %q is not the ARN of a profile", ErrInvalidRolesAnywhere, profileArn)
+	}
+	if _, err := awsarn.Parse(roleArn); err != nil {
+		return nil, fmt.Errorf("%w: %q is not the ARN of a role", ErrInvalidRolesAnywhere, roleArn)
+	}
+
+	if client == nil {
+		client = http.DefaultClient
+	}
+	suffix := "amazonaws.com"
+	if trustAnchor.Partition == "aws-cn" {
+		suffix = "amazonaws.com.cn"
+	}
+	return &RolesAnywhereProvider{
+		Endpoint:       fmt.Sprintf("https://%s.%s.%s", rolesAnywhereService, trustAnchor.Region, suffix),
+		client:         client,
+		certificate:    certificates[0],
+		chain:          certificates[1:],
+		key:            key,
+		region:         trustAnchor.Region,
+		trustAnchorArn: trustAnchorArn,
+		profileArn:     profileArn,
+		roleArn:        roleArn,
+		now:            time.Now,
+	}, nil
+}
+
+func publicKeyEqual(a, b crypto.PublicKey) bool {
+	k, ok := a.(interface{ Equal(crypto.PublicKey) bool })
+	return ok && k.Equal(b)
+}
+
+type rolesAnywhereSessionInput struct {
+	DurationSeconds int    `json:"durationSeconds"`
+	ProfileArn      string `json:"profileArn"`
+	RoleArn         string `json:"roleArn"`
+	TrustAnchorArn  string `json:"trustAnchorArn"`
+}
+
+type rolesAnywhereSessionOutput struct {
+	CredentialSet []struct {
+		Credentials struct {
+			AccessKeyID     string    `json:"accessKeyId"`
+			SecretAccessKey string    `json:"secretAccessKey"`
+			SessionToken    string    `json:"sessionToken"`
+			Expiration      time.Time `json:"expiration"`
+		} `json:"credentials"`
+	} `json:"credentialSet"`
+	Message string `json:"message"`
+}
+
+// Retrieve creates a Roles Anywhere session and returns its credentials
+func (p *RolesAnywhereProvider) Retrieve(ctx context.Context) (aws.Credentials, error) {
+	body, err := json.Marshal(rolesAnywhereSessionInput{
+		DurationSeconds: int(rolesAnywhereSessionDuration.Seconds()),
+		ProfileArn:      p.profileArn,
+		RoleArn:         p.roleArn,
+		TrustAnchorArn:  p.trustAnchorArn,
+	})
+	if err != nil {
+		return aws.Credentials{}, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, strings.TrimSuffix(p.Endpoint, "/")+"/sessions", bytes.NewReader(body))
+	if err != nil {
+		return aws.Credentials{}, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if err := p.sign(req, body); err != nil {
+		return aws.Credentials{}, err
+	}
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return aws.Credentials{}, fmt.Errorf("failed to create Roles Anywhere session: %w", err)
+	}
+	defer resp.Body.Close()
+
+	var out rolesAnywhereSessionOutput
+	if err := json.NewDecoder(io.LimitReader(resp.Body, 1<<20)).Decode(&out); err != nil && resp.StatusCode == http.StatusCreated {
+		return aws.Credentials{}, fmt.Errorf("failed to decode Roles Anywhere session: %w", err)
+	}
+	if resp.StatusCode != http.StatusCreated {
+		return aws.Credentials{}, fmt.Errorf("failed to create Roles Anywhere session: %s %s", resp.Status, out.Message)
+	}
+	if len(out.CredentialSet) == 0 {
+		return aws.Credentials{}, errors.New("failed to create Roles Anywhere session: no credentials returned")
+	}
+
+	creds := out.CredentialSet[0].Credentials
+	return aws.Credentials{
+		AccessKeyID:     creds.AccessKeyID,
+		SecretAccessKey: creds.SecretAccessKey,
+		SessionToken:    creds.SessionToken,
+		Source:          "RolesAnywhereProvider",
+		CanExpire:       true,
+		Expires:         creds.Expiration,
+	}, nil
+}
+
+// sign adds the X.509 SigV4 headers of Roles Anywhere to req. The request is
+// canonicalized like for SigV4, but the string to sign is signed with the key
+// of the client certificate, which is identified by its serial number.
+func (p *RolesAnywhereProvider) sign(req *http.Request, body []byte) error {
+	algorithm := "AWS4-X509-RSA-SHA256"
+	if _, ok := p.key.(*ecdsa.PrivateKey); ok {
+		algorithm = "AWS4-X509-ECDSA-SHA256"
+	}
+
+	now := p.now().UTC()
+	req.Header.Set("X-Amz-Date", now.Format(rolesAnywhereTimeFormat))
+	req.Header.Set("X-Amz-X509", base64.StdEncoding.EncodeToString(p.certificate.Raw))
+	signedHeaders := []string{"content-type", "host", "x-amz-date", "x-amz-x509"}
+	if len(p.chain) > 0 {
+		chain := make([]string, len(p.chain))
+		for i, cert := range p.chain {
+			chain[i] = base64.StdEncoding.EncodeToString(cert.Raw)
+		}
+		req.Header.Set("X-Amz-X509-Chain", strings.Join(chain, ","))
+		signedHeaders = append(signedHeaders, "x-amz-x509-chain")
+	}
+
+	var canonicalHeaders strings.Builder
+	for _, h := range signedHeaders {
+		value := req.Header.Get(h)
+		if h == "host" {
+			value = req.URL.Host
+		}
+		fmt.Fprintf(&canonicalHeaders, "%s:%s\n", h, strings.TrimSpace(value))
+	}
+	payloadHash := sha256.Sum256(body)
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		req.URL.EscapedPath(),
+		req.URL.RawQuery,
+		canonicalHeaders.String(),
+		strings.Join(signedHeaders, ";"),
+		hex.EncodeToString(payloadHash[:]),
+	}, "\n")
+
+	scope := fmt.Sprintf("%s/%s/%s/aws4_request", now.Format("20060102"), p.region, rolesAnywhereService)
+	canonicalHash := sha256.Sum256([]byte(canonicalRequest))
+	stringToSign := strings.Join([]string{algorithm, now.Format(rolesAnywhereTimeFormat), scope, hex.EncodeToString(canonicalHash[:])}, "\n")
+
+	digest := sha256.Sum256([]byte(stringToSign))
+	signature, err := p.key.Sign(rand.Reader, digest[:], crypto.SHA256)
+	if err != nil {
+		return fmt.Errorf("failed to sign Roles Anywhere request: %w", err)
+	}
+
+	req.Header.Set("Authorization", fmt.Sprintf("%s Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		algorithm, p.certificate.SerialNumber.String(), scope, strings.Join(signedHeaders, ";"), hex.EncodeToString(signature)))
+	return nil
+}
@@ -0,0 +1,247 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package aws
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	trustAnchorArn = "arn:aws:rolesanywhere:us-east-1:111122223333:trust-anchor/0a1b2c3d-0a1b-0a1b-0a1b-0a1b2c3d4e5f"
+	profileArn     = "arn:aws:rolesanywhere:us-east-1:111122223333:profile/0a1b2c3d-0a1b-0a1b-0a1b-0a1b2c3d4e5f"
+	roleArn        = "arn:aws:iam::111122223333:role/pca-signer"
+)
+
+func clientCertificate(t *testing.T, key crypto.Signer, serial int64) *x509.Certificate {
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(serial),
+		Subject:      pkix.Name{CommonName: "aws-privateca-issuer"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, key.Public(), key)
+	require.NoError(t, err)
+	cert, err := x509.ParseCertificate(der)
+	require.NoError(t, err)
+	return cert
+}
+
+// verifyRolesAnywhereSignature recomputes the string to sign of a request of
+// the provider and verifies its signature with the client certificate
+func verifyRolesAnywhereSignature(t *testing.T, r *http.Request, body []byte) *x509.Certificate {
+	der, err := base64.StdEncoding.DecodeString(r.Header.Get("X-Amz-X509"))
+	require.NoError(t, err)
+	cert, err := x509.ParseCertificate(der)
+	require.NoError(t, err)
+
+	var algorithm, credential, signedHeaders, signature string
+	_, err = fmt.Sscanf(strings.ReplaceAll(r.Header.Get("Authorization"), ",", ""), "%s Credential=%s SignedHeaders=%s Signature=%s", &algorithm, &credential, &signedHeaders, &signature)
+	require.NoError(t, err)
+	serial, scope, _ := strings.Cut(credential, "/")
+	assert.Equal(t, cert.SerialNumber.String(), serial)
+
+	var canonicalHeaders strings.Builder
+	for _, h := range strings.Split(signedHeaders, ";") {
+		value := r.Header.Get(h)
+		if h == "host" {
+			value = r.Host
+		}
+		fmt.Fprintf(&canonicalHeaders, "%s:%s\n", h, value)
+	}
+	payloadHash := sha256.Sum256(body)
+	canonicalHash := sha256.Sum256([]byte(strings.Join([]string{r.Method, r.URL.Path, r.URL.RawQuery, canonicalHeaders.String(), signedHeaders, hex.EncodeToString(payloadHash[:])}, "\n")))
+	digest := sha256.Sum256([]byte(strings.Join([]string{algorithm, r.Header.Get("X-Amz-Date"), scope, hex.EncodeToString(canonicalHash[:])}, "\n")))
+
+	sig, err := hex.DecodeString(signature)
+	require.NoError(t, err)
+	switch pub := cert.PublicKey.(type) {
+	case *rsa.PublicKey:
+		assert.Equal(t, "AWS4-X509-RSA-SHA256", algorithm)
+		assert.NoError(t, rsa.VerifyPKCS1v15(pub, crypto.SHA256, digest[:], sig), "invalid signature")
+	case *ecdsa.PublicKey:
+		assert.Equal(t, "AWS4-X509-ECDSA-SHA256", algorithm)
+		assert.True(t, ecdsa.VerifyASN1(pub, digest[:], sig), "invalid signature")
+	}
+	return cert
+}
+
+func TestRolesAnywhereProviderRetrieve(t *testing.T) {
+	rsaKey, _ := rsa.GenerateKey(rand.Reader, 2048)
+	ecKey, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	intermediateKey, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	expiration := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+
+	type testCase struct {
+		key           crypto.Signer
+		chain         bool
+		status        int
+		expectedError string
+	}
+	tests := map[string]testCase{
+		"success-rsa": {
+			key:    rsaKey,
+			status: http.StatusCreated,
+		},
+		"success-ecdsa": {
+			key:    ecKey,
+			status: http.StatusCreated,
+		},
+		"success-chain": {
+			key:    ecKey,
+			chain:  true,
+			status: http.StatusCreated,
+		},
+		"failure-access-denied": {
+			key:           ecKey,
+			status:        http.StatusForbidden,
+			expectedError: "failed to create Roles Anywhere session: 403 Forbidden Untrusted signing certificate",
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			certificates := []*x509.Certificate{clientCertificate(t, tc.key, 4242)}
+			if tc.chain {
+				certificates = append(certificates, clientCertificate(t, intermediateKey, 1))
+			}
+
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				body, err := io.ReadAll(r.Body)
+				require.NoError(t, err)
+				assert.Equal(t, http.MethodPost, r.Method)
+				assert.Equal(t, "/sessions", r.URL.Path)
+				assert.JSONEq(t, `{"durationSeconds":3600,"profileArn":"`+profileArn+`","roleArn":"`+roleArn+`","trustAnchorArn":"`+trustAnchorArn+`"}`, string(body))
+
+				cert := verifyRolesAnywhereSignature(t, r, body)
+				assert.Equal(t, certificates[0].Raw, cert.Raw)
+				if tc.chain {
+					assert.Equal(t, base64.StdEncoding.EncodeToString(certificates[1].Raw), r.Header.Get("X-Amz-X509-Chain"))
+				} else {
+					assert.Empty(t, r.Header.Get("X-Amz-X509-Chain"))
+				}
+
+				w.WriteHeader(tc.status)
+				if tc.status != http.StatusCreated {
+					fmt.Fprint(w, `{"message":"Untrusted signing certificate"}`)
+					return
+				}
+				_ = json.NewEncoder(w).Encode(map[string]interface{}{
+					"credentialSet": []interface{}{map[string]interface{}{
+						"credentials": map[string]interface{}{
+							"accessKeyId":     "fake-access-key-id",
+							"secretAccessKey": "fake-secret-access-key",
+							"sessionToken":    "fake-session-token",
+							"expiration":      expiration.Format(time.RFC3339),
+						},
+					}},
+				})
+			}))
+			defer server.Close()
+
+			provider, err := NewRolesAnywhereProvider(server.Client(), certificates, tc.key, trustAnchorArn, profileArn, roleArn)
+			require.NoError(t, err)
+			assert.Equal(t, "https://rolesanywhere.us-east-1.amazonaws.com", provider.Endpoint)
+			provider.Endpoint = server.URL
+
+			creds, err := provider.Retrieve(context.TODO())
+			if tc.expectedError != "" {
+				assert.EqualError(t, err, tc.expectedError)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, "fake-access-key-id", creds.AccessKeyID)
+			assert.Equal(t, "fake-secret-access-key", creds.SecretAccessKey)
+			assert.Equal(t, "fake-session-token", creds.SessionToken)
+			assert.True(t, creds.CanExpire)
+			assert.True(t, expiration.Equal(creds.Expires))
+		})
+	}
+}
+
+func TestNewRolesAnywhereProvider(t *testing.T) {
+	key, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	otherKey, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	cert := clientCertificate(t, key, 1)
+
+	type testCase struct {
+		key            crypto.Signer
+		trustAnchorArn string
+		profileArn     string
+		expectedError  string
+	}
+	tests := map[string]testCase{
+		"success": {
+			key:            key,
+			trustAnchorArn: trustAnchorArn,
+			profileArn:     profileArn,
+		},
+		"failure-key-mismatch": {
+			key:            otherKey,
+			trustAnchorArn: trustAnchorArn,
+			profileArn:     profileArn,
+			expectedError:  "invalid IAM Roles Anywhere configuration: the key does not match the client certificate",
+		},
+		"failure-trust-anchor-arn": {
+			key:            key,
+			trustAnchorArn: roleArn,
+			profileArn:     profileArn,
+			expectedError:  `invalid IAM Roles Anywhere configuration: "` + roleArn + `" is not the ARN of a trust anchor`,
+		},
+		"failure-profile-arn": {
+			key:            key,
+			trustAnchorArn: trustAnchorArn,
+			profileArn:     "profile",
+			expectedError:  `invalid IAM Roles Anywhere configuration: "profile" is not the ARN of a profile`,
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			_, err := NewRolesAnywhereProvider(nil, []*x509.Certificate{cert}, tc.key, tc.trustAnchorArn, tc.profileArn, roleArn)
+			if tc.expectedError != "" {
+				assert.ErrorIs(t, err, ErrInvalidRolesAnywhere)
+				assert.EqualError(t, err, tc.expectedError)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
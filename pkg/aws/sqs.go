@@ -0,0 +1,113 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package aws
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/sqs"
+)
+
+const (
+	// sqsWaitTime is how long a receive waits for messages to arrive
+	sqsWaitTime = 20 * time.Second
+	// sqsMaxMessages is the most messages SQS returns from a receive
+	sqsMaxMessages = 10
+)
+
+// ErrInvalidQueueURL is returned for queue URLs that are not SQS queue URLs
+var ErrInvalidQueueURL = errors.New("invalid SQS queue URL")
+
+// QueueMessage is a message received from an SQS queue
+type QueueMessage struct {
+	MessageID     string
+	ReceiptHandle string
+	Body          string
+}
+
+// SQSQueue receives and deletes the messages of an SQS queue
+type SQSQueue struct {
+	client   *sqs.Client
+	queueURL string
+}
+
+// NewSQSQueue returns the queue at queueURL, e.g.
+// https://sqs.us-east-1.amazonaws.com/111122223333/pca-events. Requests are
+// sent to the host of the queue URL with the credentials of cfg, for the
+// region of the queue URL or else the region of cfg.
+func NewSQSQueue(cfg aws.Config, queueURL string) (*SQSQueue, error) {
+	u, err := url.Parse(queueURL)
+	if err != nil || u.Host == "" || (u.Scheme != "https" && u.Scheme != "http") || strings.Count(strings.Trim(u.Path, "/"), "/") != 1 {
+		return nil, fmt.Errorf("%w: %q", ErrInvalidQueueURL, queueURL)
+	}
+	region := cfg.Region
+	if labels := strings.Split(u.Hostname(), "."); len(labels) > 2 && labels[0] == "sqs" && labels[1] != "amazonaws" {
+		region = labels[1]
+	}
+	if region == "" {
+		return nil, fmt.Errorf("%w: no region for %q", ErrInvalidQueueURL, queueURL)
+	}
+
+	client := sqs.NewFromConfig(cfg, func(o *sqs.Options) {
+		o.Region = region
+		o.BaseEndpoint = aws.String(u.Scheme + "://" + u.Host)
+	})
+	return &SQSQueue{
+		client:   client,
+		queueURL: queueURL,
+	}, nil
+}
+
+// Receive long polls the queue for up to 20 seconds and returns the messages
+// that arrived, at most 10
+func (q *SQSQueue) Receive(ctx context.Context) ([]QueueMessage, error) {
+	out, err := q.client.ReceiveMessage(ctx, &sqs.ReceiveMessageInput{
+		QueueUrl:            aws.String(q.queueURL),
+		MaxNumberOfMessages: sqsMaxMessages,
+		WaitTimeSeconds:     int32(sqsWaitTime.Seconds()),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to receive from SQS: %w", err)
+	}
+	messages := make([]QueueMessage, 0, len(out.Messages))
+	for _, message := range out.Messages {
+		messages = append(messages, QueueMessage{
+			MessageID:     aws.ToString(message.MessageId),
+			ReceiptHandle: aws.ToString(message.ReceiptHandle),
+			Body:          aws.ToString(message.Body),
+		})
+	}
+	return messages, nil
+}
+
+// Delete removes a received message from the queue
+func (q *SQSQueue) Delete(ctx context.Context, receiptHandle string) error {
+	_, err := q.client.DeleteMessage(ctx, &sqs.DeleteMessageInput{
+		QueueUrl:      aws.String(q.queueURL),
+		ReceiptHandle: aws.String(receiptHandle),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to delete from SQS: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,127 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package aws
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/credentials"
+	"github.com/aws/aws-sdk-go-v2/service/sqs/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewSQSQueue(t *testing.T) {
+	tests := map[string]struct {
+		queueURL       string
+		region         string
+		expectedRegion string
+		expectedError  bool
+	}{
+		"region-from-url": {
+			queueURL:       "https://sqs.eu-west-1.amazonaws.com/111122223333/pca-events",
+			region:         "us-east-1",
+			expectedRegion: "eu-west-1",
+		},
+		"region-from-config": {
+			queueURL:       "http://localhost:4566/111122223333/pca-events",
+			region:         "us-east-1",
+			expectedRegion: "us-east-1",
+		},
+		"failure-no-region": {
+			queueURL:      "http://localhost:4566/111122223333/pca-events",
+			expectedError: true,
+		},
+		"failure-no-queue": {
+			queueURL:      "https://sqs.eu-west-1.amazonaws.com/",
+			expectedError: true,
+		},
+		"failure-not-a-url": {
+			queueURL:      "pca-events",
+			expectedError: true,
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			queue, err := NewSQSQueue(aws.Config{Region: tc.region}, tc.queueURL)
+			if tc.expectedError {
+				assert.ErrorIs(t, err, ErrInvalidQueueURL)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tc.expectedRegion, queue.client.Options().Region)
+		})
+	}
+}
+
+func TestSQSQueue(t *testing.T) {
+	var targets []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		target := r.Header.Get("X-Amz-Target")
+		targets = append(targets, target)
+		assert.Equal(t, "/", r.URL.Path)
+		assert.Equal(t, "application/x-amz-json-1.0", r.Header.Get("Content-Type"))
+		w.Header().Set("Content-Type", "application/x-amz-json-1.0")
+		assert.True(t, strings.HasPrefix(r.Header.Get("Authorization"), "AWS4-HMAC-SHA256 Credential=fake-access-key-id/"), "expected a SigV4 signature")
+		assert.Contains(t, r.Header.Get("Authorization"), "/us-east-1/sqs/aws4_request")
+
+		var in map[string]interface{}
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&in))
+		assert.Equal(t, "http://"+r.Host+"/111122223333/pca-events", in["QueueUrl"])
+
+		switch target {
+		case "AmazonSQS.ReceiveMessage":
+			assert.Equal(t, float64(20), in["WaitTimeSeconds"])
+			assert.Equal(t, float64(10), in["MaxNumberOfMessages"])
+			fmt.Fprint(w, `{"Messages":[{"MessageId":"m1","ReceiptHandle":"r1","Body":"{}","MD5OfBody":"99914b932bd37a50b983c5e7c90ae93b"}]}`)
+		case "AmazonSQS.DeleteMessage":
+			if in["ReceiptHandle"] != "r1" {
+				w.WriteHeader(http.StatusBadRequest)
+				fmt.Fprint(w, `{"__type":"com.amazonaws.sqs#ReceiptHandleIsInvalid","message":"The receipt handle is not valid"}`)
+				return
+			}
+			fmt.Fprint(w, `{}`)
+		}
+	}))
+	defer server.Close()
+
+	queue, err := NewSQSQueue(aws.Config{
+		Region:      "us-east-1",
+		Credentials: credentials.NewStaticCredentialsProvider("fake-access-key-id", "fake-secret-access-key", ""),
+		HTTPClient:  server.Client(),
+	}, server.URL+"/111122223333/pca-events")
+	require.NoError(t, err)
+
+	messages, err := queue.Receive(context.TODO())
+	require.NoError(t, err)
+	assert.Equal(t, []QueueMessage{{MessageID: "m1", ReceiptHandle: "r1", Body: "{}"}}, messages)
+
+	assert.NoError(t, queue.Delete(context.TODO(), "r1"))
+	err = queue.Delete(context.TODO(), "r2")
+	var invalid *types.ReceiptHandleIsInvalid
+	assert.ErrorAs(t, err, &invalid)
+	assert.ErrorContains(t, err, "The receipt handle is not valid")
+	assert.Equal(t, []string{"AmazonSQS.ReceiveMessage", "AmazonSQS.DeleteMessage", "AmazonSQS.DeleteMessage"}, targets)
+}
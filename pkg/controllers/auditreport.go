@@ -0,0 +1,92 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"time"
+
+	api "github.com/cert-manager/aws-privateca-issuer/pkg/api/v1beta1"
+	core "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// auditReportAnnotation can be set on an issuer to request an audit report of
+// its CA. A report is generated each time the value changes, e.g. to the
+// current time.
+const auditReportAnnotation = "aws-privateca-issuer/audit-report"
+
+// auditReportRetryInterval is how long to wait before requesting an audit
+// report again after PCA failed to create it
+const auditReportRetryInterval = 10 * time.Minute
+
+// auditReporter requests audit reports of a CA, see
+// awspca.PCAProvisioner.CreateAuditReport
+type auditReporter interface {
+	CreateAuditReport(ctx context.Context, bucket, format string) (string, string, error)
+}
+
+// reconcileAuditReport requests an audit report of the CA if the interval of
+// the issuer elapsed since the last report, or the audit report annotation
+// changed. The report is recorded in the status of the issuer, which the caller
+// updates. It returns how long to wait before the next report is due, or zero
+// if none is scheduled.
+func (r *GenericIssuerReconciler) reconcileAuditReport(ctx context.Context, issuer api.GenericIssuer, reporter auditReporter) time.Duration {
+	log := r.Log.WithValues("genericissuer", issuer.GetName())
+	spec, status := issuer.GetSpec().AuditReport, issuer.GetStatus()
+
+	request := issuer.GetAnnotations()[auditReportAnnotation]
+	requested := request != "" && (status.AuditReport == nil || status.AuditReport.Request != request)
+	if spec == nil {
+		if requested {
+			r.Recorder.Event(issuer, core.EventTypeWarning, string(api.ReasonAuditReportNotConfigured), "Audit report requested, but the issuer has no auditReport")
+		}
+		return 0
+	}
+
+	now := r.now()
+	var interval, next time.Duration
+	if spec.Interval != nil && spec.Interval.Duration > 0 {
+		interval = spec.Interval.Duration
+		if status.AuditReport != nil {
+			next = status.AuditReport.CreatedTime.Add(interval).Sub(now)
+		}
+	}
+	due := interval > 0 && next <= 0
+	if !requested && !due {
+		return next
+	}
+
+	id, key, err := reporter.CreateAuditReport(ctx, spec.S3BucketName, spec.Format)
+	if err != nil {
+		log.Error(err, "failed to create audit report", "bucket", spec.S3BucketName)
+		r.Recorder.Eventf(issuer, core.EventTypeWarning, string(api.ReasonAuditReportFailed), "Failed to create audit report: %v", err)
+		return auditReportRetryInterval
+	}
+
+	status.AuditReport = &api.AWSPCAAuditReportStatus{
+		ID:          id,
+		S3Bucket:    spec.S3BucketName,
+		S3Key:       key,
+		CreatedTime: metav1.NewTime(now),
+		Request:     request,
+	}
+	log.Info("Created audit report", "id", id, "bucket", spec.S3BucketName, "key", key)
+	r.Recorder.Eventf(issuer, core.EventTypeNormal, string(api.ReasonAuditReportCreated), "Created audit report %s in s3://%s/%s", id, spec.S3BucketName, key)
+
+	return interval
+}
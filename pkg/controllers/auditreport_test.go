@@ -0,0 +1,184 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	logrtesting "github.com/go-logr/logr/testing"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/record"
+	clocktesting "k8s.io/utils/clock/testing"
+
+	issuerapi "github.com/cert-manager/aws-privateca-issuer/pkg/api/v1beta1"
+)
+
+type fakeAuditReporter struct {
+	bucket string
+	format string
+	calls  int
+	err    error
+}
+
+func (f *fakeAuditReporter) CreateAuditReport(_ context.Context, bucket, format string) (string, string, error) {
+	f.calls++
+	f.bucket, f.format = bucket, format
+	if f.err != nil {
+		return "", "", f.err
+	}
+	return "report-id", "audit-report/report-id.json", nil
+}
+
+func TestReconcileAuditReport(t *testing.T) {
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	lastReport := &issuerapi.AWSPCAAuditReportStatus{
+		ID:          "last-report-id",
+		S3Bucket:    "audit-bucket",
+		S3Key:       "audit-report/last-report-id.json",
+		CreatedTime: metav1.NewTime(now.Add(-time.Hour)),
+		Request:     "first",
+	}
+
+	type testCase struct {
+		auditReport          *issuerapi.AWSPCAAuditReport
+		annotation           string
+		status               *issuerapi.AWSPCAAuditReportStatus
+		reportErr            error
+		expectedCalls        int
+		expectedFormat       string
+		expectedStatus       *issuerapi.AWSPCAAuditReportStatus
+		expectedRequeueAfter time.Duration
+		expectedEvent        string
+	}
+
+	created := &issuerapi.AWSPCAAuditReportStatus{
+		ID:          "report-id",
+		S3Bucket:    "audit-bucket",
+		S3Key:       "audit-report/report-id.json",
+		CreatedTime: metav1.NewTime(now),
+	}
+	createdFor := func(request string) *issuerapi.AWSPCAAuditReportStatus {
+		status := created.DeepCopy()
+		status.Request = request
+		return status
+	}
+
+	tests := map[string]testCase{
+		"not-configured": {},
+		"not-configured-requested": {
+			annotation:    "first",
+			expectedEvent: "Warning AuditReportNotConfigured",
+		},
+		"requested": {
+			auditReport:    &issuerapi.AWSPCAAuditReport{S3BucketName: "audit-bucket", Format: "CSV"},
+			annotation:     "first",
+			expectedCalls:  1,
+			expectedFormat: "CSV",
+			expectedStatus: createdFor("first"),
+			expectedEvent:  "Normal AuditReportCreated Created audit report report-id in s3://audit-bucket/audit-report/report-id.json",
+		},
+		"already-requested": {
+			auditReport:    &issuerapi.AWSPCAAuditReport{S3BucketName: "audit-bucket"},
+			annotation:     "first",
+			status:         lastReport,
+			expectedStatus: lastReport,
+		},
+		"requested-again": {
+			auditReport:    &issuerapi.AWSPCAAuditReport{S3BucketName: "audit-bucket"},
+			annotation:     "second",
+			status:         lastReport,
+			expectedCalls:  1,
+			expectedStatus: createdFor("second"),
+			expectedEvent:  "Normal AuditReportCreated",
+		},
+		"scheduled-first-report": {
+			auditReport:          &issuerapi.AWSPCAAuditReport{S3BucketName: "audit-bucket", Interval: &metav1.Duration{Duration: 24 * time.Hour}},
+			expectedCalls:        1,
+			expectedStatus:       created,
+			expectedRequeueAfter: 24 * time.Hour,
+			expectedEvent:        "Normal AuditReportCreated",
+		},
+		"scheduled-not-due": {
+			auditReport:          &issuerapi.AWSPCAAuditReport{S3BucketName: "audit-bucket", Interval: &metav1.Duration{Duration: 24 * time.Hour}},
+			annotation:           "first",
+			status:               lastReport,
+			expectedStatus:       lastReport,
+			expectedRequeueAfter: 23 * time.Hour,
+		},
+		"scheduled-due": {
+			auditReport:          &issuerapi.AWSPCAAuditReport{S3BucketName: "audit-bucket", Interval: &metav1.Duration{Duration: time.Hour}},
+			annotation:           "first",
+			status:               lastReport,
+			expectedCalls:        1,
+			expectedStatus:       createdFor("first"),
+			expectedRequeueAfter: time.Hour,
+			expectedEvent:        "Normal AuditReportCreated",
+		},
+		"failure": {
+			auditReport:          &issuerapi.AWSPCAAuditReport{S3BucketName: "audit-bucket"},
+			annotation:           "second",
+			status:               lastReport,
+			reportErr:            errors.New("access denied to bucket"),
+			expectedCalls:        1,
+			expectedStatus:       lastReport,
+			expectedRequeueAfter: auditReportRetryInterval,
+			expectedEvent:        "Warning AuditReportFailed Failed to create audit report: access denied to bucket",
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			iss := &issuerapi.AWSPCAIssuer{
+				ObjectMeta: metav1.ObjectMeta{Name: "issuer1", Namespace: "ns1"},
+				Spec:       issuerapi.AWSPCAIssuerSpec{AuditReport: tc.auditReport},
+				Status:     issuerapi.AWSPCAIssuerStatus{AuditReport: tc.status.DeepCopy()},
+			}
+			if tc.annotation != "" {
+				metav1.SetMetaDataAnnotation(&iss.ObjectMeta, auditReportAnnotation, tc.annotation)
+			}
+			recorder := record.NewFakeRecorder(1)
+			reporter := &fakeAuditReporter{err: tc.reportErr}
+			controller := GenericIssuerReconciler{
+				Log:      logrtesting.NewTestLogger(t),
+				Recorder: recorder,
+				Clock:    clocktesting.NewFakeClock(now),
+			}
+
+			requeueAfter := controller.reconcileAuditReport(context.TODO(), iss, reporter)
+			assert.Equal(t, tc.expectedRequeueAfter, requeueAfter)
+			assert.Equal(t, tc.expectedCalls, reporter.calls)
+			if tc.expectedCalls > 0 {
+				assert.Equal(t, "audit-bucket", reporter.bucket)
+				assert.Equal(t, tc.expectedFormat, reporter.format)
+			}
+			assert.Equal(t, tc.expectedStatus, iss.Status.AuditReport)
+
+			select {
+			case event := <-recorder.Events:
+				require.NotEmpty(t, tc.expectedEvent, "unexpected event %q", event)
+				assert.Contains(t, event, tc.expectedEvent)
+			default:
+				assert.Empty(t, tc.expectedEvent, "expected an event")
+			}
+		})
+	}
+}
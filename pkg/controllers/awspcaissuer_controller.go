@@ -20,9 +20,13 @@ import (
 	"context"
 
 	"github.com/go-logr/logr"
+	core "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	"sigs.k8s.io/controller-runtime/pkg/source"
 
 	api "github.com/cert-manager/aws-privateca-issuer/pkg/api/v1beta1"
 )
@@ -39,6 +43,7 @@ type AWSPCAIssuerReconciler struct {
 // +kubebuilder:rbac:groups=awspca.cert-manager.io,resources=awspcaissuers/status,verbs=get;update;patch
 // +kubebuilder:rbac:groups=awspca.cert-manager.io,resources=awspcaissuers/finalizers,verbs=update
 // +kubebuilder:rbac:groups="",resources=secrets,verbs=get;list;watch
+// +kubebuilder:rbac:groups="",resources=configmaps,verbs=get;list;watch
 // +kubebuilder:rbac:groups="",resources=events,verbs=create;patch
 
 // Reconcile is part of the main kubernetes reconciliation loop which aims to
@@ -57,9 +62,24 @@ func (r *AWSPCAIssuerReconciler) Reconcile(ctx context.Context, req ctrl.Request
 	return r.GenericController.Reconcile(ctx, req, iss)
 }
 
-// SetupWithManager sets up the controller with the Manager.
+// SetupWithManager sets up the controller with the Manager. Issuers are also
+// reconciled when their credentials Secret changes, or the CAStateWatcher
+// reports that their CA changed state.
 func (r *AWSPCAIssuerReconciler) SetupWithManager(mgr ctrl.Manager) error {
-	return ctrl.NewControllerManagedBy(mgr).
+	if err := mgr.GetFieldIndexer().IndexField(context.Background(), &api.AWSPCAIssuer{}, secretRefField, r.GenericController.indexSecretRef); err != nil {
+		return err
+	}
+
+	builder := ctrl.NewControllerManagedBy(mgr).
 		For(&api.AWSPCAIssuer{}).
-		Complete(r)
+		Watches(&core.Secret{}, handler.EnqueueRequestsFromMapFunc(r.issuersForSecret))
+	if w := r.GenericController.CAStateWatcher; w != nil && w.Issuers != nil {
+		builder = builder.WatchesRawSource(source.Channel(w.Issuers, &handler.EnqueueRequestForObject{}))
+	}
+	return builder.Complete(r)
+}
+
+// issuersForSecret maps a credentials Secret to the AWSPCAIssuers using it
+func (r *AWSPCAIssuerReconciler) issuersForSecret(ctx context.Context, secret client.Object) []reconcile.Request {
+	return requestsForSecret(ctx, r.Client, r.Log, secret, &api.AWSPCAIssuerList{})
 }
@@ -0,0 +1,53 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"time"
+
+	acmpcatypes "github.com/aws/aws-sdk-go-v2/service/acmpca/types"
+	api "github.com/cert-manager/aws-privateca-issuer/pkg/api/v1beta1"
+)
+
+// caAlgorithmsRefreshInterval is how often the algorithms of the CA of an
+// issuer are described again, e.g. to notice a CA that was replaced
+const caAlgorithmsRefreshInterval = time.Hour
+
+// caAlgorithmsDescriber describes the algorithms of a CA, see
+// awspca.PCAProvisioner.CAAlgorithms
+type caAlgorithmsDescriber interface {
+	CAAlgorithms(ctx context.Context) (acmpcatypes.KeyAlgorithm, acmpcatypes.SigningAlgorithm, error)
+}
+
+// reconcileCAAlgorithms records the key and signing algorithm of the CA in the
+// status of the issuer, which the caller updates. The algorithms are only
+// informational, so failures keep the recorded ones. It returns how long to
+// wait before refreshing them.
+func (r *GenericIssuerReconciler) reconcileCAAlgorithms(ctx context.Context, issuer api.GenericIssuer, describer caAlgorithmsDescriber) time.Duration {
+	log := r.Log.WithValues("genericissuer", issuer.GetName())
+	keyAlgorithm, signingAlgorithm, err := describer.CAAlgorithms(ctx)
+	if err != nil {
+		log.Error(err, "failed to describe the algorithms of the certificate authority")
+		return caAlgorithmsRefreshInterval
+	}
+
+	status := issuer.GetStatus()
+	status.KeyAlgorithm = string(keyAlgorithm)
+	status.SigningAlgorithm = string(signingAlgorithm)
+	return caAlgorithmsRefreshInterval
+}
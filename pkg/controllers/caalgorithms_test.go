@@ -0,0 +1,98 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	acmpcatypes "github.com/aws/aws-sdk-go-v2/service/acmpca/types"
+	logrtesting "github.com/go-logr/logr/testing"
+	"github.com/stretchr/testify/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	issuerapi "github.com/cert-manager/aws-privateca-issuer/pkg/api/v1beta1"
+)
+
+type fakeCAAlgorithmsDescriber struct {
+	keyAlgorithm     acmpcatypes.KeyAlgorithm
+	signingAlgorithm acmpcatypes.SigningAlgorithm
+	err              error
+}
+
+func (f *fakeCAAlgorithmsDescriber) CAAlgorithms(context.Context) (acmpcatypes.KeyAlgorithm, acmpcatypes.SigningAlgorithm, error) {
+	return f.keyAlgorithm, f.signingAlgorithm, f.err
+}
+
+func TestReconcileCAAlgorithms(t *testing.T) {
+	type testCase struct {
+		describer                *fakeCAAlgorithmsDescriber
+		status                   issuerapi.AWSPCAIssuerStatus
+		expectedKeyAlgorithm     string
+		expectedSigningAlgorithm string
+	}
+
+	tests := map[string]testCase{
+		"rsa": {
+			describer: &fakeCAAlgorithmsDescriber{
+				keyAlgorithm:     acmpcatypes.KeyAlgorithmRsa2048,
+				signingAlgorithm: acmpcatypes.SigningAlgorithmSha256withrsa,
+			},
+			expectedKeyAlgorithm:     "RSA_2048",
+			expectedSigningAlgorithm: "SHA256WITHRSA",
+		},
+		"ecdsa": {
+			describer: &fakeCAAlgorithmsDescriber{
+				keyAlgorithm:     acmpcatypes.KeyAlgorithmEcSecp384r1,
+				signingAlgorithm: acmpcatypes.SigningAlgorithmSha384withecdsa,
+			},
+			expectedKeyAlgorithm:     "EC_secp384r1",
+			expectedSigningAlgorithm: "SHA384WITHECDSA",
+		},
+		"changed": {
+			describer: &fakeCAAlgorithmsDescriber{
+				keyAlgorithm:     acmpcatypes.KeyAlgorithmEcPrime256v1,
+				signingAlgorithm: acmpcatypes.SigningAlgorithmSha256withecdsa,
+			},
+			status:                   issuerapi.AWSPCAIssuerStatus{KeyAlgorithm: "RSA_2048", SigningAlgorithm: "SHA256WITHRSA"},
+			expectedKeyAlgorithm:     "EC_prime256v1",
+			expectedSigningAlgorithm: "SHA256WITHECDSA",
+		},
+		"failure-keeps-recorded": {
+			describer:                &fakeCAAlgorithmsDescriber{err: errors.New("throttled")},
+			status:                   issuerapi.AWSPCAIssuerStatus{KeyAlgorithm: "RSA_2048", SigningAlgorithm: "SHA256WITHRSA"},
+			expectedKeyAlgorithm:     "RSA_2048",
+			expectedSigningAlgorithm: "SHA256WITHRSA",
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			issuer := &issuerapi.AWSPCAIssuer{
+				ObjectMeta: metav1.ObjectMeta{Name: "issuer1", Namespace: "ns1"},
+				Status:     tc.status,
+			}
+			r := &GenericIssuerReconciler{Log: logrtesting.NewTestLogger(t)}
+
+			refresh := r.reconcileCAAlgorithms(context.TODO(), issuer, tc.describer)
+			assert.Equal(t, caAlgorithmsRefreshInterval, refresh)
+			assert.Equal(t, tc.expectedKeyAlgorithm, issuer.Status.KeyAlgorithm)
+			assert.Equal(t, tc.expectedSigningAlgorithm, issuer.Status.SigningAlgorithm)
+		})
+	}
+}
@@ -0,0 +1,101 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"bytes"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"sync"
+
+	"k8s.io/apimachinery/pkg/types"
+)
+
+// caCommonNameAnnotation records the common name of the CA that issued the
+// certificate of a CertificateRequest, e.g. for display in UIs
+const caCommonNameAnnotation = "aws-privateca-issuer/ca-common-name"
+
+// CACommonNames holds the caCommonName of each issuer. It is shared by the
+// CertificateRequestReconciler, which annotates CertificateRequests with it,
+// and the GenericIssuerReconciler, which drops the names of deleted issuers.
+type CACommonNames struct {
+	names sync.Map
+}
+
+// caCommonName is the common name of the CA certificate an issuer last
+// returned, cached so that the CA certificate is only parsed again when it
+// changes, e.g. after a failover or CA rotation
+type caCommonName struct {
+	der        []byte
+	commonName string
+}
+
+// issuingCACommonName returns the subject common name of the CA that issued
+// certPem for issuer. That is the certificate following the leaf in certPem,
+// or the first certificate of caPem if certPem holds no intermediates. The
+// CA certificate is parsed on every call if CACommonNames is nil.
+func (r *CertificateRequestReconciler) issuingCACommonName(issuer types.NamespacedName, certPem, caPem []byte) (string, error) {
+	der := issuingCACertificate(certPem, caPem)
+	if der == nil {
+		return "", errors.New("failed to read the CA certificate")
+	}
+	if r.CACommonNames == nil {
+		cert, err := x509.ParseCertificate(der)
+		if err != nil {
+			return "", err
+		}
+		return cert.Subject.CommonName, nil
+	}
+	return r.CACommonNames.commonName(issuer, der)
+}
+
+// commonName returns the subject common name of the CA certificate der of
+// issuer, parsing it only if it changed since the last call
+func (c *CACommonNames) commonName(issuer types.NamespacedName, der []byte) (string, error) {
+	if value, ok := c.names.Load(issuer); ok {
+		if cached := value.(caCommonName); bytes.Equal(cached.der, der) {
+			return cached.commonName, nil
+		}
+	}
+
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		return "", err
+	}
+	c.names.Store(issuer, caCommonName{der: der, commonName: cert.Subject.CommonName})
+	return cert.Subject.CommonName, nil
+}
+
+// forget drops the name of issuer
+func (c *CACommonNames) forget(issuer types.NamespacedName) {
+	c.names.Delete(issuer)
+}
+
+// issuingCACertificate returns the DER of the certificate following the leaf
+// in certPem, falling back to the first certificate of caPem
+func issuingCACertificate(certPem, caPem []byte) []byte {
+	if _, rest := pem.Decode(certPem); rest != nil {
+		if block, _ := pem.Decode(rest); block != nil && block.Type == "CERTIFICATE" {
+			return block.Bytes
+		}
+	}
+	if block, _ := pem.Decode(caPem); block != nil && block.Type == "CERTIFICATE" {
+		return block.Bytes
+	}
+	return nil
+}
@@ -0,0 +1,200 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"testing"
+	"time"
+
+	cmapi "github.com/cert-manager/cert-manager/pkg/apis/certmanager/v1"
+	cmmeta "github.com/cert-manager/cert-manager/pkg/apis/meta/v1"
+	cmgen "github.com/cert-manager/cert-manager/test/unit/gen"
+	logrtesting "github.com/go-logr/logr/testing"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	issuerapi "github.com/cert-manager/aws-privateca-issuer/pkg/api/v1beta1"
+	awspca "github.com/cert-manager/aws-privateca-issuer/pkg/aws"
+)
+
+// testCertificate returns a PEM encoded certificate with the common name cn,
+// signed by the parent if it is not nil and self-signed otherwise
+func testCertificate(t *testing.T, cn string, parent *x509.Certificate, parentKey *ecdsa.PrivateKey) ([]byte, *x509.Certificate, *ecdsa.PrivateKey) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(time.Now().UnixNano()),
+		Subject:               pkix.Name{CommonName: cn},
+		NotBefore:             time.Now(),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  parent == nil,
+		BasicConstraintsValid: true,
+	}
+	if parent == nil {
+		parent, parentKey = template, key
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, parent, &key.PublicKey, parentKey)
+	require.NoError(t, err)
+	cert, err := x509.ParseCertificate(der)
+	require.NoError(t, err)
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), cert, key
+}
+
+func TestIssuingCACommonName(t *testing.T) {
+	rootPem, root, rootKey := testCertificate(t, "Root CA", nil, nil)
+	intermediatePem, intermediate, intermediateKey := testCertificate(t, "Issuing CA", root, rootKey)
+	leafPem, _, _ := testCertificate(t, "example.com", intermediate, intermediateKey)
+
+	type testCase struct {
+		certPem            []byte
+		caPem              []byte
+		expectedCommonName string
+		expectError        bool
+	}
+	tests := map[string]testCase{
+		"ca-only": {
+			certPem:            leafPem,
+			caPem:              intermediatePem,
+			expectedCommonName: "Issuing CA",
+		},
+		"intermediate-in-chain": {
+			certPem:            append(append([]byte{}, leafPem...), intermediatePem...),
+			caPem:              rootPem,
+			expectedCommonName: "Issuing CA",
+		},
+		"invalid-ca": {
+			certPem:     leafPem,
+			caPem:       []byte("cacert"),
+			expectError: true,
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			controller := CertificateRequestReconciler{CACommonNames: &CACommonNames{}}
+			issuer := types.NamespacedName{Namespace: "ns1", Name: name}
+
+			commonName, err := controller.issuingCACommonName(issuer, tc.certPem, tc.caPem)
+			if tc.expectError {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tc.expectedCommonName, commonName)
+		})
+	}
+}
+
+func TestIssuingCACommonNameCache(t *testing.T) {
+	caPem, ca, caKey := testCertificate(t, "Issuing CA", nil, nil)
+	leafPem, _, _ := testCertificate(t, "example.com", ca, caKey)
+	rotatedPem, rotated, rotatedKey := testCertificate(t, "Rotated CA", nil, nil)
+	rotatedLeafPem, _, _ := testCertificate(t, "example.com", rotated, rotatedKey)
+
+	names := &CACommonNames{}
+	controller := CertificateRequestReconciler{CACommonNames: names}
+	issuer := types.NamespacedName{Namespace: "ns1", Name: "issuer1"}
+
+	commonName, err := controller.issuingCACommonName(issuer, leafPem, caPem)
+	require.NoError(t, err)
+	assert.Equal(t, "Issuing CA", commonName)
+
+	// The cached name is returned as long as the CA certificate is the same
+	names.names.Store(issuer, caCommonName{der: ca.Raw, commonName: "cached"})
+	commonName, err = controller.issuingCACommonName(issuer, leafPem, caPem)
+	require.NoError(t, err)
+	assert.Equal(t, "cached", commonName)
+
+	commonName, err = controller.issuingCACommonName(issuer, rotatedLeafPem, rotatedPem)
+	require.NoError(t, err)
+	assert.Equal(t, "Rotated CA", commonName)
+}
+
+func TestCertificateRequestReconcileCACommonName(t *testing.T) {
+	caPem, ca, caKey := testCertificate(t, "Example Issuing CA", nil, nil)
+	leafPem, _, _ := testCertificate(t, "example.com", ca, caKey)
+
+	scheme := runtime.NewScheme()
+	require.NoError(t, issuerapi.AddToScheme(scheme))
+	require.NoError(t, cmapi.AddToScheme(scheme))
+
+	objects := []client.Object{
+		cmgen.CertificateRequest(
+			"cr1",
+			cmgen.SetCertificateRequestNamespace("ns1"),
+			cmgen.SetCertificateRequestIssuer(cmmeta.ObjectReference{
+				Name:  "issuer1",
+				Group: issuerapi.GroupVersion.Group,
+				Kind:  "Issuer",
+			}),
+		),
+		&issuerapi.AWSPCAIssuer{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "issuer1",
+				Namespace: "ns1",
+			},
+			Status: issuerapi.AWSPCAIssuerStatus{
+				Conditions: []metav1.Condition{
+					{
+						Type:   issuerapi.ConditionTypeReady,
+						Status: metav1.ConditionTrue,
+					},
+				},
+			},
+		},
+	}
+
+	fakeClient := fake.NewClientBuilder().
+		WithScheme(scheme).
+		WithObjects(objects...).
+		WithStatusSubresource(objects...).
+		Build()
+	controller := CertificateRequestReconciler{
+		Client:        fakeClient,
+		Log:           logrtesting.NewTestLogger(t),
+		Scheme:        scheme,
+		Recorder:      record.NewFakeRecorder(10),
+		CACommonNames: &CACommonNames{},
+	}
+	issuerName := types.NamespacedName{Namespace: "ns1", Name: "issuer1"}
+	awspca.StoreProvisioner(issuerName, &fakeProvisioner{cert: leafPem, caCert: caPem})
+
+	ctx := context.TODO()
+	name := types.NamespacedName{Namespace: "ns1", Name: "cr1"}
+	_, err := controller.Reconcile(ctx, reconcile.Request{NamespacedName: name})
+	require.NoError(t, err)
+
+	var cr cmapi.CertificateRequest
+	require.NoError(t, fakeClient.Get(ctx, name, &cr))
+	assertCertificateRequestHasReadyCondition(t, cmmeta.ConditionTrue, cmapi.CertificateRequestReasonIssued, &cr)
+	assert.Equal(t, "Example Issuing CA", cr.Annotations[caCommonNameAnnotation])
+}
@@ -0,0 +1,226 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	acmpcatypes "github.com/aws/aws-sdk-go-v2/service/acmpca/types"
+	api "github.com/cert-manager/aws-privateca-issuer/pkg/api/v1beta1"
+	awspca "github.com/cert-manager/aws-privateca-issuer/pkg/aws"
+	"github.com/go-logr/logr"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// caHealth describes why the CA of an issuer is unhealthy
+type caHealth struct {
+	reason  api.ConditionReason
+	message string
+}
+
+// CAHealthChecker periodically calls DescribeCertificateAuthority for every
+// configured issuer. Issuers whose CA is unreachable or not ACTIVE are marked
+// not Ready until the CA recovers.
+type CAHealthChecker struct {
+	client.Client
+	Log      logr.Logger
+	Recorder record.EventRecorder
+	Interval time.Duration
+	// Namespace restricts the check to the issuers in a single namespace.
+	// AWSPCAClusterIssuers are not checked if it is set
+	Namespace string
+
+	mu        sync.Mutex
+	unhealthy map[types.NamespacedName]caHealth
+}
+
+// Start runs the check every Interval until ctx is cancelled
+func (c *CAHealthChecker) Start(ctx context.Context) error {
+	wait.UntilWithContext(ctx, c.checkAll, c.Interval)
+	return nil
+}
+
+// Check fails while the CA of any issuer is unhealthy. It can be registered
+// as a health or readiness check of the manager.
+func (c *CAHealthChecker) Check(_ *http.Request) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if len(c.unhealthy) == 0 {
+		return nil
+	}
+	issuers := make([]string, 0, len(c.unhealthy))
+	for name, health := range c.unhealthy {
+		issuers = append(issuers, fmt.Sprintf("%s (%s)", name, health.reason))
+	}
+	sort.Strings(issuers)
+	return fmt.Errorf("certificate authorities of issuers are unhealthy: %s", strings.Join(issuers, ", "))
+}
+
+// Unhealthy returns why the CA of the issuer was found unhealthy by the last
+// check, if it was
+func (c *CAHealthChecker) Unhealthy(name types.NamespacedName) (caHealth, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	health, ok := c.unhealthy[name]
+	return health, ok
+}
+
+// forget drops the health of a deleted issuer, so that it does not fail the
+// check until the next run
+func (c *CAHealthChecker) forget(name types.NamespacedName) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	delete(c.unhealthy, name)
+}
+
+func (c *CAHealthChecker) checkAll(ctx context.Context) {
+	seen := map[types.NamespacedName]bool{}
+
+	issuers := new(api.AWSPCAIssuerList)
+	if err := c.Client.List(ctx, issuers, client.InNamespace(c.Namespace)); err != nil {
+		c.Log.Error(err, "failed to list AWSPCAIssuers")
+		return
+	}
+	for i := range issuers.Items {
+		iss := &issuers.Items[i]
+		name := types.NamespacedName{Namespace: iss.Namespace, Name: iss.Name}
+		seen[name] = true
+		c.check(ctx, name, iss)
+	}
+
+	if c.Namespace == "" {
+		clusterIssuers := new(api.AWSPCAClusterIssuerList)
+		if err := c.Client.List(ctx, clusterIssuers); err != nil {
+			c.Log.Error(err, "failed to list AWSPCAClusterIssuers")
+			return
+		}
+		for i := range clusterIssuers.Items {
+			iss := &clusterIssuers.Items[i]
+			name := types.NamespacedName{Name: iss.Name}
+			seen[name] = true
+			c.check(ctx, name, iss)
+		}
+	}
+
+	// Forget issuers that have been deleted
+	c.mu.Lock()
+	for name := range c.unhealthy {
+		if !seen[name] {
+			delete(c.unhealthy, name)
+		}
+	}
+	c.mu.Unlock()
+}
+
+func (c *CAHealthChecker) check(ctx context.Context, name types.NamespacedName, issuer api.GenericIssuer) {
+	log := c.Log.WithValues("genericissuer", name)
+
+	// Issuers without a provisioner have not been verified yet
+	provisioner, ok := awspca.GetProvisioner(name)
+	if !ok {
+		return
+	}
+
+	health, healthy, err := describeCAHealth(ctx, provisioner)
+	if err != nil {
+		// Throttling says nothing about the CA, keep the current state
+		log.V(4).Info("DescribeCertificateAuthority was throttled, skipping CA health check", "error", err.Error())
+		return
+	}
+
+	c.mu.Lock()
+	if c.unhealthy == nil {
+		c.unhealthy = map[types.NamespacedName]caHealth{}
+	}
+	if healthy {
+		delete(c.unhealthy, name)
+	} else {
+		c.unhealthy[name] = health
+	}
+	c.mu.Unlock()
+
+	connectedChanged := setConnected(log, issuer, health.reason != api.ReasonCAUnreachable, health.reason, health.message)
+	condition := readyCondition(issuer)
+	switch {
+	case !healthy && (condition == nil || condition.Status != metav1.ConditionFalse || condition.Reason != string(health.reason)):
+		log.Info("certificate authority is unhealthy", "reason", health.reason, "message", health.message)
+		err = setIssuerStatus(ctx, c.Client, c.Recorder, log, issuer, metav1.ConditionFalse, health.reason, health.message)
+	case healthy && condition != nil && condition.Status == metav1.ConditionFalse && isCAHealthReason(condition.Reason) && condition.ObservedGeneration == issuer.GetGeneration():
+		log.Info("certificate authority recovered")
+		err = setIssuerStatus(ctx, c.Client, c.Recorder, log, issuer, metav1.ConditionTrue, api.ReasonVerified, "Issuer verified")
+	case connectedChanged:
+		err = c.Client.Status().Update(ctx, issuer)
+	default:
+		return
+	}
+	if err != nil {
+		log.Error(err, "failed to update issuer status")
+	}
+}
+
+// describeCAHealth describes the CA of provisioner. Throttling errors are
+// returned rather than treated as unhealthy, as they say nothing about the CA.
+func describeCAHealth(ctx context.Context, provisioner awspca.GenericProvisioner) (caHealth, bool, error) {
+	status, err := provisioner.CAStatus(ctx)
+	switch {
+	case err != nil && awspca.IsThrottlingError(err):
+		return caHealth{}, false, err
+	case err != nil:
+		return caHealth{reason: api.ReasonCAUnreachable, message: fmt.Sprintf("failed to describe certificate authority: %v", err)}, false, nil
+	}
+
+	health, healthy := caStatusHealth(status)
+	return health, healthy, nil
+}
+
+// caStatusHealth maps the status of a CA to whether it can issue certificates
+func caStatusHealth(status acmpcatypes.CertificateAuthorityStatus) (caHealth, bool) {
+	if status == acmpcatypes.CertificateAuthorityStatusActive {
+		return caHealth{}, true
+	}
+	return caHealth{reason: api.ReasonCANotActive, message: fmt.Sprintf("certificate authority is %s", status)}, false
+}
+
+func isCAHealthReason(reason string) bool {
+	return reason == string(api.ReasonCANotActive) || reason == string(api.ReasonCAUnreachable)
+}
+
+func readyCondition(issuer api.GenericIssuer) *metav1.Condition {
+	return issuerCondition(issuer, api.ConditionTypeReady)
+}
+
+func issuerCondition(issuer api.GenericIssuer, conditionType string) *metav1.Condition {
+	for i, condition := range issuer.GetStatus().Conditions {
+		if condition.Type == conditionType {
+			return &issuer.GetStatus().Conditions[i]
+		}
+	}
+	return nil
+}
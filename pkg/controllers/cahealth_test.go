@@ -0,0 +1,422 @@
+/*
+Copyright 2021.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package controllers
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	acmpcatypes "github.com/aws/aws-sdk-go-v2/service/acmpca/types"
+	"github.com/aws/smithy-go"
+	logrtesting "github.com/go-logr/logr/testing"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	issuerapi "github.com/cert-manager/aws-privateca-issuer/pkg/api/v1beta1"
+	awspca "github.com/cert-manager/aws-privateca-issuer/pkg/aws"
+)
+
+func TestCAStatusHealth(t *testing.T) {
+	tests := map[acmpcatypes.CertificateAuthorityStatus]struct {
+		expectedHealthy bool
+		expectedReason  issuerapi.ConditionReason
+	}{
+		acmpcatypes.CertificateAuthorityStatusActive:             {expectedHealthy: true},
+		acmpcatypes.CertificateAuthorityStatusDisabled:           {expectedReason: issuerapi.ReasonCANotActive},
+		acmpcatypes.CertificateAuthorityStatusPendingCertificate: {expectedReason: issuerapi.ReasonCANotActive},
+		acmpcatypes.CertificateAuthorityStatusExpired:            {expectedReason: issuerapi.ReasonCANotActive},
+	}
+
+	for status, tc := range tests {
+		t.Run(string(status), func(t *testing.T) {
+			health, healthy := caStatusHealth(status)
+			assert.Equal(t, tc.expectedHealthy, healthy)
+			assert.Equal(t, tc.expectedReason, health.reason)
+		})
+	}
+}
+
+func TestCAHealthCheckerCheck(t *testing.T) {
+	type testCase struct {
+		conditionStatus              metav1.ConditionStatus
+		conditionReason              string
+		provisioner                  *fakeProvisioner
+		expectedReadyConditionStatus metav1.ConditionStatus
+		expectedReadyConditionReason string
+		expectHealthy                bool
+		expectedConnectedStatus      metav1.ConditionStatus
+	}
+	tests := map[string]testCase{
+		"active": {
+			conditionStatus:              metav1.ConditionTrue,
+			conditionReason:              "Verified",
+			provisioner:                  &fakeProvisioner{caStatus: acmpcatypes.CertificateAuthorityStatusActive},
+			expectedReadyConditionStatus: metav1.ConditionTrue,
+			expectedReadyConditionReason: "Verified",
+			expectHealthy:                true,
+			expectedConnectedStatus:      metav1.ConditionTrue,
+		},
+		"disabled": {
+			conditionStatus:              metav1.ConditionTrue,
+			conditionReason:              "Verified",
+			provisioner:                  &fakeProvisioner{caStatus: acmpcatypes.CertificateAuthorityStatusDisabled},
+			expectedReadyConditionStatus: metav1.ConditionFalse,
+			expectedReadyConditionReason: string(issuerapi.ReasonCANotActive),
+			expectedConnectedStatus:      metav1.ConditionTrue,
+		},
+		"pending-certificate": {
+			conditionStatus:              metav1.ConditionTrue,
+			conditionReason:              "Verified",
+			provisioner:                  &fakeProvisioner{caStatus: acmpcatypes.CertificateAuthorityStatusPendingCertificate},
+			expectedReadyConditionStatus: metav1.ConditionFalse,
+			expectedReadyConditionReason: string(issuerapi.ReasonCANotActive),
+			expectedConnectedStatus:      metav1.ConditionTrue,
+		},
+		"unreachable": {
+			conditionStatus:              metav1.ConditionTrue,
+			conditionReason:              "Verified",
+			provisioner:                  &fakeProvisioner{caStatusErr: errors.New("dial tcp: i/o timeout")},
+			expectedReadyConditionStatus: metav1.ConditionFalse,
+			expectedReadyConditionReason: string(issuerapi.ReasonCAUnreachable),
+			expectedConnectedStatus:      metav1.ConditionFalse,
+		},
+		"throttled-keeps-condition": {
+			conditionStatus:              metav1.ConditionTrue,
+			conditionReason:              "Verified",
+			provisioner:                  &fakeProvisioner{caStatusErr: &smithy.GenericAPIError{Code: "ThrottlingException"}},
+			expectedReadyConditionStatus: metav1.ConditionTrue,
+			expectedReadyConditionReason: "Verified",
+			expectHealthy:                true,
+		},
+		"recovered": {
+			conditionStatus:              metav1.ConditionFalse,
+			conditionReason:              string(issuerapi.ReasonCANotActive),
+			provisioner:                  &fakeProvisioner{caStatus: acmpcatypes.CertificateAuthorityStatusActive},
+			expectedReadyConditionStatus: metav1.ConditionTrue,
+			expectedReadyConditionReason: "Verified",
+			expectHealthy:                true,
+			expectedConnectedStatus:      metav1.ConditionTrue,
+		},
+		"other-failure-untouched": {
+			conditionStatus:              metav1.ConditionFalse,
+			conditionReason:              "Validation",
+			provisioner:                  &fakeProvisioner{caStatus: acmpcatypes.CertificateAuthorityStatusActive},
+			expectedReadyConditionStatus: metav1.ConditionFalse,
+			expectedReadyConditionReason: "Validation",
+			expectHealthy:                true,
+			expectedConnectedStatus:      metav1.ConditionTrue,
+		},
+	}
+
+	scheme := runtime.NewScheme()
+	require.NoError(t, issuerapi.AddToScheme(scheme))
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			iss := &issuerapi.AWSPCAIssuer{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "issuer1",
+					Namespace: "health-ns",
+				},
+				Spec: issuerapi.AWSPCAIssuerSpec{
+					Region: "us-east-1",
+					Arn:    "arn:aws:acm-pca:us-east-1:account:certificate-authority/12345678-1234-1234-1234-123456789012",
+				},
+				Status: issuerapi.AWSPCAIssuerStatus{
+					Conditions: []metav1.Condition{
+						{
+							Type:   issuerapi.ConditionTypeReady,
+							Status: tc.conditionStatus,
+							Reason: tc.conditionReason,
+						},
+					},
+				},
+			}
+			fakeClient := fake.NewClientBuilder().
+				WithScheme(scheme).
+				WithObjects(iss).
+				WithStatusSubresource(iss).
+				Build()
+			checker := &CAHealthChecker{
+				Client:   fakeClient,
+				Log:      logrtesting.NewTestLogger(t),
+				Recorder: record.NewFakeRecorder(10),
+			}
+			name := types.NamespacedName{Namespace: "health-ns", Name: "issuer1"}
+			awspca.StoreProvisioner(name, tc.provisioner)
+
+			ctx := context.TODO()
+			checker.checkAll(ctx)
+
+			require.NoError(t, fakeClient.Get(ctx, name, iss))
+			condition := readyCondition(iss)
+			if assert.NotNil(t, condition) {
+				assert.Equal(t, tc.expectedReadyConditionStatus, condition.Status)
+				assert.Equal(t, tc.expectedReadyConditionReason, condition.Reason)
+			}
+
+			connected := issuerCondition(iss, issuerapi.ConditionTypeConnected)
+			if tc.expectedConnectedStatus == "" {
+				assert.Nil(t, connected, "expected throttled checks not to change the Connected condition")
+			} else if assert.NotNil(t, connected) {
+				assert.Equal(t, tc.expectedConnectedStatus, connected.Status)
+			}
+
+			_, unhealthy := checker.Unhealthy(name)
+			assert.Equal(t, tc.expectHealthy, !unhealthy)
+			if tc.expectHealthy {
+				assert.NoError(t, checker.Check(nil))
+			} else {
+				assert.ErrorContains(t, checker.Check(nil), "health-ns/issuer1")
+			}
+		})
+	}
+}
+
+func TestCAHealthCheckerForgetsDeletedIssuers(t *testing.T) {
+	scheme := runtime.NewScheme()
+	require.NoError(t, issuerapi.AddToScheme(scheme))
+
+	checker := &CAHealthChecker{
+		Client:   fake.NewClientBuilder().WithScheme(scheme).Build(),
+		Log:      logrtesting.NewTestLogger(t),
+		Recorder: record.NewFakeRecorder(10),
+		unhealthy: map[types.NamespacedName]caHealth{
+			{Namespace: "health-ns", Name: "deleted"}: {reason: issuerapi.ReasonCANotActive},
+		},
+	}
+	require.Error(t, checker.Check(nil))
+
+	checker.checkAll(context.TODO())
+	assert.NoError(t, checker.Check(nil))
+}
+
+func TestCAHealthCheckerNamespace(t *testing.T) {
+	scheme := runtime.NewScheme()
+	require.NoError(t, issuerapi.AddToScheme(scheme))
+
+	objects := []client.Object{
+		&issuerapi.AWSPCAIssuer{ObjectMeta: metav1.ObjectMeta{Name: "issuer1", Namespace: "tenant-a"}},
+		&issuerapi.AWSPCAIssuer{ObjectMeta: metav1.ObjectMeta{Name: "issuer1", Namespace: "tenant-b"}},
+		&issuerapi.AWSPCAClusterIssuer{ObjectMeta: metav1.ObjectMeta{Name: "clusterissuer1"}},
+	}
+	fakeClient := fake.NewClientBuilder().
+		WithScheme(scheme).
+		WithObjects(objects...).
+		WithStatusSubresource(objects...).
+		Build()
+	checker := &CAHealthChecker{
+		Client:    fakeClient,
+		Log:       logrtesting.NewTestLogger(t),
+		Recorder:  record.NewFakeRecorder(10),
+		Namespace: "tenant-a",
+	}
+	names := []types.NamespacedName{
+		{Namespace: "tenant-a", Name: "issuer1"},
+		{Namespace: "tenant-b", Name: "issuer1"},
+		{Name: "clusterissuer1"},
+	}
+	for _, name := range names {
+		awspca.StoreProvisioner(name, &fakeProvisioner{caStatus: acmpcatypes.CertificateAuthorityStatusDisabled})
+	}
+
+	checker.checkAll(context.TODO())
+
+	_, unhealthy := checker.Unhealthy(names[0])
+	assert.True(t, unhealthy)
+	for _, name := range names[1:] {
+		_, unhealthy := checker.Unhealthy(name)
+		assert.False(t, unhealthy, "%s should not be checked", name)
+	}
+}
+
+func TestIssuerReconcileCAUnhealthy(t *testing.T) {
+	scheme := runtime.NewScheme()
+	require.NoError(t, issuerapi.AddToScheme(scheme))
+
+	iss := &issuerapi.AWSPCAIssuer{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "issuer1",
+			Namespace: "health-ns",
+		},
+		Spec: issuerapi.AWSPCAIssuerSpec{
+			Region: "us-east-1",
+			Arn:    "arn:aws:acm-pca:us-east-1:account:certificate-authority/12345678-1234-1234-1234-123456789012",
+		},
+	}
+	fakeClient := fake.NewClientBuilder().
+		WithScheme(scheme).
+		WithObjects(iss).
+		WithStatusSubresource(iss).
+		Build()
+	name := types.NamespacedName{Namespace: "health-ns", Name: "issuer1"}
+	controller := GenericIssuerReconciler{
+		Client:   fakeClient,
+		Log:      logrtesting.NewTestLogger(t),
+		Scheme:   scheme,
+		Recorder: record.NewFakeRecorder(10),
+		CAHealth: &CAHealthChecker{
+			unhealthy: map[types.NamespacedName]caHealth{
+				name: {reason: issuerapi.ReasonCANotActive, message: "certificate authority is DISABLED"},
+			},
+		},
+	}
+	isolateDefaultCredentialChain(t)
+	t.Setenv("AWS_ACCESS_KEY_ID", "AKID")
+	t.Setenv("AWS_SECRET_ACCESS_KEY", "SECRET")
+	awspca.ClearProvisioners()
+
+	ctx := context.TODO()
+	require.NoError(t, fakeClient.Get(ctx, name, iss))
+	_, err := controller.Reconcile(ctx, ctrl.Request{NamespacedName: name}, iss)
+	require.NoError(t, err)
+
+	condition := readyCondition(iss)
+	if assert.NotNil(t, condition) {
+		assert.Equal(t, metav1.ConditionFalse, condition.Status)
+		assert.Equal(t, string(issuerapi.ReasonCANotActive), condition.Reason)
+	}
+}
+
+func TestIssuerVerifyCA(t *testing.T) {
+	tests := map[string]struct {
+		provisioner                  *fakeProvisioner
+		expectedReady                bool
+		expectedResult               ctrl.Result
+		expectedError                bool
+		expectedReadyConditionReason string
+		expectedConnectedStatus      metav1.ConditionStatus
+		expectedConnectedReason      string
+	}{
+		"active": {
+			provisioner:             &fakeProvisioner{caStatus: acmpcatypes.CertificateAuthorityStatusActive},
+			expectedReady:           true,
+			expectedConnectedStatus: metav1.ConditionTrue,
+			expectedConnectedReason: string(issuerapi.ReasonConnected),
+		},
+		"creating": {
+			provisioner:                  &fakeProvisioner{caStatus: acmpcatypes.CertificateAuthorityStatusCreating},
+			expectedResult:               ctrl.Result{RequeueAfter: caNotActiveRequeueInterval},
+			expectedReadyConditionReason: string(issuerapi.ReasonCANotActive),
+			expectedConnectedStatus:      metav1.ConditionTrue,
+			expectedConnectedReason:      string(issuerapi.ReasonConnected),
+		},
+		"pending-certificate": {
+			provisioner:                  &fakeProvisioner{caStatus: acmpcatypes.CertificateAuthorityStatusPendingCertificate},
+			expectedResult:               ctrl.Result{RequeueAfter: caNotActiveRequeueInterval},
+			expectedReadyConditionReason: string(issuerapi.ReasonCANotActive),
+			expectedConnectedStatus:      metav1.ConditionTrue,
+			expectedConnectedReason:      string(issuerapi.ReasonConnected),
+		},
+		"disabled": {
+			provisioner:                  &fakeProvisioner{caStatus: acmpcatypes.CertificateAuthorityStatusDisabled},
+			expectedResult:               ctrl.Result{RequeueAfter: caNotActiveRequeueInterval},
+			expectedReadyConditionReason: string(issuerapi.ReasonCANotActive),
+			expectedConnectedStatus:      metav1.ConditionTrue,
+			expectedConnectedReason:      string(issuerapi.ReasonConnected),
+		},
+		"expired": {
+			provisioner:                  &fakeProvisioner{caStatus: acmpcatypes.CertificateAuthorityStatusExpired},
+			expectedResult:               ctrl.Result{RequeueAfter: caNotActiveRequeueInterval},
+			expectedReadyConditionReason: string(issuerapi.ReasonCANotActive),
+			expectedConnectedStatus:      metav1.ConditionTrue,
+			expectedConnectedReason:      string(issuerapi.ReasonConnected),
+		},
+		"failed": {
+			provisioner:                  &fakeProvisioner{caStatus: acmpcatypes.CertificateAuthorityStatusFailed},
+			expectedResult:               ctrl.Result{RequeueAfter: caNotActiveRequeueInterval},
+			expectedReadyConditionReason: string(issuerapi.ReasonCANotActive),
+			expectedConnectedStatus:      metav1.ConditionTrue,
+			expectedConnectedReason:      string(issuerapi.ReasonConnected),
+		},
+		"deleted": {
+			provisioner:                  &fakeProvisioner{caStatus: acmpcatypes.CertificateAuthorityStatusDeleted},
+			expectedResult:               ctrl.Result{RequeueAfter: caNotActiveRequeueInterval},
+			expectedReadyConditionReason: string(issuerapi.ReasonCANotActive),
+			expectedConnectedStatus:      metav1.ConditionTrue,
+			expectedConnectedReason:      string(issuerapi.ReasonConnected),
+		},
+		"unreachable": {
+			provisioner:                  &fakeProvisioner{caStatusErr: errors.New("dial tcp: i/o timeout")},
+			expectedResult:               ctrl.Result{RequeueAfter: caNotActiveRequeueInterval},
+			expectedReadyConditionReason: string(issuerapi.ReasonCAUnreachable),
+			expectedConnectedStatus:      metav1.ConditionFalse,
+			expectedConnectedReason:      string(issuerapi.ReasonCAUnreachable),
+		},
+		"throttled": {
+			provisioner:             &fakeProvisioner{caStatusErr: &smithy.GenericAPIError{Code: "ThrottlingException"}},
+			expectedError:           true,
+			expectedConnectedStatus: metav1.ConditionFalse,
+			expectedConnectedReason: string(issuerapi.ReasonAPIError),
+		},
+	}
+
+	scheme := runtime.NewScheme()
+	require.NoError(t, issuerapi.AddToScheme(scheme))
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			iss := &issuerapi.AWSPCAIssuer{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "issuer1",
+					Namespace: "health-ns",
+				},
+			}
+			fakeClient := fake.NewClientBuilder().
+				WithScheme(scheme).
+				WithObjects(iss).
+				WithStatusSubresource(iss).
+				Build()
+			controller := GenericIssuerReconciler{
+				Client:   fakeClient,
+				Log:      logrtesting.NewTestLogger(t),
+				Scheme:   scheme,
+				Recorder: record.NewFakeRecorder(10),
+			}
+
+			ready, result, err := controller.verifyCA(context.TODO(), iss, tc.provisioner)
+			assert.Equal(t, tc.expectedReady, ready)
+			assert.Equal(t, tc.expectedResult, result)
+			if tc.expectedError {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+
+			condition := readyCondition(iss)
+			if tc.expectedReadyConditionReason == "" {
+				assert.Nil(t, condition)
+			} else if assert.NotNil(t, condition) {
+				assert.Equal(t, metav1.ConditionFalse, condition.Status)
+				assert.Equal(t, tc.expectedReadyConditionReason, condition.Reason)
+			}
+
+			// Only failed calls mark the issuer disconnected, not a CA
+			// that cannot issue certificates
+			if connected := issuerCondition(iss, issuerapi.ConditionTypeConnected); assert.NotNil(t, connected) {
+				assert.Equal(t, tc.expectedConnectedStatus, connected.Status)
+				assert.Equal(t, tc.expectedConnectedReason, connected.Reason)
+			}
+		})
+	}
+}
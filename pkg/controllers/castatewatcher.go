@@ -0,0 +1,257 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	awsarn "github.com/aws/aws-sdk-go-v2/aws/arn"
+	api "github.com/cert-manager/aws-privateca-issuer/pkg/api/v1beta1"
+	awspca "github.com/cert-manager/aws-privateca-issuer/pkg/aws"
+	"github.com/go-logr/logr"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+)
+
+// caEventRetryInterval is how long to wait before receiving from the queue
+// again after it failed
+const caEventRetryInterval = 30 * time.Second
+
+// caEventSource is the source of the EventBridge events of ACM PCA, including
+// the CloudTrail events of its API calls
+const caEventSource = "aws.acm-pca"
+
+const cloudTrailDetailType = "AWS API Call via CloudTrail"
+
+// caStateChangeCalls are the PCA API calls, recorded as CloudTrail events,
+// that change the state of a CA
+var caStateChangeCalls = map[string]bool{
+	"UpdateCertificateAuthority":            true,
+	"DeleteCertificateAuthority":            true,
+	"RestoreCertificateAuthority":           true,
+	"ImportCertificateAuthorityCertificate": true,
+}
+
+// caEventsWithoutStateChange are the EventBridge events of ACM PCA that say
+// nothing about the state of the CA
+var caEventsWithoutStateChange = map[string]bool{
+	"ACM Private CA Certificate Issuance":    true,
+	"ACM Private CA Certificate Revocation":  true,
+	"ACM Private CA Audit Report Generation": true,
+	"ACM Private CA CRL Generation":          true,
+}
+
+// caEventQueue receives the notifications of CA state changes, see
+// awspca.SQSQueue
+type caEventQueue interface {
+	Receive(ctx context.Context) ([]awspca.QueueMessage, error)
+	Delete(ctx context.Context, receiptHandle string) error
+}
+
+// CAStateWatcher consumes EventBridge events of CA state changes from an SQS
+// queue and reconciles the issuers of the affected CAs, so that their Ready
+// condition follows the CA without waiting for the next periodic check.
+type CAStateWatcher struct {
+	client.Client
+	Log   logr.Logger
+	Queue caEventQueue
+	// Namespace restricts the watcher to the issuers in a single namespace.
+	// AWSPCAClusterIssuers are not reconciled if it is set
+	Namespace string
+	// CAHealth, if set, forgets the health of affected issuers, so that the
+	// reconcile describes their CA again
+	CAHealth *CAHealthChecker
+
+	// Issuers and ClusterIssuers receive the issuers to reconcile. Either
+	// is nil if the controller of its kind is disabled
+	Issuers        chan event.GenericEvent
+	ClusterIssuers chan event.GenericEvent
+}
+
+// Start receives from the queue until ctx is cancelled
+func (w *CAStateWatcher) Start(ctx context.Context) error {
+	for ctx.Err() == nil {
+		if err := w.poll(ctx); err != nil && ctx.Err() == nil {
+			w.Log.Error(err, "failed to receive CA state change events")
+			select {
+			case <-ctx.Done():
+			case <-time.After(caEventRetryInterval):
+			}
+		}
+	}
+	return nil
+}
+
+// poll receives a batch of messages and reconciles the issuers of the CAs they
+// concern. Messages are deleted once handled, including those that are not CA
+// state changes, so that they are not received again. Messages whose issuers
+// could not be listed are kept, so that they are received again once their
+// visibility timeout expires.
+func (w *CAStateWatcher) poll(ctx context.Context) error {
+	messages, err := w.Queue.Receive(ctx)
+	if err != nil {
+		return err
+	}
+	for _, message := range messages {
+		log := w.Log.WithValues("messageId", message.MessageID)
+		arns, err := caArnsFromMessage(message.Body)
+		switch {
+		case err != nil:
+			log.Error(err, "ignoring malformed CA state change event")
+		case len(arns) > 0:
+			if err := w.reconcileIssuers(ctx, log, arns); err != nil {
+				log.Error(err, "failed to reconcile issuers after CA state change")
+				continue
+			}
+		}
+		if err := w.Queue.Delete(ctx, message.ReceiptHandle); err != nil {
+			log.Error(err, "failed to delete CA state change event")
+		}
+	}
+	return nil
+}
+
+// reconcileIssuers sends the issuers using any of the CA ARNs to the issuer
+// controllers
+func (w *CAStateWatcher) reconcileIssuers(ctx context.Context, log logr.Logger, arns map[string]bool) error {
+	if w.Issuers != nil {
+		issuers := new(api.AWSPCAIssuerList)
+		if err := w.Client.List(ctx, issuers, client.InNamespace(w.Namespace)); err != nil {
+			return fmt.Errorf("failed to list AWSPCAIssuers: %w", err)
+		}
+		for i := range issuers.Items {
+			w.reconcileIssuer(ctx, log, &issuers.Items[i], arns, w.Issuers)
+		}
+	}
+	if w.ClusterIssuers != nil && w.Namespace == "" {
+		clusterIssuers := new(api.AWSPCAClusterIssuerList)
+		if err := w.Client.List(ctx, clusterIssuers); err != nil {
+			return fmt.Errorf("failed to list AWSPCAClusterIssuers: %w", err)
+		}
+		for i := range clusterIssuers.Items {
+			w.reconcileIssuer(ctx, log, &clusterIssuers.Items[i], arns, w.ClusterIssuers)
+		}
+	}
+	return nil
+}
+
+func (w *CAStateWatcher) reconcileIssuer(ctx context.Context, log logr.Logger, issuer api.GenericIssuer, arns map[string]bool, events chan<- event.GenericEvent) {
+	name := types.NamespacedName{Namespace: issuer.GetNamespace(), Name: issuer.GetName()}
+	if !usesCA(name, issuer, arns) {
+		return
+	}
+	log.Info("Reconciling issuer after CA state change", "genericissuer", name)
+	if w.CAHealth != nil {
+		w.CAHealth.forget(name)
+	}
+	select {
+	case events <- event.GenericEvent{Object: issuer}:
+	case <-ctx.Done():
+	}
+}
+
+// usesCA returns whether the issuer issues certificates from any of the CA
+// ARNs, including its failover CAs. The ARN of the provisioner is used for
+// issuers whose ARN is resolved from a ConfigMap or Secret.
+func usesCA(name types.NamespacedName, issuer api.GenericIssuer, arns map[string]bool) bool {
+	spec := issuer.GetSpec()
+	caArns := append([]string{spec.Arn}, spec.ArnFailover...)
+	if provisioner, ok := awspca.GetProvisioner(name); ok {
+		if p, ok := provisioner.(interface{ Arn() string }); ok {
+			caArns = append(caArns, p.Arn())
+		}
+	}
+	for _, arn := range caArns {
+		if arn != "" && arns[arn] {
+			return true
+		}
+	}
+	return false
+}
+
+// caEvent holds the fields of an EventBridge event used to find the CAs it
+// concerns
+type caEvent struct {
+	Source     string   `json:"source"`
+	DetailType string   `json:"detail-type"`
+	Resources  []string `json:"resources"`
+	Detail     struct {
+		EventName         string `json:"eventName"`
+		RequestParameters struct {
+			CertificateAuthorityArn string `json:"certificateAuthorityArn"`
+		} `json:"requestParameters"`
+	} `json:"detail"`
+}
+
+// snsNotification is the envelope of messages delivered to the queue through
+// an SNS topic
+type snsNotification struct {
+	Type    string `json:"Type"`
+	Message string `json:"Message"`
+}
+
+// caArnsFromMessage returns the ARNs of the CAs whose state changed according
+// to the EventBridge event in body, which may be wrapped in an SNS
+// notification. Events of other sources, and PCA events that do not change
+// the state of a CA, such as certificate issuance, yield no ARNs.
+func caArnsFromMessage(body string) (map[string]bool, error) {
+	var notification snsNotification
+	if err := json.Unmarshal([]byte(body), &notification); err == nil && notification.Type == "Notification" {
+		body = notification.Message
+	}
+
+	var e caEvent
+	if err := json.Unmarshal([]byte(body), &e); err != nil {
+		return nil, fmt.Errorf("failed to decode event: %w", err)
+	}
+
+	var candidates []string
+	switch {
+	case e.Source != caEventSource:
+	case e.DetailType == cloudTrailDetailType:
+		if caStateChangeCalls[e.Detail.EventName] {
+			candidates = []string{e.Detail.RequestParameters.CertificateAuthorityArn}
+		}
+	case !caEventsWithoutStateChange[e.DetailType]:
+		candidates = e.Resources
+	}
+
+	arns := map[string]bool{}
+	for _, candidate := range candidates {
+		if arn, ok := certificateAuthorityArn(candidate); ok {
+			arns[arn] = true
+		}
+	}
+	return arns, nil
+}
+
+// certificateAuthorityArn returns the ARN of the CA of a PCA resource ARN,
+// e.g. of a certificate issued by it
+func certificateAuthorityArn(resource string) (string, bool) {
+	parsed, err := awsarn.Parse(resource)
+	if err != nil || parsed.Service != "acm-pca" || !strings.HasPrefix(parsed.Resource, "certificate-authority/") {
+		return "", false
+	}
+	id, _, _ := strings.Cut(strings.TrimPrefix(parsed.Resource, "certificate-authority/"), "/")
+	parsed.Resource = "certificate-authority/" + id
+	return parsed.String(), true
+}
@@ -0,0 +1,249 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	logrtesting "github.com/go-logr/logr/testing"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/client/interceptor"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+
+	issuerapi "github.com/cert-manager/aws-privateca-issuer/pkg/api/v1beta1"
+	awspca "github.com/cert-manager/aws-privateca-issuer/pkg/aws"
+)
+
+const (
+	eventCAArn      = "arn:aws:acm-pca:us-east-1:111122223333:certificate-authority/11111111-1111-1111-1111-111111111111"
+	eventOtherCAArn = "arn:aws:acm-pca:us-east-1:111122223333:certificate-authority/22222222-2222-2222-2222-222222222222"
+)
+
+// fakeCAEventQueue returns its messages from the first receive and records
+// the deleted receipt handles
+type fakeCAEventQueue struct {
+	messages []awspca.QueueMessage
+	deleted  []string
+}
+
+func (q *fakeCAEventQueue) Receive(_ context.Context) ([]awspca.QueueMessage, error) {
+	messages := q.messages
+	q.messages = nil
+	return messages, nil
+}
+
+func (q *fakeCAEventQueue) Delete(_ context.Context, receiptHandle string) error {
+	q.deleted = append(q.deleted, receiptHandle)
+	return nil
+}
+
+func TestCAArnsFromMessage(t *testing.T) {
+	tests := map[string]struct {
+		body          string
+		expectedArns  []string
+		expectedError bool
+	}{
+		"cloudtrail-update": {
+			body:         `{"source":"aws.acm-pca","detail-type":"AWS API Call via CloudTrail","detail":{"eventSource":"acm-pca.amazonaws.com","eventName":"UpdateCertificateAuthority","requestParameters":{"certificateAuthorityArn":"` + eventCAArn + `","status":"DISABLED"}}}`,
+			expectedArns: []string{eventCAArn},
+		},
+		"cloudtrail-delete": {
+			body:         `{"source":"aws.acm-pca","detail-type":"AWS API Call via CloudTrail","detail":{"eventName":"DeleteCertificateAuthority","requestParameters":{"certificateAuthorityArn":"` + eventCAArn + `"}}}`,
+			expectedArns: []string{eventCAArn},
+		},
+		"cloudtrail-issue-certificate": {
+			body: `{"source":"aws.acm-pca","detail-type":"AWS API Call via CloudTrail","detail":{"eventName":"IssueCertificate","requestParameters":{"certificateAuthorityArn":"` + eventCAArn + `"}}}`,
+		},
+		"pca-event": {
+			body:         `{"source":"aws.acm-pca","detail-type":"ACM Private CA Certificate Authority Expiration","resources":["` + eventCAArn + `","` + eventOtherCAArn + `"]}`,
+			expectedArns: []string{eventCAArn, eventOtherCAArn},
+		},
+		"pca-event-certificate-resource": {
+			body:         `{"source":"aws.acm-pca","detail-type":"ACM Private CA Certificate Authority Expiration","resources":["` + eventCAArn + `/certificate/0123456789abcdef"]}`,
+			expectedArns: []string{eventCAArn},
+		},
+		"pca-certificate-issuance": {
+			body: `{"source":"aws.acm-pca","detail-type":"ACM Private CA Certificate Issuance","resources":["` + eventCAArn + `"]}`,
+		},
+		"other-source": {
+			body: `{"source":"aws.s3","detail-type":"Object Created","resources":["` + eventCAArn + `"]}`,
+		},
+		"non-pca-resource": {
+			body: `{"source":"aws.acm-pca","detail-type":"ACM Private CA Certificate Authority Expiration","resources":["arn:aws:s3:::bucket"]}`,
+		},
+		"sns-notification": {
+			body:         `{"Type":"Notification","MessageId":"1","Message":"{\"source\":\"aws.acm-pca\",\"detail-type\":\"AWS API Call via CloudTrail\",\"detail\":{\"eventName\":\"RestoreCertificateAuthority\",\"requestParameters\":{\"certificateAuthorityArn\":\"` + eventCAArn + `\"}}}"}`,
+			expectedArns: []string{eventCAArn},
+		},
+		"malformed": {
+			body:          `not json`,
+			expectedError: true,
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			arns, err := caArnsFromMessage(tc.body)
+			if tc.expectedError {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			expected := map[string]bool{}
+			for _, arn := range tc.expectedArns {
+				expected[arn] = true
+			}
+			assert.Equal(t, expected, arns)
+		})
+	}
+}
+
+func TestUsesCA(t *testing.T) {
+	awspca.ClearProvisioners()
+	t.Cleanup(awspca.ClearProvisioners)
+	arns := map[string]bool{eventCAArn: true}
+
+	tests := map[string]struct {
+		spec           issuerapi.AWSPCAIssuerSpec
+		provisionerArn string
+		expected       bool
+	}{
+		"arn": {
+			spec:     issuerapi.AWSPCAIssuerSpec{Arn: eventCAArn},
+			expected: true,
+		},
+		"failover-arn": {
+			spec:     issuerapi.AWSPCAIssuerSpec{Arn: eventOtherCAArn, ArnFailover: []string{eventCAArn}},
+			expected: true,
+		},
+		"resolved-arn": {
+			spec:           issuerapi.AWSPCAIssuerSpec{ArnFrom: &issuerapi.AWSArnSource{}},
+			provisionerArn: eventCAArn,
+			expected:       true,
+		},
+		"other-ca": {
+			spec: issuerapi.AWSPCAIssuerSpec{Arn: eventOtherCAArn},
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			key := types.NamespacedName{Namespace: "ns1", Name: name}
+			if tc.provisionerArn != "" {
+				awspca.StoreProvisioner(key, awspca.NewProvisionerWithClient(nil, tc.provisionerArn))
+			}
+			issuer := &issuerapi.AWSPCAIssuer{
+				ObjectMeta: metav1.ObjectMeta{Namespace: key.Namespace, Name: key.Name},
+				Spec:       tc.spec,
+			}
+			assert.Equal(t, tc.expected, usesCA(key, issuer, arns))
+		})
+	}
+}
+
+func TestCAStateWatcherPoll(t *testing.T) {
+	awspca.ClearProvisioners()
+	t.Cleanup(awspca.ClearProvisioners)
+
+	scheme := runtime.NewScheme()
+	require.NoError(t, issuerapi.AddToScheme(scheme))
+	objects := []client.Object{
+		&issuerapi.AWSPCAIssuer{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "ns1", Name: "affected"},
+			Spec:       issuerapi.AWSPCAIssuerSpec{Arn: eventCAArn},
+		},
+		&issuerapi.AWSPCAIssuer{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "ns1", Name: "unaffected"},
+			Spec:       issuerapi.AWSPCAIssuerSpec{Arn: eventOtherCAArn},
+		},
+		&issuerapi.AWSPCAClusterIssuer{
+			ObjectMeta: metav1.ObjectMeta{Name: "affected"},
+			Spec:       issuerapi.AWSPCAIssuerSpec{Arn: eventCAArn},
+		},
+	}
+	fakeClient := fake.NewClientBuilder().WithScheme(scheme).WithObjects(objects...).Build()
+
+	queue := &fakeCAEventQueue{messages: []awspca.QueueMessage{
+		{MessageID: "1", ReceiptHandle: "r1", Body: `{"source":"aws.acm-pca","detail-type":"AWS API Call via CloudTrail","detail":{"eventName":"UpdateCertificateAuthority","requestParameters":{"certificateAuthorityArn":"` + eventCAArn + `"}}}`},
+		{MessageID: "2", ReceiptHandle: "r2", Body: `not json`},
+		{MessageID: "3", ReceiptHandle: "r3", Body: `{"source":"aws.acm-pca","detail-type":"ACM Private CA Certificate Issuance","resources":["` + eventCAArn + `"]}`},
+	}}
+	health := &CAHealthChecker{unhealthy: map[types.NamespacedName]caHealth{
+		{Namespace: "ns1", Name: "affected"}:   {reason: issuerapi.ReasonCANotActive},
+		{Namespace: "ns1", Name: "unaffected"}: {reason: issuerapi.ReasonCANotActive},
+	}}
+	watcher := &CAStateWatcher{
+		Client:         fakeClient,
+		Log:            logrtesting.NewTestLogger(t),
+		Queue:          queue,
+		CAHealth:       health,
+		Issuers:        make(chan event.GenericEvent, 10),
+		ClusterIssuers: make(chan event.GenericEvent, 10),
+	}
+
+	require.NoError(t, watcher.poll(context.TODO()))
+	close(watcher.Issuers)
+	close(watcher.ClusterIssuers)
+
+	var issuers, clusterIssuers []string
+	for e := range watcher.Issuers {
+		issuers = append(issuers, client.ObjectKeyFromObject(e.Object).String())
+	}
+	for e := range watcher.ClusterIssuers {
+		clusterIssuers = append(clusterIssuers, e.Object.GetName())
+	}
+	assert.Equal(t, []string{"ns1/affected"}, issuers)
+	assert.Equal(t, []string{"affected"}, clusterIssuers)
+	assert.Equal(t, []string{"r1", "r2", "r3"}, queue.deleted, "expected all messages to be deleted")
+
+	_, unhealthy := health.Unhealthy(types.NamespacedName{Namespace: "ns1", Name: "affected"})
+	assert.False(t, unhealthy, "expected the health of the affected issuer to be forgotten")
+	_, unhealthy = health.Unhealthy(types.NamespacedName{Namespace: "ns1", Name: "unaffected"})
+	assert.True(t, unhealthy)
+}
+
+func TestCAStateWatcherPollListFailure(t *testing.T) {
+	scheme := runtime.NewScheme()
+	require.NoError(t, issuerapi.AddToScheme(scheme))
+	fakeClient := fake.NewClientBuilder().WithScheme(scheme).WithInterceptorFuncs(interceptor.Funcs{
+		List: func(_ context.Context, _ client.WithWatch, _ client.ObjectList, _ ...client.ListOption) error {
+			return errors.New("apiserver unavailable")
+		},
+	}).Build()
+
+	queue := &fakeCAEventQueue{messages: []awspca.QueueMessage{
+		{MessageID: "1", ReceiptHandle: "r1", Body: `{"source":"aws.acm-pca","detail-type":"AWS API Call via CloudTrail","detail":{"eventName":"UpdateCertificateAuthority","requestParameters":{"certificateAuthorityArn":"` + eventCAArn + `"}}}`},
+		{MessageID: "2", ReceiptHandle: "r2", Body: `{"source":"aws.acm-pca","detail-type":"ACM Private CA Certificate Issuance","resources":["` + eventCAArn + `"]}`},
+	}}
+	watcher := &CAStateWatcher{
+		Client:  fakeClient,
+		Log:     logrtesting.NewTestLogger(t),
+		Queue:   queue,
+		Issuers: make(chan event.GenericEvent, 10),
+	}
+
+	require.NoError(t, watcher.poll(context.TODO()))
+	assert.Equal(t, []string{"r2"}, queue.deleted, "expected the CA state change to be kept for another attempt")
+}
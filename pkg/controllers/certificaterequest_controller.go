@@ -18,28 +18,84 @@ package controllers
 
 import (
 	"context"
+	goerrors "errors"
 	"fmt"
+	"math/rand"
+	"regexp"
+	"strconv"
+	"time"
+	"unicode/utf8"
 
+	awshttp "github.com/aws/aws-sdk-go-v2/aws/transport/http"
+	acmpcatypes "github.com/aws/aws-sdk-go-v2/service/acmpca/types"
+	"github.com/aws/smithy-go"
 	"github.com/cert-manager/aws-privateca-issuer/pkg/aws"
 	"github.com/cert-manager/aws-privateca-issuer/pkg/util"
 	cmapi "github.com/cert-manager/cert-manager/pkg/apis/certmanager/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
 	"k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/wait"
 	"k8s.io/client-go/tools/record"
+	"k8s.io/client-go/util/retry"
 
 	"github.com/go-logr/logr"
+	"go.opentelemetry.io/otel/trace"
 	core "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/utils/clock"
 	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
 	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
 
 	api "github.com/cert-manager/aws-privateca-issuer/pkg/api/v1beta1"
 	cmutil "github.com/cert-manager/cert-manager/pkg/api/util"
 	cmmeta "github.com/cert-manager/cert-manager/pkg/apis/meta/v1"
 )
 
+// maxPCAErrorMessageLength bounds the PCA errors included in the Ready
+// condition of CertificateRequests
+const maxPCAErrorMessageLength = 512
+
+// accessKeyIDPattern matches AWS access key IDs, which are redacted from the
+// errors included in the status of CertificateRequests
+var accessKeyIDPattern = regexp.MustCompile(`\b(AKIA|ASIA)[A-Z0-9]{16}\b`)
+
+const (
+	// requeueAttemptsAnnotation counts how often PCA reported that the
+	// certificate of a CertificateRequest was still being issued
+	requeueAttemptsAnnotation = "aws-privateca-issuer/requeue-attempts"
+
+	// reissueAttemptsAnnotation counts how often the certificate of a
+	// CertificateRequest was requested again because PCA could not find the
+	// recorded certificate
+	reissueAttemptsAnnotation = "aws-privateca-issuer/reissue-attempts"
+
+	// failureAttemptsAnnotation counts the consecutive failed attempts to
+	// request or retrieve the certificate of a CertificateRequest
+	failureAttemptsAnnotation = "aws-privateca-issuer/failure-attempts"
+
+	// maxReissueAttempts is how often a certificate is requested again before
+	// the CertificateRequest is marked Failed
+	maxReissueAttempts = 3
+
+	// serialNumberAnnotation records the serial number of the certificate of
+	// a CertificateRequest once it has been retrieved from PCA
+	serialNumberAnnotation = "aws-privateca-issuer/certificate-serial-number"
+
+	defaultPendingRequeueInterval = time.Second
+	defaultMaxRequeueBackoff      = time.Minute
+
+	// lastIssuedTimeResolution is how much the LastIssuedTime of an issuer
+	// must have aged before it is updated, so that busy issuers are not
+	// updated, and reconciled, for every certificate
+	lastIssuedTimeResolution = time.Minute
+)
+
 // CertificateRequestReconciler reconciles a AWSPCAIssuer object
 type CertificateRequestReconciler struct {
 	client.Client
@@ -49,6 +105,104 @@ type CertificateRequestReconciler struct {
 
 	Clock                  clock.Clock
 	CheckApprovedCondition bool
+	// PendingRequeueInterval is the delay before first retrying to retrieve
+	// a certificate that PCA is still issuing. It doubles with every further
+	// attempt. Defaults to one second.
+	PendingRequeueInterval time.Duration
+	// MaxRequeueBackoff caps the delay between attempts to retrieve a
+	// certificate that PCA is still issuing. Defaults to one minute.
+	MaxRequeueBackoff time.Duration
+	// RequeueJitter is the window over which requeues are spread: a random
+	// delay of up to it is added to every requeue backoff, so that
+	// CertificateRequests renewed at the same time do not all retry PCA at
+	// once. Requeues are not jittered if it is zero.
+	RequeueJitter time.Duration
+	// MinPollInterval and MaxPollInterval bound the delay, including jitter,
+	// between attempts to retrieve a certificate that PCA is still issuing.
+	// The delay is not bounded by either if it is zero.
+	MinPollInterval time.Duration
+	MaxPollInterval time.Duration
+	// IssuanceTimeout is how long after its creation a CertificateRequest may
+	// wait for PCA to issue its certificate before it is marked Failed, so
+	// that cert-manager recreates requests stuck on a hung CA. Requests wait
+	// indefinitely if it is zero.
+	IssuanceTimeout time.Duration
+	// TracerProvider provides the tracer for spans around reconciles and PCA
+	// calls. The global provider is used if it is nil.
+	TracerProvider trace.TracerProvider
+	// DisableIssuers ignores CertificateRequests for AWSPCAIssuers, e.g. when
+	// only AWSPCAClusterIssuers are served
+	DisableIssuers bool
+
+	// DisableClusterIssuers ignores CertificateRequests for
+	// AWSPCAClusterIssuers, e.g. when only watching a single namespace
+	DisableClusterIssuers bool
+	// CertificateArnAnnotation is the annotation the certificate ARN is
+	// recorded in. It must match the key the provisioners write, and
+	// defaults to the aws.CertificateArnAnnotation.
+	CertificateArnAnnotation string
+	// MaxConcurrentReconciles is the number of CertificateRequests reconciled
+	// in parallel. Defaults to one.
+	MaxConcurrentReconciles int
+	// ShutdownTimeout is how long an in-flight reconcile may continue after
+	// the manager starts shutting down, so that a certificate requested from
+	// PCA is recorded before the controller exits. Reconciles are cancelled
+	// immediately if it is zero.
+	ShutdownTimeout time.Duration
+	// Provisioners rebuilds the provisioner of a Ready issuer that has none,
+	// e.g. after it was invalidated because its credentials expired. Such
+	// CertificateRequests are marked Failed if it is nil.
+	Provisioners ProvisionerLoader
+	// IssuanceRateLimit is the number of certificates per second each issuer
+	// may request from PCA, unless overridden by its issuance rate limit
+	// annotation. Further CertificateRequests are requeued until the bucket
+	// of the issuer refills. Issuance is not limited if it is zero.
+	IssuanceRateLimit float64
+	// IssuanceLimiters holds the token buckets of the issuers, and should be
+	// shared with the GenericIssuerReconciler so that the buckets of deleted
+	// issuers are dropped. Issuance is not limited if it is nil.
+	IssuanceLimiters *IssuanceLimiters
+	// FailureThreshold is the number of consecutive failed attempts to
+	// request or retrieve a certificate after which a CertificateRequest is
+	// marked Failed. Earlier failures leave it Pending and are retried with
+	// backoff. Defaults to one, failing it on the first error.
+	FailureThreshold int
+	// CallTimeout bounds each Sign and Get of the provisioner, so that a hung
+	// PCA call requeues the CertificateRequest instead of blocking a worker.
+	// Get may take longer by the certificateWaitTimeout of the issuer. Calls
+	// are not bounded if it is zero.
+	CallTimeout time.Duration
+	// IssuanceQuotaThreshold is the number of certificates issued for an
+	// issuer within the IssuanceQuotaWindow at which a Warning event is
+	// emitted and its IssuanceQuota condition becomes True, so that a PCA
+	// quota increase can be requested in time. Issuances are not counted if
+	// it is zero.
+	IssuanceQuotaThreshold int
+	// IssuanceQuotaWindow is the period over which issuances are counted
+	// against the IssuanceQuotaThreshold. Defaults to a day.
+	IssuanceQuotaWindow time.Duration
+	// IssuanceCounters holds the issuance counters of the issuers, and should
+	// be shared with the GenericIssuerReconciler so that the counters of
+	// deleted issuers are dropped. Issuances are not counted if it is nil.
+	IssuanceCounters *IssuanceCounters
+	// CACommonNames caches the common names of the CAs of the issuers, and
+	// should be shared with the GenericIssuerReconciler so that the names of
+	// deleted issuers are dropped
+	CACommonNames *CACommonNames
+}
+
+// now returns the current time of the Clock, or of the system if none is set
+func (r *CertificateRequestReconciler) now() time.Time {
+	if r.Clock != nil {
+		return r.Clock.Now()
+	}
+	return time.Now()
+}
+
+// ProvisionerLoader builds the provisioner of an issuer, see
+// GenericIssuerReconciler.LoadProvisioner
+type ProvisionerLoader interface {
+	LoadProvisioner(ctx context.Context, name types.NamespacedName, issuer api.GenericIssuer) (aws.GenericProvisioner, error)
 }
 
 // +kubebuilder:rbac:groups=cert-manager.io,resources=certificaterequests,verbs=get;list;watch;update
@@ -61,11 +215,23 @@ type CertificateRequestReconciler struct {
 //
 // For more details, check Reconcile and its Result here:
 // - https://pkg.go.dev/sigs.k8s.io/controller-runtime@v0.7.0/pkg/reconcile
-func (r *CertificateRequestReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+func (r *CertificateRequestReconciler) Reconcile(ctx context.Context, req ctrl.Request) (result ctrl.Result, err error) {
+	if r.ShutdownTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = drainContext(ctx, r.ShutdownTimeout)
+		defer cancel()
+	}
+
+	ctx, span := tracer(r.TracerProvider).Start(ctx, "CertificateRequest.Reconcile",
+		trace.WithAttributes(attributeCertificateRequest.String(req.NamespacedName.String())))
+	defer func() { endSpan(span, err) }()
+
 	log := r.Log.WithValues("certificaterequest", req.NamespacedName)
 	cr := new(cmapi.CertificateRequest)
 	if err := r.Client.Get(ctx, req.NamespacedName, cr); err != nil {
 		if errors.IsNotFound(err) {
+			// A deleted CertificateRequest is never retrieved
+			forgetSigned(req.NamespacedName)
 			return ctrl.Result{}, client.IgnoreNotFound(err)
 		}
 
@@ -78,6 +244,30 @@ func (r *CertificateRequestReconciler) Reconcile(ctx context.Context, req ctrl.R
 		return ctrl.Result{}, nil
 	}
 
+	issuerName := types.NamespacedName{
+		Namespace: cr.Namespace,
+		Name:      cr.Spec.IssuerRef.Name,
+	}
+	span.SetAttributes(attributeIssuer.String(issuerName.String()))
+	if cr.Spec.IssuerRef.Kind == "AWSPCAClusterIssuer" {
+		if r.DisableClusterIssuers {
+			log.V(4).Info("CertificateRequest references an AWSPCAClusterIssuer, which are disabled. Ignoring.")
+			return ctrl.Result{}, nil
+		}
+		issuerName.Namespace = ""
+	} else if r.DisableIssuers {
+		log.V(4).Info("CertificateRequest references an AWSPCAIssuer, which are disabled. Ignoring.")
+		return ctrl.Result{}, nil
+	}
+	log = log.WithValues("issuerKind", cr.Spec.IssuerRef.Kind, "issuerName", issuerName.Name, "issuerNamespace", issuerName.Namespace)
+
+	if deleted, err := r.reconcileRevocation(ctx, log, cr, issuerName); deleted || err != nil {
+		return ctrl.Result{}, err
+	}
+	if err := r.reconcileForceReissue(ctx, log, cr); err != nil {
+		return ctrl.Result{}, err
+	}
+
 	// Ignore CertificateRequest if it is already Ready
 	if cmutil.CertificateRequestHasCondition(cr, cmapi.CertificateRequestCondition{
 		Type:   cmapi.CertificateRequestConditionReady,
@@ -104,6 +294,16 @@ func (r *CertificateRequestReconciler) Reconcile(ctx context.Context, req ctrl.R
 		log.V(4).Info("CertificateRequest already has a Ready condition with Denied Reason. Ignoring.")
 		return ctrl.Result{}, nil
 	}
+	// Ignore CertificateRequest if it was validated by a dry run, unless the
+	// dry run annotation has since been removed
+	if aws.DryRun(cr) && cmutil.CertificateRequestHasCondition(cr, cmapi.CertificateRequestCondition{
+		Type:   cmapi.CertificateRequestConditionReady,
+		Status: cmmeta.ConditionFalse,
+		Reason: string(api.ReasonDryRunValidated),
+	}) {
+		log.V(4).Info("CertificateRequest was already validated by a dry run. Ignoring.")
+		return ctrl.Result{}, nil
+	}
 
 	// If CertificateRequest has been denied, mark the CertificateRequest as
 	// Ready=Denied and set FailureTime if not already.
@@ -111,11 +311,12 @@ func (r *CertificateRequestReconciler) Reconcile(ctx context.Context, req ctrl.R
 		log.V(4).Info("CertificateRequest has been denied. Marking as failed.")
 
 		if cr.Status.FailureTime == nil {
-			nowTime := metav1.NewTime(r.Clock.Now())
+			nowTime := metav1.NewTime(r.now())
 			cr.Status.FailureTime = &nowTime
 		}
 
 		message := "The CertificateRequest was denied by an approval controller"
+		recordCertificateRequestResult(issuerName, resultFailed)
 		return ctrl.Result{}, r.setStatus(ctx, cr, cmmeta.ConditionFalse, cmapi.CertificateRequestReasonDenied, message)
 	}
 
@@ -123,6 +324,7 @@ func (r *CertificateRequestReconciler) Reconcile(ctx context.Context, req ctrl.R
 		// If CertificateRequest has not been approved, exit early.
 		if !cmutil.CertificateRequestIsApproved(cr) {
 			log.V(4).Info("certificate request has not been approved")
+			recordCertificateRequestResult(issuerName, resultPending)
 			return ctrl.Result{}, nil
 		}
 	}
@@ -132,71 +334,698 @@ func (r *CertificateRequestReconciler) Reconcile(ctx context.Context, req ctrl.R
 		return ctrl.Result{}, nil
 	}
 
-	issuerName := types.NamespacedName{
-		Namespace: cr.Namespace,
-		Name:      cr.Spec.IssuerRef.Name,
-	}
-	if cr.Spec.IssuerRef.Kind == "AWSPCAClusterIssuer" {
-		issuerName.Namespace = ""
-	}
-
 	iss, err := util.GetIssuer(ctx, r.Client, issuerName)
 	if err != nil {
 		log.Error(err, "failed to retrieve Issuer resource")
-		_ = r.setStatus(ctx, cr, cmmeta.ConditionFalse, cmapi.CertificateRequestReasonFailed, "issuer could not be found")
+		_ = r.setStatus(ctx, cr, cmmeta.ConditionFalse, cmapi.CertificateRequestReasonFailed, "issuer %s could not be found", issuerName.Name)
+		recordCertificateRequestResult(issuerName, resultFailed)
 		return ctrl.Result{}, err
 	}
 
+	if isStale(iss) {
+		// The issuer reconcile verifies the changed spec shortly
+		delay := r.requeueBackoff(requeueAttempts(cr))
+		log.Info("issuer has not been reconciled since its spec changed", "generation", iss.GetGeneration(), "requeueAfter", delay)
+		recordCertificateRequestResult(issuerName, resultPending)
+		return ctrl.Result{RequeueAfter: delay}, r.setStatus(ctx, cr, cmmeta.ConditionFalse, cmapi.CertificateRequestReasonPending, "issuer %s has not been verified since its spec changed, retrying", issuerName.Name)
+	}
 	if !isReady(iss) {
 		err := fmt.Errorf("issuer %s is not ready", iss.GetName())
-		_ = r.setStatus(ctx, cr, cmmeta.ConditionFalse, cmapi.CertificateRequestReasonFailed, "issuer is not ready")
+		_ = r.setStatus(ctx, cr, cmmeta.ConditionFalse, cmapi.CertificateRequestReasonFailed, "issuer %s is not ready", issuerName.Name)
+		recordCertificateRequestResult(issuerName, resultFailed)
 		return ctrl.Result{}, err
 	}
 
-	provisioner, ok := aws.GetProvisioner(issuerName)
+	provisioner, ok := r.provisioner(ctx, log, issuerName, iss)
 	if !ok {
 		err := fmt.Errorf("provisioner for %s not found", issuerName)
 		log.Error(err, "failed to retrieve provisioner")
-		_ = r.setStatus(ctx, cr, cmmeta.ConditionFalse, cmapi.CertificateRequestReasonFailed, "failed to retrieve provisioner")
+		_ = r.setStatus(ctx, cr, cmmeta.ConditionFalse, cmapi.CertificateRequestReasonFailed, "failed to retrieve provisioner for issuer %s", issuerName.Name)
+		recordCertificateRequestResult(issuerName, resultFailed)
 		return ctrl.Result{}, err
 	}
+	issuerArn := provisionerArn(provisioner, iss)
+	span.SetAttributes(attributeCAArn.String(issuerArn))
+
+	// A recorded certificate ARN means the CertificateRequest was signed by an
+	// earlier reconcile, possibly before the controller restarted, so only the
+	// certificate is retrieved
+	certArn, signed := aws.CertificateArn(cr, r.CertificateArnAnnotation)
+	if signed {
+		log.V(1).Info("CertificateRequest already signed, retrieving certificate", "certificateArn", certArn)
+	} else {
+		if limit := r.issuanceRateLimit(iss, log); limit > 0 && r.IssuanceLimiters != nil && !aws.DryRun(cr) {
+			if delay := r.IssuanceLimiters.delay(issuerName, limit, r.now()); delay > 0 {
+				log.Info("issuance rate limit of issuer exceeded", "limit", limit, "requeueAfter", delay)
+				recordCertificateRequestResult(issuerName, resultPending)
+				return ctrl.Result{RequeueAfter: delay}, r.setStatus(ctx, cr, cmmeta.ConditionFalse, cmapi.CertificateRequestReasonPending, "issuance rate limit of issuer %s exceeded, retrying", issuerName.Name)
+			}
+		}
+
+		if err := r.sign(ctx, provisioner, cr, issuerName, issuerArn, log); err != nil {
+			if aws.IsThrottlingError(err) {
+				return r.requeueThrottled(ctx, log, cr, issuerName, err)
+			}
+			if aws.IsExpiredTokenError(err) {
+				return r.requeueExpiredCredentials(ctx, log, cr, issuerName, err)
+			}
+			if callTimedOut(ctx, err) {
+				return r.requeueTimedOut(ctx, log, cr, issuerName, err)
+			}
+			if goerrors.Is(err, aws.ErrCAChainNotPropagated) {
+				return r.requeueWaitingForCAChain(ctx, log, cr, issuerName, err)
+			}
+			if reason := invalidRequestReason(err); reason != "" {
+				return r.rejectInvalidRequest(ctx, log, cr, issuerName, reason, err)
+			}
+			if ctx.Err() != nil {
+				// Cut off by a shutdown, so the request is retried by the
+				// next leader
+				log.Info("reconcile cancelled while requesting certificate", "error", err.Error())
+				return ctrl.Result{}, err
+			}
+			log.Error(err, "failed to request certificate from PCA")
+			return r.failOrRetry(ctx, log, cr, issuerName, fmt.Sprintf("failed to request certificate from PCA: %s", pcaErrorMessage(err)))
+		}
+		if aws.DryRun(cr) {
+			return ctrl.Result{}, r.setStatus(ctx, cr, cmmeta.ConditionFalse, api.ReasonDryRunValidated, "dry run succeeded, no certificate was issued")
+		}
+		markSigned(req.NamespacedName, r.now())
+
+		// Persist the certificate ARN so that later reconciles only poll PCA
+		// for the certificate instead of requesting it again
+		if err := r.persistSignAnnotations(ctx, cr); err != nil {
+			return ctrl.Result{}, err
+		}
+		certArn, _ = aws.CertificateArn(cr, r.CertificateArnAnnotation)
+		if validity, ok := cr.GetAnnotations()[aws.ValidityClampedAnnotation]; ok {
+			r.Recorder.Eventf(cr, core.EventTypeWarning, string(api.ReasonValidityClamped),
+				"The requested validity exceeds the issuer maxValidity, certificate %s was issued with %s", certArn, validity)
+		}
+	}
+	span.SetAttributes(attributeCertificateArn.String(certArn))
+	log = log.WithValues("certificateArn", certArn)
 
-	pem, ca, err := provisioner.Sign(ctx, cr, log)
+	// After a failover the certificate was issued by another CA of the issuer
+	issuedByArn := issuerArn
+	if issuedBy, ok := cr.GetAnnotations()[aws.CAArnAnnotation]; ok {
+		issuedByArn = issuedBy
+	}
+	var wait time.Duration
+	if timeout := iss.GetSpec().CertificateWaitTimeout; timeout != nil {
+		wait = timeout.Duration
+	}
+	pem, ca, err := r.get(ctx, provisioner, cr, certArn, issuerName, issuedByArn, wait, log)
 	if err != nil {
-		log.Error(err, "failed to request certificate from PCA")
-		return ctrl.Result{}, r.setStatus(ctx, cr, cmmeta.ConditionFalse, cmapi.CertificateRequestReasonFailed, "failed to request certificate from PCA: "+err.Error())
+		var inProgress *acmpcatypes.RequestInProgressException
+		if goerrors.As(err, &inProgress) {
+			now := r.now()
+			if elapsed, ok := r.issuanceTimedOut(cr, now); ok {
+				log.Info("certificate was not issued by PCA within the issuance timeout", "elapsed", elapsed, "issuanceTimeout", r.IssuanceTimeout)
+				forgetSigned(req.NamespacedName)
+				if cr.Status.FailureTime == nil {
+					nowTime := metav1.NewTime(now)
+					cr.Status.FailureTime = &nowTime
+				}
+				r.Recorder.Eventf(cr, core.EventTypeWarning, string(api.ReasonIssuanceTimeout), "Certificate %s was not issued by PCA within %s", certArn, r.IssuanceTimeout)
+				recordCertificateRequestResult(issuerName, resultFailed)
+				return ctrl.Result{}, r.setStatus(ctx, cr, cmmeta.ConditionFalse, cmapi.CertificateRequestReasonFailed, "%s: certificate %s was not issued by PCA within %s", api.ReasonIssuanceTimeout, certArn, r.IssuanceTimeout)
+			}
+			attempts := requeueAttempts(cr)
+			delay := r.pollInterval(attempts)
+			log.V(4).Info("certificate is still being issued by PCA", "attempt", attempts+1, "requeueAfter", delay)
+			metav1.SetMetaDataAnnotation(&cr.ObjectMeta, requeueAttemptsAnnotation, strconv.Itoa(attempts+1))
+			recordCertificateRequestResult(issuerName, resultPending)
+			return ctrl.Result{RequeueAfter: delay}, r.Client.Update(ctx, cr)
+		}
+		if aws.IsThrottlingError(err) {
+			return r.requeueThrottled(ctx, log, cr, issuerName, err)
+		}
+		if aws.IsExpiredTokenError(err) {
+			return r.requeueExpiredCredentials(ctx, log, cr, issuerName, err)
+		}
+		if callTimedOut(ctx, err) {
+			return r.requeueTimedOut(ctx, log, cr, issuerName, err)
+		}
+		// PCA no longer knows the certificate, e.g. because the CA was
+		// recreated, so it is requested again
+		var notFound *acmpcatypes.ResourceNotFoundException
+		if goerrors.As(err, &notFound) {
+			if attempts := reissueAttempts(cr); attempts < maxReissueAttempts {
+				log.Info("certificate not found in PCA, requesting it again", "attempt", attempts+1, "error", err.Error())
+				forgetSigned(req.NamespacedName)
+				for _, key := range []string{aws.CertificateArnKey(r.CertificateArnAnnotation), aws.CertificateArnAnnotation, aws.CAArnAnnotation, aws.ValidityClampedAnnotation, requeueAttemptsAnnotation} {
+					delete(cr.Annotations, key)
+				}
+				metav1.SetMetaDataAnnotation(&cr.ObjectMeta, reissueAttemptsAnnotation, strconv.Itoa(attempts+1))
+				recordCertificateRequestResult(issuerName, resultPending)
+				return ctrl.Result{Requeue: true}, r.Client.Update(ctx, cr)
+			}
+		}
+
+		if ctx.Err() != nil {
+			log.Info("reconcile cancelled while retrieving certificate", "error", err.Error())
+			return ctrl.Result{}, err
+		}
+		log.Error(err, "failed to retrieve certificate from PCA")
+		forgetSigned(req.NamespacedName)
+		return r.failOrRetry(ctx, log, cr, issuerName, fmt.Sprintf("failed to retrieve certificate %s from PCA: %s", certArn, pcaErrorMessage(err)))
+	}
+
+	// The certificate has been retrieved, so reset the backoff and failure
+	// count, record its serial number to correlate the request with PCA
+	// audit reports, and the common name of the CA that issued it
+	annotations := cr.GetAnnotations()
+	_, updated := annotations[requeueAttemptsAnnotation]
+	if _, ok := annotations[failureAttemptsAnnotation]; ok {
+		updated = true
+	}
+	delete(cr.Annotations, requeueAttemptsAnnotation)
+	delete(cr.Annotations, failureAttemptsAnnotation)
+	if serial, err := aws.CertificateSerialNumber(pem); err != nil {
+		log.Error(err, "failed to parse the serial number of the certificate")
+	} else if annotations[serialNumberAnnotation] != serial {
+		metav1.SetMetaDataAnnotation(&cr.ObjectMeta, serialNumberAnnotation, serial)
+		updated = true
+	}
+	if commonName, err := r.issuingCACommonName(issuerName, pem, ca); err != nil {
+		log.Error(err, "failed to parse the common name of the CA certificate")
+	} else if annotations[caCommonNameAnnotation] != commonName {
+		metav1.SetMetaDataAnnotation(&cr.ObjectMeta, caCommonNameAnnotation, commonName)
+		updated = true
+	}
+	if updated {
+		if err := r.Client.Update(ctx, cr); err != nil {
+			return ctrl.Result{}, err
+		}
 	}
+
 	cr.Status.Certificate = pem
 	cr.Status.CA = ca
+	observeIssuanceDuration(issuerName, req.NamespacedName, r.now())
+	recordCertificateRequestResult(issuerName, resultIssued)
 
-	return ctrl.Result{}, r.setStatus(ctx, cr, cmmeta.ConditionTrue, cmapi.CertificateRequestReasonIssued, "certificate issued")
+	if err := r.setStatus(ctx, cr, cmmeta.ConditionTrue, cmapi.CertificateRequestReasonIssued, "certificate %s issued", certArn); err != nil {
+		return ctrl.Result{}, err
+	}
+	// The certificate is issued either way, so a failure is only logged
+	if err := r.recordLastIssued(ctx, iss); err != nil {
+		log.Error(err, "failed to update the last issued time of the issuer")
+	}
+	if err := r.recordIssuanceQuota(ctx, log, issuerName, iss); err != nil {
+		log.Error(err, "failed to update the issuance quota condition of the issuer")
+	}
+	return ctrl.Result{}, nil
+}
+
+// recordLastIssued sets the LastIssuedTime of iss to now, unless it was set
+// less than the lastIssuedTimeResolution ago
+func (r *CertificateRequestReconciler) recordLastIssued(ctx context.Context, iss api.GenericIssuer) error {
+	now := r.now()
+	return retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		status := iss.GetStatus()
+		if status.LastIssuedTime != nil && now.Sub(status.LastIssuedTime.Time) < lastIssuedTimeResolution {
+			return nil
+		}
+		lastIssued := metav1.NewTime(now)
+		status.LastIssuedTime = &lastIssued
+
+		err := r.Client.Status().Update(ctx, iss)
+		if !errors.IsConflict(err) {
+			return err
+		}
+		if err := r.Client.Get(ctx, client.ObjectKeyFromObject(iss), iss); err != nil {
+			return err
+		}
+		return err
+	})
+}
+
+// provisionerArn returns the ARN of the CA of a provisioner, which was resolved
+// when the issuer was reconciled, or the arn of the issuer for provisioners that
+// do not report it
+func provisionerArn(provisioner aws.GenericProvisioner, iss api.GenericIssuer) string {
+	if p, ok := provisioner.(interface{ Arn() string }); ok {
+		return p.Arn()
+	}
+	return iss.GetSpec().Arn
+}
+
+// provisioner returns the provisioner of the issuer, rebuilding it through
+// Provisioners if it is not stored
+func (r *CertificateRequestReconciler) provisioner(ctx context.Context, log logr.Logger, issuerName types.NamespacedName, iss api.GenericIssuer) (aws.GenericProvisioner, bool) {
+	provisioner, ok := aws.GetProvisioner(issuerName)
+	if !ok && r.Provisioners != nil {
+		log.Info("rebuilding provisioner of issuer")
+		var err error
+		if provisioner, err = r.Provisioners.LoadProvisioner(ctx, issuerName, iss); err != nil {
+			log.Error(err, "failed to rebuild provisioner")
+		}
+		ok = err == nil
+	}
+	return provisioner, ok
+}
+
+// invalidRequestReason returns the reason of the InvalidRequest condition of
+// CertificateRequests that Sign rejected with err before calling PCA, or "" if
+// err came from PCA
+func invalidRequestReason(err error) api.CertificateRequestReason {
+	switch {
+	case goerrors.Is(err, aws.ErrDeniedByPolicy):
+		return api.ReasonDeniedByPolicy
+	case goerrors.Is(err, aws.ErrInvalidCSR):
+		return api.ReasonInvalidCSR
+	case goerrors.Is(err, aws.ErrUsageNotAllowed):
+		return api.ReasonUsageNotAllowed
+	default:
+		return ""
+	}
+}
+
+// rejectInvalidRequest marks cr Failed with an InvalidRequest condition of
+// reason. cert-manager treats either as final, so that it backs off and
+// creates a new CertificateRequest, e.g. once the policy of the issuer allows
+// it, instead of waiting for this one.
+func (r *CertificateRequestReconciler) rejectInvalidRequest(ctx context.Context, log logr.Logger, cr *cmapi.CertificateRequest, issuerName types.NamespacedName, reason api.CertificateRequestReason, err error) (ctrl.Result, error) {
+	log.Info("CertificateRequest rejected", "reason", reason, "error", err.Error())
+	if cr.Status.FailureTime == nil {
+		nowTime := metav1.NewTime(r.now())
+		cr.Status.FailureTime = &nowTime
+	}
+	cmutil.SetCertificateRequestCondition(cr, cmapi.CertificateRequestConditionInvalidRequest, cmmeta.ConditionTrue, string(reason), err.Error())
+	recordCertificateRequestResult(issuerName, resultFailed)
+	return ctrl.Result{}, r.setStatus(ctx, cr, cmmeta.ConditionFalse, cmapi.CertificateRequestReasonFailed, "%s: %v", reason, err)
+}
+
+// persistSignAnnotations updates cr with the annotations Sign recorded. On
+// conflicts with concurrent updates of the CertificateRequest the annotations
+// are applied to its latest version, as losing the certificate ARN would make
+// the next reconcile request another certificate once the idempotency token of
+// the first request has expired.
+func (r *CertificateRequestReconciler) persistSignAnnotations(ctx context.Context, cr *cmapi.CertificateRequest) error {
+	annotations := map[string]string{}
+	for _, key := range []string{aws.CertificateArnKey(r.CertificateArnAnnotation), aws.CAArnAnnotation, aws.ValidityClampedAnnotation} {
+		if value, ok := cr.GetAnnotations()[key]; ok {
+			annotations[key] = value
+		}
+	}
+
+	return retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		err := r.Client.Update(ctx, cr)
+		if !errors.IsConflict(err) {
+			return err
+		}
+
+		latest := &cmapi.CertificateRequest{}
+		if err := r.Client.Get(ctx, client.ObjectKeyFromObject(cr), latest); err != nil {
+			return err
+		}
+		for key, value := range annotations {
+			metav1.SetMetaDataAnnotation(&latest.ObjectMeta, key, value)
+		}
+		*cr = *latest
+		return err
+	})
+}
+
+// sign calls Sign of the provisioner in a span
+func (r *CertificateRequestReconciler) sign(ctx context.Context, provisioner aws.GenericProvisioner, cr *cmapi.CertificateRequest, issuerName types.NamespacedName, caArn string, log logr.Logger) (err error) {
+	ctx, span := tracer(r.TracerProvider).Start(ctx, "PCA.Sign", trace.WithAttributes(
+		attributeIssuer.String(issuerName.String()),
+		attributeCAArn.String(caArn),
+	))
+	defer func() { endSpan(span, err) }()
+
+	ctx, cancel := r.withCallTimeout(ctx, 0)
+	defer cancel()
+	if err := provisioner.Sign(ctx, cr, log); err != nil {
+		recordAPIError(issuerName, operationSign, err)
+		return err
+	}
+	if certArn, ok := aws.CertificateArn(cr, r.CertificateArnAnnotation); ok {
+		span.SetAttributes(attributeCertificateArn.String(certArn))
+	}
+	if issuedBy, ok := cr.GetAnnotations()[aws.CAArnAnnotation]; ok {
+		span.SetAttributes(attributeCAArn.String(issuedBy))
+	}
+	return nil
+}
+
+// get calls Get of the provisioner in a span. A certificate that is still
+// being issued is not recorded as an error. wait is how long Get may poll PCA
+// for the certificate in addition to the CallTimeout.
+func (r *CertificateRequestReconciler) get(ctx context.Context, provisioner aws.GenericProvisioner, cr *cmapi.CertificateRequest, certArn string, issuerName types.NamespacedName, caArn string, wait time.Duration, log logr.Logger) ([]byte, []byte, error) {
+	ctx, span := tracer(r.TracerProvider).Start(ctx, "PCA.Get", trace.WithAttributes(
+		attributeIssuer.String(issuerName.String()),
+		attributeCAArn.String(caArn),
+		attributeCertificateArn.String(certArn),
+	))
+
+	ctx, cancel := r.withCallTimeout(ctx, wait)
+	defer cancel()
+	pem, ca, err := provisioner.Get(ctx, cr, certArn, log)
+	recordAPIError(issuerName, operationGet, err)
+	var inProgress *acmpcatypes.RequestInProgressException
+	if goerrors.As(err, &inProgress) {
+		span.AddEvent("certificate is still being issued")
+		endSpan(span, nil)
+	} else {
+		endSpan(span, err)
+	}
+	return pem, ca, err
 }
 
 // SetupWithManager sets up the controller with the Manager.
 func (r *CertificateRequestReconciler) SetupWithManager(mgr ctrl.Manager) error {
 	return ctrl.NewControllerManagedBy(mgr).
-		For(&cmapi.CertificateRequest{}).
+		For(&cmapi.CertificateRequest{}, builder.WithPredicates(ignoreRequeueAnnotationUpdates(r.CertificateArnAnnotation))).
+		WithOptions(r.controllerOptions()).
 		Complete(r)
 }
 
+// controllerOptions returns the options the controller is built with
+func (r *CertificateRequestReconciler) controllerOptions() controller.Options {
+	return controller.Options{MaxConcurrentReconciles: r.MaxConcurrentReconciles}
+}
+
+// requeueAttempts returns the number of times the CertificateRequest has been
+// requeued while PCA was still issuing its certificate
+func requeueAttempts(cr *cmapi.CertificateRequest) int {
+	return countAnnotation(cr, requeueAttemptsAnnotation)
+}
+
+// reissueAttempts returns how often the certificate of cr was requested again
+// because PCA could not find it
+func reissueAttempts(cr *cmapi.CertificateRequest) int {
+	return countAnnotation(cr, reissueAttemptsAnnotation)
+}
+
+// failureAttempts returns the number of consecutive failed attempts to
+// request or retrieve the certificate of cr
+func failureAttempts(cr *cmapi.CertificateRequest) int {
+	return countAnnotation(cr, failureAttemptsAnnotation)
+}
+
+func countAnnotation(cr *cmapi.CertificateRequest, key string) int {
+	attempts, err := strconv.Atoi(cr.GetAnnotations()[key])
+	if err != nil || attempts < 0 {
+		return 0
+	}
+	return attempts
+}
+
+// requeueBackoff returns the delay before polling PCA again: the exponential
+// backoff of attempts, plus up to RequeueJitter at random
+func (r *CertificateRequestReconciler) requeueBackoff(attempts int) time.Duration {
+	return r.exponentialBackoff(attempts) + r.requeueJitter()
+}
+
+// pollInterval returns the delay before polling PCA again for a certificate it
+// is still issuing: the requeue backoff of attempts, bounded by MinPollInterval
+// and MaxPollInterval
+func (r *CertificateRequestReconciler) pollInterval(attempts int) time.Duration {
+	delay := r.requeueBackoff(attempts)
+	if r.MaxPollInterval > 0 && delay > r.MaxPollInterval {
+		delay = r.MaxPollInterval
+	}
+	if delay < r.MinPollInterval {
+		delay = r.MinPollInterval
+	}
+	return delay
+}
+
+// issuanceTimedOut returns how long before now cr was created, and whether
+// that exceeds the IssuanceTimeout
+func (r *CertificateRequestReconciler) issuanceTimedOut(cr *cmapi.CertificateRequest, now time.Time) (time.Duration, bool) {
+	if r.IssuanceTimeout <= 0 || cr.CreationTimestamp.IsZero() {
+		return 0, false
+	}
+	elapsed := now.Sub(cr.CreationTimestamp.Time)
+	return elapsed, elapsed > r.IssuanceTimeout
+}
+
+// requeueJitter returns a uniformly random delay in [0, RequeueJitter)
+func (r *CertificateRequestReconciler) requeueJitter() time.Duration {
+	if r.RequeueJitter <= 0 {
+		return 0
+	}
+	return time.Duration(rand.Int63n(int64(r.RequeueJitter)))
+}
+
+// exponentialBackoff doubles with every attempt from PendingRequeueInterval up
+// to MaxRequeueBackoff
+func (r *CertificateRequestReconciler) exponentialBackoff(attempts int) time.Duration {
+	max := r.MaxRequeueBackoff
+	if max <= 0 {
+		max = defaultMaxRequeueBackoff
+	}
+
+	delay := r.PendingRequeueInterval
+	if delay <= 0 {
+		delay = defaultPendingRequeueInterval
+	}
+	for i := 0; i < attempts && delay < max; i++ {
+		delay *= 2
+	}
+	if delay > max {
+		return max
+	}
+	return delay
+}
+
+// requeueThrottled leaves the CertificateRequest pending after PCA throttled a
+// request. It waits for as long as PCA asked, or otherwise for a jittered
+// backoff.
+func (r *CertificateRequestReconciler) requeueThrottled(ctx context.Context, log logr.Logger, cr *cmapi.CertificateRequest, issuerName types.NamespacedName, err error) (ctrl.Result, error) {
+	attempts := requeueAttempts(cr)
+	delay, ok := aws.RetryAfter(err)
+	if !ok {
+		delay = wait.Jitter(r.requeueBackoff(attempts), 0.5)
+	}
+	log.Info("PCA is throttling requests", "error", err.Error(), "requeueAfter", delay)
+
+	metav1.SetMetaDataAnnotation(&cr.ObjectMeta, requeueAttemptsAnnotation, strconv.Itoa(attempts+1))
+	if err := r.Client.Update(ctx, cr); err != nil {
+		return ctrl.Result{}, err
+	}
+
+	recordCertificateRequestResult(issuerName, resultPending)
+	// The message is kept constant so repeated throttling does not change the
+	// status and trigger a reconcile before the requeue
+	return ctrl.Result{RequeueAfter: delay}, r.setStatus(ctx, cr, cmmeta.ConditionFalse, api.ReasonThrottled, "PCA is throttling requests, retrying")
+}
+
+// failOrRetry marks the CertificateRequest Failed with message once
+// FailureThreshold consecutive attempts failed. Until then the failure is
+// counted and the request is retried with backoff, so that a transient error
+// does not fail it while a later attempt would succeed. The Pending condition
+// only changes with the error, so repeated failures do not update the status
+// and trigger a reconcile before the requeue.
+func (r *CertificateRequestReconciler) failOrRetry(ctx context.Context, log logr.Logger, cr *cmapi.CertificateRequest, issuerName types.NamespacedName, message string) (ctrl.Result, error) {
+	threshold := r.FailureThreshold
+	if threshold < 1 {
+		threshold = 1
+	}
+	attempts := failureAttempts(cr) + 1
+	if attempts >= threshold {
+		recordCertificateRequestResult(issuerName, resultFailed)
+		return ctrl.Result{}, r.setStatus(ctx, cr, cmmeta.ConditionFalse, cmapi.CertificateRequestReasonFailed, "%s", message)
+	}
+
+	delay := r.requeueBackoff(attempts - 1)
+	log.Info("retrying after failure", "attempt", attempts, "failureThreshold", threshold, "requeueAfter", delay)
+	metav1.SetMetaDataAnnotation(&cr.ObjectMeta, failureAttemptsAnnotation, strconv.Itoa(attempts))
+	if err := r.Client.Update(ctx, cr); err != nil {
+		return ctrl.Result{}, err
+	}
+
+	recordCertificateRequestResult(issuerName, resultPending)
+	return ctrl.Result{RequeueAfter: delay}, r.setStatus(ctx, cr, cmmeta.ConditionFalse, cmapi.CertificateRequestReasonPending, "%s, retrying", message)
+}
+
+// withCallTimeout bounds ctx by the CallTimeout extended by wait, unless the
+// CallTimeout is zero
+func (r *CertificateRequestReconciler) withCallTimeout(ctx context.Context, wait time.Duration) (context.Context, context.CancelFunc) {
+	if r.CallTimeout <= 0 {
+		return ctx, func() {}
+	}
+	return context.WithTimeout(ctx, r.CallTimeout+wait)
+}
+
+// callTimedOut reports whether err is the CallTimeout of a provisioner call
+// expiring, rather than ctx of the reconcile being cancelled
+func callTimedOut(ctx context.Context, err error) bool {
+	return goerrors.Is(err, context.DeadlineExceeded) && ctx.Err() == nil
+}
+
+// requeueTimedOut leaves the CertificateRequest pending after a call to PCA
+// exceeded the CallTimeout, and retries it with the requeue backoff. A
+// certificate requested again uses the same idempotency token, so PCA does
+// not issue it twice.
+func (r *CertificateRequestReconciler) requeueTimedOut(ctx context.Context, log logr.Logger, cr *cmapi.CertificateRequest, issuerName types.NamespacedName, err error) (ctrl.Result, error) {
+	attempts := requeueAttempts(cr)
+	delay := r.requeueBackoff(attempts)
+	log.Info("PCA call timed out", "timeout", r.CallTimeout, "error", err.Error(), "requeueAfter", delay)
+
+	metav1.SetMetaDataAnnotation(&cr.ObjectMeta, requeueAttemptsAnnotation, strconv.Itoa(attempts+1))
+	if err := r.Client.Update(ctx, cr); err != nil {
+		return ctrl.Result{}, err
+	}
+
+	recordCertificateRequestResult(issuerName, resultPending)
+	return ctrl.Result{RequeueAfter: delay}, r.setStatus(ctx, cr, cmmeta.ConditionFalse, cmapi.CertificateRequestReasonPending, "PCA call timed out after %s, retrying", r.CallTimeout)
+}
+
+// requeueWaitingForCAChain leaves the CertificateRequest unsigned while PCA
+// does not return the chain of the CA yet, and retries it with the requeue
+// backoff
+func (r *CertificateRequestReconciler) requeueWaitingForCAChain(ctx context.Context, log logr.Logger, cr *cmapi.CertificateRequest, issuerName types.NamespacedName, err error) (ctrl.Result, error) {
+	attempts := requeueAttempts(cr)
+	delay := r.requeueBackoff(attempts)
+	log.Info("waiting for the chain of the certificate authority", "error", err.Error(), "requeueAfter", delay)
+
+	metav1.SetMetaDataAnnotation(&cr.ObjectMeta, requeueAttemptsAnnotation, strconv.Itoa(attempts+1))
+	if err := r.Client.Update(ctx, cr); err != nil {
+		return ctrl.Result{}, err
+	}
+
+	recordCertificateRequestResult(issuerName, resultPending)
+	return ctrl.Result{RequeueAfter: delay}, r.setStatus(ctx, cr, cmmeta.ConditionFalse, api.ReasonWaitingForCAChain, "%v, retrying", err)
+}
+
+// drainContext returns a context that is only cancelled timeout after parent,
+// so that calls in flight when the manager starts shutting down can complete
+func drainContext(parent context.Context, timeout time.Duration) (context.Context, context.CancelFunc) {
+	ctx, cancel := context.WithCancel(context.WithoutCancel(parent))
+	stop := context.AfterFunc(parent, func() {
+		timer := time.NewTimer(timeout)
+		defer timer.Stop()
+		select {
+		case <-timer.C:
+			cancel()
+		case <-ctx.Done():
+		}
+	})
+
+	return ctx, func() {
+		stop()
+		cancel()
+	}
+}
+
+// requeueExpiredCredentials leaves the CertificateRequest pending after PCA
+// rejected the session token of the issuer as expired. The cached provisioner
+// and AWS client of the issuer are invalidated, so that the next attempt loads
+// the credentials again, e.g. from its rotated Secret.
+func (r *CertificateRequestReconciler) requeueExpiredCredentials(ctx context.Context, log logr.Logger, cr *cmapi.CertificateRequest, issuerName types.NamespacedName, err error) (ctrl.Result, error) {
+	aws.InvalidateProvisioner(issuerName)
+
+	attempts := requeueAttempts(cr)
+	delay := r.requeueBackoff(attempts)
+	log.Info("AWS credentials of the issuer have expired", "error", err.Error(), "requeueAfter", delay)
+
+	metav1.SetMetaDataAnnotation(&cr.ObjectMeta, requeueAttemptsAnnotation, strconv.Itoa(attempts+1))
+	if err := r.Client.Update(ctx, cr); err != nil {
+		return ctrl.Result{}, err
+	}
+
+	recordCertificateRequestResult(issuerName, resultPending)
+	return ctrl.Result{RequeueAfter: delay}, r.setStatus(ctx, cr, cmmeta.ConditionFalse, cmapi.CertificateRequestReasonPending, "AWS credentials of issuer %s have expired, retrying with renewed credentials", issuerName.Name)
+}
+
+// ignoreRequeueAnnotationUpdates drops update events that only change the
+// annotations written by the reconciler while waiting for PCA. Without it every
+// requeue would trigger an immediate reconcile and defeat the backoff.
+func ignoreRequeueAnnotationUpdates(certificateArnAnnotation string) predicate.Predicate {
+	return predicate.Funcs{
+		UpdateFunc: func(e event.UpdateEvent) bool {
+			oldCR, ok := e.ObjectOld.(*cmapi.CertificateRequest)
+			if !ok {
+				return true
+			}
+			newCR, ok := e.ObjectNew.(*cmapi.CertificateRequest)
+			if !ok {
+				return true
+			}
+
+			return !equality.Semantic.DeepEqual(oldCR.Spec, newCR.Spec) ||
+				!equality.Semantic.DeepEqual(oldCR.Status, newCR.Status) ||
+				!equality.Semantic.DeepEqual(oldCR.Labels, newCR.Labels) ||
+				!equality.Semantic.DeepEqual(unmanagedAnnotations(oldCR, certificateArnAnnotation), unmanagedAnnotations(newCR, certificateArnAnnotation)) ||
+				!oldCR.DeletionTimestamp.Equal(newCR.DeletionTimestamp)
+		},
+	}
+}
+
+func unmanagedAnnotations(cr *cmapi.CertificateRequest, certificateArnAnnotation string) map[string]string {
+	annotations := map[string]string{}
+	for k, v := range cr.GetAnnotations() {
+		switch k {
+		case aws.CertificateArnAnnotation, aws.CertificateArnKey(certificateArnAnnotation), aws.CAArnAnnotation, aws.ValidityClampedAnnotation, requeueAttemptsAnnotation, reissueAttemptsAnnotation, failureAttemptsAnnotation, serialNumberAnnotation, forceReissueProcessedAnnotation:
+			continue
+		}
+		annotations[k] = v
+	}
+	return annotations
+}
+
+// isReady reports whether the issuer was found Ready for its current
+// generation. A Ready condition observed for an earlier generation predates a
+// spec change that has not been verified yet.
 func isReady(issuer api.GenericIssuer) bool {
 	for _, condition := range issuer.GetStatus().Conditions {
 		if condition.Type == api.ConditionTypeReady && condition.Status == metav1.ConditionTrue {
-			return true
+			return condition.ObservedGeneration == issuer.GetGeneration()
 		}
 	}
 	return false
 }
 
-func (r *CertificateRequestReconciler) setStatus(ctx context.Context, cr *cmapi.CertificateRequest, status cmmeta.ConditionStatus, reason, message string, args ...interface{}) error {
+// isStale reports whether the Ready condition of the issuer was observed for
+// an earlier generation than its current one
+func isStale(issuer api.GenericIssuer) bool {
+	condition := issuerCondition(issuer, api.ConditionTypeReady)
+	return condition != nil && condition.ObservedGeneration != issuer.GetGeneration()
+}
+
+// pcaErrorMessage describes err for the Ready condition of a
+// CertificateRequest. Errors of the PCA API are reduced to their code, message
+// and request ID, so that users can diagnose them without the controller logs.
+// Access key IDs are redacted and the message is truncated.
+func pcaErrorMessage(err error) string {
+	message := err.Error()
+	var apiErr smithy.APIError
+	if goerrors.As(err, &apiErr) {
+		message = apiErr.ErrorCode()
+		if apiMessage := apiErr.ErrorMessage(); apiMessage != "" {
+			message += ": " + apiMessage
+		}
+		var respErr *awshttp.ResponseError
+		if goerrors.As(err, &respErr) && respErr.ServiceRequestID() != "" {
+			message += fmt.Sprintf(" (request ID %s)", respErr.ServiceRequestID())
+		}
+	}
+
+	message = accessKeyIDPattern.ReplaceAllString(message, "<redacted>")
+	if len(message) > maxPCAErrorMessageLength {
+		cut := maxPCAErrorMessageLength
+		for cut > 0 && !utf8.RuneStart(message[cut]) {
+			cut--
+		}
+		message = message[:cut] + "..."
+	}
+	return message
+}
+
+func (r *CertificateRequestReconciler) setStatus(ctx context.Context, cr *cmapi.CertificateRequest, status cmmeta.ConditionStatus, reason api.CertificateRequestReason, message string, args ...interface{}) error {
 	completeMessage := fmt.Sprintf(message, args...)
-	cmutil.SetCertificateRequestCondition(cr, "Ready", status, reason, completeMessage)
+	cmutil.SetCertificateRequestCondition(cr, "Ready", status, string(reason), completeMessage)
 
 	eventType := core.EventTypeNormal
 	if status == cmmeta.ConditionFalse {
 		eventType = core.EventTypeWarning
 	}
-	r.Recorder.Event(cr, eventType, reason, completeMessage)
+	r.Recorder.Event(cr, eventType, string(reason), completeMessage)
 
 	return r.Client.Status().Update(ctx, cr)
 }
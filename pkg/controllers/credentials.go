@@ -0,0 +1,345 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"crypto"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/hex"
+	"fmt"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/credentials"
+	"github.com/aws/aws-sdk-go-v2/credentials/stscreds"
+	"github.com/aws/aws-sdk-go-v2/service/sts"
+	api "github.com/cert-manager/aws-privateca-issuer/pkg/api/v1beta1"
+	awspca "github.com/cert-manager/aws-privateca-issuer/pkg/aws"
+	"github.com/go-logr/logr"
+	core "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const defaultAssumeRoleSessionName = "aws-privateca-issuer"
+
+// Keys of the Secret of an issuer using IAM Roles Anywhere besides the client
+// certificate and key
+const (
+	rolesAnywhereTrustAnchorArnKey = "trustAnchorArn"
+	rolesAnywhereProfileArnKey     = "profileArn"
+	rolesAnywhereRoleArnKey        = "roleArn"
+)
+
+var rolesAnywhereKeys = []string{core.TLSCertKey, core.TLSPrivateKeyKey, rolesAnywhereTrustAnchorArnKey, rolesAnywhereProfileArnKey, rolesAnywhereRoleArnKey}
+
+// CredentialsProvider is a source of the AWS credentials of an issuer. The
+// provider of an issuer is selected from its spec, see credentialsProvider.
+type CredentialsProvider interface {
+	// Config loads the AWS config with the credentials of the provider. opts
+	// hold the settings shared by all providers, such as the region.
+	Config(ctx context.Context, opts ...func(*config.LoadOptions) error) (aws.Config, error)
+	// ClientKey adds the identity of the credentials to key, so that cached
+	// clients are rebuilt when the credentials change
+	ClientKey(ctx context.Context, key *awspca.ClientKey) error
+}
+
+// credentialsProvider selects the CredentialsProvider of an issuer: the
+// secretRef, the client certificate of rolesAnywhere, or else the default
+// credential chain, optionally assuming the role of assumeRole with the
+// resulting credentials
+func (r *GenericIssuerReconciler) credentialsProvider(spec *api.AWSPCAIssuerSpec) CredentialsProvider {
+	var provider CredentialsProvider
+	switch {
+	case spec.SecretRef.Name != "":
+		accessKeyIDKey, secretAccessKeyKey, sessionTokenKey := secretKeys(spec)
+		provider = &secretCredentials{
+			client:             r.Client,
+			name:               types.NamespacedName{Namespace: spec.SecretRef.Namespace, Name: spec.SecretRef.Name},
+			accessKeyIDKey:     accessKeyIDKey,
+			secretAccessKeyKey: secretAccessKeyKey,
+			sessionTokenKey:    sessionTokenKey,
+		}
+	case spec.RolesAnywhere != nil:
+		provider = &rolesAnywhereCredentials{
+			client: r.Client,
+			name:   types.NamespacedName{Namespace: spec.RolesAnywhere.SecretRef.Namespace, Name: spec.RolesAnywhere.SecretRef.Name},
+		}
+	default:
+		provider = &defaultChainCredentials{log: r.Log, profile: spec.Profile}
+	}
+
+	if spec.AssumeRole != nil {
+		provider = &assumeRoleCredentials{base: provider, role: spec.AssumeRole}
+	}
+	return provider
+}
+
+// getSecret returns the credentials Secret name
+func getSecret(ctx context.Context, c client.Client, name types.NamespacedName) (*core.Secret, error) {
+	secret := new(core.Secret)
+	if err := c.Get(ctx, name, secret); err != nil {
+		return nil, fmt.Errorf("failed to retrieve secret: %v", err)
+	}
+	return secret, nil
+}
+
+// secretCredentials are the static access keys of a Secret
+type secretCredentials struct {
+	client             client.Client
+	name               types.NamespacedName
+	accessKeyIDKey     string
+	secretAccessKeyKey string
+	sessionTokenKey    string
+}
+
+func (p *secretCredentials) Config(ctx context.Context, opts ...func(*config.LoadOptions) error) (aws.Config, error) {
+	secret, err := getSecret(ctx, p.client, p.name)
+	if err != nil {
+		return aws.Config{}, err
+	}
+
+	var missing []string
+	for _, key := range []string{p.accessKeyIDKey, p.secretAccessKeyKey} {
+		if len(secret.Data[key]) == 0 {
+			missing = append(missing, key)
+		}
+	}
+	if len(missing) > 0 {
+		return aws.Config{}, fmt.Errorf("%w %s: missing %s", errInvalidCredentialsSecret, p.name, strings.Join(missing, ", "))
+	}
+	accessKey, secretKey := secret.Data[p.accessKeyIDKey], secret.Data[p.secretAccessKeyKey]
+
+	// The session token is only present for temporary credentials
+	sessionToken := secret.Data[p.sessionTokenKey]
+
+	opts = append(opts,
+		config.WithCredentialsProvider(credentials.NewStaticCredentialsProvider(string(accessKey), string(secretKey), string(sessionToken))),
+	)
+	return config.LoadDefaultConfig(ctx, opts...)
+}
+
+// ClientKey fingerprints the resourceVersion of the Secret and the selected
+// keys
+func (p *secretCredentials) ClientKey(ctx context.Context, key *awspca.ClientKey) error {
+	secret, err := getSecret(ctx, p.client, p.name)
+	if err != nil {
+		return err
+	}
+
+	h := sha256.New()
+	fmt.Fprintf(h, "%s\x00%s\x00%s\x00", p.name, secret.ResourceVersion, p.accessKeyIDKey)
+	h.Write(secret.Data[p.accessKeyIDKey])
+	fmt.Fprintf(h, "\x00%s\x00", p.secretAccessKeyKey)
+	h.Write(secret.Data[p.secretAccessKeyKey])
+	fmt.Fprintf(h, "\x00%s\x00", p.sessionTokenKey)
+	h.Write(secret.Data[p.sessionTokenKey])
+	key.CredentialsFingerprint = hex.EncodeToString(h.Sum(nil))
+	return nil
+}
+
+// defaultChainCredentials are resolved by the default credential chain of the
+// SDK, which covers environment variables, IRSA (AWS_WEB_IDENTITY_TOKEN_FILE
+// and AWS_ROLE_ARN), shared config files, EKS Pod Identity
+// (AWS_CONTAINER_CREDENTIALS_FULL_URI and
+// AWS_CONTAINER_AUTHORIZATION_TOKEN_FILE) and instance metadata, in that order
+type defaultChainCredentials struct {
+	log     logr.Logger
+	profile string
+}
+
+func (p *defaultChainCredentials) Config(ctx context.Context, opts ...func(*config.LoadOptions) error) (aws.Config, error) {
+	if p.profile != "" {
+		opts = append(opts, config.WithSharedConfigProfile(p.profile))
+	}
+
+	cfg, err := config.LoadDefaultConfig(ctx, opts...)
+	if err != nil {
+		return aws.Config{}, err
+	}
+
+	if cfg.Credentials == nil {
+		return aws.Config{}, errNoCredentials
+	}
+	creds, err := cfg.Credentials.Retrieve(ctx)
+	if err != nil {
+		return aws.Config{}, fmt.Errorf("%w: %v", errNoCredentials, err)
+	}
+	// The source tells apart e.g. IRSA (WebIdentityCredentials) and EKS Pod
+	// Identity (CredentialsEndpointProvider)
+	p.log.V(1).Info("Resolved credentials from the default credential chain", "source", creds.Source)
+
+	return cfg, nil
+}
+
+// ClientKey only records the profile, the SDK refreshes the credentials of the
+// default chain itself
+func (p *defaultChainCredentials) ClientKey(_ context.Context, key *awspca.ClientKey) error {
+	key.Profile = p.profile
+	return nil
+}
+
+// rolesAnywhereCredentials are the credentials of IAM Roles Anywhere, which the
+// credentials cache refreshes with the client certificate of a Secret before
+// they expire
+type rolesAnywhereCredentials struct {
+	client client.Client
+	name   types.NamespacedName
+}
+
+func (p *rolesAnywhereCredentials) Config(ctx context.Context, opts ...func(*config.LoadOptions) error) (aws.Config, error) {
+	secret, err := getSecret(ctx, p.client, p.name)
+	if err != nil {
+		return aws.Config{}, err
+	}
+
+	cfg, err := config.LoadDefaultConfig(ctx, opts...)
+	if err != nil {
+		return aws.Config{}, err
+	}
+
+	provider, err := rolesAnywhereProvider(cfg.HTTPClient, secret)
+	if err != nil {
+		return aws.Config{}, err
+	}
+	cfg.Credentials = aws.NewCredentialsCache(provider)
+	return cfg, nil
+}
+
+// ClientKey fingerprints the resourceVersion and the keys of the Secret, so
+// that a renewed client certificate gets a new client
+func (p *rolesAnywhereCredentials) ClientKey(ctx context.Context, key *awspca.ClientKey) error {
+	secret, err := getSecret(ctx, p.client, p.name)
+	if err != nil {
+		return err
+	}
+
+	h := sha256.New()
+	fmt.Fprintf(h, "%s/%s\x00%s", secret.Namespace, secret.Name, secret.ResourceVersion)
+	for _, k := range rolesAnywhereKeys {
+		fmt.Fprintf(h, "\x00%s\x00", k)
+		h.Write(secret.Data[k])
+	}
+	key.CredentialsFingerprint = hex.EncodeToString(h.Sum(nil))
+	return nil
+}
+
+// assumeRoleCredentials assume a role with the credentials of base
+type assumeRoleCredentials struct {
+	base CredentialsProvider
+	role *api.AWSAssumeRole
+}
+
+func (p *assumeRoleCredentials) Config(ctx context.Context, opts ...func(*config.LoadOptions) error) (aws.Config, error) {
+	cfg, err := p.base.Config(ctx, opts...)
+	if err != nil {
+		return aws.Config{}, err
+	}
+	return assumeRoleConfig(cfg, p.role), nil
+}
+
+func (p *assumeRoleCredentials) ClientKey(ctx context.Context, key *awspca.ClientKey) error {
+	if err := p.base.ClientKey(ctx, key); err != nil {
+		return err
+	}
+	key.RoleARN = p.role.RoleARN
+	key.ExternalID = p.role.ExternalID
+	key.SessionName = p.role.SessionName
+	return nil
+}
+
+// assumeRoleConfig wraps the base credentials of cfg with an STS AssumeRole
+// provider. The credentials cache refreshes the assumed credentials before
+// they expire.
+func assumeRoleConfig(cfg aws.Config, role *api.AWSAssumeRole) aws.Config {
+	provider := stscreds.NewAssumeRoleProvider(sts.NewFromConfig(cfg), role.RoleARN, assumeRoleOptions(role))
+	cfg.Credentials = aws.NewCredentialsCache(provider)
+	return cfg
+}
+
+func assumeRoleOptions(role *api.AWSAssumeRole) func(*stscreds.AssumeRoleOptions) {
+	return func(o *stscreds.AssumeRoleOptions) {
+		o.RoleARN = role.RoleARN
+		o.RoleSessionName = defaultAssumeRoleSessionName
+		if role.SessionName != "" {
+			o.RoleSessionName = role.SessionName
+		}
+		if role.ExternalID != "" {
+			o.ExternalID = aws.String(role.ExternalID)
+		}
+	}
+}
+
+// secretKeys returns the keys of the access key ID, secret access key and
+// session token in the Secret referenced by the issuer
+func secretKeys(spec *api.AWSPCAIssuerSpec) (string, string, string) {
+	accessKeyIDKey := "AWS_ACCESS_KEY_ID"
+	if spec.SecretRef.AccessKeyIDSelector.Key != "" {
+		accessKeyIDKey = spec.SecretRef.AccessKeyIDSelector.Key
+	}
+	secretAccessKeyKey := "AWS_SECRET_ACCESS_KEY"
+	if spec.SecretRef.SecretAccessKeySelector.Key != "" {
+		secretAccessKeyKey = spec.SecretRef.SecretAccessKeySelector.Key
+	}
+	sessionTokenKey := "AWS_SESSION_TOKEN"
+	if spec.SecretRef.SessionTokenSelector.Key != "" {
+		sessionTokenKey = spec.SecretRef.SessionTokenSelector.Key
+	}
+	return accessKeyIDKey, secretAccessKeyKey, sessionTokenKey
+}
+
+// rolesAnywhereProvider returns a Roles Anywhere credential provider for the
+// client certificate, key and ARNs in secret
+func rolesAnywhereProvider(httpClient aws.HTTPClient, secret *core.Secret) (*awspca.RolesAnywhereProvider, error) {
+	secretNamespaceName := client.ObjectKeyFromObject(secret)
+	var missing []string
+	for _, key := range rolesAnywhereKeys {
+		if len(secret.Data[key]) == 0 {
+			missing = append(missing, key)
+		}
+	}
+	if len(missing) > 0 {
+		return nil, fmt.Errorf("%w %s: missing %s", errInvalidCredentialsSecret, secretNamespaceName, strings.Join(missing, ", "))
+	}
+
+	// X509KeyPair also checks that the key belongs to the first certificate
+	pair, err := tls.X509KeyPair(secret.Data[core.TLSCertKey], secret.Data[core.TLSPrivateKeyKey])
+	if err != nil {
+		return nil, fmt.Errorf("%w %s: %v", errInvalidCredentialsSecret, secretNamespaceName, err)
+	}
+	certificates := make([]*x509.Certificate, len(pair.Certificate))
+	for i, der := range pair.Certificate {
+		if certificates[i], err = x509.ParseCertificate(der); err != nil {
+			return nil, fmt.Errorf("%w %s: %v", errInvalidCredentialsSecret, secretNamespaceName, err)
+		}
+	}
+	key, ok := pair.PrivateKey.(crypto.Signer)
+	if !ok {
+		return nil, fmt.Errorf("%w %s: unsupported private key", errInvalidCredentialsSecret, secretNamespaceName)
+	}
+
+	provider, err := awspca.NewRolesAnywhereProvider(httpClient, certificates, key,
+		string(secret.Data[rolesAnywhereTrustAnchorArnKey]), string(secret.Data[rolesAnywhereProfileArnKey]), string(secret.Data[rolesAnywhereRoleArnKey]))
+	if err != nil {
+		return nil, fmt.Errorf("%w %s: %w", errInvalidCredentialsSecret, secretNamespaceName, err)
+	}
+	return provider, nil
+}
@@ -0,0 +1,188 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/credentials/stscreds"
+	logrtesting "github.com/go-logr/logr/testing"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+
+	issuerapi "github.com/cert-manager/aws-privateca-issuer/pkg/api/v1beta1"
+	awspca "github.com/cert-manager/aws-privateca-issuer/pkg/aws"
+)
+
+func TestCredentialsProvider(t *testing.T) {
+	secretRef := issuerapi.AWSCredentialsSecretReference{
+		SecretReference: v1.SecretReference{Name: "issuer1-credentials", Namespace: "ns1"},
+	}
+	role := &issuerapi.AWSAssumeRole{RoleARN: "arn:aws:iam::111122223333:role/pca-signer"}
+
+	type testCase struct {
+		spec     *issuerapi.AWSPCAIssuerSpec
+		expected CredentialsProvider
+	}
+	tests := map[string]testCase{
+		"secret": {
+			spec: &issuerapi.AWSPCAIssuerSpec{SecretRef: secretRef},
+			expected: &secretCredentials{
+				name:               types.NamespacedName{Namespace: "ns1", Name: "issuer1-credentials"},
+				accessKeyIDKey:     "AWS_ACCESS_KEY_ID",
+				secretAccessKeyKey: "AWS_SECRET_ACCESS_KEY",
+				sessionTokenKey:    "AWS_SESSION_TOKEN",
+			},
+		},
+		"secret-with-selectors": {
+			spec: &issuerapi.AWSPCAIssuerSpec{SecretRef: issuerapi.AWSCredentialsSecretReference{
+				SecretReference:         secretRef.SecretReference,
+				AccessKeyIDSelector:     v1.SecretKeySelector{Key: "id"},
+				SecretAccessKeySelector: v1.SecretKeySelector{Key: "secret"},
+				SessionTokenSelector:    v1.SecretKeySelector{Key: "token"},
+			}},
+			expected: &secretCredentials{
+				name:               types.NamespacedName{Namespace: "ns1", Name: "issuer1-credentials"},
+				accessKeyIDKey:     "id",
+				secretAccessKeyKey: "secret",
+				sessionTokenKey:    "token",
+			},
+		},
+		"roles-anywhere": {
+			spec: &issuerapi.AWSPCAIssuerSpec{RolesAnywhere: &issuerapi.AWSRolesAnywhere{
+				SecretRef: v1.SecretReference{Name: "issuer1-client-certificate", Namespace: "ns1"},
+			}},
+			expected: &rolesAnywhereCredentials{
+				name: types.NamespacedName{Namespace: "ns1", Name: "issuer1-client-certificate"},
+			},
+		},
+		"default-chain": {
+			spec:     &issuerapi.AWSPCAIssuerSpec{},
+			expected: &defaultChainCredentials{},
+		},
+		"default-chain-with-profile": {
+			spec:     &issuerapi.AWSPCAIssuerSpec{Profile: "dev"},
+			expected: &defaultChainCredentials{profile: "dev"},
+		},
+		"assume-role-with-secret": {
+			spec: &issuerapi.AWSPCAIssuerSpec{SecretRef: secretRef, AssumeRole: role},
+			expected: &assumeRoleCredentials{
+				base: &secretCredentials{
+					name:               types.NamespacedName{Namespace: "ns1", Name: "issuer1-credentials"},
+					accessKeyIDKey:     "AWS_ACCESS_KEY_ID",
+					secretAccessKeyKey: "AWS_SECRET_ACCESS_KEY",
+					sessionTokenKey:    "AWS_SESSION_TOKEN",
+				},
+				role: role,
+			},
+		},
+		"assume-role-with-default-chain": {
+			spec: &issuerapi.AWSPCAIssuerSpec{AssumeRole: role},
+			expected: &assumeRoleCredentials{
+				base: &defaultChainCredentials{},
+				role: role,
+			},
+		},
+	}
+
+	// The client and logger are not compared
+	controller := GenericIssuerReconciler{}
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			assert.Equal(t, tc.expected, controller.credentialsProvider(tc.spec))
+		})
+	}
+}
+
+// fakeCredentials is a CredentialsProvider with static credentials
+type fakeCredentials struct {
+	err error
+}
+
+func (p *fakeCredentials) Config(ctx context.Context, opts ...func(*config.LoadOptions) error) (aws.Config, error) {
+	if p.err != nil {
+		return aws.Config{}, p.err
+	}
+	return aws.Config{Region: "us-east-1", Credentials: aws.AnonymousCredentials{}}, nil
+}
+
+func (p *fakeCredentials) ClientKey(_ context.Context, key *awspca.ClientKey) error {
+	if p.err != nil {
+		return p.err
+	}
+	key.CredentialsFingerprint = "fake-fingerprint"
+	return nil
+}
+
+func TestAssumeRoleCredentials(t *testing.T) {
+	role := &issuerapi.AWSAssumeRole{
+		RoleARN:     "arn:aws:iam::111122223333:role/pca-signer",
+		ExternalID:  "fake-external-id",
+		SessionName: "fake-session",
+	}
+	provider := &assumeRoleCredentials{base: &fakeCredentials{}, role: role}
+
+	cfg, err := provider.Config(context.TODO())
+	require.NoError(t, err)
+	assert.Equal(t, "us-east-1", cfg.Region)
+	require.IsType(t, &aws.CredentialsCache{}, cfg.Credentials)
+	assert.True(t, cfg.Credentials.(*aws.CredentialsCache).IsCredentialsProvider(&stscreds.AssumeRoleProvider{}), "expected an STS AssumeRole provider")
+
+	key := awspca.ClientKey{Region: "us-east-1"}
+	require.NoError(t, provider.ClientKey(context.TODO(), &key))
+	assert.Equal(t, awspca.ClientKey{
+		Region:                 "us-east-1",
+		CredentialsFingerprint: "fake-fingerprint",
+		RoleARN:                role.RoleARN,
+		ExternalID:             role.ExternalID,
+		SessionName:            role.SessionName,
+	}, key)
+
+	// Failures of the base credentials are returned
+	baseErr := errors.New("no base credentials")
+	provider = &assumeRoleCredentials{base: &fakeCredentials{err: baseErr}, role: role}
+	_, err = provider.Config(context.TODO())
+	assert.ErrorIs(t, err, baseErr)
+	assert.ErrorIs(t, provider.ClientKey(context.TODO(), &awspca.ClientKey{}), baseErr)
+}
+
+func TestDefaultChainCredentials(t *testing.T) {
+	isolateDefaultCredentialChain(t)
+
+	provider := &defaultChainCredentials{log: logrtesting.NewTestLogger(t), profile: "dev"}
+	key := awspca.ClientKey{}
+	require.NoError(t, provider.ClientKey(context.TODO(), &key))
+	assert.Equal(t, awspca.ClientKey{Profile: "dev"}, key)
+
+	provider.profile = ""
+	_, err := provider.Config(context.TODO(), config.WithRegion("us-east-1"))
+	assert.ErrorIs(t, err, errNoCredentials)
+
+	t.Setenv("AWS_ACCESS_KEY_ID", "ZXhhbXBsZQ==")
+	t.Setenv("AWS_SECRET_ACCESS_KEY", "ZXhhbXBsZQ==")
+	cfg, err := provider.Config(context.TODO(), config.WithRegion("us-east-1"))
+	require.NoError(t, err)
+	creds, err := cfg.Credentials.Retrieve(context.TODO())
+	require.NoError(t, err)
+	assert.Equal(t, "ZXhhbXBsZQ==", creds.AccessKeyID)
+}
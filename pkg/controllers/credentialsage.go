@@ -0,0 +1,76 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"time"
+
+	api "github.com/cert-manager/aws-privateca-issuer/pkg/api/v1beta1"
+	"github.com/go-logr/logr"
+	core "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+// credentialsRotatedAnnotation can be set on a credentials Secret to the time
+// its credentials were last rotated, in RFC 3339 format. It takes precedence
+// over the metadata of the Secret in the credentials Secret age metric.
+const credentialsRotatedAnnotation = "aws-privateca-issuer/credentials-rotated"
+
+// recordCredentialsAge records when the credentials Secret of an issuer was
+// last rotated for the credentials Secret age metric. Issuers without a
+// credentials Secret are not reported. Failing to get the Secret is left for
+// loading the credentials to report.
+func (r *GenericIssuerReconciler) recordCredentialsAge(ctx context.Context, log logr.Logger, issuer types.NamespacedName, spec *api.AWSPCAIssuerSpec) {
+	var name types.NamespacedName
+	switch {
+	case spec.SecretRef.Name != "":
+		name = types.NamespacedName{Namespace: spec.SecretRef.Namespace, Name: spec.SecretRef.Name}
+	case spec.RolesAnywhere != nil:
+		name = types.NamespacedName{Namespace: spec.RolesAnywhere.SecretRef.Namespace, Name: spec.RolesAnywhere.SecretRef.Name}
+	default:
+		credentialsSecretAge.forget(issuer)
+		return
+	}
+
+	secret := new(core.Secret)
+	if err := r.Client.Get(ctx, name, secret); err != nil {
+		return
+	}
+	credentialsSecretAge.record(issuer, secretRotationTime(log, secret))
+}
+
+// secretRotationTime returns the time of the rotation annotation of secret if
+// it is valid. Otherwise it is the last time a manager of the Secret updated
+// it, or its creation if the Secret has no managed fields.
+func secretRotationTime(log logr.Logger, secret *core.Secret) time.Time {
+	if value, ok := secret.GetAnnotations()[credentialsRotatedAnnotation]; ok {
+		rotated, err := time.Parse(time.RFC3339, value)
+		if err == nil {
+			return rotated
+		}
+		log.Info("ignoring invalid rotation time of credentials secret", "annotation", credentialsRotatedAnnotation, "value", value)
+	}
+
+	rotated := secret.CreationTimestamp.Time
+	for _, field := range secret.ManagedFields {
+		if field.Time != nil && field.Time.After(rotated) {
+			rotated = field.Time.Time
+		}
+	}
+	return rotated
+}
@@ -0,0 +1,147 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/go-logr/logr"
+	logrtesting "github.com/go-logr/logr/testing"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	issuerapi "github.com/cert-manager/aws-privateca-issuer/pkg/api/v1beta1"
+	awspca "github.com/cert-manager/aws-privateca-issuer/pkg/aws"
+)
+
+func TestSecretRotationTime(t *testing.T) {
+	created := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	updated := created.Add(24 * time.Hour)
+
+	type testCase struct {
+		annotations   map[string]string
+		managedFields []metav1.ManagedFieldsEntry
+		expectedTime  time.Time
+	}
+	tests := map[string]testCase{
+		"created": {
+			expectedTime: created,
+		},
+		"managed-fields": {
+			managedFields: []metav1.ManagedFieldsEntry{
+				{Manager: "kubectl", Operation: metav1.ManagedFieldsOperationUpdate, Time: &metav1.Time{Time: updated}},
+				{Manager: "external-secrets", Operation: metav1.ManagedFieldsOperationApply, Time: &metav1.Time{Time: created.Add(time.Hour)}},
+				{Manager: "unknown"},
+			},
+			expectedTime: updated,
+		},
+		"annotation": {
+			annotations: map[string]string{credentialsRotatedAnnotation: "2024-03-01T12:00:00Z"},
+			managedFields: []metav1.ManagedFieldsEntry{
+				{Manager: "kubectl", Operation: metav1.ManagedFieldsOperationUpdate, Time: &metav1.Time{Time: updated}},
+			},
+			expectedTime: time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC),
+		},
+		"invalid-annotation": {
+			annotations:  map[string]string{credentialsRotatedAnnotation: "yesterday"},
+			expectedTime: created,
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			secret := &v1.Secret{
+				ObjectMeta: metav1.ObjectMeta{
+					Annotations:       tc.annotations,
+					CreationTimestamp: metav1.Time{Time: created},
+					ManagedFields:     tc.managedFields,
+				},
+			}
+			assert.True(t, tc.expectedTime.Equal(secretRotationTime(logr.Discard(), secret)), "expected %v, got %v", tc.expectedTime, secretRotationTime(logr.Discard(), secret))
+		})
+	}
+}
+
+func TestIssuerReconcileCredentialsSecretAge(t *testing.T) {
+	scheme := runtime.NewScheme()
+	require.NoError(t, issuerapi.AddToScheme(scheme))
+	require.NoError(t, v1.AddToScheme(scheme))
+
+	awspca.ClearProvisioners()
+	rotated := time.Now().Add(-time.Hour).Truncate(time.Second)
+	iss := &issuerapi.AWSPCAIssuer{
+		ObjectMeta: metav1.ObjectMeta{Name: "credentials-age", Namespace: "ns1"},
+		Spec: issuerapi.AWSPCAIssuerSpec{
+			SecretRef: issuerapi.AWSCredentialsSecretReference{
+				SecretReference: v1.SecretReference{Name: "issuer1-credentials", Namespace: "ns1"},
+			},
+			Region: "us-east-1",
+			Arn:    "arn:aws:acm-pca:us-east-1:account:certificate-authority/12345678-1234-1234-1234-123456789012",
+		},
+	}
+	secret := &v1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        "issuer1-credentials",
+			Namespace:   "ns1",
+			Annotations: map[string]string{credentialsRotatedAnnotation: rotated.Format(time.RFC3339)},
+		},
+		Data: map[string][]byte{
+			"AWS_ACCESS_KEY_ID":     []byte("ZXhhbXBsZQ=="),
+			"AWS_SECRET_ACCESS_KEY": []byte("ZXhhbXBsZQ=="),
+		},
+	}
+	fakeClient := fake.NewClientBuilder().
+		WithScheme(scheme).
+		WithObjects(iss, secret).
+		WithStatusSubresource(iss).
+		Build()
+	controller := GenericIssuerReconciler{
+		Client:   fakeClient,
+		Log:      logrtesting.NewTestLogger(t),
+		Scheme:   scheme,
+		Recorder: record.NewFakeRecorder(10),
+	}
+
+	ctx := context.TODO()
+	name := types.NamespacedName{Namespace: "ns1", Name: "credentials-age"}
+	t.Cleanup(func() { credentialsSecretAge.forget(name) })
+	labels := map[string]string{"issuer_namespace": "ns1", "issuer_name": "credentials-age"}
+	_, err := controller.Reconcile(ctx, reconcile.Request{NamespacedName: name}, iss)
+	require.NoError(t, err)
+
+	families, err := metrics.Registry.Gather()
+	require.NoError(t, err)
+	if age := findMetric(families, "awspca_credentials_secret_age_seconds", labels); assert.NotNil(t, age, "credentials secret age gauge not found") {
+		assert.InDelta(t, time.Hour.Seconds(), age.GetGauge().GetValue(), 60)
+	}
+
+	// Issuers that stop using a credentials Secret are no longer reported
+	controller.recordCredentialsAge(ctx, controller.Log, name, &issuerapi.AWSPCAIssuerSpec{})
+	families, err = metrics.Registry.Gather()
+	require.NoError(t, err)
+	assert.Nil(t, findMetric(families, "awspca_credentials_secret_age_seconds", labels))
+}
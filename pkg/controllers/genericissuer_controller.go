@@ -18,35 +18,68 @@ package controllers
 
 import (
 	"context"
+	"crypto/tls"
+	"crypto/x509"
 	"errors"
 	"fmt"
+	"net/http"
 	"os"
+	"path"
+	"sort"
+	"strings"
+	"time"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/aws/retry"
+	awshttp "github.com/aws/aws-sdk-go-v2/aws/transport/http"
 	"github.com/aws/aws-sdk-go-v2/config"
-	"github.com/aws/aws-sdk-go-v2/credentials"
+	"github.com/aws/aws-sdk-go-v2/service/acmpca"
 	"github.com/aws/aws-sdk-go-v2/service/sts"
 	api "github.com/cert-manager/aws-privateca-issuer/pkg/api/v1beta1"
 	awspca "github.com/cert-manager/aws-privateca-issuer/pkg/aws"
 	"github.com/cert-manager/aws-privateca-issuer/pkg/util"
 	"github.com/go-logr/logr"
 	core "k8s.io/api/core/v1"
+	apimeta "k8s.io/apimachinery/pkg/api/meta"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/client-go/tools/record"
+	"k8s.io/utils/clock"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
 )
 
 var (
-	errNoSecretAccessKey = errors.New("no AWS Secret Access Key Found")
-	errNoAccessKeyID     = errors.New("no AWS Access Key ID Found")
-	errNoArnInSpec       = errors.New("no Arn found in Issuer Spec")
-	errNoRegionInSpec    = errors.New("no Region found in Issuer Spec")
+	errInvalidCredentialsSecret = errors.New("invalid credentials secret")
+	errNoArnInSpec              = errors.New("no Arn found in Issuer Spec")
+	errNoRegionInSpec           = errors.New("no Region found in Issuer Spec, and no default region is configured")
+	errNoCredentials            = errors.New("no AWS credentials could be resolved from the default credential chain")
+	errInvalidTemplateArn       = errors.New("templateArn in Issuer Spec is not a valid PCA template ARN")
+	errInvalidTags              = errors.New("tags in Issuer Spec are invalid")
+	errInvalidAPIPassthrough    = errors.New("apiPassthrough in Issuer Spec is invalid")
+	errInvalidPathLength        = errors.New("pathLength in Issuer Spec must be between 0 and 3")
+	errNoAuditReportBucket      = errors.New("auditReport in Issuer Spec has no s3BucketName")
+	errInvalidEndpoint          = errors.New("endpoint in Issuer Spec must be an https URL")
+	errNoFIPSEndpoint           = errors.New("PCA has no FIPS endpoint in the region of the Issuer")
+	errInvalidArn               = errors.New("arn in Issuer Spec is not a valid PCA certificate authority ARN")
+	errInvalidArnFrom           = errors.New("failed to resolve arnFrom in Issuer Spec")
+	errTagsOnSharedCA           = errors.New("tags cannot be applied to a CA shared from another account")
+	errCANotAllowed             = errors.New("the CA is not allowed for ClusterIssuers")
+	errArnPartitionMismatch     = errors.New("partition of the arn in Issuer Spec does not match its region")
+	errArnRegionMismatch        = errors.New("region of the arn in Issuer Spec does not match the region of the Issuer")
+	errInvalidCABundle          = errors.New("the CA bundle contains no PEM encoded certificates")
+	errConflictingCredentials   = errors.New("credentials in Issuer Spec are conflicting")
 )
 
-var awsDefaultRegion = os.Getenv("AWS_REGION")
+// secretRefField indexes issuers by the namespace/name of their credentials
+// Secret, so that the issuers of an updated Secret can be listed cheaply
+const secretRefField = ".spec.secretRef"
+
+// caNotActiveRequeueInterval is how often the CA of an issuer is described
+// again while it is not ACTIVE
+const caNotActiveRequeueInterval = time.Minute
 
 // GenericIssuerReconciler reconciles both AWSPCAIssuer and AWSPCAClusterIssuer objects
 type GenericIssuerReconciler struct {
@@ -61,6 +94,97 @@ type GenericIssuerReconciler struct {
 	// but can be skipped during unit tests to avoid having a dependency on a
 	// live STS service.
 	GetCallerIdentity bool
+
+	// CheckCAStatus should be set to true to only mark issuers Ready once
+	// acmpca.DescribeCertificateAuthority reports that their CA is ACTIVE.
+	// Like GetCallerIdentity, it can be skipped during unit tests.
+	CheckCAStatus bool
+
+	// CAHealth is consulted before marking an issuer Ready, so that issuers
+	// whose CA was found unhealthy by the periodic check stay not Ready.
+	// It is nil when the check is disabled.
+	CAHealth *CAHealthChecker
+
+	// IssuanceLimiters are the token buckets of the
+	// CertificateRequestReconciler, from which those of deleted issuers are
+	// dropped. It is nil when issuance is not rate limited.
+	IssuanceLimiters *IssuanceLimiters
+
+	// IssuanceCounters are the issuance counters of the
+	// CertificateRequestReconciler, from which those of deleted issuers are
+	// dropped. It is nil when issuances are not counted.
+	IssuanceCounters *IssuanceCounters
+
+	// CACommonNames are the CA common names cached by the
+	// CertificateRequestReconciler, from which those of deleted issuers are
+	// dropped. It may be nil.
+	CACommonNames *CACommonNames
+
+	// CAStateWatcher sends the issuers whose CA changed state to the issuer
+	// controllers. It is nil when no event queue is configured.
+	CAStateWatcher *CAStateWatcher
+
+	// CertificateArnAnnotation is the annotation the provisioners record the
+	// certificate ARN in. The aws.CertificateArnAnnotation is used if empty.
+	CertificateArnAnnotation string
+
+	// RetryMaxAttempts and RetryMaxBackoff configure how the AWS SDK retries
+	// failed PCA and STS calls within a reconcile. The SDK defaults are used
+	// if they are zero.
+	RetryMaxAttempts int
+	RetryMaxBackoff  time.Duration
+
+	// MaxIdleConns, IdleConnTimeout and MaxConnsPerHost configure the
+	// connection pool of the HTTP client of the AWS SDK. MaxIdleConns bounds
+	// the idle connections both in total and per host, as the controller only
+	// connects to the PCA and STS endpoints of few regions. The SDK defaults
+	// are used if they are zero.
+	MaxIdleConns    int
+	IdleConnTimeout time.Duration
+	MaxConnsPerHost int
+
+	// DefaultRegion is the region of issuers that do not specify one. The
+	// AWS_REGION environment variable is used if it is empty.
+	DefaultRegion string
+
+	// UserAgentSuffix is a product name added with the controller version to
+	// the User-Agent of PCA calls, in addition to the name of the issuer
+	UserAgentSuffix string
+
+	// AllowedCAArns are glob patterns, as of path.Match, of the CA ARNs that
+	// AWSPCAClusterIssuers may reference. All CAs are allowed if it is empty.
+	AllowedCAArns []string
+
+	// CABundle holds PEM encoded certificates that are trusted in addition to
+	// the system roots when connecting to AWS, e.g. the CA of a TLS
+	// intercepting proxy. Proxies are configured with HTTPS_PROXY.
+	CABundle []byte
+
+	// ClusterResourceNamespace is the namespace of the credentials Secrets of
+	// AWSPCAClusterIssuers whose secretRef does not name one, like the
+	// cluster resource namespace of cert-manager
+	ClusterResourceNamespace string
+
+	// DefaultTags are applied to the CA of every issuer in addition to the
+	// tags of its spec, which take precedence for the same key
+	DefaultTags map[string]string
+
+	// Finalize adds a finalizer to issuers, so that the provisioner and other
+	// state cached for them is cleaned up once they are deleted, and their
+	// tags removed from the CA if requested by the untag on delete annotation
+	Finalize bool
+
+	// Clock is used to schedule audit reports. The system clock is used if
+	// it is nil.
+	Clock clock.Clock
+}
+
+// now returns the current time of the Clock, or of the system if none is set
+func (r *GenericIssuerReconciler) now() time.Time {
+	if r.Clock != nil {
+		return r.Clock.Now()
+	}
+	return time.Now()
 }
 
 // Reconcile is part of the main kubernetes reconciliation loop which aims to
@@ -70,19 +194,48 @@ type GenericIssuerReconciler struct {
 // - https://pkg.go.dev/sigs.k8s.io/controller-runtime@v0.7.0/pkg/reconcile
 func (r *GenericIssuerReconciler) Reconcile(ctx context.Context, req ctrl.Request, issuer api.GenericIssuer) (ctrl.Result, error) {
 	log := r.Log.WithValues("genericissuer", req.NamespacedName)
-	spec := issuer.GetSpec()
-	err := validateIssuer(spec)
+	if deleted, err := r.reconcileFinalizer(ctx, log, req.NamespacedName, issuer); deleted || err != nil {
+		return ctrl.Result{}, err
+	}
+
+	spec, err := r.resolveArn(ctx, r.withDefaultTags(r.defaultSecretNamespaces(issuer)))
 	if err != nil {
+		log.Error(err, "failed to resolve the CA ARN")
+		_ = r.setStatus(ctx, issuer, metav1.ConditionFalse, api.ReasonInvalidArnFrom, "%v", err)
+		return ctrl.Result{}, err
+	}
+	err = validateIssuer(spec, r.region(spec))
+	if errors.Is(err, errArnRegionMismatch) {
 		log.Error(err, "failed to validate issuer")
-		_ = r.setStatus(ctx, issuer, metav1.ConditionFalse, "Validation", "Failed to validate resource: %v", err)
+		_ = r.setStatus(ctx, issuer, metav1.ConditionFalse, api.ReasonRegionMismatch, "%v", err)
 		return ctrl.Result{}, err
 	}
+	if err != nil {
+		log.Error(err, "failed to validate issuer")
+		_ = r.setStatus(ctx, issuer, metav1.ConditionFalse, api.ReasonValidation, "Failed to validate resource: %v", err)
+		return ctrl.Result{}, err
+	}
+	if _, ok := issuer.(*api.AWSPCAClusterIssuer); ok {
+		if err := validateAllowedCA(spec, r.AllowedCAArns); err != nil {
+			log.Error(err, "failed to validate issuer")
+			_ = r.setStatus(ctx, issuer, metav1.ConditionFalse, api.ReasonCANotAllowed, "%v", err)
+			return ctrl.Result{}, err
+		}
+	}
 
-	var cfg, cfgErr = r.getConfig(ctx, spec)
+	r.recordCredentialsAge(ctx, log, req.NamespacedName, spec)
+	cfg, pcaClient, cfgErr := r.loadClient(ctx, req.NamespacedName, spec)
 
 	if cfgErr != nil {
 		log.Error(cfgErr, "Error loading config")
-		_ = r.setStatus(ctx, issuer, metav1.ConditionFalse, "Error", cfgErr.Error())
+		reason := api.ReasonError
+		switch {
+		case errors.Is(cfgErr, errNoCredentials):
+			reason = api.ReasonNoCredentials
+		case errors.Is(cfgErr, errInvalidCredentialsSecret):
+			reason = api.ReasonInvalidCredentialsSecret
+		}
+		_ = r.setStatus(ctx, issuer, metav1.ConditionFalse, reason, cfgErr.Error())
 		return ctrl.Result{}, cfgErr
 	}
 
@@ -90,19 +243,143 @@ func (r *GenericIssuerReconciler) Reconcile(ctx context.Context, req ctrl.Reques
 		id, err := sts.NewFromConfig(cfg).GetCallerIdentity(ctx, &sts.GetCallerIdentityInput{})
 		if err != nil {
 			log.Error(err, "failed to sts.GetCallerIdentity")
+			if awspca.IsExpiredTokenError(err) {
+				setConnected(log, issuer, false, api.ReasonExpiredCredentials, fmt.Sprintf("AWS session token has expired: %v", err))
+				_ = r.setStatus(ctx, issuer, metav1.ConditionFalse, api.ReasonExpiredCredentials, "AWS session token has expired: %v", err)
+			} else if setConnected(log, issuer, false, api.ReasonAPIError, fmt.Sprintf("failed to call sts.GetCallerIdentity: %v", err)) {
+				_ = r.Client.Status().Update(ctx, issuer)
+			}
 			return ctrl.Result{}, err
 		}
+		setConnected(log, issuer, true, api.ReasonConnected, "")
 		log.Info("sts.GetCallerIdentity", "arn", id.Arn, "account", id.Account, "user_id", id.UserId)
+
+		if err := validateCAOwner(spec, aws.ToString(id.Account)); err != nil {
+			log.Error(err, "failed to validate issuer")
+			_ = r.setStatus(ctx, issuer, metav1.ConditionFalse, api.ReasonTagsOnSharedCA, "%v", err)
+			return ctrl.Result{}, err
+		}
 	}
 
 	log.Info("Calling StoreProvisioner")
-	awspca.StoreProvisioner(req.NamespacedName, awspca.NewProvisioner(cfg, spec.Arn))
+	provisioner := r.newProvisioner(pcaClient, req.NamespacedName, spec)
+	awspca.StoreProvisioner(req.NamespacedName, provisioner)
+
+	if r.CheckCAStatus {
+		if ready, result, err := r.verifyCA(ctx, issuer, provisioner); !ready {
+			return result, err
+		}
+	}
+
+	if r.CAHealth != nil {
+		if health, unhealthy := r.CAHealth.Unhealthy(req.NamespacedName); unhealthy {
+			return ctrl.Result{}, r.setStatus(ctx, issuer, metav1.ConditionFalse, health.reason, health.message)
+		}
+	}
+
+	// An audit report does not affect issuance, so failures only delay the
+	// next attempt
+	requeueAfter := r.reconcileAuditReport(ctx, issuer, provisioner)
+	if r.CheckCAStatus {
+		if refresh := r.reconcileCAAlgorithms(ctx, issuer, provisioner); requeueAfter == 0 || refresh < requeueAfter {
+			requeueAfter = refresh
+		}
+	}
+
+	return ctrl.Result{RequeueAfter: requeueAfter}, r.setStatus(ctx, issuer, metav1.ConditionTrue, api.ReasonVerified, "Issuer verified")
+}
+
+// newProvisioner returns the provisioner of an issuer with spec, which has
+// been validated
+func (r *GenericIssuerReconciler) newProvisioner(pcaClient *acmpca.Client, issuer types.NamespacedName, spec *api.AWSPCAIssuerSpec) *awspca.PCAProvisioner {
+	// The extensions were validated with the issuer
+	apiPassthrough, _ := awspca.APIPassthrough(spec.APIPassthrough)
+	// The cached client may be shared with other issuers, so the User-Agent of
+	// the issuer is added to a copy of it
+	pcaClient = acmpca.New(pcaClient.Options(), awspca.WithUserAgent(r.UserAgentSuffix, issuer))
+
+	return awspca.NewProvisionerWithClient(pcaClient, spec.Arn,
+		awspca.WithTemplateArn(spec.TemplateArn),
+		awspca.WithValidity(spec.DefaultValidity, spec.MaxValidity),
+		awspca.WithValidityPeriodType(spec.ValidityPeriodType),
+		awspca.WithTags(spec.Tags),
+		awspca.WithFullChain(spec.FullChain),
+		awspca.WithChainMode(spec.ChainMode),
+		awspca.WithCertificateChain(spec.CertificateChain),
+		awspca.WithCACertificateCacheTTL(spec.CACertificateCacheTTL),
+		awspca.WithWaitForCAChain(spec.WaitForCAChain),
+		awspca.WithCertificateWaitTimeout(spec.CertificateWaitTimeout),
+		awspca.WithFailoverArns(spec.ArnFailover),
+		awspca.WithRegionalArns(spec.RegionalArns),
+		awspca.WithCertificateArnAnnotation(r.CertificateArnAnnotation),
+		awspca.WithPolicy(spec.AllowedDomains, spec.AllowedNamespaces),
+		awspca.WithAPIPassthrough(apiPassthrough),
+		awspca.WithPathLength(spec.PathLength),
+	)
+}
+
+// LoadProvisioner builds and stores the provisioner of a Ready issuer that has
+// none, e.g. because it was invalidated after its credentials expired. The AWS
+// credentials are loaded again from the issuer's Secret or the default chain.
+func (r *GenericIssuerReconciler) LoadProvisioner(ctx context.Context, name types.NamespacedName, issuer api.GenericIssuer) (awspca.GenericProvisioner, error) {
+	spec, err := r.resolveArn(ctx, r.withDefaultTags(r.defaultSecretNamespaces(issuer)))
+	if err != nil {
+		return nil, err
+	}
+	_, pcaClient, err := r.loadClient(ctx, name, spec)
+	if err != nil {
+		return nil, err
+	}
+
+	provisioner := r.newProvisioner(pcaClient, name, spec)
+	awspca.StoreProvisioner(name, provisioner)
+	return provisioner, nil
+}
+
+// verifyCA checks that the CA of the issuer is ACTIVE. Otherwise the issuer
+// is marked not Ready and ready is false.
+func (r *GenericIssuerReconciler) verifyCA(ctx context.Context, issuer api.GenericIssuer, provisioner awspca.GenericProvisioner) (ready bool, result ctrl.Result, err error) {
+	log := r.Log.WithValues("genericissuer", issuer.GetName())
+	health, healthy, err := describeCAHealth(ctx, provisioner)
+	if err != nil {
+		log.Error(err, "failed to describe certificate authority")
+		if setConnected(log, issuer, false, api.ReasonAPIError, fmt.Sprintf("failed to describe certificate authority: %v", err)) {
+			_ = r.Client.Status().Update(ctx, issuer)
+		}
+		return false, ctrl.Result{}, err
+	}
+	setConnected(log, issuer, health.reason != api.ReasonCAUnreachable, health.reason, health.message)
+	if !healthy {
+		log.Info("certificate authority cannot issue certificates", "reason", health.reason, "message", health.message)
+		return false, ctrl.Result{RequeueAfter: caNotActiveRequeueInterval}, r.setStatus(ctx, issuer, metav1.ConditionFalse, health.reason, health.message)
+	}
+	return true, ctrl.Result{}, nil
+}
 
-	return ctrl.Result{}, r.setStatus(ctx, issuer, metav1.ConditionTrue, "Verified", "Issuer verified")
+// setConnected sets the Connected condition of an issuer to whether its last
+// AWS API call succeeded, with reason and message describing the failure
+// otherwise. It reports whether the condition changed; the issuer status is
+// updated by the caller.
+func setConnected(log logr.Logger, issuer api.GenericIssuer, connected bool, reason api.ConditionReason, message string) bool {
+	status := metav1.ConditionFalse
+	if connected {
+		status, reason, message = metav1.ConditionTrue, api.ReasonConnected, "Last AWS API call succeeded"
+	}
+	if c := issuerCondition(issuer, api.ConditionTypeConnected); c != nil && c.Status == status && c.Reason == string(reason) && c.Message == message {
+		return false
+	}
+	util.SetIssuerCondition(log, issuer, api.ConditionTypeConnected, status, reason, message)
+	return true
 }
 
-func (r *GenericIssuerReconciler) setStatus(ctx context.Context, issuer api.GenericIssuer, status metav1.ConditionStatus, reason, message string, args ...interface{}) error {
+func (r *GenericIssuerReconciler) setStatus(ctx context.Context, issuer api.GenericIssuer, status metav1.ConditionStatus, reason api.ConditionReason, message string, args ...interface{}) error {
 	log := r.Log.WithValues("genericissuer", issuer.GetName())
+	return setIssuerStatus(ctx, r.Client, r.Recorder, log, issuer, status, reason, message, args...)
+}
+
+// setIssuerStatus sets the Ready condition of an issuer, records a matching
+// event and updates the issuer status in the cluster
+func setIssuerStatus(ctx context.Context, c client.Client, recorder record.EventRecorder, log logr.Logger, issuer api.GenericIssuer, status metav1.ConditionStatus, reason api.ConditionReason, message string, args ...interface{}) error {
 	completeMessage := fmt.Sprintf(message, args...)
 	util.SetIssuerCondition(log, issuer, api.ConditionTypeReady, status, reason, completeMessage)
 
@@ -110,66 +387,387 @@ func (r *GenericIssuerReconciler) setStatus(ctx context.Context, issuer api.Gene
 	if status == metav1.ConditionFalse {
 		eventType = core.EventTypeWarning
 	}
-	r.Recorder.Event(issuer, eventType, reason, completeMessage)
+	recorder.Event(issuer, eventType, string(reason), completeMessage)
 
-	return r.Client.Status().Update(ctx, issuer)
+	return c.Status().Update(ctx, issuer)
 }
 
-func validateIssuer(spec *api.AWSPCAIssuerSpec) error {
+// validateCAOwner checks that an issuer whose CA is owned by another account
+// than that of its credentials, e.g. because it is shared through AWS RAM, has
+// no tags, which only the owner of the CA can apply. Signing certificates with
+// a shared CA needs no further configuration.
+func validateCAOwner(spec *api.AWSPCAIssuerSpec, account string) error {
+	_, _, owner, _, err := awspca.ParseCertificateAuthorityARN(spec.Arn)
+	if err != nil || account == "" || owner == account || len(spec.Tags) == 0 {
+		return nil
+	}
+	return fmt.Errorf("%w: the CA is owned by account %s, but the credentials are of account %s", errTagsOnSharedCA, owner, account)
+}
+
+// validateAllowedCA checks that every CA of spec, including its failover and
+// regional CAs, matches one of the allowed ARN patterns, if any
+func validateAllowedCA(spec *api.AWSPCAIssuerSpec, allowed []string) error {
+	if len(allowed) == 0 {
+		return nil
+	}
+	var regional []string
+	for _, arn := range spec.RegionalArns {
+		regional = append(regional, arn)
+	}
+	sort.Strings(regional)
+	arns := append(append([]string{spec.Arn}, spec.ArnFailover...), regional...)
+CAs:
+	for _, arn := range arns {
+		for _, pattern := range allowed {
+			if ok, _ := path.Match(pattern, arn); ok {
+				continue CAs
+			}
+		}
+		return fmt.Errorf("%w: %s does not match any of the allowed CA ARNs", errCANotAllowed, arn)
+	}
+	return nil
+}
+
+// resolveArn returns spec with the Arn read from the ConfigMap or Secret key
+// referenced by its ArnFrom, or spec itself if it has none. spec is not
+// modified, so that the resolved ARN is not written back to the issuer.
+func (r *GenericIssuerReconciler) resolveArn(ctx context.Context, spec *api.AWSPCAIssuerSpec) (*api.AWSPCAIssuerSpec, error) {
+	if spec.ArnFrom == nil {
+		return spec, nil
+	}
+	if spec.Arn != "" {
+		return nil, fmt.Errorf("%w: arn and arnFrom are mutually exclusive", errInvalidArnFrom)
+	}
+
+	var data map[string]string
+	var ref *api.AWSKeySelector
+	switch from := spec.ArnFrom; {
+	case (from.ConfigMapKeyRef == nil) == (from.SecretKeyRef == nil):
+		return nil, fmt.Errorf("%w: exactly one of configMapKeyRef and secretKeyRef must be set", errInvalidArnFrom)
+	case from.ConfigMapKeyRef != nil:
+		ref = from.ConfigMapKeyRef
+		configMap := new(core.ConfigMap)
+		if err := r.Client.Get(ctx, types.NamespacedName{Namespace: ref.Namespace, Name: ref.Name}, configMap); err != nil {
+			return nil, fmt.Errorf("%w: failed to retrieve configmap: %v", errInvalidArnFrom, err)
+		}
+		data = configMap.Data
+	default:
+		ref = from.SecretKeyRef
+		secret := new(core.Secret)
+		if err := r.Client.Get(ctx, types.NamespacedName{Namespace: ref.Namespace, Name: ref.Name}, secret); err != nil {
+			return nil, fmt.Errorf("%w: failed to retrieve secret: %v", errInvalidArnFrom, err)
+		}
+		data = make(map[string]string, len(secret.Data))
+		for k, v := range secret.Data {
+			data[k] = string(v)
+		}
+	}
+
+	arn := strings.TrimSpace(data[ref.Key])
+	if arn == "" {
+		return nil, fmt.Errorf("%w: %s/%s has no key %s", errInvalidArnFrom, ref.Namespace, ref.Name, ref.Key)
+	}
+	resolved := spec.DeepCopy()
+	resolved.Arn = arn
+	return resolved, nil
+}
+
+// validateIssuer validates spec for an issuer in region, which is resolved from
+// the spec or the controller default
+func validateIssuer(spec *api.AWSPCAIssuerSpec, region string) error {
 	switch {
 	case spec.Arn == "":
 		return fmt.Errorf(errNoArnInSpec.Error())
-	case spec.Region == "" && awsDefaultRegion == "":
+	case region == "":
 		return fmt.Errorf(errNoRegionInSpec.Error())
+	case spec.TemplateArn != "" && !awspca.ValidTemplateArn(spec.TemplateArn):
+		return errInvalidTemplateArn
+	case spec.Endpoint != "" && !awspca.ValidEndpoint(spec.Endpoint):
+		return errInvalidEndpoint
+	case spec.PathLength != nil && !awspca.ValidPathLength(*spec.PathLength):
+		return errInvalidPathLength
+	case spec.AuditReport != nil && spec.AuditReport.S3BucketName == "":
+		return errNoAuditReportBucket
+	case spec.SecretRef.Name != "" && spec.RolesAnywhere != nil:
+		return fmt.Errorf("%w: rolesAnywhere cannot be combined with the access key of secretRef", errConflictingCredentials)
+	case spec.Profile != "" && spec.SecretRef.Name != "":
+		return fmt.Errorf("%w: profile cannot be combined with the access key of secretRef", errConflictingCredentials)
+	case spec.Profile != "" && spec.RolesAnywhere != nil:
+		return fmt.Errorf("%w: profile cannot be combined with rolesAnywhere", errConflictingCredentials)
+	}
+	caPartition, caRegion, _, _, err := awspca.ParseCertificateAuthorityARN(spec.Arn)
+	if err != nil {
+		return fmt.Errorf("%w: %v", errInvalidArn, err)
+	}
+	if partition := awspca.RegionPartition(region); caPartition != partition {
+		return fmt.Errorf("%w: %s is in partition %s, but region %s is in %s", errArnPartitionMismatch, spec.Arn, caPartition, region, partition)
+	}
+	// Calling PCA in another region than the CA's fails with confusing
+	// authorization or not found errors
+	if caRegion != region {
+		return fmt.Errorf("%w: %s is in region %s, but the Issuer uses region %s", errArnRegionMismatch, spec.Arn, caRegion, region)
+	}
+	for _, failoverArn := range spec.ArnFailover {
+		partition, _, _, _, err := awspca.ParseCertificateAuthorityARN(failoverArn)
+		if err != nil {
+			return fmt.Errorf("%w: arnFailover: %v", errInvalidArn, err)
+		}
+		if partition != caPartition {
+			return fmt.Errorf("%w: %s is in partition %s, but %s is in %s", errArnPartitionMismatch, failoverArn, partition, spec.Arn, caPartition)
+		}
+	}
+	for regionalRegion, regionalArn := range spec.RegionalArns {
+		partition, region, _, _, err := awspca.ParseCertificateAuthorityARN(regionalArn)
+		if err != nil {
+			return fmt.Errorf("%w: regionalArns: %v", errInvalidArn, err)
+		}
+		if partition != caPartition {
+			return fmt.Errorf("%w: %s is in partition %s, but %s is in %s", errArnPartitionMismatch, regionalArn, partition, spec.Arn, caPartition)
+		}
+		if region != regionalRegion {
+			return fmt.Errorf("%w: %s is in region %s, but is the regional CA of %s", errArnRegionMismatch, regionalArn, region, regionalRegion)
+		}
+	}
+	// A custom endpoint takes precedence over the FIPS endpoint of the region
+	if spec.Endpoint == "" && useFIPSEndpoint(spec) {
+		if !awspca.FIPSEndpointAvailable(region) {
+			return fmt.Errorf("%w: %s", errNoFIPSEndpoint, region)
+		}
+	}
+	if err := awspca.ValidateTags(spec.Tags); err != nil {
+		return fmt.Errorf("%w: %v", errInvalidTags, err)
+	}
+	if _, err := awspca.APIPassthrough(spec.APIPassthrough); err != nil {
+		return fmt.Errorf("%w: %v", errInvalidAPIPassthrough, err)
 	}
 	return nil
 }
 
+// getConfig loads the AWS config of an issuer with the credentials of its
+// CredentialsProvider
 func (r *GenericIssuerReconciler) getConfig(ctx context.Context, spec *api.AWSPCAIssuerSpec) (aws.Config, error) {
+	opts, err := r.loadOptions()
+	if err != nil {
+		return aws.Config{}, err
+	}
+	if region := r.region(spec); region != "" {
+		opts = append(opts, config.WithRegion(region))
+	}
+	return r.credentialsProvider(spec).Config(ctx, opts...)
+}
+
+// clientKey identifies the AWS configuration of an issuer. Its
+// CredentialsProvider adds the identity of the credentials, e.g. the
+// fingerprint of the referenced Secret, so that clients are rebuilt when the
+// credentials are updated.
+func (r *GenericIssuerReconciler) clientKey(ctx context.Context, spec *api.AWSPCAIssuerSpec) (awspca.ClientKey, error) {
+	key := awspca.ClientKey{Region: r.region(spec), Endpoint: spec.Endpoint, UseFIPSEndpoint: spec.UseFIPSEndpoint}
+	if err := r.credentialsProvider(spec).ClientKey(ctx, &key); err != nil {
+		return awspca.ClientKey{}, err
+	}
+	return key, nil
+}
+
+// loadClient returns the AWS config and PCA client for the issuer, reusing
+// those cached from an earlier reconcile unless the configuration changed
+func (r *GenericIssuerReconciler) loadClient(ctx context.Context, issuer types.NamespacedName, spec *api.AWSPCAIssuerSpec) (aws.Config, *acmpca.Client, error) {
+	key, err := r.clientKey(ctx, spec)
+	if err != nil {
+		return aws.Config{}, nil, err
+	}
+
+	return awspca.LoadClient(issuer, key, func() (aws.Config, error) {
+		return r.getConfig(ctx, spec)
+	})
+}
+
+// defaultSecretNamespaces returns the spec of issuer with the namespace of its
+// credentials Secrets set to the ClusterResourceNamespace if it is an
+// AWSPCAClusterIssuer that does not name one. The spec of issuer is not
+// modified, so that the namespace is not written back to the issuer.
+func (r *GenericIssuerReconciler) defaultSecretNamespaces(issuer api.GenericIssuer) *api.AWSPCAIssuerSpec {
+	spec := issuer.GetSpec()
+	if _, ok := issuer.(*api.AWSPCAClusterIssuer); !ok {
+		return spec
+	}
+	secretRef := spec.SecretRef.Name != "" && spec.SecretRef.Namespace == ""
+	rolesAnywhere := spec.RolesAnywhere != nil && spec.RolesAnywhere.SecretRef.Name != "" && spec.RolesAnywhere.SecretRef.Namespace == ""
+	if !secretRef && !rolesAnywhere {
+		return spec
+	}
+
+	spec = spec.DeepCopy()
+	if secretRef {
+		spec.SecretRef.Namespace = r.ClusterResourceNamespace
+	}
+	if rolesAnywhere {
+		spec.RolesAnywhere.SecretRef.Namespace = r.ClusterResourceNamespace
+	}
+	return spec
+}
+
+// withDefaultTags returns spec with the DefaultTags merged into its tags. The
+// tags of spec win on conflicts, and spec is not modified.
+func (r *GenericIssuerReconciler) withDefaultTags(spec *api.AWSPCAIssuerSpec) *api.AWSPCAIssuerSpec {
+	if len(r.DefaultTags) == 0 {
+		return spec
+	}
+
+	spec = spec.DeepCopy()
+	tags := make(map[string]string, len(r.DefaultTags)+len(spec.Tags))
+	for key, value := range r.DefaultTags {
+		tags[key] = value
+	}
+	for key, value := range spec.Tags {
+		tags[key] = value
+	}
+	spec.Tags = tags
+	return spec
+}
+
+// indexSecretRef returns the namespace/name of the credentials Secret of an
+// issuer for the secretRefField index
+func (r *GenericIssuerReconciler) indexSecretRef(obj client.Object) []string {
+	issuer, ok := obj.(api.GenericIssuer)
+	if !ok {
+		return nil
+	}
+
+	var names []string
+	spec := r.defaultSecretNamespaces(issuer)
 	if spec.SecretRef.Name != "" {
-		secretNamespaceName := types.NamespacedName{
-			Namespace: spec.SecretRef.Namespace,
-			Name:      spec.SecretRef.Name,
-		}
+		names = append(names, types.NamespacedName{Namespace: spec.SecretRef.Namespace, Name: spec.SecretRef.Name}.String())
+	}
+	if spec.RolesAnywhere != nil && spec.RolesAnywhere.SecretRef.Name != "" {
+		ref := spec.RolesAnywhere.SecretRef
+		names = append(names, types.NamespacedName{Namespace: ref.Namespace, Name: ref.Name}.String())
+	}
+	return names
+}
 
-		secret := new(core.Secret)
-		if err := r.Client.Get(ctx, secretNamespaceName, secret); err != nil {
-			return aws.Config{}, fmt.Errorf("failed to retrieve secret: %v", err)
+// requestsForSecret lists the issuers in list that reference secret for their
+// credentials and returns reconcile requests for them. Their provisioners are
+// dropped from the cache, so that no certificates are signed with the previous
+// credentials until the issuers have been reconciled.
+func requestsForSecret(ctx context.Context, c client.Client, log logr.Logger, secret client.Object, list client.ObjectList) []reconcile.Request {
+	secretName := client.ObjectKeyFromObject(secret).String()
+	if err := c.List(ctx, list, client.MatchingFields{secretRefField: secretName}); err != nil {
+		log.Error(err, "failed to list issuers of credentials secret", "secret", secretName)
+		return nil
+	}
+
+	var requests []reconcile.Request
+	_ = apimeta.EachListItem(list, func(obj runtime.Object) error {
+		name := client.ObjectKeyFromObject(obj.(client.Object))
+		awspca.DeleteProvisioner(name)
+		requests = append(requests, reconcile.Request{NamespacedName: name})
+		return nil
+	})
+	return requests
+}
+
+// useFIPSEndpoint reports whether the issuer uses the FIPS endpoint of PCA,
+// either through its spec or the AWS_USE_FIPS_ENDPOINT environment variable
+func useFIPSEndpoint(spec *api.AWSPCAIssuerSpec) bool {
+	return spec.UseFIPSEndpoint || strings.EqualFold(os.Getenv("AWS_USE_FIPS_ENDPOINT"), "true")
+}
+
+// region returns the region of the issuer, falling back to the DefaultRegion
+// and then the AWS_REGION environment variable if its spec has none
+func (r *GenericIssuerReconciler) region(spec *api.AWSPCAIssuerSpec) string {
+	switch {
+	case spec.Region != "":
+		return spec.Region
+	case r.DefaultRegion != "":
+		return r.DefaultRegion
+	default:
+		return os.Getenv("AWS_REGION")
+	}
+}
+
+// ControllerConfig loads the AWS config of the controller itself, e.g. for the
+// event queue of the CAStateWatcher, from the default credential chain. Like
+// the configs of issuers, it uses the DefaultRegion and trusts the CABundle.
+func (r *GenericIssuerReconciler) ControllerConfig(ctx context.Context) (aws.Config, error) {
+	opts, err := r.loadOptions()
+	if err != nil {
+		return aws.Config{}, err
+	}
+	if region := r.region(&api.AWSPCAIssuerSpec{}); region != "" {
+		opts = append(opts, config.WithRegion(region))
+	}
+	return config.LoadDefaultConfig(ctx, opts...)
+}
+
+// loadOptions returns the options every AWS config of an issuer is loaded with
+func (r *GenericIssuerReconciler) loadOptions() ([]func(*config.LoadOptions) error, error) {
+	opts := r.retryOptions()
+	if len(r.CABundle) > 0 || r.MaxIdleConns > 0 || r.IdleConnTimeout > 0 || r.MaxConnsPerHost > 0 {
+		httpClient, err := r.httpClient()
+		if err != nil {
+			return nil, err
 		}
+		opts = append(opts, config.WithHTTPClient(httpClient))
+	}
+	return opts, nil
+}
 
-		key := "AWS_ACCESS_KEY_ID"
-		if spec.SecretRef.AccessKeyIDSelector.Key != "" {
-			key = spec.SecretRef.AccessKeyIDSelector.Key
+// httpClient returns an HTTP client for the AWS SDK with the connection pool
+// settings of the controller that trusts the CABundle in addition to the
+// system roots. The config option of the SDK for custom CA bundles replaces
+// the system roots instead. Like the default client of the SDK, it sends
+// requests through the proxy configured with HTTPS_PROXY.
+func (r *GenericIssuerReconciler) httpClient() (*awshttp.BuildableClient, error) {
+	var roots *x509.CertPool
+	if len(r.CABundle) > 0 {
+		var err error
+		roots, err = x509.SystemCertPool()
+		if err != nil {
+			roots = x509.NewCertPool()
 		}
-		accessKey, ok := secret.Data[key]
-		if !ok {
-			return aws.Config{}, errNoAccessKeyID
+		if !roots.AppendCertsFromPEM(r.CABundle) {
+			return nil, errInvalidCABundle
 		}
+	}
 
-		key = "AWS_SECRET_ACCESS_KEY"
-		if spec.SecretRef.SecretAccessKeySelector.Key != "" {
-			key = spec.SecretRef.SecretAccessKeySelector.Key
+	return awshttp.NewBuildableClient().WithTransportOptions(func(tr *http.Transport) {
+		if roots != nil {
+			if tr.TLSClientConfig == nil {
+				tr.TLSClientConfig = &tls.Config{MinVersion: tls.VersionTLS12}
+			}
+			tr.TLSClientConfig.RootCAs = roots
 		}
-		secretKey, ok := secret.Data[key]
-		if !ok {
-			return aws.Config{}, errNoSecretAccessKey
+		if r.MaxIdleConns > 0 {
+			tr.MaxIdleConns = r.MaxIdleConns
+			tr.MaxIdleConnsPerHost = r.MaxIdleConns
 		}
-
-		if spec.Region != "" {
-			return config.LoadDefaultConfig(ctx,
-				config.WithCredentialsProvider(credentials.NewStaticCredentialsProvider(string(accessKey), string(secretKey), "")),
-				config.WithRegion(spec.Region),
-			)
+		if r.IdleConnTimeout > 0 {
+			tr.IdleConnTimeout = r.IdleConnTimeout
+		}
+		if r.MaxConnsPerHost > 0 {
+			tr.MaxConnsPerHost = r.MaxConnsPerHost
 		}
+	}), nil
+}
 
-		return config.LoadDefaultConfig(ctx,
-			config.WithCredentialsProvider(credentials.NewStaticCredentialsProvider(string(accessKey), string(secretKey), "")),
-		)
-	} else if spec.Region != "" {
-		return config.LoadDefaultConfig(ctx,
-			config.WithRegion(spec.Region),
-		)
+// retryOptions configures the retryer of the AWS SDK from RetryMaxAttempts and
+// RetryMaxBackoff. The SDK ignores the max attempts of the config once a custom
+// retryer is set, so the retryer is given both.
+func (r *GenericIssuerReconciler) retryOptions() []func(*config.LoadOptions) error {
+	if r.RetryMaxBackoff <= 0 {
+		if r.RetryMaxAttempts > 0 {
+			return []func(*config.LoadOptions) error{config.WithRetryMaxAttempts(r.RetryMaxAttempts)}
+		}
+		return nil
 	}
 
-	return config.LoadDefaultConfig(ctx)
+	maxAttempts, maxBackoff := r.RetryMaxAttempts, r.RetryMaxBackoff
+	return []func(*config.LoadOptions) error{config.WithRetryer(func() aws.Retryer {
+		return retry.NewStandard(func(o *retry.StandardOptions) {
+			if maxAttempts > 0 {
+				o.MaxAttempts = maxAttempts
+			}
+			o.MaxBackoff = maxBackoff
+		})
+	})}
 }
@@ -16,9 +16,36 @@ package controllers
 
 import (
 	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"errors"
 	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"io"
+	"log"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
 	"testing"
+	"time"
 
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/aws/retry"
+	awshttp "github.com/aws/aws-sdk-go-v2/aws/transport/http"
+	"github.com/aws/aws-sdk-go-v2/credentials"
+	"github.com/aws/aws-sdk-go-v2/credentials/endpointcreds"
+	"github.com/aws/aws-sdk-go-v2/credentials/stscreds"
+	"github.com/aws/aws-sdk-go-v2/service/acmpca"
 	logrtesting "github.com/go-logr/logr/testing"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -27,12 +54,14 @@ import (
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/client-go/tools/record"
+	"k8s.io/utils/ptr"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/client/fake"
 	"sigs.k8s.io/controller-runtime/pkg/reconcile"
 
 	issuerapi "github.com/cert-manager/aws-privateca-issuer/pkg/api/v1beta1"
+	awspca "github.com/cert-manager/aws-privateca-issuer/pkg/aws"
 )
 
 const (
@@ -297,7 +326,7 @@ func TestIssuerReconcile(t *testing.T) {
 				},
 			},
 			expectedReadyConditionStatus: metav1.ConditionFalse,
-			expectedError:                errNoAccessKeyID,
+			expectedError:                fmt.Errorf("%w ns1/issuer1-credentials: missing AWS_ACCESS_KEY_ID", errInvalidCredentialsSecret),
 			expectedResult:               ctrl.Result{},
 		},
 		"failure-issuer-no-access-key-specified-with-selector": {
@@ -342,7 +371,7 @@ func TestIssuerReconcile(t *testing.T) {
 				},
 			},
 			expectedReadyConditionStatus: metav1.ConditionFalse,
-			expectedError:                errNoAccessKeyID,
+			expectedError:                fmt.Errorf("%w ns1/issuer1-credentials: missing fake-access-key-id", errInvalidCredentialsSecret),
 			expectedResult:               ctrl.Result{},
 		},
 		"failure-issuer-no-secret-access-key-specified": {
@@ -383,7 +412,7 @@ func TestIssuerReconcile(t *testing.T) {
 				},
 			},
 			expectedReadyConditionStatus: metav1.ConditionFalse,
-			expectedError:                errNoSecretAccessKey,
+			expectedError:                fmt.Errorf("%w ns1/issuer1-credentials: missing AWS_SECRET_ACCESS_KEY", errInvalidCredentialsSecret),
 			expectedResult:               ctrl.Result{},
 		},
 		"failure-issuer-no-secret-access-key-specified-with-selector": {
@@ -428,9 +457,2186 @@ func TestIssuerReconcile(t *testing.T) {
 				},
 			},
 			expectedReadyConditionStatus: metav1.ConditionFalse,
-			expectedError:                errNoSecretAccessKey,
+			expectedError:                fmt.Errorf("%w ns1/issuer1-credentials: missing fake-secret-access-key", errInvalidCredentialsSecret),
 			expectedResult:               ctrl.Result{},
 		},
+		"failure-issuer-invalid-template-arn": {
+			name: types.NamespacedName{Namespace: "ns1", Name: "issuer1"},
+			objects: []client.Object{
+				&issuerapi.AWSPCAIssuer{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:      "issuer1",
+						Namespace: "ns1",
+					},
+					Spec: issuerapi.AWSPCAIssuerSpec{
+						SecretRef: issuerapi.AWSCredentialsSecretReference{
+							SecretReference: v1.SecretReference{
+								Name:      "issuer1-credentials",
+								Namespace: "ns1",
+							},
+						},
+						Region:      "us-east-1",
+						Arn:         "arn:aws:acm-pca:us-east-1:account:certificate-authority/12345678-1234-1234-1234-123456789012",
+						TemplateArn: "EndEntityCertificate/V1",
+					},
+					Status: issuerapi.AWSPCAIssuerStatus{
+						Conditions: []metav1.Condition{
+							{
+								Type:   issuerapi.ConditionTypeReady,
+								Status: metav1.ConditionUnknown,
+							},
+						},
+					},
+				},
+				&v1.Secret{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:      "issuer1-credentials",
+						Namespace: "ns1",
+					},
+					Data: map[string][]byte{
+						"AWS_ACCESS_KEY_ID":     []byte("ZXhhbXBsZQ=="),
+						"AWS_SECRET_ACCESS_KEY": []byte("ZXhhbXBsZQ=="),
+					},
+				},
+			},
+			expectedReadyConditionStatus: metav1.ConditionFalse,
+			expectedError:                errInvalidTemplateArn,
+			expectedResult:               ctrl.Result{},
+		},
+		"failure-issuer-invalid-arn": {
+			name: types.NamespacedName{Namespace: "ns1", Name: "issuer1"},
+			objects: []client.Object{
+				&issuerapi.AWSPCAIssuer{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:      "issuer1",
+						Namespace: "ns1",
+					},
+					Spec: issuerapi.AWSPCAIssuerSpec{
+						SecretRef: issuerapi.AWSCredentialsSecretReference{
+							SecretReference: v1.SecretReference{
+								Name:      "issuer1-credentials",
+								Namespace: "ns1",
+							},
+						},
+						Region: "us-east-1",
+						Arn:    "arn:aws:s3:::bucket",
+					},
+					Status: issuerapi.AWSPCAIssuerStatus{
+						Conditions: []metav1.Condition{
+							{
+								Type:   issuerapi.ConditionTypeReady,
+								Status: metav1.ConditionUnknown,
+							},
+						},
+					},
+				},
+				&v1.Secret{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:      "issuer1-credentials",
+						Namespace: "ns1",
+					},
+					Data: map[string][]byte{
+						"AWS_ACCESS_KEY_ID":     []byte("ZXhhbXBsZQ=="),
+						"AWS_SECRET_ACCESS_KEY": []byte("ZXhhbXBsZQ=="),
+					},
+				},
+			},
+			expectedReadyConditionStatus: metav1.ConditionFalse,
+			expectedError:                fmt.Errorf("%w: %v", errInvalidArn, "arn:aws:s3:::bucket is not the ARN of a PCA certificate authority"),
+			expectedResult:               ctrl.Result{},
+		},
+		"failure-issuer-arn-partition-mismatch": {
+			name: types.NamespacedName{Namespace: "ns1", Name: "issuer1"},
+			objects: []client.Object{
+				&issuerapi.AWSPCAIssuer{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:      "issuer1",
+						Namespace: "ns1",
+					},
+					Spec: issuerapi.AWSPCAIssuerSpec{
+						SecretRef: issuerapi.AWSCredentialsSecretReference{
+							SecretReference: v1.SecretReference{
+								Name:      "issuer1-credentials",
+								Namespace: "ns1",
+							},
+						},
+						Region: "us-gov-west-1",
+						Arn:    "arn:aws:acm-pca:us-east-1:account:certificate-authority/12345678-1234-1234-1234-123456789012",
+					},
+					Status: issuerapi.AWSPCAIssuerStatus{
+						Conditions: []metav1.Condition{
+							{
+								Type:   issuerapi.ConditionTypeReady,
+								Status: metav1.ConditionUnknown,
+							},
+						},
+					},
+				},
+				&v1.Secret{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:      "issuer1-credentials",
+						Namespace: "ns1",
+					},
+					Data: map[string][]byte{
+						"AWS_ACCESS_KEY_ID":     []byte("ZXhhbXBsZQ=="),
+						"AWS_SECRET_ACCESS_KEY": []byte("ZXhhbXBsZQ=="),
+					},
+				},
+			},
+			expectedReadyConditionStatus: metav1.ConditionFalse,
+			expectedError:                fmt.Errorf("%w: arn:aws:acm-pca:us-east-1:account:certificate-authority/12345678-1234-1234-1234-123456789012 is in partition aws, but region us-gov-west-1 is in aws-us-gov", errArnPartitionMismatch),
+			expectedResult:               ctrl.Result{},
+		},
+		"failure-issuer-failover-arn-partition-mismatch": {
+			name: types.NamespacedName{Namespace: "ns1", Name: "issuer1"},
+			objects: []client.Object{
+				&issuerapi.AWSPCAIssuer{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:      "issuer1",
+						Namespace: "ns1",
+					},
+					Spec: issuerapi.AWSPCAIssuerSpec{
+						SecretRef: issuerapi.AWSCredentialsSecretReference{
+							SecretReference: v1.SecretReference{
+								Name:      "issuer1-credentials",
+								Namespace: "ns1",
+							},
+						},
+						Region: "us-east-1",
+						Arn:    "arn:aws:acm-pca:us-east-1:account:certificate-authority/12345678-1234-1234-1234-123456789012",
+						ArnFailover: []string{
+							"arn:aws:acm-pca:us-west-2:account:certificate-authority/12345678-1234-1234-1234-123456789012",
+							"arn:aws-cn:acm-pca:cn-north-1:account:certificate-authority/12345678-1234-1234-1234-123456789012",
+						},
+					},
+					Status: issuerapi.AWSPCAIssuerStatus{
+						Conditions: []metav1.Condition{
+							{
+								Type:   issuerapi.ConditionTypeReady,
+								Status: metav1.ConditionUnknown,
+							},
+						},
+					},
+				},
+				&v1.Secret{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:      "issuer1-credentials",
+						Namespace: "ns1",
+					},
+					Data: map[string][]byte{
+						"AWS_ACCESS_KEY_ID":     []byte("ZXhhbXBsZQ=="),
+						"AWS_SECRET_ACCESS_KEY": []byte("ZXhhbXBsZQ=="),
+					},
+				},
+			},
+			expectedReadyConditionStatus: metav1.ConditionFalse,
+			expectedError:                fmt.Errorf("%w: arn:aws-cn:acm-pca:cn-north-1:account:certificate-authority/12345678-1234-1234-1234-123456789012 is in partition aws-cn, but arn:aws:acm-pca:us-east-1:account:certificate-authority/12345678-1234-1234-1234-123456789012 is in aws", errArnPartitionMismatch),
+			expectedResult:               ctrl.Result{},
+		},
+		"failure-issuer-regional-arn-region-mismatch": {
+			name: types.NamespacedName{Namespace: "ns1", Name: "issuer1"},
+			objects: []client.Object{
+				&issuerapi.AWSPCAIssuer{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:      "issuer1",
+						Namespace: "ns1",
+					},
+					Spec: issuerapi.AWSPCAIssuerSpec{
+						SecretRef: issuerapi.AWSCredentialsSecretReference{
+							SecretReference: v1.SecretReference{
+								Name:      "issuer1-credentials",
+								Namespace: "ns1",
+							},
+						},
+						Region: "us-east-1",
+						Arn:    "arn:aws:acm-pca:us-east-1:account:certificate-authority/12345678-1234-1234-1234-123456789012",
+						RegionalArns: map[string]string{
+							"eu-west-1": "arn:aws:acm-pca:us-west-2:account:certificate-authority/12345678-1234-1234-1234-123456789012",
+						},
+					},
+					Status: issuerapi.AWSPCAIssuerStatus{
+						Conditions: []metav1.Condition{
+							{
+								Type:   issuerapi.ConditionTypeReady,
+								Status: metav1.ConditionUnknown,
+							},
+						},
+					},
+				},
+				&v1.Secret{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:      "issuer1-credentials",
+						Namespace: "ns1",
+					},
+					Data: map[string][]byte{
+						"AWS_ACCESS_KEY_ID":     []byte("ZXhhbXBsZQ=="),
+						"AWS_SECRET_ACCESS_KEY": []byte("ZXhhbXBsZQ=="),
+					},
+				},
+			},
+			expectedReadyConditionStatus: metav1.ConditionFalse,
+			expectedError:                fmt.Errorf("%w: arn:aws:acm-pca:us-west-2:account:certificate-authority/12345678-1234-1234-1234-123456789012 is in region us-west-2, but is the regional CA of eu-west-1", errArnRegionMismatch),
+			expectedResult:               ctrl.Result{},
+		},
+		"success-issuer-us-gov": {
+			name: types.NamespacedName{Namespace: "ns1", Name: "issuer1"},
+			objects: []client.Object{
+				&issuerapi.AWSPCAIssuer{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:      "issuer1",
+						Namespace: "ns1",
+					},
+					Spec: issuerapi.AWSPCAIssuerSpec{
+						SecretRef: issuerapi.AWSCredentialsSecretReference{
+							SecretReference: v1.SecretReference{
+								Name:      "issuer1-credentials",
+								Namespace: "ns1",
+							},
+						},
+						Region: "us-gov-west-1",
+						Arn:    "arn:aws-us-gov:acm-pca:us-gov-west-1:account:certificate-authority/12345678-1234-1234-1234-123456789012",
+					},
+					Status: issuerapi.AWSPCAIssuerStatus{
+						Conditions: []metav1.Condition{
+							{
+								Type:   issuerapi.ConditionTypeReady,
+								Status: metav1.ConditionUnknown,
+							},
+						},
+					},
+				},
+				&v1.Secret{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:      "issuer1-credentials",
+						Namespace: "ns1",
+					},
+					Data: map[string][]byte{
+						"AWS_ACCESS_KEY_ID":     []byte("ZXhhbXBsZQ=="),
+						"AWS_SECRET_ACCESS_KEY": []byte("ZXhhbXBsZQ=="),
+					},
+				},
+			},
+			expectedReadyConditionStatus: metav1.ConditionTrue,
+			expectedResult:               ctrl.Result{},
+		},
+		"success-issuer-china": {
+			name: types.NamespacedName{Namespace: "ns1", Name: "issuer1"},
+			objects: []client.Object{
+				&issuerapi.AWSPCAIssuer{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:      "issuer1",
+						Namespace: "ns1",
+					},
+					Spec: issuerapi.AWSPCAIssuerSpec{
+						SecretRef: issuerapi.AWSCredentialsSecretReference{
+							SecretReference: v1.SecretReference{
+								Name:      "issuer1-credentials",
+								Namespace: "ns1",
+							},
+						},
+						Region: "cn-north-1",
+						Arn:    "arn:aws-cn:acm-pca:cn-north-1:account:certificate-authority/12345678-1234-1234-1234-123456789012",
+					},
+					Status: issuerapi.AWSPCAIssuerStatus{
+						Conditions: []metav1.Condition{
+							{
+								Type:   issuerapi.ConditionTypeReady,
+								Status: metav1.ConditionUnknown,
+							},
+						},
+					},
+				},
+				&v1.Secret{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:      "issuer1-credentials",
+						Namespace: "ns1",
+					},
+					Data: map[string][]byte{
+						"AWS_ACCESS_KEY_ID":     []byte("ZXhhbXBsZQ=="),
+						"AWS_SECRET_ACCESS_KEY": []byte("ZXhhbXBsZQ=="),
+					},
+				},
+			},
+			expectedReadyConditionStatus: metav1.ConditionTrue,
+			expectedResult:               ctrl.Result{},
+		},
+		"failure-issuer-invalid-endpoint": {
+			name: types.NamespacedName{Namespace: "ns1", Name: "issuer1"},
+			objects: []client.Object{
+				&issuerapi.AWSPCAIssuer{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:      "issuer1",
+						Namespace: "ns1",
+					},
+					Spec: issuerapi.AWSPCAIssuerSpec{
+						SecretRef: issuerapi.AWSCredentialsSecretReference{
+							SecretReference: v1.SecretReference{
+								Name:      "issuer1-credentials",
+								Namespace: "ns1",
+							},
+						},
+						Region:   "us-east-1",
+						Arn:      "arn:aws:acm-pca:us-east-1:account:certificate-authority/12345678-1234-1234-1234-123456789012",
+						Endpoint: "http://acm-pca.us-east-1.amazonaws.com",
+					},
+					Status: issuerapi.AWSPCAIssuerStatus{
+						Conditions: []metav1.Condition{
+							{
+								Type:   issuerapi.ConditionTypeReady,
+								Status: metav1.ConditionUnknown,
+							},
+						},
+					},
+				},
+				&v1.Secret{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:      "issuer1-credentials",
+						Namespace: "ns1",
+					},
+					Data: map[string][]byte{
+						"AWS_ACCESS_KEY_ID":     []byte("ZXhhbXBsZQ=="),
+						"AWS_SECRET_ACCESS_KEY": []byte("ZXhhbXBsZQ=="),
+					},
+				},
+			},
+			expectedReadyConditionStatus: metav1.ConditionFalse,
+			expectedError:                errInvalidEndpoint,
+			expectedResult:               ctrl.Result{},
+		},
+		"failure-issuer-invalid-tags": {
+			name: types.NamespacedName{Namespace: "ns1", Name: "issuer1"},
+			objects: []client.Object{
+				&issuerapi.AWSPCAIssuer{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:      "issuer1",
+						Namespace: "ns1",
+					},
+					Spec: issuerapi.AWSPCAIssuerSpec{
+						SecretRef: issuerapi.AWSCredentialsSecretReference{
+							SecretReference: v1.SecretReference{
+								Name:      "issuer1-credentials",
+								Namespace: "ns1",
+							},
+						},
+						Region: "us-east-1",
+						Arn:    "arn:aws:acm-pca:us-east-1:account:certificate-authority/12345678-1234-1234-1234-123456789012",
+						Tags:   map[string]string{"aws:cost-center": "1234"},
+					},
+					Status: issuerapi.AWSPCAIssuerStatus{
+						Conditions: []metav1.Condition{
+							{
+								Type:   issuerapi.ConditionTypeReady,
+								Status: metav1.ConditionUnknown,
+							},
+						},
+					},
+				},
+				&v1.Secret{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:      "issuer1-credentials",
+						Namespace: "ns1",
+					},
+					Data: map[string][]byte{
+						"AWS_ACCESS_KEY_ID":     []byte("ZXhhbXBsZQ=="),
+						"AWS_SECRET_ACCESS_KEY": []byte("ZXhhbXBsZQ=="),
+					},
+				},
+			},
+			expectedReadyConditionStatus: metav1.ConditionFalse,
+			expectedError:                fmt.Errorf("%w: %v", errInvalidTags, `tag key "aws:cost-center" uses the reserved prefix aws:`),
+			expectedResult:               ctrl.Result{},
+		},
+		"failure-issuer-invalid-path-length": {
+			name: types.NamespacedName{Namespace: "ns1", Name: "issuer1"},
+			objects: []client.Object{
+				&issuerapi.AWSPCAIssuer{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:      "issuer1",
+						Namespace: "ns1",
+					},
+					Spec: issuerapi.AWSPCAIssuerSpec{
+						SecretRef: issuerapi.AWSCredentialsSecretReference{
+							SecretReference: v1.SecretReference{
+								Name:      "issuer1-credentials",
+								Namespace: "ns1",
+							},
+						},
+						Region:     "us-east-1",
+						Arn:        "arn:aws:acm-pca:us-east-1:account:certificate-authority/12345678-1234-1234-1234-123456789012",
+						PathLength: ptr.To[int32](4),
+					},
+					Status: issuerapi.AWSPCAIssuerStatus{
+						Conditions: []metav1.Condition{
+							{
+								Type:   issuerapi.ConditionTypeReady,
+								Status: metav1.ConditionUnknown,
+							},
+						},
+					},
+				},
+				&v1.Secret{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:      "issuer1-credentials",
+						Namespace: "ns1",
+					},
+					Data: map[string][]byte{
+						"AWS_ACCESS_KEY_ID":     []byte("ZXhhbXBsZQ=="),
+						"AWS_SECRET_ACCESS_KEY": []byte("ZXhhbXBsZQ=="),
+					},
+				},
+			},
+			expectedReadyConditionStatus: metav1.ConditionFalse,
+			expectedError:                errInvalidPathLength,
+			expectedResult:               ctrl.Result{},
+		},
+		"failure-issuer-no-audit-report-bucket": {
+			name: types.NamespacedName{Namespace: "ns1", Name: "issuer1"},
+			objects: []client.Object{
+				&issuerapi.AWSPCAIssuer{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:      "issuer1",
+						Namespace: "ns1",
+					},
+					Spec: issuerapi.AWSPCAIssuerSpec{
+						SecretRef: issuerapi.AWSCredentialsSecretReference{
+							SecretReference: v1.SecretReference{
+								Name:      "issuer1-credentials",
+								Namespace: "ns1",
+							},
+						},
+						Region:      "us-east-1",
+						Arn:         "arn:aws:acm-pca:us-east-1:account:certificate-authority/12345678-1234-1234-1234-123456789012",
+						AuditReport: &issuerapi.AWSPCAAuditReport{},
+					},
+					Status: issuerapi.AWSPCAIssuerStatus{
+						Conditions: []metav1.Condition{
+							{
+								Type:   issuerapi.ConditionTypeReady,
+								Status: metav1.ConditionUnknown,
+							},
+						},
+					},
+				},
+				&v1.Secret{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:      "issuer1-credentials",
+						Namespace: "ns1",
+					},
+					Data: map[string][]byte{
+						"AWS_ACCESS_KEY_ID":     []byte("ZXhhbXBsZQ=="),
+						"AWS_SECRET_ACCESS_KEY": []byte("ZXhhbXBsZQ=="),
+					},
+				},
+			},
+			expectedReadyConditionStatus: metav1.ConditionFalse,
+			expectedError:                errNoAuditReportBucket,
+			expectedResult:               ctrl.Result{},
+		},
+		"failure-issuer-invalid-api-passthrough": {
+			name: types.NamespacedName{Namespace: "ns1", Name: "issuer1"},
+			objects: []client.Object{
+				&issuerapi.AWSPCAIssuer{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:      "issuer1",
+						Namespace: "ns1",
+					},
+					Spec: issuerapi.AWSPCAIssuerSpec{
+						SecretRef: issuerapi.AWSCredentialsSecretReference{
+							SecretReference: v1.SecretReference{
+								Name:      "issuer1-credentials",
+								Namespace: "ns1",
+							},
+						},
+						Region: "us-east-1",
+						Arn:    "arn:aws:acm-pca:us-east-1:account:certificate-authority/12345678-1234-1234-1234-123456789012",
+						APIPassthrough: &issuerapi.AWSPCAAPIPassthrough{
+							CertificatePolicies: []string{"not-an-oid"},
+						},
+					},
+					Status: issuerapi.AWSPCAIssuerStatus{
+						Conditions: []metav1.Condition{
+							{
+								Type:   issuerapi.ConditionTypeReady,
+								Status: metav1.ConditionUnknown,
+							},
+						},
+					},
+				},
+				&v1.Secret{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:      "issuer1-credentials",
+						Namespace: "ns1",
+					},
+					Data: map[string][]byte{
+						"AWS_ACCESS_KEY_ID":     []byte("ZXhhbXBsZQ=="),
+						"AWS_SECRET_ACCESS_KEY": []byte("ZXhhbXBsZQ=="),
+					},
+				},
+			},
+			expectedReadyConditionStatus: metav1.ConditionFalse,
+			expectedError:                fmt.Errorf("%w: %v", errInvalidAPIPassthrough, `certificate policy: "not-an-oid" is not a dotted decimal OID`),
+			expectedResult:               ctrl.Result{},
+		},
+		"failure-issuer-no-fips-endpoint": {
+			name: types.NamespacedName{Namespace: "ns1", Name: "issuer1"},
+			objects: []client.Object{
+				&issuerapi.AWSPCAIssuer{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:      "issuer1",
+						Namespace: "ns1",
+					},
+					Spec: issuerapi.AWSPCAIssuerSpec{
+						SecretRef: issuerapi.AWSCredentialsSecretReference{
+							SecretReference: v1.SecretReference{
+								Name:      "issuer1-credentials",
+								Namespace: "ns1",
+							},
+						},
+						Region:          "eu-west-1",
+						Arn:             "arn:aws:acm-pca:eu-west-1:account:certificate-authority/12345678-1234-1234-1234-123456789012",
+						UseFIPSEndpoint: true,
+					},
+					Status: issuerapi.AWSPCAIssuerStatus{
+						Conditions: []metav1.Condition{
+							{
+								Type:   issuerapi.ConditionTypeReady,
+								Status: metav1.ConditionUnknown,
+							},
+						},
+					},
+				},
+				&v1.Secret{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:      "issuer1-credentials",
+						Namespace: "ns1",
+					},
+					Data: map[string][]byte{
+						"AWS_ACCESS_KEY_ID":     []byte("ZXhhbXBsZQ=="),
+						"AWS_SECRET_ACCESS_KEY": []byte("ZXhhbXBsZQ=="),
+					},
+				},
+			},
+			expectedReadyConditionStatus: metav1.ConditionFalse,
+			expectedError:                fmt.Errorf("%w: %s", errNoFIPSEndpoint, "eu-west-1"),
+			expectedResult:               ctrl.Result{},
+		},
+	}
+
+	scheme := runtime.NewScheme()
+	require.NoError(t, issuerapi.AddToScheme(scheme))
+	require.NoError(t, v1.AddToScheme(scheme))
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			fakeClient := fake.NewClientBuilder().
+				WithScheme(scheme).
+				WithObjects(tc.objects...).
+				WithStatusSubresource(tc.objects...).
+				Build()
+
+			controller := GenericIssuerReconciler{
+				Client:   fakeClient,
+				Log:      logrtesting.NewTestLogger(t),
+				Scheme:   scheme,
+				Recorder: record.NewFakeRecorder(10),
+			}
+
+			var (
+				result reconcile.Result
+				err    error
+				status issuerapi.AWSPCAIssuerStatus
+			)
+
+			ctx := context.TODO()
+
+			if tc.kind == ClusterIssuer {
+				iss := new(issuerapi.AWSPCAClusterIssuer)
+
+				require.NoError(t, controller.Client.Get(ctx, tc.name, iss))
+
+				result, err = controller.Reconcile(ctx, reconcile.Request{NamespacedName: tc.name}, iss)
+
+				status = iss.Status
+			} else {
+				iss := new(issuerapi.AWSPCAIssuer)
+
+				require.NoError(t, controller.Client.Get(ctx, tc.name, iss))
+
+				result, err = controller.Reconcile(ctx, reconcile.Request{NamespacedName: tc.name}, iss)
+
+				status = iss.Status
+			}
+
+			if tc.expectedError != nil {
+				assertErrorIs(t, tc.expectedError, err)
+			} else {
+				assert.NoError(t, err)
+			}
+
+			assert.Equal(t, tc.expectedResult, result, "Unexpected result")
+
+			if tc.expectedReadyConditionStatus != "" {
+				assertIssuerHasReadyCondition(t, tc.expectedReadyConditionStatus, &status)
+			}
+		})
+	}
+}
+
+func TestIssuerReconcileDefaultCredentialChain(t *testing.T) {
+	type testCase struct {
+		env                          map[string]string
+		expectedError                error
+		expectedReadyConditionStatus metav1.ConditionStatus
+		expectedReadyConditionReason string
+	}
+
+	tests := map[string]testCase{
+		"success-default-credential-chain": {
+			env: map[string]string{
+				"AWS_ACCESS_KEY_ID":     "ZXhhbXBsZQ==",
+				"AWS_SECRET_ACCESS_KEY": "ZXhhbXBsZQ==",
+			},
+			expectedReadyConditionStatus: metav1.ConditionTrue,
+			expectedReadyConditionReason: "Verified",
+		},
+		"failure-no-credentials-resolved": {
+			env:                          map[string]string{},
+			expectedError:                errNoCredentials,
+			expectedReadyConditionStatus: metav1.ConditionFalse,
+			expectedReadyConditionReason: "NoCredentials",
+		},
+	}
+
+	scheme := runtime.NewScheme()
+	require.NoError(t, issuerapi.AddToScheme(scheme))
+	require.NoError(t, v1.AddToScheme(scheme))
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			isolateDefaultCredentialChain(t)
+			for k, v := range tc.env {
+				t.Setenv(k, v)
+			}
+			// Clients loaded from the default credential chain are cached
+			// regardless of the environment
+			awspca.ClearProvisioners()
+
+			iss := &issuerapi.AWSPCAIssuer{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "issuer1",
+					Namespace: "ns1",
+				},
+				Spec: issuerapi.AWSPCAIssuerSpec{
+					Region: "us-east-1",
+					Arn:    "arn:aws:acm-pca:us-east-1:account:certificate-authority/12345678-1234-1234-1234-123456789012",
+				},
+			}
+			fakeClient := fake.NewClientBuilder().
+				WithScheme(scheme).
+				WithObjects(iss).
+				WithStatusSubresource(iss).
+				Build()
+
+			controller := GenericIssuerReconciler{
+				Client:   fakeClient,
+				Log:      logrtesting.NewTestLogger(t),
+				Scheme:   scheme,
+				Recorder: record.NewFakeRecorder(10),
+			}
+
+			ctx := context.TODO()
+			name := types.NamespacedName{Namespace: "ns1", Name: "issuer1"}
+			require.NoError(t, controller.Client.Get(ctx, name, iss))
+
+			_, err := controller.Reconcile(ctx, reconcile.Request{NamespacedName: name}, iss)
+			if tc.expectedError != nil {
+				assert.ErrorIs(t, err, tc.expectedError)
+			} else {
+				assert.NoError(t, err)
+			}
+
+			assertIssuerHasReadyCondition(t, tc.expectedReadyConditionStatus, &iss.Status)
+			assert.Equal(t, tc.expectedReadyConditionReason, iss.Status.Conditions[0].Reason)
+		})
+	}
+}
+
+func TestIssuerReconcileRegionMismatch(t *testing.T) {
+	type testCase struct {
+		region                       string
+		defaultRegion                string
+		expectedError                error
+		expectedReadyConditionStatus metav1.ConditionStatus
+		expectedReadyConditionReason string
+		expectedMessage              string
+	}
+
+	tests := map[string]testCase{
+		"success-matching-region": {
+			region:                       "us-west-2",
+			expectedReadyConditionStatus: metav1.ConditionTrue,
+			expectedReadyConditionReason: "Verified",
+		},
+		"success-matching-default-region": {
+			defaultRegion:                "us-west-2",
+			expectedReadyConditionStatus: metav1.ConditionTrue,
+			expectedReadyConditionReason: "Verified",
+		},
+		"failure-region-mismatch": {
+			region:                       "us-east-1",
+			expectedError:                errArnRegionMismatch,
+			expectedReadyConditionStatus: metav1.ConditionFalse,
+			expectedReadyConditionReason: "RegionMismatch",
+			expectedMessage:              "is in region us-west-2, but the Issuer uses region us-east-1",
+		},
+		"failure-default-region-mismatch": {
+			defaultRegion:                "eu-west-1",
+			expectedError:                errArnRegionMismatch,
+			expectedReadyConditionStatus: metav1.ConditionFalse,
+			expectedReadyConditionReason: "RegionMismatch",
+			expectedMessage:              "is in region us-west-2, but the Issuer uses region eu-west-1",
+		},
+	}
+
+	scheme := runtime.NewScheme()
+	require.NoError(t, issuerapi.AddToScheme(scheme))
+	require.NoError(t, v1.AddToScheme(scheme))
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			isolateDefaultCredentialChain(t)
+			t.Setenv("AWS_ACCESS_KEY_ID", "ZXhhbXBsZQ==")
+			t.Setenv("AWS_SECRET_ACCESS_KEY", "ZXhhbXBsZQ==")
+			t.Setenv("AWS_REGION", "")
+			awspca.ClearProvisioners()
+
+			iss := &issuerapi.AWSPCAIssuer{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "issuer1",
+					Namespace: "ns1",
+				},
+				Spec: issuerapi.AWSPCAIssuerSpec{
+					Region: tc.region,
+					Arn:    "arn:aws:acm-pca:us-west-2:account:certificate-authority/12345678-1234-1234-1234-123456789012",
+				},
+			}
+			fakeClient := fake.NewClientBuilder().
+				WithScheme(scheme).
+				WithObjects(iss).
+				WithStatusSubresource(iss).
+				Build()
+
+			controller := GenericIssuerReconciler{
+				Client:        fakeClient,
+				Log:           logrtesting.NewTestLogger(t),
+				Scheme:        scheme,
+				Recorder:      record.NewFakeRecorder(10),
+				DefaultRegion: tc.defaultRegion,
+			}
+
+			ctx := context.TODO()
+			name := types.NamespacedName{Namespace: "ns1", Name: "issuer1"}
+			require.NoError(t, controller.Client.Get(ctx, name, iss))
+
+			_, err := controller.Reconcile(ctx, reconcile.Request{NamespacedName: name}, iss)
+			if tc.expectedError != nil {
+				assert.ErrorIs(t, err, tc.expectedError)
+			} else {
+				assert.NoError(t, err)
+			}
+
+			assertIssuerHasReadyCondition(t, tc.expectedReadyConditionStatus, &iss.Status)
+			assert.Equal(t, tc.expectedReadyConditionReason, iss.Status.Conditions[0].Reason)
+			assert.Contains(t, iss.Status.Conditions[0].Message, tc.expectedMessage)
+		})
+	}
+}
+
+func TestGetConfigDefaultCredentialChain(t *testing.T) {
+	isolateDefaultCredentialChain(t)
+	t.Setenv("AWS_ACCESS_KEY_ID", "ZXhhbXBsZQ==")
+	t.Setenv("AWS_SECRET_ACCESS_KEY", "ZXhhbXBsZQ==")
+
+	controller := GenericIssuerReconciler{}
+	cfg, err := controller.getConfig(context.TODO(), &issuerapi.AWSPCAIssuerSpec{Region: "us-west-2"})
+	require.NoError(t, err)
+
+	creds, err := cfg.Credentials.Retrieve(context.TODO())
+	require.NoError(t, err)
+	assert.Equal(t, "EnvConfigCredentials", creds.Source, "expected credentials from the default chain")
+	assert.Equal(t, "us-west-2", cfg.Region)
+}
+
+func TestGetConfigSharedConfigProfile(t *testing.T) {
+	type testCase struct {
+		profile         string
+		secretRef       bool
+		expectedKeyID   string
+		expectedProfile string
+	}
+	tests := map[string]testCase{
+		"default-profile": {
+			expectedKeyID: "DEFAULT_KEY_ID",
+		},
+		"named-profile": {
+			profile:         "dev",
+			expectedKeyID:   "DEV_KEY_ID",
+			expectedProfile: "dev",
+		},
+		"secret-takes-precedence": {
+			profile:       "dev",
+			secretRef:     true,
+			expectedKeyID: "SECRET_KEY_ID",
+		},
+	}
+
+	scheme := runtime.NewScheme()
+	require.NoError(t, v1.AddToScheme(scheme))
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			isolateDefaultCredentialChain(t)
+			dir := t.TempDir()
+			configFile := filepath.Join(dir, "config")
+			require.NoError(t, os.WriteFile(configFile, []byte("[default]\nregion = us-east-1\n\n[profile dev]\nregion = us-east-1\n"), 0o600))
+			credentialsFile := filepath.Join(dir, "credentials")
+			require.NoError(t, os.WriteFile(credentialsFile, []byte(
+				"[default]\naws_access_key_id = DEFAULT_KEY_ID\naws_secret_access_key = ZXhhbXBsZQ==\n\n"+
+					"[dev]\naws_access_key_id = DEV_KEY_ID\naws_secret_access_key = ZXhhbXBsZQ==\n"), 0o600))
+			t.Setenv("AWS_CONFIG_FILE", configFile)
+			t.Setenv("AWS_SHARED_CREDENTIALS_FILE", credentialsFile)
+
+			spec := &issuerapi.AWSPCAIssuerSpec{Region: "us-east-1", Profile: tc.profile}
+			secret := &v1.Secret{
+				ObjectMeta: metav1.ObjectMeta{Name: "issuer1-credentials", Namespace: "ns1"},
+				Data: map[string][]byte{
+					"AWS_ACCESS_KEY_ID":     []byte("SECRET_KEY_ID"),
+					"AWS_SECRET_ACCESS_KEY": []byte("ZXhhbXBsZQ=="),
+				},
+			}
+			if tc.secretRef {
+				spec.SecretRef.SecretReference = v1.SecretReference{Name: "issuer1-credentials", Namespace: "ns1"}
+			}
+			controller := GenericIssuerReconciler{
+				Client: fake.NewClientBuilder().WithScheme(scheme).WithObjects(secret).Build(),
+				Scheme: scheme,
+			}
+
+			cfg, err := controller.getConfig(context.TODO(), spec)
+			require.NoError(t, err)
+			creds, err := cfg.Credentials.Retrieve(context.TODO())
+			require.NoError(t, err)
+			assert.Equal(t, tc.expectedKeyID, creds.AccessKeyID)
+
+			key, err := controller.clientKey(context.TODO(), spec)
+			require.NoError(t, err)
+			assert.Equal(t, tc.expectedProfile, key.Profile)
+		})
+	}
+}
+
+func TestGetConfigPodIdentity(t *testing.T) {
+	const token = "pod-identity-token"
+
+	type testCase struct {
+		env            map[string]string
+		secretRef      bool
+		expectedSource string
+		expectedKeyID  string
+	}
+	tests := map[string]testCase{
+		"pod-identity": {
+			expectedSource: endpointcreds.ProviderName,
+			expectedKeyID:  "POD_IDENTITY_KEY_ID",
+		},
+		"environment-takes-precedence": {
+			env: map[string]string{
+				"AWS_ACCESS_KEY_ID":     "ENV_KEY_ID",
+				"AWS_SECRET_ACCESS_KEY": "ZXhhbXBsZQ==",
+			},
+			expectedSource: "EnvConfigCredentials",
+			expectedKeyID:  "ENV_KEY_ID",
+		},
+		"secret-takes-precedence": {
+			secretRef:      true,
+			expectedSource: credentials.StaticCredentialsName,
+			expectedKeyID:  "SECRET_KEY_ID",
+		},
+	}
+
+	// The EKS Pod Identity agent serves credentials to requests authorized
+	// with the token mounted into the pod
+	agent := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != token {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprintf(w, `{"AccessKeyId":"POD_IDENTITY_KEY_ID","SecretAccessKey":"c2VjcmV0","Token":"dG9rZW4=","Expiration":%q}`,
+			time.Now().Add(time.Hour).UTC().Format(time.RFC3339))
+	}))
+	defer agent.Close()
+
+	tokenFile := filepath.Join(t.TempDir(), "eks-pod-identity-token")
+	require.NoError(t, os.WriteFile(tokenFile, []byte(token), 0600))
+
+	scheme := runtime.NewScheme()
+	require.NoError(t, v1.AddToScheme(scheme))
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			isolateDefaultCredentialChain(t)
+			t.Setenv("AWS_CONTAINER_CREDENTIALS_FULL_URI", agent.URL+"/v1/credentials")
+			t.Setenv("AWS_CONTAINER_AUTHORIZATION_TOKEN_FILE", tokenFile)
+			for k, v := range tc.env {
+				t.Setenv(k, v)
+			}
+
+			secret := &v1.Secret{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "issuer1-credentials",
+					Namespace: "ns1",
+				},
+				Data: map[string][]byte{
+					"AWS_ACCESS_KEY_ID":     []byte("SECRET_KEY_ID"),
+					"AWS_SECRET_ACCESS_KEY": []byte("ZXhhbXBsZQ=="),
+				},
+			}
+			controller := GenericIssuerReconciler{
+				Client: fake.NewClientBuilder().WithScheme(scheme).WithObjects(secret).Build(),
+				Scheme: scheme,
+			}
+
+			spec := &issuerapi.AWSPCAIssuerSpec{Region: "us-east-1"}
+			if tc.secretRef {
+				spec.SecretRef.SecretReference = v1.SecretReference{Name: "issuer1-credentials", Namespace: "ns1"}
+			}
+			cfg, err := controller.getConfig(context.TODO(), spec)
+			require.NoError(t, err)
+
+			creds, err := cfg.Credentials.Retrieve(context.TODO())
+			require.NoError(t, err)
+			assert.Equal(t, tc.expectedSource, creds.Source)
+			assert.Equal(t, tc.expectedKeyID, creds.AccessKeyID)
+		})
+	}
+}
+
+func TestValidateIssuerCredentials(t *testing.T) {
+	arn := "arn:aws:acm-pca:us-east-1:account:certificate-authority/12345678-1234-1234-1234-123456789012"
+	secretRef := issuerapi.AWSCredentialsSecretReference{
+		SecretReference: v1.SecretReference{Name: "issuer1-credentials", Namespace: "ns1"},
+	}
+	rolesAnywhere := &issuerapi.AWSRolesAnywhere{
+		SecretRef: v1.SecretReference{Name: "issuer1-client-certificate", Namespace: "ns1"},
+	}
+
+	tests := map[string]struct {
+		spec          issuerapi.AWSPCAIssuerSpec
+		expectedError error
+	}{
+		"secret-ref": {
+			spec: issuerapi.AWSPCAIssuerSpec{SecretRef: secretRef},
+		},
+		"roles-anywhere": {
+			spec: issuerapi.AWSPCAIssuerSpec{RolesAnywhere: rolesAnywhere},
+		},
+		"failure-secret-ref-with-roles-anywhere": {
+			spec:          issuerapi.AWSPCAIssuerSpec{SecretRef: secretRef, RolesAnywhere: rolesAnywhere},
+			expectedError: errConflictingCredentials,
+		},
+		"profile": {
+			spec: issuerapi.AWSPCAIssuerSpec{Profile: "dev"},
+		},
+		"failure-profile-with-secret-ref": {
+			spec:          issuerapi.AWSPCAIssuerSpec{SecretRef: secretRef, Profile: "dev"},
+			expectedError: errConflictingCredentials,
+		},
+		"failure-profile-with-roles-anywhere": {
+			spec:          issuerapi.AWSPCAIssuerSpec{RolesAnywhere: rolesAnywhere, Profile: "dev"},
+			expectedError: errConflictingCredentials,
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			tc.spec.Arn = arn
+			err := validateIssuer(&tc.spec, "us-east-1")
+			if tc.expectedError != nil {
+				assert.ErrorIs(t, err, tc.expectedError)
+				return
+			}
+			assert.NoError(t, err)
+		})
+	}
+}
+
+func TestGenericIssuerRegion(t *testing.T) {
+	type testCase struct {
+		specRegion     string
+		defaultRegion  string
+		envRegion      string
+		expectedRegion string
+		expectedError  error
+	}
+	tests := map[string]testCase{
+		"spec-region": {
+			specRegion:     "us-west-2",
+			expectedRegion: "us-west-2",
+		},
+		"spec-region-takes-precedence": {
+			specRegion:     "us-west-2",
+			defaultRegion:  "eu-west-1",
+			envRegion:      "ap-south-1",
+			expectedRegion: "us-west-2",
+		},
+		"default-region": {
+			defaultRegion:  "eu-west-1",
+			envRegion:      "ap-south-1",
+			expectedRegion: "eu-west-1",
+		},
+		"env-region": {
+			envRegion:      "ap-south-1",
+			expectedRegion: "ap-south-1",
+		},
+		"no-region": {
+			expectedError: errNoRegionInSpec,
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			isolateDefaultCredentialChain(t)
+			t.Setenv("AWS_ACCESS_KEY_ID", "ZXhhbXBsZQ==")
+			t.Setenv("AWS_SECRET_ACCESS_KEY", "ZXhhbXBsZQ==")
+			t.Setenv("AWS_REGION", tc.envRegion)
+			t.Setenv("AWS_DEFAULT_REGION", "")
+
+			controller := GenericIssuerReconciler{DefaultRegion: tc.defaultRegion}
+			spec := &issuerapi.AWSPCAIssuerSpec{
+				Arn:    fmt.Sprintf("arn:aws:acm-pca:%s:account:certificate-authority/12345678-1234-1234-1234-123456789012", tc.expectedRegion),
+				Region: tc.specRegion,
+			}
+			err := validateIssuer(spec, controller.region(spec))
+			if tc.expectedError != nil {
+				assert.EqualError(t, err, tc.expectedError.Error())
+				return
+			}
+			require.NoError(t, err)
+
+			cfg, err := controller.getConfig(context.TODO(), spec)
+			require.NoError(t, err)
+			assert.Equal(t, tc.expectedRegion, cfg.Region)
+
+			key, err := controller.clientKey(context.TODO(), spec)
+			require.NoError(t, err)
+			assert.Equal(t, tc.expectedRegion, key.Region)
+		})
+	}
+}
+
+func TestGetConfigCABundle(t *testing.T) {
+	// The server stands in for PCA behind a TLS intercepting proxy, whose
+	// certificate is only trusted through the CA bundle
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/x-amz-json-1.1")
+		fmt.Fprint(w, `{"CertificateAuthority":{"Status":"ACTIVE"}}`)
+	}))
+	// Rejected handshakes are expected without the CA bundle
+	server.Config.ErrorLog = log.New(io.Discard, "", 0)
+	server.StartTLS()
+	defer server.Close()
+	caBundle := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw})
+
+	type testCase struct {
+		caBundle          []byte
+		expectedError     error
+		expectedCallError bool
+	}
+	tests := map[string]testCase{
+		"ca-bundle": {
+			caBundle: caBundle,
+		},
+		"system-roots-only": {
+			expectedCallError: true,
+		},
+		"invalid-ca-bundle": {
+			caBundle:      []byte("not a certificate"),
+			expectedError: errInvalidCABundle,
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			isolateDefaultCredentialChain(t)
+			t.Setenv("AWS_ACCESS_KEY_ID", "ZXhhbXBsZQ==")
+			t.Setenv("AWS_SECRET_ACCESS_KEY", "ZXhhbXBsZQ==")
+
+			controller := GenericIssuerReconciler{CABundle: tc.caBundle, RetryMaxAttempts: 1}
+			cfg, err := controller.getConfig(context.TODO(), &issuerapi.AWSPCAIssuerSpec{Region: "us-east-1"})
+			if tc.expectedError != nil {
+				assert.ErrorIs(t, err, tc.expectedError)
+				return
+			}
+			require.NoError(t, err)
+
+			if tc.caBundle != nil {
+				// Requests are still sent through the proxy of HTTPS_PROXY
+				if assert.IsType(t, &awshttp.BuildableClient{}, cfg.HTTPClient) {
+					assert.NotNil(t, cfg.HTTPClient.(*awshttp.BuildableClient).GetTransport().Proxy)
+				}
+			}
+
+			client := awspca.NewClient(cfg, awspca.WithEndpoint(server.URL))
+			_, err = client.DescribeCertificateAuthority(context.TODO(), &acmpca.DescribeCertificateAuthorityInput{
+				CertificateAuthorityArn: aws.String("arn:aws:acm-pca:us-east-1:account:certificate-authority/12345678-1234-1234-1234-123456789012"),
+			})
+			if tc.expectedCallError {
+				var unknownAuthority x509.UnknownAuthorityError
+				assert.ErrorAs(t, err, &unknownAuthority)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestGetConfigConnectionPool(t *testing.T) {
+	type testCase struct {
+		controller                  GenericIssuerReconciler
+		expectedMaxIdleConns        int
+		expectedMaxIdleConnsPerHost int
+		expectedIdleConnTimeout     time.Duration
+		expectedMaxConnsPerHost     int
+	}
+	caBundle, _, _ := testCertificate(t, "Proxy CA", nil, nil)
+	tests := map[string]testCase{
+		"sdk-defaults": {
+			controller:                  GenericIssuerReconciler{CABundle: caBundle},
+			expectedMaxIdleConns:        awshttp.DefaultHTTPTransportMaxIdleConns,
+			expectedMaxIdleConnsPerHost: awshttp.DefaultHTTPTransportMaxIdleConnsPerHost,
+			expectedIdleConnTimeout:     awshttp.DefaultHTTPTransportIdleConnTimeout,
+		},
+		"pool": {
+			controller: GenericIssuerReconciler{
+				MaxIdleConns:    256,
+				IdleConnTimeout: 5 * time.Minute,
+				MaxConnsPerHost: 64,
+			},
+			expectedMaxIdleConns:        256,
+			expectedMaxIdleConnsPerHost: 256,
+			expectedIdleConnTimeout:     5 * time.Minute,
+			expectedMaxConnsPerHost:     64,
+		},
+		"idle-timeout-only": {
+			controller:                  GenericIssuerReconciler{IdleConnTimeout: time.Second},
+			expectedMaxIdleConns:        awshttp.DefaultHTTPTransportMaxIdleConns,
+			expectedMaxIdleConnsPerHost: awshttp.DefaultHTTPTransportMaxIdleConnsPerHost,
+			expectedIdleConnTimeout:     time.Second,
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			isolateDefaultCredentialChain(t)
+			t.Setenv("AWS_ACCESS_KEY_ID", "ZXhhbXBsZQ==")
+			t.Setenv("AWS_SECRET_ACCESS_KEY", "ZXhhbXBsZQ==")
+
+			cfg, err := tc.controller.getConfig(context.TODO(), &issuerapi.AWSPCAIssuerSpec{Region: "us-east-1"})
+			require.NoError(t, err)
+			require.IsType(t, &awshttp.BuildableClient{}, cfg.HTTPClient)
+			tr := cfg.HTTPClient.(*awshttp.BuildableClient).GetTransport()
+			assert.Equal(t, tc.expectedMaxIdleConns, tr.MaxIdleConns)
+			assert.Equal(t, tc.expectedMaxIdleConnsPerHost, tr.MaxIdleConnsPerHost)
+			assert.Equal(t, tc.expectedIdleConnTimeout, tr.IdleConnTimeout)
+			assert.Equal(t, tc.expectedMaxConnsPerHost, tr.MaxConnsPerHost)
+			assert.NotNil(t, tr.Proxy)
+		})
+	}
+}
+
+func TestGetConfigRetryOptions(t *testing.T) {
+	type testCase struct {
+		maxAttempts         int
+		maxBackoff          time.Duration
+		expectedMaxAttempts int
+		expectedMaxBackoff  time.Duration
+	}
+	tests := map[string]testCase{
+		"sdk-defaults": {
+			expectedMaxAttempts: retry.DefaultMaxAttempts,
+			expectedMaxBackoff:  retry.DefaultMaxBackoff,
+		},
+		"max-attempts": {
+			maxAttempts:         7,
+			expectedMaxAttempts: 7,
+			expectedMaxBackoff:  retry.DefaultMaxBackoff,
+		},
+		"max-backoff": {
+			maxBackoff:          50 * time.Millisecond,
+			expectedMaxAttempts: retry.DefaultMaxAttempts,
+			expectedMaxBackoff:  50 * time.Millisecond,
+		},
+		"max-attempts-and-backoff": {
+			maxAttempts:         10,
+			maxBackoff:          50 * time.Millisecond,
+			expectedMaxAttempts: 10,
+			expectedMaxBackoff:  50 * time.Millisecond,
+		},
+	}
+
+	scheme := runtime.NewScheme()
+	require.NoError(t, v1.AddToScheme(scheme))
+	secret := &v1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "issuer1-credentials", Namespace: "ns1"},
+		Data: map[string][]byte{
+			"AWS_ACCESS_KEY_ID":     []byte("ZXhhbXBsZQ=="),
+			"AWS_SECRET_ACCESS_KEY": []byte("ZXhhbXBsZQ=="),
+		},
+	}
+	specs := map[string]*issuerapi.AWSPCAIssuerSpec{
+		"default-credential-chain": {Region: "us-east-1"},
+		"secret-credentials": {
+			Region: "us-east-1",
+			SecretRef: issuerapi.AWSCredentialsSecretReference{
+				SecretReference: v1.SecretReference{Name: "issuer1-credentials", Namespace: "ns1"},
+			},
+		},
+	}
+
+	for name, tc := range tests {
+		for credentialSource, spec := range specs {
+			t.Run(name+"/"+credentialSource, func(t *testing.T) {
+				isolateDefaultCredentialChain(t)
+				t.Setenv("AWS_ACCESS_KEY_ID", "ZXhhbXBsZQ==")
+				t.Setenv("AWS_SECRET_ACCESS_KEY", "ZXhhbXBsZQ==")
+
+				controller := GenericIssuerReconciler{
+					Client:           fake.NewClientBuilder().WithScheme(scheme).WithObjects(secret).Build(),
+					Scheme:           scheme,
+					RetryMaxAttempts: tc.maxAttempts,
+					RetryMaxBackoff:  tc.maxBackoff,
+				}
+				cfg, err := controller.getConfig(context.TODO(), spec)
+				require.NoError(t, err)
+
+				retryer := acmpca.NewFromConfig(cfg).Options().Retryer
+				assert.Equal(t, tc.expectedMaxAttempts, retryer.MaxAttempts())
+				for i := 0; i < 100; i++ {
+					delay, err := retryer.RetryDelay(10, errors.New("throttled"))
+					require.NoError(t, err)
+					assert.LessOrEqual(t, delay, tc.expectedMaxBackoff)
+				}
+			})
+		}
+	}
+}
+
+func TestAssumeRoleOptions(t *testing.T) {
+	type testCase struct {
+		role                *issuerapi.AWSAssumeRole
+		expectedSessionName string
+		expectedExternalID  *string
+	}
+
+	tests := map[string]testCase{
+		"role-with-external-id": {
+			role: &issuerapi.AWSAssumeRole{
+				RoleARN:    "arn:aws:iam::111122223333:role/pca-signer",
+				ExternalID: "fake-external-id",
+			},
+			expectedSessionName: defaultAssumeRoleSessionName,
+			expectedExternalID:  aws.String("fake-external-id"),
+		},
+		"role-with-session-name": {
+			role: &issuerapi.AWSAssumeRole{
+				RoleARN:     "arn:aws:iam::111122223333:role/pca-signer",
+				SessionName: "fake-session",
+			},
+			expectedSessionName: "fake-session",
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			opts := stscreds.AssumeRoleOptions{}
+			assumeRoleOptions(tc.role)(&opts)
+			assert.Equal(t, tc.role.RoleARN, opts.RoleARN)
+			assert.Equal(t, tc.expectedSessionName, opts.RoleSessionName)
+			assert.Equal(t, tc.expectedExternalID, opts.ExternalID)
+
+			cfg := assumeRoleConfig(aws.Config{Region: "us-east-1"}, tc.role)
+			assert.IsType(t, &aws.CredentialsCache{}, cfg.Credentials)
+			assert.True(t, cfg.Credentials.(*aws.CredentialsCache).IsCredentialsProvider(&stscreds.AssumeRoleProvider{}), "expected an STS AssumeRole provider")
+		})
+	}
+}
+
+func TestGetConfigAssumeRole(t *testing.T) {
+	isolateDefaultCredentialChain(t)
+	t.Setenv("AWS_ACCESS_KEY_ID", "ZXhhbXBsZQ==")
+	t.Setenv("AWS_SECRET_ACCESS_KEY", "ZXhhbXBsZQ==")
+
+	controller := GenericIssuerReconciler{}
+	cfg, err := controller.getConfig(context.TODO(), &issuerapi.AWSPCAIssuerSpec{
+		Region: "us-east-1",
+		AssumeRole: &issuerapi.AWSAssumeRole{
+			RoleARN: "arn:aws:iam::111122223333:role/pca-signer",
+		},
+	})
+	require.NoError(t, err)
+	assert.True(t, cfg.Credentials.(*aws.CredentialsCache).IsCredentialsProvider(&stscreds.AssumeRoleProvider{}), "expected an STS AssumeRole provider")
+}
+
+func TestIssuerReconcileInvalidCredentialsSecret(t *testing.T) {
+	type testCase struct {
+		data            map[string][]byte
+		expectedMessage string
+	}
+
+	tests := map[string]testCase{
+		"missing-access-key-id": {
+			data:            map[string][]byte{"AWS_SECRET_ACCESS_KEY": []byte("ZXhhbXBsZQ==")},
+			expectedMessage: "invalid credentials secret ns1/issuer1-credentials: missing AWS_ACCESS_KEY_ID",
+		},
+		"missing-secret-access-key": {
+			data:            map[string][]byte{"AWS_ACCESS_KEY_ID": []byte("ZXhhbXBsZQ==")},
+			expectedMessage: "invalid credentials secret ns1/issuer1-credentials: missing AWS_SECRET_ACCESS_KEY",
+		},
+		"missing-both": {
+			data:            map[string][]byte{"AWS_SESSION_TOKEN": []byte("ZXhhbXBsZQ==")},
+			expectedMessage: "invalid credentials secret ns1/issuer1-credentials: missing AWS_ACCESS_KEY_ID, AWS_SECRET_ACCESS_KEY",
+		},
+		"empty-secret-access-key": {
+			data: map[string][]byte{
+				"AWS_ACCESS_KEY_ID":     []byte("ZXhhbXBsZQ=="),
+				"AWS_SECRET_ACCESS_KEY": {},
+			},
+			expectedMessage: "invalid credentials secret ns1/issuer1-credentials: missing AWS_SECRET_ACCESS_KEY",
+		},
+	}
+
+	scheme := runtime.NewScheme()
+	require.NoError(t, issuerapi.AddToScheme(scheme))
+	require.NoError(t, v1.AddToScheme(scheme))
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			iss := &issuerapi.AWSPCAIssuer{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "issuer1",
+					Namespace: "ns1",
+				},
+				Spec: issuerapi.AWSPCAIssuerSpec{
+					SecretRef: issuerapi.AWSCredentialsSecretReference{
+						SecretReference: v1.SecretReference{
+							Name:      "issuer1-credentials",
+							Namespace: "ns1",
+						},
+					},
+					Region: "us-east-1",
+					Arn:    "arn:aws:acm-pca:us-east-1:account:certificate-authority/12345678-1234-1234-1234-123456789012",
+				},
+			}
+			secret := &v1.Secret{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "issuer1-credentials",
+					Namespace: "ns1",
+				},
+				Data: tc.data,
+			}
+			fakeClient := fake.NewClientBuilder().
+				WithScheme(scheme).
+				WithObjects(iss, secret).
+				WithStatusSubresource(iss).
+				Build()
+			// The secret is validated before GetCallerIdentity calls AWS
+			controller := GenericIssuerReconciler{
+				Client:            fakeClient,
+				Log:               logrtesting.NewTestLogger(t),
+				Scheme:            scheme,
+				Recorder:          record.NewFakeRecorder(10),
+				GetCallerIdentity: true,
+			}
+
+			ctx := context.TODO()
+			_, err := controller.Reconcile(ctx, reconcile.Request{NamespacedName: types.NamespacedName{Namespace: "ns1", Name: "issuer1"}}, iss)
+			assert.ErrorIs(t, err, errInvalidCredentialsSecret)
+
+			if assert.Len(t, iss.Status.Conditions, 1) {
+				assert.Equal(t, metav1.ConditionFalse, iss.Status.Conditions[0].Status)
+				assert.Equal(t, "InvalidCredentialsSecret", iss.Status.Conditions[0].Reason)
+				assert.Equal(t, tc.expectedMessage, iss.Status.Conditions[0].Message)
+			}
+		})
+	}
+}
+
+func TestGetConfigSecretKeys(t *testing.T) {
+	type testCase struct {
+		data                    map[string][]byte
+		accessKeyIDSelector     v1.SecretKeySelector
+		secretAccessKeySelector v1.SecretKeySelector
+		sessionTokenSelector    v1.SecretKeySelector
+		expectedSessionToken    string
+	}
+
+	tests := map[string]testCase{
+		"default-keys": {
+			data: map[string][]byte{
+				"AWS_ACCESS_KEY_ID":     []byte("fake-access-key-id"),
+				"AWS_SECRET_ACCESS_KEY": []byte("fake-secret-access-key"),
+			},
+		},
+		"session-token": {
+			data: map[string][]byte{
+				"AWS_ACCESS_KEY_ID":     []byte("fake-access-key-id"),
+				"AWS_SECRET_ACCESS_KEY": []byte("fake-secret-access-key"),
+				"AWS_SESSION_TOKEN":     []byte("fake-session-token"),
+			},
+			expectedSessionToken: "fake-session-token",
+		},
+		"session-token-selector": {
+			data: map[string][]byte{
+				"AWS_ACCESS_KEY_ID":     []byte("fake-access-key-id"),
+				"AWS_SECRET_ACCESS_KEY": []byte("fake-secret-access-key"),
+				"token":                 []byte("fake-session-token"),
+			},
+			sessionTokenSelector: v1.SecretKeySelector{Key: "token"},
+			expectedSessionToken: "fake-session-token",
+		},
+		"custom-keys": {
+			data: map[string][]byte{
+				"accessKeyID":     []byte("fake-access-key-id"),
+				"secretAccessKey": []byte("fake-secret-access-key"),
+				"sessionToken":    []byte("fake-session-token"),
+				// The default keys are ignored when selectors are set
+				"AWS_ACCESS_KEY_ID":     []byte("other-access-key-id"),
+				"AWS_SECRET_ACCESS_KEY": []byte("other-secret-access-key"),
+				"AWS_SESSION_TOKEN":     []byte("other-session-token"),
+			},
+			accessKeyIDSelector:     v1.SecretKeySelector{Key: "accessKeyID"},
+			secretAccessKeySelector: v1.SecretKeySelector{Key: "secretAccessKey"},
+			sessionTokenSelector:    v1.SecretKeySelector{Key: "sessionToken"},
+			expectedSessionToken:    "fake-session-token",
+		},
+		"custom-keys-without-session-token": {
+			data: map[string][]byte{
+				"accessKeyID":     []byte("fake-access-key-id"),
+				"secretAccessKey": []byte("fake-secret-access-key"),
+			},
+			accessKeyIDSelector:     v1.SecretKeySelector{Key: "accessKeyID"},
+			secretAccessKeySelector: v1.SecretKeySelector{Key: "secretAccessKey"},
+		},
+	}
+
+	scheme := runtime.NewScheme()
+	require.NoError(t, v1.AddToScheme(scheme))
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			isolateDefaultCredentialChain(t)
+			secret := &v1.Secret{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "issuer1-credentials",
+					Namespace: "ns1",
+				},
+				Data: tc.data,
+			}
+			controller := GenericIssuerReconciler{
+				Client: fake.NewClientBuilder().WithScheme(scheme).WithObjects(secret).Build(),
+				Scheme: scheme,
+			}
+
+			cfg, err := controller.getConfig(context.TODO(), &issuerapi.AWSPCAIssuerSpec{
+				Region: "us-east-1",
+				SecretRef: issuerapi.AWSCredentialsSecretReference{
+					SecretReference: v1.SecretReference{
+						Name:      "issuer1-credentials",
+						Namespace: "ns1",
+					},
+					AccessKeyIDSelector:     tc.accessKeyIDSelector,
+					SecretAccessKeySelector: tc.secretAccessKeySelector,
+					SessionTokenSelector:    tc.sessionTokenSelector,
+				},
+			})
+			require.NoError(t, err)
+
+			creds, err := cfg.Credentials.Retrieve(context.TODO())
+			require.NoError(t, err)
+			assert.Equal(t, "fake-access-key-id", creds.AccessKeyID)
+			assert.Equal(t, "fake-secret-access-key", creds.SecretAccessKey)
+			assert.Equal(t, tc.expectedSessionToken, creds.SessionToken)
+		})
+	}
+}
+
+// rolesAnywhereSecretData returns the PEM encoded client certificate and key
+// and the ARNs of a Roles Anywhere Secret
+func rolesAnywhereSecretData(t *testing.T) map[string][]byte {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "aws-privateca-issuer"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, key.Public(), key)
+	require.NoError(t, err)
+	keyDER, err := x509.MarshalPKCS8PrivateKey(key)
+	require.NoError(t, err)
+
+	return map[string][]byte{
+		"tls.crt":        pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}),
+		"tls.key":        pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: keyDER}),
+		"trustAnchorArn": []byte("arn:aws:rolesanywhere:us-east-1:111122223333:trust-anchor/0a1b2c3d-0a1b-0a1b-0a1b-0a1b2c3d4e5f"),
+		"profileArn":     []byte("arn:aws:rolesanywhere:us-east-1:111122223333:profile/0a1b2c3d-0a1b-0a1b-0a1b-0a1b2c3d4e5f"),
+		"roleArn":        []byte("arn:aws:iam::111122223333:role/pca-signer"),
+	}
+}
+
+func TestGetConfigRolesAnywhere(t *testing.T) {
+	type testCase struct {
+		data            func(data map[string][]byte)
+		expectedError   error
+		expectedMessage string
+	}
+
+	tests := map[string]testCase{
+		"success": {},
+		"failure-missing-keys": {
+			data: func(data map[string][]byte) {
+				delete(data, "tls.key")
+				delete(data, "roleArn")
+			},
+			expectedError:   errInvalidCredentialsSecret,
+			expectedMessage: "invalid credentials secret ns1/issuer1-client-certificate: missing tls.key, roleArn",
+		},
+		"failure-key-mismatch": {
+			data: func(data map[string][]byte) {
+				data["tls.key"] = rolesAnywhereSecretData(t)["tls.key"]
+			},
+			expectedError:   errInvalidCredentialsSecret,
+			expectedMessage: "invalid credentials secret ns1/issuer1-client-certificate: tls: private key does not match public key",
+		},
+		"failure-invalid-trust-anchor-arn": {
+			data: func(data map[string][]byte) {
+				data["trustAnchorArn"] = []byte("trust-anchor")
+			},
+			expectedError:   awspca.ErrInvalidRolesAnywhere,
+			expectedMessage: `invalid credentials secret ns1/issuer1-client-certificate: invalid IAM Roles Anywhere configuration: "trust-anchor" is not the ARN of a trust anchor`,
+		},
+	}
+
+	scheme := runtime.NewScheme()
+	require.NoError(t, v1.AddToScheme(scheme))
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			isolateDefaultCredentialChain(t)
+			secret := &v1.Secret{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "issuer1-client-certificate",
+					Namespace: "ns1",
+				},
+				Data: rolesAnywhereSecretData(t),
+			}
+			if tc.data != nil {
+				tc.data(secret.Data)
+			}
+			controller := GenericIssuerReconciler{
+				Client: fake.NewClientBuilder().WithScheme(scheme).WithObjects(secret).Build(),
+				Scheme: scheme,
+			}
+
+			spec := &issuerapi.AWSPCAIssuerSpec{
+				Region: "us-east-1",
+				RolesAnywhere: &issuerapi.AWSRolesAnywhere{
+					SecretRef: v1.SecretReference{Name: "issuer1-client-certificate", Namespace: "ns1"},
+				},
+			}
+			cfg, err := controller.getConfig(context.TODO(), spec)
+			if tc.expectedError != nil {
+				assert.ErrorIs(t, err, errInvalidCredentialsSecret)
+				assert.ErrorIs(t, err, tc.expectedError)
+				assert.EqualError(t, err, tc.expectedMessage)
+				return
+			}
+			require.NoError(t, err)
+			assert.True(t, cfg.Credentials.(*aws.CredentialsCache).IsCredentialsProvider(&awspca.RolesAnywhereProvider{}), "expected a Roles Anywhere provider")
+
+			// Renewing the client certificate changes the client key
+			key, err := controller.clientKey(context.TODO(), spec)
+			require.NoError(t, err)
+			secret.Data["tls.crt"] = []byte("renewed")
+			require.NoError(t, controller.Client.Update(context.TODO(), secret))
+			renewedKey, err := controller.clientKey(context.TODO(), spec)
+			require.NoError(t, err)
+			assert.NotEqual(t, key.CredentialsFingerprint, renewedKey.CredentialsFingerprint)
+		})
+	}
+}
+
+func TestIndexSecretRefRolesAnywhere(t *testing.T) {
+	issuer := &issuerapi.AWSPCAClusterIssuer{Spec: issuerapi.AWSPCAIssuerSpec{
+		RolesAnywhere: &issuerapi.AWSRolesAnywhere{
+			SecretRef: v1.SecretReference{Name: "issuer1-client-certificate", Namespace: "ns1"},
+		},
+	}}
+	assert.Equal(t, []string{"ns1/issuer1-client-certificate"}, (&GenericIssuerReconciler{}).indexSecretRef(issuer))
+}
+
+func TestIssuerReconcileClusterResourceNamespace(t *testing.T) {
+	credentials := func(namespace string) *v1.Secret {
+		return &v1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Name: "clusterissuer1-credentials", Namespace: namespace},
+			Data: map[string][]byte{
+				"AWS_ACCESS_KEY_ID":     []byte("ZXhhbXBsZQ=="),
+				"AWS_SECRET_ACCESS_KEY": []byte("ZXhhbXBsZQ=="),
+			},
+		}
+	}
+
+	type testCase struct {
+		secretNamespace        string
+		objects                []client.Object
+		expectedSecretName     string
+		expectedReadyCondition metav1.ConditionStatus
+	}
+	tests := map[string]testCase{
+		"cluster-resource-namespace": {
+			objects:                []client.Object{credentials("aws-privateca-issuer")},
+			expectedSecretName:     "aws-privateca-issuer/clusterissuer1-credentials",
+			expectedReadyCondition: metav1.ConditionTrue,
+		},
+		"explicit-namespace": {
+			secretNamespace:        "ns1",
+			objects:                []client.Object{credentials("ns1")},
+			expectedSecretName:     "ns1/clusterissuer1-credentials",
+			expectedReadyCondition: metav1.ConditionTrue,
+		},
+		"secret-in-other-namespace": {
+			objects:                []client.Object{credentials("ns1")},
+			expectedSecretName:     "aws-privateca-issuer/clusterissuer1-credentials",
+			expectedReadyCondition: metav1.ConditionFalse,
+		},
+	}
+
+	scheme := runtime.NewScheme()
+	require.NoError(t, issuerapi.AddToScheme(scheme))
+	require.NoError(t, v1.AddToScheme(scheme))
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			issuer := &issuerapi.AWSPCAClusterIssuer{
+				ObjectMeta: metav1.ObjectMeta{Name: "clusterissuer1"},
+				Spec: issuerapi.AWSPCAIssuerSpec{
+					SecretRef: issuerapi.AWSCredentialsSecretReference{
+						SecretReference: v1.SecretReference{Name: "clusterissuer1-credentials", Namespace: tc.secretNamespace},
+					},
+					Region: "us-east-1",
+					Arn:    "arn:aws:acm-pca:us-east-1:account:certificate-authority/12345678-1234-1234-1234-123456789012",
+				},
+			}
+			objects := append([]client.Object{issuer}, tc.objects...)
+			fakeClient := fake.NewClientBuilder().
+				WithScheme(scheme).
+				WithObjects(objects...).
+				WithStatusSubresource(issuer).
+				Build()
+			controller := GenericIssuerReconciler{
+				Client:                   fakeClient,
+				Log:                      logrtesting.NewTestLogger(t),
+				Scheme:                   scheme,
+				Recorder:                 record.NewFakeRecorder(10),
+				ClusterResourceNamespace: "aws-privateca-issuer",
+			}
+			assert.Equal(t, []string{tc.expectedSecretName}, controller.indexSecretRef(issuer))
+
+			ctx := context.TODO()
+			name := types.NamespacedName{Name: "clusterissuer1"}
+			var iss issuerapi.AWSPCAClusterIssuer
+			require.NoError(t, fakeClient.Get(ctx, name, &iss))
+			_, err := controller.Reconcile(ctx, reconcile.Request{NamespacedName: name}, &iss)
+			if tc.expectedReadyCondition == metav1.ConditionTrue {
+				assert.NoError(t, err)
+			} else {
+				assert.Error(t, err)
+			}
+			assertIssuerHasReadyCondition(t, tc.expectedReadyCondition, &iss.Status)
+			assert.Equal(t, tc.secretNamespace, iss.Spec.SecretRef.Namespace, "expected the spec of the issuer not to be modified")
+		})
+	}
+
+	// The Secrets of AWSPCAIssuers are not defaulted
+	issuer := &issuerapi.AWSPCAIssuer{
+		ObjectMeta: metav1.ObjectMeta{Name: "issuer1", Namespace: "ns1"},
+		Spec: issuerapi.AWSPCAIssuerSpec{SecretRef: issuerapi.AWSCredentialsSecretReference{
+			SecretReference: v1.SecretReference{Name: "issuer1-credentials"},
+		}},
+	}
+	controller := GenericIssuerReconciler{ClusterResourceNamespace: "aws-privateca-issuer"}
+	assert.Equal(t, []string{"/issuer1-credentials"}, controller.indexSecretRef(issuer))
+}
+
+func TestWithDefaultTags(t *testing.T) {
+	type testCase struct {
+		defaultTags  map[string]string
+		tags         map[string]string
+		expectedTags map[string]string
+	}
+	tests := map[string]testCase{
+		"no-default-tags": {
+			tags:         map[string]string{"team": "a"},
+			expectedTags: map[string]string{"team": "a"},
+		},
+		"default-tags-only": {
+			defaultTags:  map[string]string{"managed-by": "aws-privateca-issuer"},
+			expectedTags: map[string]string{"managed-by": "aws-privateca-issuer"},
+		},
+		"merged": {
+			defaultTags:  map[string]string{"managed-by": "aws-privateca-issuer"},
+			tags:         map[string]string{"team": "a"},
+			expectedTags: map[string]string{"managed-by": "aws-privateca-issuer", "team": "a"},
+		},
+		"issuer-wins": {
+			defaultTags:  map[string]string{"managed-by": "aws-privateca-issuer", "environment": "prod"},
+			tags:         map[string]string{"environment": "dev"},
+			expectedTags: map[string]string{"managed-by": "aws-privateca-issuer", "environment": "dev"},
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			spec := &issuerapi.AWSPCAIssuerSpec{Tags: tc.tags}
+			original := spec.DeepCopy()
+			controller := GenericIssuerReconciler{DefaultTags: tc.defaultTags}
+			assert.Equal(t, tc.expectedTags, controller.withDefaultTags(spec).Tags)
+			assert.Equal(t, original, spec, "expected the spec not to be modified")
+		})
+	}
+}
+
+func TestIssuerReconcileDefaultTagsLimit(t *testing.T) {
+	tags := func(prefix string, n int) map[string]string {
+		tags := map[string]string{}
+		for i := 0; i < n; i++ {
+			tags[fmt.Sprintf("%s-%d", prefix, i)] = "value"
+		}
+		return tags
+	}
+
+	type testCase struct {
+		defaultTags            map[string]string
+		tags                   map[string]string
+		expectedError          string
+		expectedReadyCondition metav1.ConditionStatus
+	}
+	tests := map[string]testCase{
+		"within-limit": {
+			defaultTags:            tags("default", 48),
+			tags:                   tags("issuer", 2),
+			expectedReadyCondition: metav1.ConditionTrue,
+		},
+		"conflicts-counted-once": {
+			defaultTags:            tags("tag", 50),
+			tags:                   tags("tag", 2),
+			expectedReadyCondition: metav1.ConditionTrue,
+		},
+		"over-limit": {
+			defaultTags:            tags("default", 49),
+			tags:                   tags("issuer", 2),
+			expectedError:          "tags in Issuer Spec are invalid: at most 50 tags are allowed, got 51",
+			expectedReadyCondition: metav1.ConditionFalse,
+		},
+	}
+
+	scheme := runtime.NewScheme()
+	require.NoError(t, issuerapi.AddToScheme(scheme))
+	require.NoError(t, v1.AddToScheme(scheme))
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			issuer := &issuerapi.AWSPCAIssuer{
+				ObjectMeta: metav1.ObjectMeta{Name: "issuer1", Namespace: "ns1"},
+				Spec: issuerapi.AWSPCAIssuerSpec{
+					SecretRef: issuerapi.AWSCredentialsSecretReference{
+						SecretReference: v1.SecretReference{Name: "issuer1-credentials", Namespace: "ns1"},
+					},
+					Region: "us-east-1",
+					Arn:    "arn:aws:acm-pca:us-east-1:account:certificate-authority/12345678-1234-1234-1234-123456789012",
+					Tags:   tc.tags,
+				},
+			}
+			secret := &v1.Secret{
+				ObjectMeta: metav1.ObjectMeta{Name: "issuer1-credentials", Namespace: "ns1"},
+				Data: map[string][]byte{
+					"AWS_ACCESS_KEY_ID":     []byte("ZXhhbXBsZQ=="),
+					"AWS_SECRET_ACCESS_KEY": []byte("ZXhhbXBsZQ=="),
+				},
+			}
+			fakeClient := fake.NewClientBuilder().
+				WithScheme(scheme).
+				WithObjects(issuer, secret).
+				WithStatusSubresource(issuer).
+				Build()
+			controller := GenericIssuerReconciler{
+				Client:      fakeClient,
+				Log:         logrtesting.NewTestLogger(t),
+				Scheme:      scheme,
+				Recorder:    record.NewFakeRecorder(10),
+				DefaultTags: tc.defaultTags,
+			}
+
+			ctx := context.TODO()
+			name := types.NamespacedName{Namespace: "ns1", Name: "issuer1"}
+			var iss issuerapi.AWSPCAIssuer
+			require.NoError(t, fakeClient.Get(ctx, name, &iss))
+			_, err := controller.Reconcile(ctx, reconcile.Request{NamespacedName: name}, &iss)
+			if tc.expectedError != "" {
+				assert.EqualError(t, err, tc.expectedError)
+				assert.ErrorIs(t, err, errInvalidTags)
+			} else {
+				assert.NoError(t, err)
+			}
+			assertIssuerHasReadyCondition(t, tc.expectedReadyCondition, &iss.Status)
+			assert.Equal(t, tc.tags, iss.Spec.Tags, "expected the default tags not to be written to the issuer")
+		})
+	}
+}
+
+func TestIssuerReconcileExpiredSessionToken(t *testing.T) {
+	stsServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/xml")
+		w.WriteHeader(http.StatusForbidden)
+		fmt.Fprint(w, `<ErrorResponse xmlns="https://sts.amazonaws.com/doc/2011-06-15/">
+  <Error>
+    <Type>Sender</Type>
+    <Code>ExpiredToken</Code>
+    <Message>The security token included in the request is expired</Message>
+  </Error>
+  <RequestId>fake-request-id</RequestId>
+</ErrorResponse>`)
+	}))
+	defer stsServer.Close()
+	isolateDefaultCredentialChain(t)
+	t.Setenv("AWS_ENDPOINT_URL_STS", stsServer.URL)
+	awspca.ClearProvisioners()
+
+	scheme := runtime.NewScheme()
+	require.NoError(t, issuerapi.AddToScheme(scheme))
+	require.NoError(t, v1.AddToScheme(scheme))
+
+	iss := &issuerapi.AWSPCAIssuer{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "issuer1",
+			Namespace: "ns1",
+		},
+		Spec: issuerapi.AWSPCAIssuerSpec{
+			SecretRef: issuerapi.AWSCredentialsSecretReference{
+				SecretReference: v1.SecretReference{
+					Name:      "issuer1-credentials",
+					Namespace: "ns1",
+				},
+			},
+			Region: "us-east-1",
+			Arn:    "arn:aws:acm-pca:us-east-1:account:certificate-authority/12345678-1234-1234-1234-123456789012",
+		},
+	}
+	secret := &v1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "issuer1-credentials",
+			Namespace: "ns1",
+		},
+		Data: map[string][]byte{
+			"AWS_ACCESS_KEY_ID":     []byte("ZXhhbXBsZQ=="),
+			"AWS_SECRET_ACCESS_KEY": []byte("ZXhhbXBsZQ=="),
+			"AWS_SESSION_TOKEN":     []byte("ZXhwaXJlZA=="),
+		},
+	}
+	fakeClient := fake.NewClientBuilder().
+		WithScheme(scheme).
+		WithObjects(iss, secret).
+		WithStatusSubresource(iss).
+		Build()
+	controller := GenericIssuerReconciler{
+		Client:            fakeClient,
+		Log:               logrtesting.NewTestLogger(t),
+		Scheme:            scheme,
+		Recorder:          record.NewFakeRecorder(10),
+		GetCallerIdentity: true,
+	}
+
+	ctx := context.TODO()
+	name := types.NamespacedName{Namespace: "ns1", Name: "issuer1"}
+	_, err := controller.Reconcile(ctx, reconcile.Request{NamespacedName: name}, iss)
+	assert.True(t, awspca.IsExpiredTokenError(err), "expected an ExpiredToken error, got %v", err)
+
+	require.NoError(t, fakeClient.Get(ctx, name, iss))
+	for _, conditionType := range []string{issuerapi.ConditionTypeReady, issuerapi.ConditionTypeConnected} {
+		if condition := issuerCondition(iss, conditionType); assert.NotNil(t, condition, conditionType) {
+			assert.Equal(t, metav1.ConditionFalse, condition.Status)
+			assert.Equal(t, "ExpiredCredentials", condition.Reason)
+		}
+	}
+}
+
+// isolateDefaultCredentialChain clears every source the default AWS credential
+// chain consults so tests only see the environment they set up themselves.
+func isolateDefaultCredentialChain(t *testing.T) {
+	for _, k := range []string{
+		"AWS_ACCESS_KEY_ID",
+		"AWS_SECRET_ACCESS_KEY",
+		"AWS_SESSION_TOKEN",
+		"AWS_PROFILE",
+		"AWS_ROLE_ARN",
+		"AWS_WEB_IDENTITY_TOKEN_FILE",
+		"AWS_CONTAINER_CREDENTIALS_FULL_URI",
+		"AWS_CONTAINER_CREDENTIALS_RELATIVE_URI",
+		"AWS_CONTAINER_AUTHORIZATION_TOKEN",
+		"AWS_CONTAINER_AUTHORIZATION_TOKEN_FILE",
+	} {
+		t.Setenv(k, "")
+	}
+	t.Setenv("AWS_CONFIG_FILE", "/nonexistent")
+	t.Setenv("AWS_SHARED_CREDENTIALS_FILE", "/nonexistent")
+	t.Setenv("AWS_EC2_METADATA_DISABLED", "true")
+}
+
+func TestClientKeySecretChanges(t *testing.T) {
+	scheme := runtime.NewScheme()
+	require.NoError(t, v1.AddToScheme(scheme))
+
+	secret := &v1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "issuer1-credentials",
+			Namespace: "ns1",
+		},
+		Data: map[string][]byte{
+			"AWS_ACCESS_KEY_ID":     []byte("ZXhhbXBsZQ=="),
+			"AWS_SECRET_ACCESS_KEY": []byte("ZXhhbXBsZQ=="),
+		},
+	}
+	fakeClient := fake.NewClientBuilder().WithScheme(scheme).WithObjects(secret).Build()
+	controller := GenericIssuerReconciler{Client: fakeClient, Scheme: scheme}
+
+	spec := &issuerapi.AWSPCAIssuerSpec{
+		Region: "us-east-1",
+		SecretRef: issuerapi.AWSCredentialsSecretReference{
+			SecretReference: v1.SecretReference{
+				Name:      "issuer1-credentials",
+				Namespace: "ns1",
+			},
+		},
+	}
+
+	ctx := context.TODO()
+	key, err := controller.clientKey(ctx, spec)
+	require.NoError(t, err)
+	assert.NotEmpty(t, key.CredentialsFingerprint)
+
+	unchanged, err := controller.clientKey(ctx, spec)
+	require.NoError(t, err)
+	assert.Equal(t, key, unchanged)
+
+	secret.Data["AWS_SECRET_ACCESS_KEY"] = []byte("cm90YXRlZA==")
+	require.NoError(t, fakeClient.Update(ctx, secret))
+	rotated, err := controller.clientKey(ctx, spec)
+	require.NoError(t, err)
+	assert.NotEqual(t, key, rotated)
+
+	spec.AssumeRole = &issuerapi.AWSAssumeRole{RoleARN: "arn:aws:iam::123456789012:role/pca"}
+	assumed, err := controller.clientKey(ctx, spec)
+	require.NoError(t, err)
+	assert.NotEqual(t, rotated, assumed)
+	assert.Equal(t, rotated.CredentialsFingerprint, assumed.CredentialsFingerprint)
+
+	// The profile only applies to the default credential chain
+	spec.Profile = "dev"
+	profile, err := controller.clientKey(ctx, spec)
+	require.NoError(t, err)
+	assert.Equal(t, assumed, profile)
+}
+
+func TestIssuersForSecret(t *testing.T) {
+	scheme := runtime.NewScheme()
+	require.NoError(t, issuerapi.AddToScheme(scheme))
+	require.NoError(t, v1.AddToScheme(scheme))
+
+	secret := &v1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "issuer1-credentials", Namespace: "ns1"},
+		Data: map[string][]byte{
+			"AWS_ACCESS_KEY_ID":     []byte("ZXhhbXBsZQ=="),
+			"AWS_SECRET_ACCESS_KEY": []byte("ZXhhbXBsZQ=="),
+		},
+	}
+	spec := func(secretName string) issuerapi.AWSPCAIssuerSpec {
+		return issuerapi.AWSPCAIssuerSpec{
+			Region: "us-east-1",
+			Arn:    "arn:aws:acm-pca:us-east-1:account:certificate-authority/12345678-1234-1234-1234-123456789012",
+			SecretRef: issuerapi.AWSCredentialsSecretReference{
+				SecretReference: v1.SecretReference{Name: secretName, Namespace: "ns1"},
+			},
+		}
+	}
+	issuer := &issuerapi.AWSPCAIssuer{ObjectMeta: metav1.ObjectMeta{Name: "issuer1", Namespace: "ns1"}, Spec: spec("issuer1-credentials")}
+	otherIssuer := &issuerapi.AWSPCAIssuer{ObjectMeta: metav1.ObjectMeta{Name: "issuer2", Namespace: "ns1"}, Spec: spec("issuer2-credentials")}
+	clusterIssuer := &issuerapi.AWSPCAClusterIssuer{ObjectMeta: metav1.ObjectMeta{Name: "clusterissuer1"}, Spec: spec("issuer1-credentials")}
+
+	fakeClient := fake.NewClientBuilder().
+		WithScheme(scheme).
+		WithObjects(secret, issuer, otherIssuer, clusterIssuer).
+		WithStatusSubresource(issuer, otherIssuer, clusterIssuer).
+		WithIndex(&issuerapi.AWSPCAIssuer{}, secretRefField, (&GenericIssuerReconciler{}).indexSecretRef).
+		WithIndex(&issuerapi.AWSPCAClusterIssuer{}, secretRefField, (&GenericIssuerReconciler{}).indexSecretRef).
+		Build()
+	generic := &GenericIssuerReconciler{
+		Client:   fakeClient,
+		Log:      logrtesting.NewTestLogger(t),
+		Scheme:   scheme,
+		Recorder: record.NewFakeRecorder(10),
+	}
+	issuerReconciler := &AWSPCAIssuerReconciler{Client: fakeClient, Log: generic.Log, Scheme: scheme, GenericController: generic}
+	clusterIssuerReconciler := &AWSPCAClusterIssuerReconciler{Client: fakeClient, Log: generic.Log, Scheme: scheme, GenericController: generic}
+
+	issuerName := types.NamespacedName{Namespace: "ns1", Name: "issuer1"}
+	otherIssuerName := types.NamespacedName{Namespace: "ns1", Name: "issuer2"}
+	clusterIssuerName := types.NamespacedName{Name: "clusterissuer1"}
+
+	awspca.ClearProvisioners()
+	defer awspca.ClearProvisioners()
+	for _, name := range []types.NamespacedName{issuerName, otherIssuerName, clusterIssuerName} {
+		awspca.StoreProvisioner(name, &fakeProvisioner{})
+	}
+
+	ctx := context.TODO()
+	secret.Data["AWS_SECRET_ACCESS_KEY"] = []byte("cm90YXRlZA==")
+	require.NoError(t, fakeClient.Update(ctx, secret))
+
+	requests := issuerReconciler.issuersForSecret(ctx, secret)
+	assert.Equal(t, []reconcile.Request{{NamespacedName: issuerName}}, requests)
+	clusterRequests := clusterIssuerReconciler.issuersForSecret(ctx, secret)
+	assert.Equal(t, []reconcile.Request{{NamespacedName: clusterIssuerName}}, clusterRequests)
+
+	_, ok := awspca.GetProvisioner(issuerName)
+	assert.False(t, ok, "expected the provisioner of issuer1 to be invalidated")
+	_, ok = awspca.GetProvisioner(clusterIssuerName)
+	assert.False(t, ok, "expected the provisioner of clusterissuer1 to be invalidated")
+	_, ok = awspca.GetProvisioner(otherIssuerName)
+	assert.True(t, ok, "expected the provisioner of issuer2 to be kept")
+
+	unrelated := &v1.Secret{ObjectMeta: metav1.ObjectMeta{Name: "unrelated", Namespace: "ns1"}}
+	assert.Empty(t, issuerReconciler.issuersForSecret(ctx, unrelated))
+
+	_, err := issuerReconciler.Reconcile(ctx, requests[0])
+	require.NoError(t, err)
+	provisioner, ok := awspca.GetProvisioner(issuerName)
+	require.True(t, ok, "expected the reconcile to store a new provisioner")
+	assert.IsType(t, &awspca.PCAProvisioner{}, provisioner)
+}
+
+func assertErrorIs(t *testing.T, expectedError, actualError error) {
+	if !assert.Error(t, actualError) {
+		return
+	}
+	assert.Equal(t, actualError, expectedError, "Errors do not match!")
+}
+
+func assertIssuerHasReadyCondition(t *testing.T, status metav1.ConditionStatus, issuerStatus *issuerapi.AWSPCAIssuerStatus) {
+	fmt.Printf("%v", issuerStatus.Conditions)
+	assert.Equal(t, status, issuerStatus.Conditions[0].Status, "unexpected condition status")
+}
+
+func TestResolveArn(t *testing.T) {
+	const caArn = "arn:aws:acm-pca:us-east-1:account:certificate-authority/12345678-1234-1234-1234-123456789012"
+	objects := []client.Object{
+		&v1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{Name: "ca", Namespace: "ns1"},
+			Data:       map[string]string{"arn": caArn + "\n"},
+		},
+		&v1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Name: "ca", Namespace: "ns1"},
+			Data:       map[string][]byte{"caArn": []byte(caArn)},
+		},
+	}
+
+	type testCase struct {
+		arnFrom       *issuerapi.AWSArnSource
+		arn           string
+		expectedArn   string
+		expectedError string
+	}
+	tests := map[string]testCase{
+		"success-arn": {
+			arn:         caArn,
+			expectedArn: caArn,
+		},
+		"success-configmap": {
+			arnFrom:     &issuerapi.AWSArnSource{ConfigMapKeyRef: &issuerapi.AWSKeySelector{Name: "ca", Namespace: "ns1", Key: "arn"}},
+			expectedArn: caArn,
+		},
+		"success-secret": {
+			arnFrom:     &issuerapi.AWSArnSource{SecretKeyRef: &issuerapi.AWSKeySelector{Name: "ca", Namespace: "ns1", Key: "caArn"}},
+			expectedArn: caArn,
+		},
+		"failure-missing-key": {
+			arnFrom:       &issuerapi.AWSArnSource{ConfigMapKeyRef: &issuerapi.AWSKeySelector{Name: "ca", Namespace: "ns1", Key: "caArn"}},
+			expectedError: "failed to resolve arnFrom in Issuer Spec: ns1/ca has no key caArn",
+		},
+		"failure-missing-secret": {
+			arnFrom:       &issuerapi.AWSArnSource{SecretKeyRef: &issuerapi.AWSKeySelector{Name: "other", Namespace: "ns1", Key: "caArn"}},
+			expectedError: `failed to resolve arnFrom in Issuer Spec: failed to retrieve secret: secrets "other" not found`,
+		},
+		"failure-arn-and-arn-from": {
+			arn:           caArn,
+			arnFrom:       &issuerapi.AWSArnSource{SecretKeyRef: &issuerapi.AWSKeySelector{Name: "ca", Namespace: "ns1", Key: "caArn"}},
+			expectedError: "failed to resolve arnFrom in Issuer Spec: arn and arnFrom are mutually exclusive",
+		},
+		"failure-no-ref": {
+			arnFrom:       &issuerapi.AWSArnSource{},
+			expectedError: "failed to resolve arnFrom in Issuer Spec: exactly one of configMapKeyRef and secretKeyRef must be set",
+		},
+	}
+
+	scheme := runtime.NewScheme()
+	require.NoError(t, v1.AddToScheme(scheme))
+	controller := GenericIssuerReconciler{
+		Client: fake.NewClientBuilder().WithScheme(scheme).WithObjects(objects...).Build(),
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			spec := &issuerapi.AWSPCAIssuerSpec{Arn: tc.arn, ArnFrom: tc.arnFrom}
+			resolved, err := controller.resolveArn(context.TODO(), spec)
+			if tc.expectedError != "" {
+				assert.ErrorIs(t, err, errInvalidArnFrom)
+				assert.EqualError(t, err, tc.expectedError)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tc.expectedArn, resolved.Arn)
+			assert.Equal(t, tc.arn, spec.Arn, "expected the spec not to be modified")
+		})
+	}
+}
+
+func TestIssuerReconcileArnFrom(t *testing.T) {
+	type testCase struct {
+		arn                          string
+		expectedError                error
+		expectedReadyConditionStatus metav1.ConditionStatus
+	}
+	tests := map[string]testCase{
+		"success": {
+			arn:                          "arn:aws:acm-pca:us-east-1:account:certificate-authority/12345678-1234-1234-1234-123456789012",
+			expectedReadyConditionStatus: metav1.ConditionTrue,
+		},
+		"failure-invalid-arn": {
+			arn:                          "not-an-arn",
+			expectedError:                errInvalidArn,
+			expectedReadyConditionStatus: metav1.ConditionFalse,
+		},
 	}
 
 	scheme := runtime.NewScheme()
@@ -439,12 +2645,36 @@ func TestIssuerReconcile(t *testing.T) {
 
 	for name, tc := range tests {
 		t.Run(name, func(t *testing.T) {
+			awspca.ClearProvisioners()
+			iss := &issuerapi.AWSPCAIssuer{
+				ObjectMeta: metav1.ObjectMeta{Name: "issuer1", Namespace: "ns1"},
+				Spec: issuerapi.AWSPCAIssuerSpec{
+					SecretRef: issuerapi.AWSCredentialsSecretReference{
+						SecretReference: v1.SecretReference{Name: "issuer1-credentials", Namespace: "ns1"},
+					},
+					Region:  "us-east-1",
+					ArnFrom: &issuerapi.AWSArnSource{ConfigMapKeyRef: &issuerapi.AWSKeySelector{Name: "ca", Namespace: "ns1", Key: "arn"}},
+				},
+			}
+			objects := []client.Object{
+				iss,
+				&v1.ConfigMap{
+					ObjectMeta: metav1.ObjectMeta{Name: "ca", Namespace: "ns1"},
+					Data:       map[string]string{"arn": tc.arn},
+				},
+				&v1.Secret{
+					ObjectMeta: metav1.ObjectMeta{Name: "issuer1-credentials", Namespace: "ns1"},
+					Data: map[string][]byte{
+						"AWS_ACCESS_KEY_ID":     []byte("ZXhhbXBsZQ=="),
+						"AWS_SECRET_ACCESS_KEY": []byte("ZXhhbXBsZQ=="),
+					},
+				},
+			}
 			fakeClient := fake.NewClientBuilder().
 				WithScheme(scheme).
-				WithObjects(tc.objects...).
-				WithStatusSubresource(tc.objects...).
+				WithObjects(objects...).
+				WithStatusSubresource(iss).
 				Build()
-
 			controller := GenericIssuerReconciler{
 				Client:   fakeClient,
 				Log:      logrtesting.NewTestLogger(t),
@@ -452,55 +2682,423 @@ func TestIssuerReconcile(t *testing.T) {
 				Recorder: record.NewFakeRecorder(10),
 			}
 
-			var (
-				result reconcile.Result
-				err    error
-				status issuerapi.AWSPCAIssuerStatus
-			)
+			name := types.NamespacedName{Namespace: "ns1", Name: "issuer1"}
+			_, err := controller.Reconcile(context.TODO(), reconcile.Request{NamespacedName: name}, iss)
+			if tc.expectedError != nil {
+				assert.ErrorIs(t, err, tc.expectedError)
+			} else {
+				require.NoError(t, err)
+				provisioner, ok := awspca.GetProvisioner(name)
+				require.True(t, ok)
+				assert.Equal(t, tc.arn, provisioner.(*awspca.PCAProvisioner).Arn())
+			}
+			assertIssuerHasReadyCondition(t, tc.expectedReadyConditionStatus, &iss.Status)
 
-			ctx := context.TODO()
+			var got issuerapi.AWSPCAIssuer
+			require.NoError(t, fakeClient.Get(context.TODO(), name, &got))
+			assert.Empty(t, got.Spec.Arn, "expected the resolved ARN not to be written to the issuer")
+		})
+	}
+}
 
-			if tc.kind == ClusterIssuer {
-				iss := new(issuerapi.AWSPCAClusterIssuer)
+func TestIssuerReconcileSharedCA(t *testing.T) {
+	stsServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/xml")
+		fmt.Fprint(w, `<GetCallerIdentityResponse xmlns="https://sts.amazonaws.com/doc/2011-06-15/">
+  <GetCallerIdentityResult>
+    <Arn>arn:aws:iam::111122223333:user/issuer</Arn>
+    <UserId>AIDAEXAMPLE</UserId>
+    <Account>111122223333</Account>
+  </GetCallerIdentityResult>
+  <ResponseMetadata>
+    <RequestId>fake-request-id</RequestId>
+  </ResponseMetadata>
+</GetCallerIdentityResponse>`)
+	}))
+	defer stsServer.Close()
+	isolateDefaultCredentialChain(t)
+	t.Setenv("AWS_ENDPOINT_URL_STS", stsServer.URL)
 
-				require.NoError(t, controller.Client.Get(ctx, tc.name, iss))
+	// The CA is owned by another account than the credentials, e.g. because
+	// it is shared through AWS RAM
+	const sharedArn = "arn:aws:acm-pca:us-east-1:444455556666:certificate-authority/12345678-1234-1234-1234-123456789012"
+	type testCase struct {
+		arn                          string
+		tags                         map[string]string
+		expectedError                error
+		expectedReadyConditionStatus metav1.ConditionStatus
+		expectedReason               string
+	}
+	tests := map[string]testCase{
+		"success-shared-ca": {
+			arn:                          sharedArn,
+			expectedReadyConditionStatus: metav1.ConditionTrue,
+			expectedReason:               "Verified",
+		},
+		"success-own-ca-with-tags": {
+			arn:                          "arn:aws:acm-pca:us-east-1:111122223333:certificate-authority/12345678-1234-1234-1234-123456789012",
+			tags:                         map[string]string{"team": "platform"},
+			expectedReadyConditionStatus: metav1.ConditionTrue,
+			expectedReason:               "Verified",
+		},
+		"failure-shared-ca-with-tags": {
+			arn:                          sharedArn,
+			tags:                         map[string]string{"team": "platform"},
+			expectedError:                errTagsOnSharedCA,
+			expectedReadyConditionStatus: metav1.ConditionFalse,
+			expectedReason:               "TagsOnSharedCA",
+		},
+	}
 
-				result, err = controller.Reconcile(ctx, reconcile.Request{NamespacedName: tc.name}, iss)
+	scheme := runtime.NewScheme()
+	require.NoError(t, issuerapi.AddToScheme(scheme))
+	require.NoError(t, v1.AddToScheme(scheme))
 
-				status = iss.Status
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			awspca.ClearProvisioners()
+			iss := &issuerapi.AWSPCAIssuer{
+				ObjectMeta: metav1.ObjectMeta{Name: "issuer1", Namespace: "ns1"},
+				Spec: issuerapi.AWSPCAIssuerSpec{
+					SecretRef: issuerapi.AWSCredentialsSecretReference{
+						SecretReference: v1.SecretReference{Name: "issuer1-shared-ca-credentials", Namespace: "ns1"},
+					},
+					Region: "us-east-1",
+					Arn:    tc.arn,
+					Tags:   tc.tags,
+				},
+			}
+			secret := &v1.Secret{
+				ObjectMeta: metav1.ObjectMeta{Name: "issuer1-shared-ca-credentials", Namespace: "ns1"},
+				Data: map[string][]byte{
+					"AWS_ACCESS_KEY_ID":     []byte("ZXhhbXBsZQ=="),
+					"AWS_SECRET_ACCESS_KEY": []byte("c2hhcmVkLWNh"),
+				},
+			}
+			fakeClient := fake.NewClientBuilder().
+				WithScheme(scheme).
+				WithObjects(iss, secret).
+				WithStatusSubresource(iss).
+				Build()
+			controller := GenericIssuerReconciler{
+				Client:            fakeClient,
+				Log:               logrtesting.NewTestLogger(t),
+				Scheme:            scheme,
+				Recorder:          record.NewFakeRecorder(10),
+				GetCallerIdentity: true,
+			}
+
+			name := types.NamespacedName{Namespace: "ns1", Name: "issuer1"}
+			_, err := controller.Reconcile(context.TODO(), reconcile.Request{NamespacedName: name}, iss)
+			if tc.expectedError != nil {
+				assert.ErrorIs(t, err, tc.expectedError)
+				assert.ErrorContains(t, err, "owned by account 444455556666, but the credentials are of account 111122223333")
 			} else {
-				iss := new(issuerapi.AWSPCAIssuer)
+				assert.NoError(t, err)
+			}
+			if condition := issuerCondition(iss, issuerapi.ConditionTypeReady); assert.NotNil(t, condition) {
+				assert.Equal(t, tc.expectedReadyConditionStatus, condition.Status)
+				assert.Equal(t, tc.expectedReason, condition.Reason)
+			}
+		})
+	}
+}
 
-				require.NoError(t, controller.Client.Get(ctx, tc.name, iss))
+func TestClusterIssuerReconcileAllowedCAArns(t *testing.T) {
+	const caArn = "arn:aws:acm-pca:us-east-1:111122223333:certificate-authority/12345678-1234-1234-1234-123456789012"
+	type testCase struct {
+		allowed                      []string
+		namespaced                   bool
+		expectedError                error
+		expectedReadyConditionStatus metav1.ConditionStatus
+		expectedReason               string
+	}
+	tests := map[string]testCase{
+		"success-no-allow-list": {
+			expectedReadyConditionStatus: metav1.ConditionTrue,
+			expectedReason:               "Verified",
+		},
+		"success-exact": {
+			allowed:                      []string{"arn:aws:acm-pca:us-east-1:444455556666:certificate-authority/other", caArn},
+			expectedReadyConditionStatus: metav1.ConditionTrue,
+			expectedReason:               "Verified",
+		},
+		"success-glob": {
+			allowed:                      []string{"arn:aws:acm-pca:*:111122223333:certificate-authority/*"},
+			expectedReadyConditionStatus: metav1.ConditionTrue,
+			expectedReason:               "Verified",
+		},
+		"success-issuer-not-restricted": {
+			allowed:                      []string{"arn:aws:acm-pca:*:444455556666:certificate-authority/*"},
+			namespaced:                   true,
+			expectedReadyConditionStatus: metav1.ConditionTrue,
+			expectedReason:               "Verified",
+		},
+		"failure-exact": {
+			allowed:                      []string{"arn:aws:acm-pca:us-east-1:111122223333:certificate-authority/other"},
+			expectedError:                errCANotAllowed,
+			expectedReadyConditionStatus: metav1.ConditionFalse,
+			expectedReason:               "CANotAllowed",
+		},
+		"failure-glob": {
+			allowed:                      []string{"arn:aws:acm-pca:*:444455556666:certificate-authority/*"},
+			expectedError:                errCANotAllowed,
+			expectedReadyConditionStatus: metav1.ConditionFalse,
+			expectedReason:               "CANotAllowed",
+		},
+	}
 
-				result, err = controller.Reconcile(ctx, reconcile.Request{NamespacedName: tc.name}, iss)
+	scheme := runtime.NewScheme()
+	require.NoError(t, issuerapi.AddToScheme(scheme))
+	require.NoError(t, v1.AddToScheme(scheme))
 
-				status = iss.Status
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			awspca.ClearProvisioners()
+			spec := issuerapi.AWSPCAIssuerSpec{
+				SecretRef: issuerapi.AWSCredentialsSecretReference{
+					SecretReference: v1.SecretReference{Name: "issuer1-credentials", Namespace: "ns1"},
+				},
+				Region: "us-east-1",
+				Arn:    caArn,
+			}
+			var iss issuerapi.GenericIssuer = &issuerapi.AWSPCAClusterIssuer{
+				ObjectMeta: metav1.ObjectMeta{Name: "issuer1"},
+				Spec:       spec,
+			}
+			if tc.namespaced {
+				iss = &issuerapi.AWSPCAIssuer{
+					ObjectMeta: metav1.ObjectMeta{Name: "issuer1", Namespace: "ns1"},
+					Spec:       spec,
+				}
+			}
+			secret := &v1.Secret{
+				ObjectMeta: metav1.ObjectMeta{Name: "issuer1-credentials", Namespace: "ns1"},
+				Data: map[string][]byte{
+					"AWS_ACCESS_KEY_ID":     []byte("ZXhhbXBsZQ=="),
+					"AWS_SECRET_ACCESS_KEY": []byte("ZXhhbXBsZQ=="),
+				},
+			}
+			fakeClient := fake.NewClientBuilder().
+				WithScheme(scheme).
+				WithObjects(iss, secret).
+				WithStatusSubresource(iss).
+				Build()
+			controller := GenericIssuerReconciler{
+				Client:        fakeClient,
+				Log:           logrtesting.NewTestLogger(t),
+				Scheme:        scheme,
+				Recorder:      record.NewFakeRecorder(10),
+				AllowedCAArns: tc.allowed,
 			}
 
+			_, err := controller.Reconcile(context.TODO(), reconcile.Request{NamespacedName: client.ObjectKeyFromObject(iss)}, iss)
 			if tc.expectedError != nil {
-				assertErrorIs(t, tc.expectedError, err)
+				assert.ErrorIs(t, err, tc.expectedError)
+				assert.ErrorContains(t, err, caArn+" does not match any of the allowed CA ARNs")
+				_, ok := awspca.GetProvisioner(client.ObjectKeyFromObject(iss))
+				assert.False(t, ok, "expected no provisioner for a disallowed CA")
 			} else {
 				assert.NoError(t, err)
 			}
+			if condition := issuerCondition(iss, issuerapi.ConditionTypeReady); assert.NotNil(t, condition) {
+				assert.Equal(t, tc.expectedReadyConditionStatus, condition.Status)
+				assert.Equal(t, tc.expectedReason, condition.Reason)
+			}
+		})
+	}
+}
 
-			assert.Equal(t, tc.expectedResult, result, "Unexpected result")
-
-			if tc.expectedReadyConditionStatus != "" {
-				assertIssuerHasReadyCondition(t, tc.expectedReadyConditionStatus, &status)
+func TestValidateAllowedCA(t *testing.T) {
+	const (
+		caArn       = "arn:aws:acm-pca:us-east-1:111122223333:certificate-authority/12345678-1234-1234-1234-123456789012"
+		failoverArn = "arn:aws:acm-pca:us-west-2:111122223333:certificate-authority/87654321-4321-4321-4321-210987654321"
+		otherArn    = "arn:aws:acm-pca:eu-west-1:444455556666:certificate-authority/11111111-2222-3333-4444-555555555555"
+	)
+	allowed := []string{caArn, "arn:aws:acm-pca:us-west-2:111122223333:certificate-authority/*"}
+	tests := map[string]struct {
+		spec          issuerapi.AWSPCAIssuerSpec
+		expectedError string
+	}{
+		"allowed-failover": {
+			spec: issuerapi.AWSPCAIssuerSpec{Arn: caArn, ArnFailover: []string{failoverArn}},
+		},
+		"allowed-regional": {
+			spec: issuerapi.AWSPCAIssuerSpec{Arn: caArn, RegionalArns: map[string]string{"us-west-2": failoverArn}},
+		},
+		"disallowed-failover": {
+			spec:          issuerapi.AWSPCAIssuerSpec{Arn: caArn, ArnFailover: []string{failoverArn, otherArn}},
+			expectedError: "the CA is not allowed for ClusterIssuers: " + otherArn + " does not match any of the allowed CA ARNs",
+		},
+		"disallowed-regional": {
+			spec:          issuerapi.AWSPCAIssuerSpec{Arn: caArn, RegionalArns: map[string]string{"eu-west-1": otherArn}},
+			expectedError: "the CA is not allowed for ClusterIssuers: " + otherArn + " does not match any of the allowed CA ARNs",
+		},
+	}
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			err := validateAllowedCA(&tc.spec, allowed)
+			if tc.expectedError != "" {
+				assert.ErrorIs(t, err, errCANotAllowed)
+				assert.EqualError(t, err, tc.expectedError)
+			} else {
+				assert.NoError(t, err)
 			}
 		})
 	}
 }
 
-func assertErrorIs(t *testing.T, expectedError, actualError error) {
-	if !assert.Error(t, actualError) {
-		return
+func TestIssuerReconcileObservedGeneration(t *testing.T) {
+	scheme := runtime.NewScheme()
+	require.NoError(t, issuerapi.AddToScheme(scheme))
+	require.NoError(t, v1.AddToScheme(scheme))
+
+	awspca.ClearProvisioners()
+	iss := &issuerapi.AWSPCAIssuer{
+		ObjectMeta: metav1.ObjectMeta{Name: "issuer1", Namespace: "ns1", Generation: 1},
+		Spec: issuerapi.AWSPCAIssuerSpec{
+			SecretRef: issuerapi.AWSCredentialsSecretReference{
+				SecretReference: v1.SecretReference{Name: "issuer1-credentials", Namespace: "ns1"},
+			},
+			Region: "us-east-1",
+			Arn:    "arn:aws:acm-pca:us-east-1:account:certificate-authority/12345678-1234-1234-1234-123456789012",
+		},
+	}
+	secret := &v1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "issuer1-credentials", Namespace: "ns1"},
+		Data: map[string][]byte{
+			"AWS_ACCESS_KEY_ID":     []byte("ZXhhbXBsZQ=="),
+			"AWS_SECRET_ACCESS_KEY": []byte("ZXhhbXBsZQ=="),
+		},
+	}
+	fakeClient := fake.NewClientBuilder().
+		WithScheme(scheme).
+		WithObjects(iss, secret).
+		WithStatusSubresource(iss).
+		Build()
+	controller := GenericIssuerReconciler{
+		Client:   fakeClient,
+		Log:      logrtesting.NewTestLogger(t),
+		Scheme:   scheme,
+		Recorder: record.NewFakeRecorder(10),
+	}
+
+	ctx := context.TODO()
+	name := types.NamespacedName{Namespace: "ns1", Name: "issuer1"}
+	_, err := controller.Reconcile(ctx, reconcile.Request{NamespacedName: name}, iss)
+	require.NoError(t, err)
+	if condition := issuerCondition(iss, issuerapi.ConditionTypeReady); assert.NotNil(t, condition) {
+		assert.Equal(t, metav1.ConditionTrue, condition.Status)
+		assert.Equal(t, int64(1), condition.ObservedGeneration)
+	}
+	assert.True(t, isReady(iss))
+
+	// A spec change bumps the generation, as the API server would, which the
+	// condition has not observed until the issuer is reconciled again
+	iss.Spec.Region = "us-west-2"
+	iss.Generation = 2
+	require.NoError(t, fakeClient.Update(ctx, iss))
+	assert.False(t, isReady(iss), "expected an issuer with a stale Ready condition not to be ready")
+	assert.True(t, isStale(iss))
+
+	_, err = controller.Reconcile(ctx, reconcile.Request{NamespacedName: name}, iss)
+	assert.ErrorIs(t, err, errArnRegionMismatch)
+	if condition := issuerCondition(iss, issuerapi.ConditionTypeReady); assert.NotNil(t, condition) {
+		assert.Equal(t, metav1.ConditionFalse, condition.Status)
+		assert.Equal(t, int64(2), condition.ObservedGeneration)
+	}
+
+	iss.Spec.Region = "us-east-1"
+	iss.Generation = 3
+	require.NoError(t, fakeClient.Update(ctx, iss))
+	_, err = controller.Reconcile(ctx, reconcile.Request{NamespacedName: name}, iss)
+	require.NoError(t, err)
+	if condition := issuerCondition(iss, issuerapi.ConditionTypeReady); assert.NotNil(t, condition) {
+		assert.Equal(t, metav1.ConditionTrue, condition.Status)
+		assert.Equal(t, int64(3), condition.ObservedGeneration)
+	}
+	assert.True(t, isReady(iss))
+	assert.False(t, isStale(iss))
+
+	var got issuerapi.AWSPCAIssuer
+	require.NoError(t, fakeClient.Get(ctx, name, &got))
+	if condition := issuerCondition(&got, issuerapi.ConditionTypeReady); assert.NotNil(t, condition) {
+		assert.Equal(t, int64(3), condition.ObservedGeneration, "expected the observed generation to be persisted")
 	}
-	assert.Equal(t, actualError, expectedError, "Errors do not match!")
 }
 
-func assertIssuerHasReadyCondition(t *testing.T, status metav1.ConditionStatus, issuerStatus *issuerapi.AWSPCAIssuerStatus) {
-	fmt.Printf("%v", issuerStatus.Conditions)
-	assert.Equal(t, status, issuerStatus.Conditions[0].Status, "unexpected condition status")
+func TestConditionReasons(t *testing.T) {
+	fset := token.NewFileSet()
+	types, err := parser.ParseFile(fset, filepath.Join("..", "api", "v1beta1", "awspcaissuer_types.go"), nil, 0)
+	require.NoError(t, err)
+
+	// Every reason is named after its value
+	reasons := map[string]bool{}
+	for _, decl := range types.Decls {
+		gen, ok := decl.(*ast.GenDecl)
+		if !ok || gen.Tok != token.CONST {
+			continue
+		}
+		for _, spec := range gen.Specs {
+			value := spec.(*ast.ValueSpec)
+			if ident, ok := value.Type.(*ast.Ident); !ok || (ident.Name != "ConditionReason" && ident.Name != "CertificateRequestReason") {
+				continue
+			}
+			reason, err := strconv.Unquote(value.Values[0].(*ast.BasicLit).Value)
+			require.NoError(t, err)
+			assert.Equal(t, "Reason"+reason, value.Names[0].Name)
+			assert.False(t, reasons[value.Names[0].Name], "duplicate reason %s", reason)
+			reasons[value.Names[0].Name] = true
+		}
+	}
+	require.NotEmpty(t, reasons)
+
+	// The reconcilers set the conditions, and record the events, of issuers
+	// and CertificateRequests only with the reason constants, and use every
+	// one of them
+	reasonArgs := map[string]int{"setStatus": 3, "setIssuerStatus": 6, "setConnected": 3, "SetIssuerCondition": 4}
+	used := map[string]bool{}
+	sources, err := filepath.Glob("*.go")
+	require.NoError(t, err)
+	for _, source := range sources {
+		if strings.HasSuffix(source, "_test.go") {
+			continue
+		}
+		file, err := parser.ParseFile(fset, source, nil, 0)
+		require.NoError(t, err)
+		ast.Inspect(file, func(n ast.Node) bool {
+			switch n := n.(type) {
+			case *ast.SelectorExpr:
+				if pkg, ok := n.X.(*ast.Ident); ok && pkg.Name == "api" && reasons[n.Sel.Name] {
+					used[n.Sel.Name] = true
+				}
+			case *ast.CallExpr:
+				var name string
+				switch fun := n.Fun.(type) {
+				case *ast.Ident:
+					name = fun.Name
+				case *ast.SelectorExpr:
+					name = fun.Sel.Name
+				}
+				if i, ok := reasonArgs[name]; ok && i < len(n.Args) {
+					_, literal := n.Args[i].(*ast.BasicLit)
+					assert.False(t, literal, "%s passes a string literal as the reason of a condition", fset.Position(n.Pos()))
+				}
+				// Events take the reason as a string, converted from a reason
+				// constant or a typed reason parameter
+				if (name == "Event" || name == "Eventf") && len(n.Args) > 2 {
+					typed := false
+					if conversion, ok := n.Args[2].(*ast.CallExpr); ok {
+						fun, ok := conversion.Fun.(*ast.Ident)
+						typed = ok && fun.Name == "string"
+					}
+					assert.True(t, typed, "%s passes an untyped reason to an event", fset.Position(n.Pos()))
+				}
+			}
+			return true
+		})
+	}
+	for reason := range reasons {
+		assert.True(t, used[reason], "reason %s is not used by the reconcilers", reason)
+	}
 }
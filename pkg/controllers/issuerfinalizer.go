@@ -0,0 +1,123 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+
+	api "github.com/cert-manager/aws-privateca-issuer/pkg/api/v1beta1"
+	awspca "github.com/cert-manager/aws-privateca-issuer/pkg/aws"
+	"github.com/go-logr/logr"
+	core "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+)
+
+const (
+	// issuerFinalizer keeps deleted issuers until the state the controller
+	// holds for them has been cleaned up
+	issuerFinalizer = "awspca.cert-manager.io/issuer-cleanup"
+
+	// untagOnDeleteAnnotation can be set to "true" on an issuer to remove its
+	// tags from the CA when it is deleted
+	untagOnDeleteAnnotation = "aws-privateca-issuer/untag-on-delete"
+)
+
+// caUntagger removes the tags of an issuer from its CA, see
+// awspca.PCAProvisioner.UntagCertificateAuthority
+type caUntagger interface {
+	UntagCertificateAuthority(ctx context.Context) error
+}
+
+// reconcileFinalizer adds the issuer finalizer to issuers if Finalize is set,
+// and removes it from them otherwise. Once an issuer with the finalizer is
+// deleted it is cleaned up before the finalizer is removed. It reports whether
+// the issuer is being deleted, in which case it is not reconciled any further.
+func (r *GenericIssuerReconciler) reconcileFinalizer(ctx context.Context, log logr.Logger, name types.NamespacedName, issuer api.GenericIssuer) (bool, error) {
+	finalized := controllerutil.ContainsFinalizer(issuer, issuerFinalizer)
+
+	if issuer.GetDeletionTimestamp().IsZero() {
+		switch {
+		case r.Finalize && !finalized:
+			controllerutil.AddFinalizer(issuer, issuerFinalizer)
+		case !r.Finalize && finalized:
+			controllerutil.RemoveFinalizer(issuer, issuerFinalizer)
+		default:
+			return false, nil
+		}
+		return false, r.Client.Update(ctx, issuer)
+	}
+
+	if !finalized {
+		return true, nil
+	}
+	r.cleanup(ctx, log, name, issuer)
+
+	controllerutil.RemoveFinalizer(issuer, issuerFinalizer)
+	return true, client.IgnoreNotFound(r.Client.Update(ctx, issuer))
+}
+
+// cleanup drops the cached provisioner and AWS client, the issuance rate limit
+// and quota counters, the credentials Secret age and the CA health of a deleted
+// issuer. With the untag on delete annotation its tags, including the
+// DefaultTags, are removed from the CA first. Failing to untag
+// the CA is reported in an event, but does not block the deletion, e.g. when
+// the credentials of the issuer were deleted with it.
+func (r *GenericIssuerReconciler) cleanup(ctx context.Context, log logr.Logger, name types.NamespacedName, issuer api.GenericIssuer) {
+	if issuer.GetAnnotations()[untagOnDeleteAnnotation] == "true" && len(r.withDefaultTags(issuer.GetSpec()).Tags) > 0 {
+		if err := r.untag(ctx, name, issuer); err != nil {
+			log.Error(err, "failed to remove the tags of the issuer from its CA")
+			r.Recorder.Eventf(issuer, core.EventTypeWarning, string(api.ReasonUntagFailed), "Failed to remove tags from the certificate authority: %v", err)
+		} else {
+			r.Recorder.Event(issuer, core.EventTypeNormal, string(api.ReasonUntagged), "Removed tags from the certificate authority")
+		}
+	}
+
+	awspca.InvalidateProvisioner(name)
+	if r.IssuanceLimiters != nil {
+		r.IssuanceLimiters.forget(name)
+	}
+	if r.IssuanceCounters != nil {
+		r.IssuanceCounters.forget(name)
+	}
+	if r.CACommonNames != nil {
+		r.CACommonNames.forget(name)
+	}
+	credentialsSecretAge.forget(name)
+	if r.CAHealth != nil {
+		r.CAHealth.forget(name)
+	}
+	log.Info("Cleaned up deleted issuer")
+}
+
+// untag removes the tags of the issuer from its CA, building its provisioner
+// if none is cached
+func (r *GenericIssuerReconciler) untag(ctx context.Context, name types.NamespacedName, issuer api.GenericIssuer) error {
+	provisioner, ok := awspca.GetProvisioner(name)
+	if !ok {
+		var err error
+		if provisioner, err = r.LoadProvisioner(ctx, name, issuer); err != nil {
+			return err
+		}
+	}
+	untagger, ok := provisioner.(caUntagger)
+	if !ok {
+		return nil
+	}
+	return untagger.UntagCertificateAuthority(ctx)
+}
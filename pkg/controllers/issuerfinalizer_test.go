@@ -0,0 +1,204 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	logrtesting "github.com/go-logr/logr/testing"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	v1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	issuerapi "github.com/cert-manager/aws-privateca-issuer/pkg/api/v1beta1"
+	awspca "github.com/cert-manager/aws-privateca-issuer/pkg/aws"
+)
+
+func TestIssuerReconcileFinalizer(t *testing.T) {
+	type testCase struct {
+		finalize           bool
+		finalizers         []string
+		annotations        map[string]string
+		tags               map[string]string
+		untagErr           error
+		expectedFinalizer  bool
+		expectedUntagCalls int
+		expectedEvent      string
+	}
+
+	tests := map[string]testCase{
+		"disabled": {},
+		"disabled-removes-finalizer": {
+			finalizers: []string{issuerFinalizer},
+		},
+		"cleanup": {
+			finalize:          true,
+			tags:              map[string]string{"team": "platform"},
+			expectedFinalizer: true,
+		},
+		"untag": {
+			finalize:           true,
+			annotations:        map[string]string{untagOnDeleteAnnotation: "true"},
+			tags:               map[string]string{"team": "platform"},
+			expectedFinalizer:  true,
+			expectedUntagCalls: 1,
+			expectedEvent:      "Normal " + string(issuerapi.ReasonUntagged),
+		},
+		"untag-without-tags": {
+			finalize:          true,
+			annotations:       map[string]string{untagOnDeleteAnnotation: "true"},
+			expectedFinalizer: true,
+		},
+		"untag-failed": {
+			finalize:           true,
+			annotations:        map[string]string{untagOnDeleteAnnotation: "true"},
+			tags:               map[string]string{"team": "platform"},
+			untagErr:           errors.New("AccessDeniedException"),
+			expectedFinalizer:  true,
+			expectedUntagCalls: 1,
+			expectedEvent:      "Warning " + string(issuerapi.ReasonUntagFailed),
+		},
+	}
+
+	scheme := runtime.NewScheme()
+	require.NoError(t, issuerapi.AddToScheme(scheme))
+	require.NoError(t, v1.AddToScheme(scheme))
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			awspca.ClearProvisioners()
+			iss := &issuerapi.AWSPCAIssuer{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:        "issuer1",
+					Namespace:   "ns1",
+					Annotations: tc.annotations,
+					Finalizers:  tc.finalizers,
+				},
+				Spec: issuerapi.AWSPCAIssuerSpec{
+					SecretRef: issuerapi.AWSCredentialsSecretReference{
+						SecretReference: v1.SecretReference{Name: "issuer1-credentials", Namespace: "ns1"},
+					},
+					Region: "us-east-1",
+					Arn:    "arn:aws:acm-pca:us-east-1:account:certificate-authority/12345678-1234-1234-1234-123456789012",
+					Tags:   tc.tags,
+				},
+			}
+			secret := &v1.Secret{
+				ObjectMeta: metav1.ObjectMeta{Name: "issuer1-credentials", Namespace: "ns1"},
+				Data: map[string][]byte{
+					"AWS_ACCESS_KEY_ID":     []byte("ZXhhbXBsZQ=="),
+					"AWS_SECRET_ACCESS_KEY": []byte("ZXhhbXBsZQ=="),
+				},
+			}
+			fakeClient := fake.NewClientBuilder().
+				WithScheme(scheme).
+				WithObjects(iss, secret).
+				WithStatusSubresource(iss).
+				Build()
+			recorder := record.NewFakeRecorder(10)
+			limiters := &IssuanceLimiters{}
+			counters := &IssuanceCounters{}
+			names := &CACommonNames{}
+			controller := GenericIssuerReconciler{
+				Client:           fakeClient,
+				Log:              logrtesting.NewTestLogger(t),
+				Scheme:           scheme,
+				Recorder:         recorder,
+				Finalize:         tc.finalize,
+				IssuanceLimiters: limiters,
+				IssuanceCounters: counters,
+				CACommonNames:    names,
+			}
+
+			ctx := context.TODO()
+			issuerName := types.NamespacedName{Namespace: "ns1", Name: "issuer1"}
+			_, err := controller.Reconcile(ctx, reconcile.Request{NamespacedName: issuerName}, iss)
+			require.NoError(t, err)
+			require.NoError(t, fakeClient.Get(ctx, issuerName, iss))
+			assert.Equal(t, tc.expectedFinalizer, controllerutil.ContainsFinalizer(iss, issuerFinalizer))
+			assert.True(t, isReady(iss))
+
+			provisioner := &fakeProvisioner{untagErr: tc.untagErr}
+			awspca.StoreProvisioner(issuerName, provisioner)
+			counters.count(issuerName, time.Hour, time.Now())
+			names.names.Store(issuerName, caCommonName{commonName: "Example CA"})
+			limiters.delay(issuerName, 1, time.Now())
+
+			require.NoError(t, fakeClient.Delete(ctx, iss))
+			if !tc.expectedFinalizer {
+				assert.True(t, apierrors.IsNotFound(fakeClient.Get(ctx, issuerName, iss)), "expected the issuer to be deleted without a finalizer")
+				return
+			}
+			require.NoError(t, fakeClient.Get(ctx, issuerName, iss))
+			require.False(t, iss.DeletionTimestamp.IsZero())
+
+			for len(recorder.Events) > 0 {
+				<-recorder.Events
+			}
+			_, err = controller.Reconcile(ctx, reconcile.Request{NamespacedName: issuerName}, iss)
+			require.NoError(t, err)
+			assert.True(t, apierrors.IsNotFound(fakeClient.Get(ctx, issuerName, iss)), "expected the issuer to be deleted once cleaned up")
+
+			assert.Equal(t, tc.expectedUntagCalls, provisioner.untagCalls)
+			_, cached := awspca.GetProvisioner(issuerName)
+			assert.False(t, cached, "expected the provisioner to be invalidated")
+			_, counted := counters.counters.Load(issuerName)
+			assert.False(t, counted, "expected the issuance counter to be dropped")
+			_, limited := limiters.limiters.Load(issuerName)
+			assert.False(t, limited, "expected the issuance rate limiter to be dropped")
+			_, named := names.names.Load(issuerName)
+			assert.False(t, named, "expected the CA common name to be dropped")
+
+			var events []string
+			for len(recorder.Events) > 0 {
+				events = append(events, <-recorder.Events)
+			}
+			if tc.expectedEvent == "" {
+				assert.Empty(t, events)
+			} else if assert.Len(t, events, 1) {
+				assert.True(t, strings.HasPrefix(events[0], tc.expectedEvent), "unexpected event %q", events[0])
+			}
+		})
+	}
+}
+
+func TestIssuerCleanupCAHealth(t *testing.T) {
+	name := types.NamespacedName{Namespace: "ns1", Name: "issuer1"}
+	checker := &CAHealthChecker{unhealthy: map[types.NamespacedName]caHealth{name: {reason: issuerapi.ReasonCANotActive}}}
+	controller := GenericIssuerReconciler{
+		Log:      logrtesting.NewTestLogger(t),
+		Recorder: record.NewFakeRecorder(10),
+		CAHealth: checker,
+	}
+
+	controller.cleanup(context.TODO(), controller.Log, name, &issuerapi.AWSPCAIssuer{})
+	_, unhealthy := checker.Unhealthy(name)
+	assert.False(t, unhealthy)
+	assert.NoError(t, checker.Check(nil))
+}
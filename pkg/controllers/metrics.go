@@ -0,0 +1,154 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"errors"
+	"runtime"
+	"sync"
+	"time"
+
+	acmpcatypes "github.com/aws/aws-sdk-go-v2/service/acmpca/types"
+	"github.com/aws/smithy-go"
+	"github.com/prometheus/client_golang/prometheus"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+
+	"github.com/cert-manager/aws-privateca-issuer/pkg/api/injections"
+)
+
+const (
+	resultIssued  = "issued"
+	resultFailed  = "failed"
+	resultPending = "pending"
+)
+
+const (
+	operationSign = "Sign"
+	operationGet  = "Get"
+)
+
+var (
+	certificateRequestsTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "awspca_certificate_requests_total",
+		Help: "Number of CertificateRequests reconciled, partitioned by issuer and result.",
+	}, []string{"issuer_namespace", "issuer_name", "result"})
+
+	issuanceDurationSeconds = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "awspca_certificate_issuance_duration_seconds",
+		Help:    "Time from requesting a certificate from PCA until the issued certificate is retrieved.",
+		Buckets: prometheus.ExponentialBuckets(0.25, 2, 12),
+	}, []string{"issuer_namespace", "issuer_name"})
+
+	apiErrorsTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "awspca_api_errors_total",
+		Help: "Number of errors returned by ACM PCA, partitioned by issuer, provisioner operation and AWS error code.",
+	}, []string{"issuer_namespace", "issuer_name", "operation", "error_code"})
+
+	buildInfo = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "awspca_build_info",
+		Help: "Build information of the controller, always 1.",
+	}, []string{"version", "git_commit", "go_version"})
+
+	credentialsSecretAge = &credentialsAgeCollector{
+		desc: prometheus.NewDesc(
+			"awspca_credentials_secret_age_seconds",
+			"Time since the credentials Secret of an issuer was last rotated, per its rotation annotation or metadata.",
+			[]string{"issuer_namespace", "issuer_name"}, nil,
+		),
+	}
+)
+
+// signTimes records when a certificate was requested from PCA for a
+// CertificateRequest, as the certificate is retrieved in a later reconcile
+var signTimes sync.Map
+
+func init() {
+	// Registering with the controller-runtime registry exposes the metrics on
+	// the manager's metrics endpoint.
+	metrics.Registry.MustRegister(certificateRequestsTotal, issuanceDurationSeconds, apiErrorsTotal, buildInfo, credentialsSecretAge)
+	buildInfo.WithLabelValues(injections.PlugInVersion, injections.GitCommit, runtime.Version()).Set(1)
+}
+
+func recordCertificateRequestResult(issuer types.NamespacedName, result string) {
+	certificateRequestsTotal.WithLabelValues(issuer.Namespace, issuer.Name, result).Inc()
+}
+
+func markSigned(cr types.NamespacedName, at time.Time) {
+	signTimes.Store(cr, at)
+}
+
+func forgetSigned(cr types.NamespacedName) {
+	signTimes.Delete(cr)
+}
+
+// observeIssuanceDuration records the time from the request of the certificate
+// of cr until now. Nothing is recorded if the request was made by another
+// process.
+func observeIssuanceDuration(issuer, cr types.NamespacedName, now time.Time) {
+	at, ok := signTimes.LoadAndDelete(cr)
+	if !ok {
+		return
+	}
+	issuanceDurationSeconds.WithLabelValues(issuer.Namespace, issuer.Name).Observe(now.Sub(at.(time.Time)).Seconds())
+}
+
+// recordAPIError counts err by its AWS error code if it is an error returned by
+// the ACM PCA API. Certificates that are still being issued are not counted, as
+// Get returns a RequestInProgressException for every poll.
+func recordAPIError(issuer types.NamespacedName, operation string, err error) {
+	var apiErr smithy.APIError
+	if !errors.As(err, &apiErr) {
+		return
+	}
+	var inProgress *acmpcatypes.RequestInProgressException
+	if errors.As(err, &inProgress) {
+		return
+	}
+	apiErrorsTotal.WithLabelValues(issuer.Namespace, issuer.Name, operation, apiErr.ErrorCode()).Inc()
+}
+
+// credentialsAgeCollector reports the age of the credentials Secret of each
+// issuer at the time of the scrape, so that it keeps growing between the
+// reconciles of the issuer that record when the Secret was rotated
+type credentialsAgeCollector struct {
+	desc *prometheus.Desc
+	// rotated holds the rotation time of the credentials Secret of each
+	// issuer by its name
+	rotated sync.Map
+}
+
+func (c *credentialsAgeCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.desc
+}
+
+func (c *credentialsAgeCollector) Collect(ch chan<- prometheus.Metric) {
+	now := time.Now()
+	c.rotated.Range(func(key, value interface{}) bool {
+		issuer := key.(types.NamespacedName)
+		ch <- prometheus.MustNewConstMetric(c.desc, prometheus.GaugeValue, now.Sub(value.(time.Time)).Seconds(), issuer.Namespace, issuer.Name)
+		return true
+	})
+}
+
+func (c *credentialsAgeCollector) record(issuer types.NamespacedName, rotated time.Time) {
+	c.rotated.Store(issuer, rotated)
+}
+
+func (c *credentialsAgeCollector) forget(issuer types.NamespacedName) {
+	c.rotated.Delete(issuer)
+}
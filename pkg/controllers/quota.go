@@ -0,0 +1,115 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	api "github.com/cert-manager/aws-privateca-issuer/pkg/api/v1beta1"
+	"github.com/cert-manager/aws-privateca-issuer/pkg/util"
+	"github.com/go-logr/logr"
+	core "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/util/retry"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// defaultIssuanceQuotaWindow is the period over which issuances are counted
+// against the IssuanceQuotaThreshold if no window is configured
+const defaultIssuanceQuotaWindow = 24 * time.Hour
+
+// IssuanceCounters holds the issuanceCounter of each issuer. It is shared by
+// the CertificateRequestReconciler, which counts issuances, and the
+// GenericIssuerReconciler, which drops the counters of deleted issuers.
+type IssuanceCounters struct {
+	counters sync.Map
+}
+
+// issuanceCounter counts the certificates issued for an issuer since the start
+// of the current window
+type issuanceCounter struct {
+	mu    sync.Mutex
+	start time.Time
+	count int
+}
+
+// count counts a certificate issued for issuer at now and returns the number
+// of certificates issued in the current window, including it. A new window
+// starts once window elapsed since the start of the current one.
+func (c *IssuanceCounters) count(issuer types.NamespacedName, window time.Duration, now time.Time) int {
+	value, _ := c.counters.LoadOrStore(issuer, &issuanceCounter{start: now})
+	counter := value.(*issuanceCounter)
+	counter.mu.Lock()
+	defer counter.mu.Unlock()
+
+	if now.Sub(counter.start) >= window {
+		counter.start, counter.count = now, 0
+	}
+	counter.count++
+	return counter.count
+}
+
+// forget drops the counter of issuer
+func (c *IssuanceCounters) forget(issuer types.NamespacedName) {
+	c.counters.Delete(issuer)
+}
+
+// recordIssuanceQuota counts a certificate issued for iss and sets its
+// IssuanceQuota condition to whether the IssuanceQuotaThreshold was reached in
+// the current window. A Warning event is emitted when the threshold is
+// crossed. The status of iss is updated only if the condition changed.
+func (r *CertificateRequestReconciler) recordIssuanceQuota(ctx context.Context, log logr.Logger, issuer types.NamespacedName, iss api.GenericIssuer) error {
+	if r.IssuanceQuotaThreshold <= 0 || r.IssuanceCounters == nil {
+		return nil
+	}
+	window := r.IssuanceQuotaWindow
+	if window <= 0 {
+		window = defaultIssuanceQuotaWindow
+	}
+	count := r.IssuanceCounters.count(issuer, window, r.now())
+	status, reason := metav1.ConditionFalse, api.ReasonWithinIssuanceQuota
+	message := fmt.Sprintf("%d certificates issued in the last %s, below the threshold of %d", count, window, r.IssuanceQuotaThreshold)
+	if count >= r.IssuanceQuotaThreshold {
+		status, reason = metav1.ConditionTrue, api.ReasonIssuanceQuotaApproaching
+		message = fmt.Sprintf("%d certificates issued in the last %s, reaching the threshold of %d; consider requesting an increase of the PCA quotas", count, window, r.IssuanceQuotaThreshold)
+	}
+	if count == r.IssuanceQuotaThreshold {
+		log.Info("Issuance quota threshold reached", "count", count, "window", window)
+		r.Recorder.Event(iss, core.EventTypeWarning, string(api.ReasonIssuanceQuotaApproaching), message)
+	}
+
+	return retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		if c := issuerCondition(iss, api.ConditionTypeIssuanceQuota); c != nil && c.Status == status {
+			return nil
+		}
+		util.SetIssuerCondition(log, iss, api.ConditionTypeIssuanceQuota, status, reason, message)
+
+		err := r.Client.Status().Update(ctx, iss)
+		if !errors.IsConflict(err) {
+			return err
+		}
+		if err := r.Client.Get(ctx, client.ObjectKeyFromObject(iss), iss); err != nil {
+			return err
+		}
+		return err
+	})
+}
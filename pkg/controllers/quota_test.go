@@ -0,0 +1,194 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	cmapi "github.com/cert-manager/cert-manager/pkg/apis/certmanager/v1"
+	cmmeta "github.com/cert-manager/cert-manager/pkg/apis/meta/v1"
+	cmgen "github.com/cert-manager/cert-manager/test/unit/gen"
+	logrtesting "github.com/go-logr/logr/testing"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
+	clocktesting "k8s.io/utils/clock/testing"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	issuerapi "github.com/cert-manager/aws-privateca-issuer/pkg/api/v1beta1"
+	awspca "github.com/cert-manager/aws-privateca-issuer/pkg/aws"
+)
+
+func TestCountIssuance(t *testing.T) {
+	counters := &IssuanceCounters{}
+	issuer := types.NamespacedName{Namespace: "ns1", Name: "count-issuance"}
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	for i := 1; i <= 3; i++ {
+		assert.Equal(t, i, counters.count(issuer, time.Hour, now), "issuance %d", i)
+		now = now.Add(20 * time.Minute)
+	}
+
+	// A new window starts once the window elapsed since the first issuance
+	assert.Equal(t, 1, counters.count(issuer, time.Hour, now))
+	assert.Equal(t, 2, counters.count(issuer, time.Hour, now.Add(59*time.Minute)))
+	assert.Equal(t, 1, counters.count(issuer, time.Hour, now.Add(time.Hour)))
+
+	// Other issuers are counted separately
+	other := types.NamespacedName{Namespace: "ns2", Name: "count-issuance"}
+	assert.Equal(t, 1, counters.count(other, time.Hour, now))
+}
+
+func TestCertificateRequestReconcileIssuanceQuota(t *testing.T) {
+	scheme := runtime.NewScheme()
+	require.NoError(t, issuerapi.AddToScheme(scheme))
+	require.NoError(t, cmapi.AddToScheme(scheme))
+
+	objects := []client.Object{
+		&issuerapi.AWSPCAIssuer{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "issuance-quota",
+				Namespace: "ns1",
+			},
+			Status: issuerapi.AWSPCAIssuerStatus{
+				Conditions: []metav1.Condition{
+					{
+						Type:   issuerapi.ConditionTypeReady,
+						Status: metav1.ConditionTrue,
+					},
+				},
+			},
+		},
+	}
+	for i := 1; i <= 5; i++ {
+		objects = append(objects, cmgen.CertificateRequest(
+			fmt.Sprintf("cr%d", i),
+			cmgen.SetCertificateRequestNamespace("ns1"),
+			cmgen.SetCertificateRequestIssuer(cmmeta.ObjectReference{
+				Name:  "issuance-quota",
+				Group: issuerapi.GroupVersion.Group,
+				Kind:  "Issuer",
+			}),
+		))
+	}
+	fakeClient := fake.NewClientBuilder().
+		WithScheme(scheme).
+		WithObjects(objects...).
+		WithStatusSubresource(objects...).
+		Build()
+
+	fakeClock := clocktesting.NewFakeClock(time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC))
+	recorder := record.NewFakeRecorder(10)
+	controller := CertificateRequestReconciler{
+		Client:                 fakeClient,
+		Log:                    logrtesting.NewTestLogger(t),
+		Scheme:                 scheme,
+		Recorder:               recorder,
+		Clock:                  fakeClock,
+		IssuanceQuotaThreshold: 2,
+		IssuanceQuotaWindow:    time.Hour,
+		IssuanceCounters:       &IssuanceCounters{},
+	}
+
+	ctx := context.TODO()
+	issuerName := types.NamespacedName{Namespace: "ns1", Name: "issuance-quota"}
+	awspca.StoreProvisioner(issuerName, &fakeProvisioner{caCert: []byte("cacert"), cert: []byte("cert")})
+	issue := func(name string) {
+		_, err := controller.Reconcile(ctx, reconcile.Request{NamespacedName: types.NamespacedName{Namespace: "ns1", Name: name}})
+		require.NoError(t, err)
+	}
+	quotaCondition := func() *metav1.Condition {
+		var iss issuerapi.AWSPCAIssuer
+		require.NoError(t, fakeClient.Get(ctx, issuerName, &iss))
+		return issuerCondition(&iss, issuerapi.ConditionTypeIssuanceQuota)
+	}
+	quotaEvents := func() int {
+		count := 0
+		for len(recorder.Events) > 0 {
+			if event := <-recorder.Events; event == "Warning "+string(issuerapi.ReasonIssuanceQuotaApproaching)+" "+
+				"2 certificates issued in the last 1h0m0s, reaching the threshold of 2; consider requesting an increase of the PCA quotas" {
+				count++
+			}
+		}
+		return count
+	}
+
+	issue("cr1")
+	require.NotNil(t, quotaCondition())
+	assert.Equal(t, metav1.ConditionFalse, quotaCondition().Status)
+	assert.Equal(t, string(issuerapi.ReasonWithinIssuanceQuota), quotaCondition().Reason)
+	assert.Zero(t, quotaEvents())
+
+	// Crossing the threshold emits a Warning event once
+	issue("cr2")
+	assert.Equal(t, metav1.ConditionTrue, quotaCondition().Status)
+	assert.Equal(t, string(issuerapi.ReasonIssuanceQuotaApproaching), quotaCondition().Reason)
+	assert.Equal(t, 1, quotaEvents())
+
+	issue("cr3")
+	assert.Equal(t, metav1.ConditionTrue, quotaCondition().Status)
+	assert.Zero(t, quotaEvents())
+
+	// The condition is reset in a new window
+	fakeClock.Step(time.Hour)
+	issue("cr4")
+	assert.Equal(t, metav1.ConditionFalse, quotaCondition().Status)
+	assert.Zero(t, quotaEvents())
+
+	// The threshold is crossed again in the new window
+	issue("cr5")
+	assert.Equal(t, metav1.ConditionTrue, quotaCondition().Status)
+	assert.Equal(t, 1, quotaEvents())
+}
+
+func TestRecordIssuanceQuotaConflict(t *testing.T) {
+	scheme := runtime.NewScheme()
+	require.NoError(t, issuerapi.AddToScheme(scheme))
+
+	iss := &issuerapi.AWSPCAIssuer{ObjectMeta: metav1.ObjectMeta{Name: "issuance-quota", Namespace: "ns1"}}
+	fakeClient := fake.NewClientBuilder().WithScheme(scheme).WithObjects(iss).WithStatusSubresource(iss).Build()
+	controller := CertificateRequestReconciler{
+		Client:                 fakeClient,
+		Recorder:               record.NewFakeRecorder(10),
+		IssuanceQuotaThreshold: 1,
+		IssuanceCounters:       &IssuanceCounters{},
+	}
+
+	// Another client updates the issuer after the controller read it
+	ctx := context.TODO()
+	issuerName := client.ObjectKeyFromObject(iss)
+	stale := &issuerapi.AWSPCAIssuer{}
+	require.NoError(t, fakeClient.Get(ctx, issuerName, stale))
+	metav1.SetMetaDataLabel(&iss.ObjectMeta, "updated-by", "someone-else")
+	require.NoError(t, fakeClient.Update(ctx, iss))
+
+	require.NoError(t, controller.recordIssuanceQuota(ctx, logrtesting.NewTestLogger(t), issuerName, stale))
+
+	var latest issuerapi.AWSPCAIssuer
+	require.NoError(t, fakeClient.Get(ctx, issuerName, &latest))
+	require.NotNil(t, issuerCondition(&latest, issuerapi.ConditionTypeIssuanceQuota))
+	assert.Equal(t, metav1.ConditionTrue, issuerCondition(&latest, issuerapi.ConditionTypeIssuanceQuota).Status)
+	assert.Equal(t, "someone-else", latest.Labels["updated-by"])
+}
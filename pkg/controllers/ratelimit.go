@@ -0,0 +1,81 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"strconv"
+	"sync"
+	"time"
+
+	api "github.com/cert-manager/aws-privateca-issuer/pkg/api/v1beta1"
+	"github.com/go-logr/logr"
+	"golang.org/x/time/rate"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+// issuanceRateLimitAnnotation can be set on an issuer to the number of
+// certificates per second it may request from PCA, overriding the
+// IssuanceRateLimit of the controller. "0" disables rate limiting.
+const issuanceRateLimitAnnotation = "aws-privateca-issuer/issuance-rate-limit"
+
+// IssuanceLimiters holds the token bucket of each issuer. It is shared by the
+// CertificateRequestReconciler, which takes tokens from it, and the
+// GenericIssuerReconciler, which drops the buckets of deleted issuers.
+type IssuanceLimiters struct {
+	limiters sync.Map
+}
+
+// issuanceRateLimit returns the number of certificates per second iss may
+// request, or zero if it is not limited
+func (r *CertificateRequestReconciler) issuanceRateLimit(iss api.GenericIssuer, log logr.Logger) float64 {
+	value, ok := iss.GetAnnotations()[issuanceRateLimitAnnotation]
+	if !ok {
+		return r.IssuanceRateLimit
+	}
+	limit, err := strconv.ParseFloat(value, 64)
+	if err != nil || limit < 0 {
+		log.Info("ignoring invalid issuance rate limit of issuer", "annotation", issuanceRateLimitAnnotation, "value", value)
+		return r.IssuanceRateLimit
+	}
+	return limit
+}
+
+// delay takes a token from the bucket of issuer, which holds up to one
+// second of requests and refills at limit per second. It returns zero if a
+// token was available, and otherwise how long to wait until one is, without
+// taking it.
+func (l *IssuanceLimiters) delay(issuer types.NamespacedName, limit float64, now time.Time) time.Duration {
+	burst := max(int(limit), 1)
+	value, _ := l.limiters.LoadOrStore(issuer, rate.NewLimiter(rate.Limit(limit), burst))
+	limiter := value.(*rate.Limiter)
+	if limiter.Limit() != rate.Limit(limit) {
+		limiter.SetLimitAt(now, rate.Limit(limit))
+		limiter.SetBurstAt(now, burst)
+	}
+
+	reservation := limiter.ReserveN(now, 1)
+	delay := reservation.DelayFrom(now)
+	if delay > 0 {
+		reservation.CancelAt(now)
+	}
+	return delay
+}
+
+// forget drops the bucket of issuer
+func (l *IssuanceLimiters) forget(issuer types.NamespacedName) {
+	l.limiters.Delete(issuer)
+}
@@ -0,0 +1,187 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	cmapi "github.com/cert-manager/cert-manager/pkg/apis/certmanager/v1"
+	cmmeta "github.com/cert-manager/cert-manager/pkg/apis/meta/v1"
+	cmgen "github.com/cert-manager/cert-manager/test/unit/gen"
+	logrtesting "github.com/go-logr/logr/testing"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
+	clocktesting "k8s.io/utils/clock/testing"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	issuerapi "github.com/cert-manager/aws-privateca-issuer/pkg/api/v1beta1"
+	awspca "github.com/cert-manager/aws-privateca-issuer/pkg/aws"
+)
+
+func TestIssuanceDelay(t *testing.T) {
+	issuer := types.NamespacedName{Namespace: "ns1", Name: "issuance-delay"}
+	limiters := &IssuanceLimiters{}
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	// The bucket holds a second of requests
+	for i := 0; i < 2; i++ {
+		assert.Zero(t, limiters.delay(issuer, 2, now), "request %d", i)
+	}
+	assert.Equal(t, 500*time.Millisecond, limiters.delay(issuer, 2, now))
+	// Deferred requests do not take a token
+	assert.Equal(t, 500*time.Millisecond, limiters.delay(issuer, 2, now))
+
+	// The bucket refills at the rate limit
+	now = now.Add(250 * time.Millisecond)
+	assert.Equal(t, 250*time.Millisecond, limiters.delay(issuer, 2, now))
+	now = now.Add(250 * time.Millisecond)
+	assert.Zero(t, limiters.delay(issuer, 2, now))
+	assert.Equal(t, 500*time.Millisecond, limiters.delay(issuer, 2, now))
+
+	// Changing the limit applies to the existing bucket
+	assert.Equal(t, 2*time.Second, limiters.delay(issuer, 0.5, now))
+}
+
+func TestCertificateRequestReconcileIssuanceRateLimit(t *testing.T) {
+	type testCase struct {
+		issuerAnnotations map[string]string
+		rateLimit         float64
+		expectedDelay     time.Duration
+	}
+
+	tests := map[string]testCase{
+		"controller-limit": {
+			rateLimit:     1,
+			expectedDelay: time.Second,
+		},
+		"issuer-annotation": {
+			issuerAnnotations: map[string]string{issuanceRateLimitAnnotation: "0.25"},
+			rateLimit:         1,
+			expectedDelay:     4 * time.Second,
+		},
+		"issuer-annotation-unlimited": {
+			issuerAnnotations: map[string]string{issuanceRateLimitAnnotation: "0"},
+			rateLimit:         1,
+		},
+		"invalid-issuer-annotation": {
+			issuerAnnotations: map[string]string{issuanceRateLimitAnnotation: "fast"},
+			rateLimit:         1,
+			expectedDelay:     time.Second,
+		},
+		"unlimited": {},
+	}
+
+	scheme := runtime.NewScheme()
+	require.NoError(t, issuerapi.AddToScheme(scheme))
+	require.NoError(t, cmapi.AddToScheme(scheme))
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			issuerName := types.NamespacedName{Namespace: "ns1", Name: "issuer1"}
+
+			newCertificateRequest := func(name string) *cmapi.CertificateRequest {
+				return cmgen.CertificateRequest(
+					name,
+					cmgen.SetCertificateRequestNamespace("ns1"),
+					cmgen.SetCertificateRequestIssuer(cmmeta.ObjectReference{
+						Name:  "issuer1",
+						Group: issuerapi.GroupVersion.Group,
+						Kind:  "Issuer",
+					}),
+				)
+			}
+			objects := []client.Object{
+				newCertificateRequest("cr1"),
+				newCertificateRequest("cr2"),
+				&issuerapi.AWSPCAIssuer{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:        "issuer1",
+						Namespace:   "ns1",
+						Annotations: tc.issuerAnnotations,
+					},
+					Status: issuerapi.AWSPCAIssuerStatus{
+						Conditions: []metav1.Condition{
+							{
+								Type:   issuerapi.ConditionTypeReady,
+								Status: metav1.ConditionTrue,
+							},
+						},
+					},
+				},
+			}
+
+			fakeClient := fake.NewClientBuilder().
+				WithScheme(scheme).
+				WithObjects(objects...).
+				WithStatusSubresource(objects...).
+				Build()
+			fakeClock := clocktesting.NewFakeClock(time.Now())
+			controller := CertificateRequestReconciler{
+				Client:            fakeClient,
+				Log:               logrtesting.NewTestLogger(t),
+				Scheme:            scheme,
+				Recorder:          record.NewFakeRecorder(10),
+				Clock:             fakeClock,
+				IssuanceRateLimit: tc.rateLimit,
+				IssuanceLimiters:  &IssuanceLimiters{},
+			}
+			provisioner := &fakeProvisioner{caCert: []byte("cacert"), cert: []byte("cert")}
+			awspca.StoreProvisioner(issuerName, provisioner)
+
+			ctx := context.TODO()
+			first := types.NamespacedName{Namespace: "ns1", Name: "cr1"}
+			second := types.NamespacedName{Namespace: "ns1", Name: "cr2"}
+			var cr cmapi.CertificateRequest
+
+			result, err := controller.Reconcile(ctx, reconcile.Request{NamespacedName: first})
+			require.NoError(t, err)
+			assert.Zero(t, result.RequeueAfter)
+			assert.Equal(t, 1, provisioner.signCalls)
+
+			// The second request is deferred without calling PCA
+			result, err = controller.Reconcile(ctx, reconcile.Request{NamespacedName: second})
+			require.NoError(t, err)
+			require.NoError(t, fakeClient.Get(ctx, second, &cr))
+			if tc.expectedDelay == 0 {
+				assert.Zero(t, result.RequeueAfter)
+				assert.Equal(t, 2, provisioner.signCalls)
+				assertCertificateRequestHasReadyCondition(t, cmmeta.ConditionTrue, cmapi.CertificateRequestReasonIssued, &cr)
+				return
+			}
+			assert.Equal(t, tc.expectedDelay, result.RequeueAfter)
+			assert.Equal(t, 1, provisioner.signCalls)
+			assertCertificateRequestHasReadyCondition(t, cmmeta.ConditionFalse, cmapi.CertificateRequestReasonPending, &cr)
+
+			// Once the bucket refilled the request is signed
+			fakeClock.Step(tc.expectedDelay)
+			result, err = controller.Reconcile(ctx, reconcile.Request{NamespacedName: second})
+			require.NoError(t, err)
+			assert.Zero(t, result.RequeueAfter)
+			assert.Equal(t, 2, provisioner.signCalls)
+			require.NoError(t, fakeClient.Get(ctx, second, &cr))
+			assertCertificateRequestHasReadyCondition(t, cmmeta.ConditionTrue, cmapi.CertificateRequestReasonIssued, &cr)
+		})
+	}
+}
@@ -0,0 +1,70 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+
+	api "github.com/cert-manager/aws-privateca-issuer/pkg/api/v1beta1"
+	"github.com/cert-manager/aws-privateca-issuer/pkg/aws"
+	cmapi "github.com/cert-manager/cert-manager/pkg/apis/certmanager/v1"
+	"github.com/go-logr/logr"
+	core "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// forceReissueProcessedAnnotation records the last force reissue nonce of a
+// CertificateRequest that was acted on, so that each nonce reissues the
+// certificate only once
+const forceReissueProcessedAnnotation = "aws-privateca-issuer/force-reissue-processed"
+
+// reconcileForceReissue resets CertificateRequests whose force reissue nonce
+// differs from the processed one. The certificate, CA, failure time and Ready
+// condition are removed from the status, and the annotations recording the
+// previous certificate are dropped, so that the rest of the reconcile signs it
+// again. The nonce is recorded once the status has been reset.
+func (r *CertificateRequestReconciler) reconcileForceReissue(ctx context.Context, log logr.Logger, cr *cmapi.CertificateRequest) error {
+	nonce := cr.GetAnnotations()[aws.ForceReissueAnnotation]
+	if nonce == "" || nonce == cr.GetAnnotations()[forceReissueProcessedAnnotation] {
+		return nil
+	}
+
+	log.Info("Forcing reissue of certificate", "nonce", nonce)
+	cr.Status.Certificate = nil
+	cr.Status.CA = nil
+	cr.Status.FailureTime = nil
+	conditions := cr.Status.Conditions[:0]
+	for _, condition := range cr.Status.Conditions {
+		if condition.Type != cmapi.CertificateRequestConditionReady {
+			conditions = append(conditions, condition)
+		}
+	}
+	cr.Status.Conditions = conditions
+	if err := r.Client.Status().Update(ctx, cr); err != nil {
+		return err
+	}
+
+	for _, key := range []string{aws.CertificateArnKey(r.CertificateArnAnnotation), aws.CertificateArnAnnotation, aws.CAArnAnnotation, aws.ValidityClampedAnnotation, requeueAttemptsAnnotation, reissueAttemptsAnnotation, failureAttemptsAnnotation, serialNumberAnnotation, caCommonNameAnnotation} {
+		delete(cr.Annotations, key)
+	}
+	metav1.SetMetaDataAnnotation(&cr.ObjectMeta, forceReissueProcessedAnnotation, nonce)
+	forgetSigned(client.ObjectKeyFromObject(cr))
+	r.Recorder.Eventf(cr, core.EventTypeNormal, string(api.ReasonForceReissue), "Reissuing certificate for force reissue nonce %q", nonce)
+
+	return r.Client.Update(ctx, cr)
+}
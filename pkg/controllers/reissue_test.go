@@ -0,0 +1,181 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"testing"
+
+	cmapi "github.com/cert-manager/cert-manager/pkg/apis/certmanager/v1"
+	cmmeta "github.com/cert-manager/cert-manager/pkg/apis/meta/v1"
+	cmgen "github.com/cert-manager/cert-manager/test/unit/gen"
+	logrtesting "github.com/go-logr/logr/testing"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	issuerapi "github.com/cert-manager/aws-privateca-issuer/pkg/api/v1beta1"
+	awspca "github.com/cert-manager/aws-privateca-issuer/pkg/aws"
+)
+
+func TestCertificateRequestReconcileForceReissue(t *testing.T) {
+	type testCase struct {
+		annotations         map[string]string
+		condition           cmapi.CertificateRequestCondition
+		expectedSignCalls   int
+		expectedCertificate string
+		expectedProcessed   string
+	}
+	issued := cmapi.CertificateRequestCondition{
+		Type:   cmapi.CertificateRequestConditionReady,
+		Status: cmmeta.ConditionTrue,
+		Reason: cmapi.CertificateRequestReasonIssued,
+	}
+	tests := map[string]testCase{
+		"no-nonce": {
+			annotations:         map[string]string{awspca.CertificateArnAnnotation: "old-arn"},
+			condition:           issued,
+			expectedCertificate: "old-cert",
+		},
+		"stable-nonce": {
+			annotations: map[string]string{
+				awspca.CertificateArnAnnotation: "old-arn",
+				awspca.ForceReissueAnnotation:   "1",
+				forceReissueProcessedAnnotation: "1",
+			},
+			condition:           issued,
+			expectedCertificate: "old-cert",
+			expectedProcessed:   "1",
+		},
+		"changed-nonce": {
+			annotations: map[string]string{
+				awspca.CertificateArnAnnotation: "old-arn",
+				serialNumberAnnotation:          "0a",
+				awspca.ForceReissueAnnotation:   "2",
+				forceReissueProcessedAnnotation: "1",
+			},
+			condition:           issued,
+			expectedSignCalls:   1,
+			expectedCertificate: "new-cert",
+			expectedProcessed:   "2",
+		},
+		"first-nonce": {
+			annotations: map[string]string{
+				awspca.CertificateArnAnnotation: "old-arn",
+				awspca.ForceReissueAnnotation:   "1",
+			},
+			condition:           issued,
+			expectedSignCalls:   1,
+			expectedCertificate: "new-cert",
+			expectedProcessed:   "1",
+		},
+		"changed-nonce-failed": {
+			annotations: map[string]string{
+				awspca.ForceReissueAnnotation:   "2",
+				forceReissueProcessedAnnotation: "1",
+			},
+			condition: cmapi.CertificateRequestCondition{
+				Type:   cmapi.CertificateRequestConditionReady,
+				Status: cmmeta.ConditionFalse,
+				Reason: cmapi.CertificateRequestReasonFailed,
+			},
+			expectedSignCalls:   1,
+			expectedCertificate: "new-cert",
+			expectedProcessed:   "2",
+		},
+	}
+
+	scheme := runtime.NewScheme()
+	require.NoError(t, issuerapi.AddToScheme(scheme))
+	require.NoError(t, cmapi.AddToScheme(scheme))
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			cr := cmgen.CertificateRequest(
+				"cr1",
+				cmgen.SetCertificateRequestNamespace("ns1"),
+				cmgen.SetCertificateRequestAnnotations(tc.annotations),
+				cmgen.SetCertificateRequestIssuer(cmmeta.ObjectReference{
+					Name:  "issuer1",
+					Group: issuerapi.GroupVersion.Group,
+					Kind:  "Issuer",
+				}),
+				cmgen.SetCertificateRequestStatusCondition(tc.condition),
+			)
+			if tc.condition.Status == cmmeta.ConditionTrue {
+				cr.Status.Certificate = []byte("old-cert")
+				cr.Status.CA = []byte("old-ca")
+			}
+			objects := []client.Object{
+				cr,
+				&issuerapi.AWSPCAIssuer{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:      "issuer1",
+						Namespace: "ns1",
+					},
+					Status: issuerapi.AWSPCAIssuerStatus{
+						Conditions: []metav1.Condition{
+							{
+								Type:   issuerapi.ConditionTypeReady,
+								Status: metav1.ConditionTrue,
+							},
+						},
+					},
+				},
+			}
+			fakeClient := fake.NewClientBuilder().
+				WithScheme(scheme).
+				WithObjects(objects...).
+				WithStatusSubresource(objects...).
+				Build()
+			controller := CertificateRequestReconciler{
+				Client:   fakeClient,
+				Log:      logrtesting.NewTestLogger(t),
+				Scheme:   scheme,
+				Recorder: record.NewFakeRecorder(10),
+			}
+			provisioner := &fakeProvisioner{cert: []byte("new-cert"), caCert: []byte("new-ca")}
+			awspca.StoreProvisioner(types.NamespacedName{Namespace: "ns1", Name: "issuer1"}, provisioner)
+
+			ctx := context.TODO()
+			name := types.NamespacedName{Namespace: "ns1", Name: "cr1"}
+			_, err := controller.Reconcile(ctx, reconcile.Request{NamespacedName: name})
+			require.NoError(t, err)
+			// A stable nonce is not processed again
+			_, err = controller.Reconcile(ctx, reconcile.Request{NamespacedName: name})
+			require.NoError(t, err)
+
+			var got cmapi.CertificateRequest
+			require.NoError(t, fakeClient.Get(ctx, name, &got))
+			assert.Equal(t, tc.expectedSignCalls, provisioner.signCalls)
+			assert.Equal(t, tc.expectedCertificate, string(got.Status.Certificate))
+			assert.Equal(t, tc.expectedProcessed, got.Annotations[forceReissueProcessedAnnotation])
+			assertCertificateRequestHasReadyCondition(t, cmmeta.ConditionTrue, cmapi.CertificateRequestReasonIssued, &got)
+			if tc.expectedSignCalls > 0 {
+				assert.Equal(t, "arn", provisioner.getCertArn, "expected the new certificate to be retrieved")
+				assert.Equal(t, "arn", got.Annotations[awspca.CertificateArnAnnotation])
+				assert.Nil(t, got.Status.FailureTime)
+			}
+		})
+	}
+}
@@ -0,0 +1,116 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+
+	api "github.com/cert-manager/aws-privateca-issuer/pkg/api/v1beta1"
+	"github.com/cert-manager/aws-privateca-issuer/pkg/aws"
+	"github.com/cert-manager/aws-privateca-issuer/pkg/util"
+	cmapi "github.com/cert-manager/cert-manager/pkg/apis/certmanager/v1"
+	"github.com/go-logr/logr"
+	core "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+)
+
+const (
+	// revokeOnDeleteAnnotation can be set to "true" on a CertificateRequest
+	// to revoke its certificate when the CertificateRequest, or the
+	// Certificate owning it, is deleted
+	revokeOnDeleteAnnotation = "aws-privateca-issuer/revoke-on-delete"
+
+	// revocationReasonAnnotation selects the PCA revocation reason of
+	// certificates revoked on deletion, defaulting to UNSPECIFIED
+	revocationReasonAnnotation = "aws-privateca-issuer/revocation-reason"
+
+	// revokeFinalizer keeps CertificateRequests that are revoked on deletion
+	// until their certificate has been revoked
+	revokeFinalizer = "awspca.cert-manager.io/revoke-on-delete"
+)
+
+// reconcileRevocation adds the revoke finalizer to CertificateRequests with the
+// revoke on delete annotation, and removes it from those without. Once such a
+// CertificateRequest is deleted its certificate is revoked before the finalizer
+// is removed. It reports whether the CertificateRequest is being deleted, in
+// which case it is not reconciled any further.
+func (r *CertificateRequestReconciler) reconcileRevocation(ctx context.Context, log logr.Logger, cr *cmapi.CertificateRequest, issuerName types.NamespacedName) (bool, error) {
+	revoke := cr.GetAnnotations()[revokeOnDeleteAnnotation] == "true"
+	finalized := controllerutil.ContainsFinalizer(cr, revokeFinalizer)
+
+	if cr.DeletionTimestamp.IsZero() {
+		switch {
+		case revoke && !finalized:
+			controllerutil.AddFinalizer(cr, revokeFinalizer)
+		case !revoke && finalized:
+			controllerutil.RemoveFinalizer(cr, revokeFinalizer)
+		default:
+			return false, nil
+		}
+		return false, r.Client.Update(ctx, cr)
+	}
+
+	if !finalized {
+		return true, nil
+	}
+	if revoke {
+		if err := r.revoke(ctx, log, cr, issuerName); err != nil {
+			log.Error(err, "failed to revoke certificate")
+			r.Recorder.Eventf(cr, core.EventTypeWarning, string(api.ReasonRevocationFailed), "Failed to revoke certificate: %v", err)
+			return true, err
+		}
+	}
+
+	controllerutil.RemoveFinalizer(cr, revokeFinalizer)
+	return true, client.IgnoreNotFound(r.Client.Update(ctx, cr))
+}
+
+// revoke revokes the certificate of cr at PCA. CertificateRequests without a
+// certificate have nothing to revoke.
+func (r *CertificateRequestReconciler) revoke(ctx context.Context, log logr.Logger, cr *cmapi.CertificateRequest, issuerName types.NamespacedName) error {
+	if len(cr.Status.Certificate) == 0 {
+		log.Info("CertificateRequest has no certificate to revoke")
+		return nil
+	}
+	serial, err := aws.CertificateSerialNumber(cr.Status.Certificate)
+	if err != nil {
+		log.Error(err, "failed to parse the certificate, it cannot be revoked")
+		return nil
+	}
+	reason, err := aws.ParseRevocationReason(cr.GetAnnotations()[revocationReasonAnnotation])
+	if err != nil {
+		return fmt.Errorf("annotation %s: %w", revocationReasonAnnotation, err)
+	}
+
+	iss, err := util.GetIssuer(ctx, r.Client, issuerName)
+	if err != nil {
+		return err
+	}
+	provisioner, ok := r.provisioner(ctx, log, issuerName, iss)
+	if !ok {
+		return fmt.Errorf("provisioner for %s not found", issuerName)
+	}
+
+	if err := provisioner.Revoke(ctx, cr, serial, reason, log); err != nil {
+		return err
+	}
+	r.Recorder.Eventf(cr, core.EventTypeNormal, string(api.ReasonRevoked), "Revoked certificate %s with reason %s", serial, reason)
+	return nil
+}
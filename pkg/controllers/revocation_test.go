@@ -0,0 +1,221 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"errors"
+	"math/big"
+	"strings"
+	"testing"
+	"time"
+
+	acmpcatypes "github.com/aws/aws-sdk-go-v2/service/acmpca/types"
+	cmapi "github.com/cert-manager/cert-manager/pkg/apis/certmanager/v1"
+	cmmeta "github.com/cert-manager/cert-manager/pkg/apis/meta/v1"
+	cmgen "github.com/cert-manager/cert-manager/test/unit/gen"
+	logrtesting "github.com/go-logr/logr/testing"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	core "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	issuerapi "github.com/cert-manager/aws-privateca-issuer/pkg/api/v1beta1"
+	awspca "github.com/cert-manager/aws-privateca-issuer/pkg/aws"
+)
+
+func TestCertificateRequestReconcileRevokeOnDelete(t *testing.T) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(0x0a1b2c3d4e),
+		Subject:      pkix.Name{CommonName: "example.com"},
+		NotBefore:    time.Now(),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	require.NoError(t, err)
+	certPem := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+	revokeErr := errors.New("the CA has no CRL or OCSP configured")
+
+	type testCase struct {
+		annotations          map[string]string
+		removeAnnotation     bool
+		revokeErr            error
+		expectedFinalizer    bool
+		expectedRevokeCalls  int
+		expectedReason       acmpcatypes.RevocationReason
+		expectedError        error
+		expectedDeleted      bool
+		expectedFailureEvent bool
+	}
+
+	tests := map[string]testCase{
+		"revokes-on-delete": {
+			annotations:         map[string]string{revokeOnDeleteAnnotation: "true", revocationReasonAnnotation: "KEY_COMPROMISE"},
+			expectedFinalizer:   true,
+			expectedRevokeCalls: 1,
+			expectedReason:      acmpcatypes.RevocationReasonKeyCompromise,
+			expectedDeleted:     true,
+		},
+		"revokes-on-delete-default-reason": {
+			annotations:         map[string]string{revokeOnDeleteAnnotation: "true"},
+			expectedFinalizer:   true,
+			expectedRevokeCalls: 1,
+			expectedReason:      acmpcatypes.RevocationReasonUnspecified,
+			expectedDeleted:     true,
+		},
+		"not-annotated": {
+			expectedDeleted: true,
+		},
+		"annotation-removed": {
+			annotations:       map[string]string{revokeOnDeleteAnnotation: "true"},
+			removeAnnotation:  true,
+			expectedFinalizer: true,
+			expectedDeleted:   true,
+		},
+		"revoke-failed": {
+			annotations:          map[string]string{revokeOnDeleteAnnotation: "true"},
+			revokeErr:            revokeErr,
+			expectedFinalizer:    true,
+			expectedRevokeCalls:  1,
+			expectedReason:       acmpcatypes.RevocationReasonUnspecified,
+			expectedError:        revokeErr,
+			expectedFailureEvent: true,
+		},
+		"invalid-reason": {
+			annotations:          map[string]string{revokeOnDeleteAnnotation: "true", revocationReasonAnnotation: "STOLEN"},
+			expectedFinalizer:    true,
+			expectedError:        awspca.ErrInvalidRevocationReason,
+			expectedFailureEvent: true,
+		},
+	}
+
+	scheme := runtime.NewScheme()
+	require.NoError(t, issuerapi.AddToScheme(scheme))
+	require.NoError(t, cmapi.AddToScheme(scheme))
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			objects := []client.Object{
+				cmgen.CertificateRequest(
+					"cr1",
+					cmgen.SetCertificateRequestNamespace("ns1"),
+					cmgen.AddCertificateRequestAnnotations(tc.annotations),
+					cmgen.SetCertificateRequestIssuer(cmmeta.ObjectReference{
+						Name:  "issuer1",
+						Group: issuerapi.GroupVersion.Group,
+						Kind:  "Issuer",
+					}),
+				),
+				&issuerapi.AWSPCAIssuer{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:      "issuer1",
+						Namespace: "ns1",
+					},
+					Status: issuerapi.AWSPCAIssuerStatus{
+						Conditions: []metav1.Condition{
+							{
+								Type:   issuerapi.ConditionTypeReady,
+								Status: metav1.ConditionTrue,
+							},
+						},
+					},
+				},
+			}
+
+			fakeClient := fake.NewClientBuilder().
+				WithScheme(scheme).
+				WithObjects(objects...).
+				WithStatusSubresource(objects...).
+				Build()
+			recorder := record.NewFakeRecorder(10)
+			controller := CertificateRequestReconciler{
+				Client:   fakeClient,
+				Log:      logrtesting.NewTestLogger(t),
+				Scheme:   scheme,
+				Recorder: recorder,
+			}
+			provisioner := &fakeProvisioner{caCert: []byte("cacert"), cert: certPem, revokeErr: tc.revokeErr}
+			awspca.StoreProvisioner(types.NamespacedName{Namespace: "ns1", Name: "issuer1"}, provisioner)
+
+			ctx := context.TODO()
+			name := types.NamespacedName{Namespace: "ns1", Name: "cr1"}
+			var cr cmapi.CertificateRequest
+
+			// The finalizer is added while the certificate is issued
+			_, err := controller.Reconcile(ctx, reconcile.Request{NamespacedName: name})
+			require.NoError(t, err)
+			require.NoError(t, fakeClient.Get(ctx, name, &cr))
+			assertCertificateRequestHasReadyCondition(t, cmmeta.ConditionTrue, cmapi.CertificateRequestReasonIssued, &cr)
+			assert.Equal(t, tc.expectedFinalizer, controllerutil.ContainsFinalizer(&cr, revokeFinalizer))
+
+			if tc.removeAnnotation {
+				delete(cr.Annotations, revokeOnDeleteAnnotation)
+				require.NoError(t, fakeClient.Update(ctx, &cr))
+				_, err = controller.Reconcile(ctx, reconcile.Request{NamespacedName: name})
+				require.NoError(t, err)
+				require.NoError(t, fakeClient.Get(ctx, name, &cr))
+				assert.False(t, controllerutil.ContainsFinalizer(&cr, revokeFinalizer), "expected the finalizer to be removed with the annotation")
+			}
+
+			require.NoError(t, fakeClient.Delete(ctx, &cr))
+			_, err = controller.Reconcile(ctx, reconcile.Request{NamespacedName: name})
+			if tc.expectedError != nil {
+				assert.ErrorIs(t, err, tc.expectedError)
+			} else {
+				assert.NoError(t, err)
+			}
+
+			assert.Equal(t, tc.expectedRevokeCalls, provisioner.revokeCalls)
+			if tc.expectedRevokeCalls > 0 {
+				assert.Equal(t, "0a:1b:2c:3d:4e", provisioner.revokeSerial)
+				assert.Equal(t, tc.expectedReason, provisioner.revokeReason)
+			}
+
+			err = fakeClient.Get(ctx, name, &cr)
+			if tc.expectedDeleted {
+				assert.True(t, apierrors.IsNotFound(err), "expected the CertificateRequest to be deleted")
+			} else {
+				require.NoError(t, err)
+				assert.True(t, controllerutil.ContainsFinalizer(&cr, revokeFinalizer), "expected the finalizer to be kept until the certificate is revoked")
+			}
+
+			failureEvent := false
+			for len(recorder.Events) > 0 {
+				if strings.HasPrefix(<-recorder.Events, core.EventTypeWarning+" "+string(issuerapi.ReasonRevocationFailed)) {
+					failureEvent = true
+				}
+			}
+			assert.Equal(t, tc.expectedFailureEvent, failureEvent)
+		})
+	}
+}
@@ -46,13 +46,15 @@ func GetIssuer(ctx context.Context, client client.Client, name types.NamespacedN
 	return iss, nil
 }
 
-// SetIssuerCondition sets the ready state of an issuer and updates it in the cluster
-func SetIssuerCondition(log logr.Logger, issuer api.GenericIssuer, conditionType string, status metav1.ConditionStatus, reason, message string) {
+// SetIssuerCondition sets the ready state of an issuer and updates it in the cluster.
+// The condition records the generation of the issuer it was observed for.
+func SetIssuerCondition(log logr.Logger, issuer api.GenericIssuer, conditionType string, status metav1.ConditionStatus, reason api.ConditionReason, message string) {
 	newCondition := metav1.Condition{
-		Type:    conditionType,
-		Status:  status,
-		Reason:  reason,
-		Message: message,
+		Type:               conditionType,
+		Status:             status,
+		ObservedGeneration: issuer.GetGeneration(),
+		Reason:             string(reason),
+		Message:            message,
 	}
 
 	now := metav1.NewTime(realtimeClock.Now())
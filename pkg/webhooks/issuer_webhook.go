@@ -0,0 +1,266 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package webhooks
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+
+	api "github.com/cert-manager/aws-privateca-issuer/pkg/api/v1beta1"
+	awspca "github.com/cert-manager/aws-privateca-issuer/pkg/aws"
+)
+
+// +kubebuilder:webhook:path=/validate-awspca-cert-manager-io-v1beta1-awspcaissuer,mutating=false,failurePolicy=fail,sideEffects=None,groups=awspca.cert-manager.io,resources=awspcaissuers,verbs=create;update,versions=v1beta1,name=vawspcaissuer.awspca.cert-manager.io,admissionReviewVersions=v1
+// +kubebuilder:webhook:path=/validate-awspca-cert-manager-io-v1beta1-awspcaclusterissuer,mutating=false,failurePolicy=fail,sideEffects=None,groups=awspca.cert-manager.io,resources=awspcaclusterissuers,verbs=create;update,versions=v1beta1,name=vawspcaclusterissuer.awspca.cert-manager.io,admissionReviewVersions=v1
+
+// IssuerValidator rejects AWSPCAIssuers and AWSPCAClusterIssuers whose spec
+// could never be used to issue certificates at admission, instead of only
+// marking them not Ready once they are reconciled
+type IssuerValidator struct{}
+
+var _ admission.CustomValidator = &IssuerValidator{}
+
+// SetupWithManager registers the validating webhooks of both issuer kinds
+func (v *IssuerValidator) SetupWithManager(mgr ctrl.Manager) error {
+	for _, issuer := range []runtime.Object{&api.AWSPCAIssuer{}, &api.AWSPCAClusterIssuer{}} {
+		if err := ctrl.NewWebhookManagedBy(mgr).For(issuer).WithValidator(v).Complete(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ValidateCreate validates the spec of a new issuer
+func (v *IssuerValidator) ValidateCreate(ctx context.Context, obj runtime.Object) (admission.Warnings, error) {
+	return nil, Validate(obj)
+}
+
+// ValidateUpdate validates the spec of an updated issuer
+func (v *IssuerValidator) ValidateUpdate(ctx context.Context, oldObj, newObj runtime.Object) (admission.Warnings, error) {
+	return nil, Validate(newObj)
+}
+
+// ValidateDelete allows all issuers to be deleted
+func (v *IssuerValidator) ValidateDelete(ctx context.Context, obj runtime.Object) (admission.Warnings, error) {
+	return nil, nil
+}
+
+// Validate returns an Invalid error listing the problems of the spec of an
+// AWSPCAIssuer or AWSPCAClusterIssuer. It only checks the object itself, so it
+// can also be used without access to Kubernetes or AWS.
+func Validate(obj runtime.Object) error {
+	var kind string
+	switch obj.(type) {
+	case *api.AWSPCAIssuer:
+		kind = "AWSPCAIssuer"
+	case *api.AWSPCAClusterIssuer:
+		kind = "AWSPCAClusterIssuer"
+	default:
+		return fmt.Errorf("expected an AWSPCAIssuer or AWSPCAClusterIssuer, got %T", obj)
+	}
+	issuer := obj.(api.GenericIssuer)
+
+	_, clusterScoped := obj.(*api.AWSPCAClusterIssuer)
+	errs := validateSpec(issuer.GetSpec(), field.NewPath("spec"), clusterScoped)
+	if len(errs) == 0 {
+		return nil
+	}
+	return apierrors.NewInvalid(api.GroupVersion.WithKind(kind).GroupKind(), issuer.GetName(), errs)
+}
+
+// validateSpec checks that the CA ARN is well-formed and in the issuer region,
+// that the policy names valid domains and namespaces, that passed through
+// extensions are well-formed, and that the credential source is unambiguous.
+// The credentials Secrets of cluster scoped issuers default to the cluster
+// resource namespace of the controller, so they need not name a namespace.
+func validateSpec(spec *api.AWSPCAIssuerSpec, path *field.Path, clusterScoped bool) field.ErrorList {
+	var errs field.ErrorList
+
+	arnPath := path.Child("arn")
+	if spec.ArnFrom != nil {
+		// The referenced ARN is validated when the issuer is reconciled
+		errs = append(errs, validateArnFrom(spec, path.Child("arnFrom"))...)
+	} else if spec.Arn == "" {
+		errs = append(errs, field.Required(arnPath, "the ARN of the PCA certificate authority is required"))
+	} else if _, region, _, _, err := awspca.ParseCertificateAuthorityARN(spec.Arn); err != nil {
+		errs = append(errs, field.Invalid(arnPath, spec.Arn, err.Error()))
+	} else if spec.Region != "" && region != spec.Region {
+		errs = append(errs, field.Invalid(path.Child("region"), spec.Region, fmt.Sprintf("must match the region %s of the CA ARN", region)))
+	}
+
+	for i, failoverArn := range spec.ArnFailover {
+		if _, _, _, _, err := awspca.ParseCertificateAuthorityARN(failoverArn); err != nil {
+			errs = append(errs, field.Invalid(path.Child("arnFailover").Index(i), failoverArn, err.Error()))
+		}
+	}
+	for region, regionalArn := range spec.RegionalArns {
+		if _, caRegion, _, _, err := awspca.ParseCertificateAuthorityARN(regionalArn); err != nil {
+			errs = append(errs, field.Invalid(path.Child("regionalArns").Key(region), regionalArn, err.Error()))
+		} else if caRegion != region {
+			errs = append(errs, field.Invalid(path.Child("regionalArns").Key(region), regionalArn, fmt.Sprintf("must be a CA in region %s", region)))
+		}
+	}
+
+	for i, domain := range spec.AllowedDomains {
+		name := strings.TrimSuffix(strings.TrimPrefix(strings.ToLower(domain), "*."), ".")
+		if msgs := validation.IsDNS1123Subdomain(name); len(msgs) > 0 {
+			errs = append(errs, field.Invalid(path.Child("allowedDomains").Index(i), domain, strings.Join(msgs, "; ")))
+		}
+	}
+	for i, namespace := range spec.AllowedNamespaces {
+		if msgs := validation.IsDNS1123Label(namespace); len(msgs) > 0 {
+			errs = append(errs, field.Invalid(path.Child("allowedNamespaces").Index(i), namespace, strings.Join(msgs, "; ")))
+		}
+	}
+
+	if spec.PathLength != nil && !awspca.ValidPathLength(*spec.PathLength) {
+		errs = append(errs, field.Invalid(path.Child("pathLength"), *spec.PathLength, "must be between 0 and 3"))
+	}
+
+	if spec.APIPassthrough != nil {
+		errs = append(errs, validateAPIPassthrough(spec.APIPassthrough, path.Child("apiPassthrough"))...)
+	}
+
+	if spec.RolesAnywhere != nil {
+		errs = append(errs, validateRolesAnywhere(spec, path.Child("rolesAnywhere"), clusterScoped)...)
+	}
+
+	// A profile only selects credentials of the default credential chain
+	if spec.Profile != "" && spec.SecretRef.Name != "" {
+		errs = append(errs, field.Forbidden(path.Child("profile"), "cannot be combined with the access key of secretRef"))
+	}
+	if spec.Profile != "" && spec.RolesAnywhere != nil {
+		errs = append(errs, field.Forbidden(path.Child("profile"), "cannot be combined with rolesAnywhere"))
+	}
+
+	return append(errs, validateCredentials(&spec.SecretRef, path.Child("secretRef"), clusterScoped)...)
+}
+
+// validateRolesAnywhere checks that the Secret of the client certificate for
+// IAM Roles Anywhere is fully referenced, and not mixed with an access key
+func validateRolesAnywhere(spec *api.AWSPCAIssuerSpec, path *field.Path, clusterScoped bool) field.ErrorList {
+	var errs field.ErrorList
+
+	if spec.SecretRef.Name != "" {
+		errs = append(errs, field.Forbidden(path, "cannot be combined with the access key of secretRef"))
+	}
+	ref := spec.RolesAnywhere.SecretRef
+	if ref.Name == "" {
+		errs = append(errs, field.Required(path.Child("secretRef", "name"), "the name of the client certificate Secret is required"))
+	}
+	if ref.Namespace == "" && !clusterScoped {
+		errs = append(errs, field.Required(path.Child("secretRef", "namespace"), "the namespace of the client certificate Secret is required"))
+	}
+	return errs
+}
+
+// validateArnFrom checks that the ARN of the issuer is referenced by exactly
+// one ConfigMap or Secret key
+func validateArnFrom(spec *api.AWSPCAIssuerSpec, path *field.Path) field.ErrorList {
+	var errs field.ErrorList
+
+	if spec.Arn != "" {
+		errs = append(errs, field.Forbidden(path, "cannot be combined with arn"))
+	}
+	from := spec.ArnFrom
+	if (from.ConfigMapKeyRef == nil) == (from.SecretKeyRef == nil) {
+		return append(errs, field.Invalid(path, "", "exactly one of configMapKeyRef and secretKeyRef must be set"))
+	}
+	ref, refPath := from.ConfigMapKeyRef, path.Child("configMapKeyRef")
+	if ref == nil {
+		ref, refPath = from.SecretKeyRef, path.Child("secretKeyRef")
+	}
+	for _, f := range []struct{ name, value string }{{"name", ref.Name}, {"namespace", ref.Namespace}, {"key", ref.Key}} {
+		if f.value == "" {
+			errs = append(errs, field.Required(refPath.Child(f.name), ""))
+		}
+	}
+	return errs
+}
+
+// validateAPIPassthrough checks the OIDs and values of the extensions the
+// issuer passes through to PCA
+func validateAPIPassthrough(passthrough *api.AWSPCAAPIPassthrough, path *field.Path) field.ErrorList {
+	var errs field.ErrorList
+
+	policies := map[string]bool{}
+	for i, oid := range passthrough.CertificatePolicies {
+		policyPath := path.Child("certificatePolicies").Index(i)
+		if err := awspca.ValidateObjectIdentifier(oid); err != nil {
+			errs = append(errs, field.Invalid(policyPath, oid, err.Error()))
+		} else if policies[oid] {
+			errs = append(errs, field.Duplicate(policyPath, oid))
+		}
+		policies[oid] = true
+	}
+
+	extensions := map[string]bool{}
+	for i, ext := range passthrough.CustomExtensions {
+		extPath := path.Child("customExtensions").Index(i)
+		if err := awspca.ValidateObjectIdentifier(ext.ObjectIdentifier); err != nil {
+			errs = append(errs, field.Invalid(extPath.Child("objectIdentifier"), ext.ObjectIdentifier, err.Error()))
+		} else if extensions[ext.ObjectIdentifier] {
+			errs = append(errs, field.Duplicate(extPath.Child("objectIdentifier"), ext.ObjectIdentifier))
+		}
+		extensions[ext.ObjectIdentifier] = true
+		if err := awspca.ValidateExtensionValue(ext.Value); err != nil {
+			errs = append(errs, field.Invalid(extPath.Child("value"), ext.Value, err.Error()))
+		}
+	}
+
+	return errs
+}
+
+// validateCredentials checks that the issuer either uses the credentials of a
+// Secret or the default credential chain of the controller, but not a partial
+// mix. The key selectors only apply to credentials from a Secret.
+func validateCredentials(ref *api.AWSCredentialsSecretReference, path *field.Path, clusterScoped bool) field.ErrorList {
+	var errs field.ErrorList
+
+	if ref.Name == "" {
+		if ref.Namespace != "" {
+			errs = append(errs, field.Required(path.Child("name"), "the name of the credentials Secret is required when its namespace is set"))
+		}
+		selectors := []struct {
+			name string
+			key  string
+		}{
+			{name: "accessKeyIDSelector", key: ref.AccessKeyIDSelector.Key},
+			{name: "secretAccessKeySelector", key: ref.SecretAccessKeySelector.Key},
+			{name: "sessionTokenSelector", key: ref.SessionTokenSelector.Key},
+		}
+		for _, selector := range selectors {
+			if selector.key != "" {
+				errs = append(errs, field.Forbidden(path.Child(selector.name), "key selectors require a credentials Secret; without secretRef.name the default credential chain is used"))
+			}
+		}
+		return errs
+	}
+
+	if ref.Namespace == "" && !clusterScoped {
+		errs = append(errs, field.Required(path.Child("namespace"), "the namespace of the credentials Secret is required"))
+	}
+	return errs
+}
@@ -0,0 +1,262 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package webhooks
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	v1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/utils/ptr"
+
+	api "github.com/cert-manager/aws-privateca-issuer/pkg/api/v1beta1"
+)
+
+const caArn = "arn:aws:acm-pca:us-east-1:account:certificate-authority/12345678-1234-1234-1234-123456789012"
+
+func TestIssuerValidator(t *testing.T) {
+	secretRef := api.AWSCredentialsSecretReference{
+		SecretReference: v1.SecretReference{Name: "issuer1-credentials", Namespace: "ns1"},
+	}
+
+	type testCase struct {
+		spec            api.AWSPCAIssuerSpec
+		expectedMessage string
+		// clusterIssuerValid is set for specs that are only invalid for
+		// AWSPCAIssuers
+		clusterIssuerValid bool
+	}
+	tests := map[string]testCase{
+		"success-secret-credentials": {
+			spec: api.AWSPCAIssuerSpec{Arn: caArn, Region: "us-east-1", SecretRef: secretRef},
+		},
+		"success-arn-from-configmap": {
+			spec: api.AWSPCAIssuerSpec{ArnFrom: &api.AWSArnSource{
+				ConfigMapKeyRef: &api.AWSKeySelector{Name: "ca", Namespace: "ns1", Key: "arn"},
+			}},
+		},
+		"failure-arn-and-arn-from": {
+			spec: api.AWSPCAIssuerSpec{Arn: caArn, ArnFrom: &api.AWSArnSource{
+				SecretKeyRef: &api.AWSKeySelector{Name: "ca", Namespace: "ns1", Key: "arn"},
+			}},
+			expectedMessage: "spec.arnFrom: Forbidden: cannot be combined with arn",
+		},
+		"failure-arn-from-both-refs": {
+			spec: api.AWSPCAIssuerSpec{ArnFrom: &api.AWSArnSource{
+				ConfigMapKeyRef: &api.AWSKeySelector{Name: "ca", Namespace: "ns1", Key: "arn"},
+				SecretKeyRef:    &api.AWSKeySelector{Name: "ca", Namespace: "ns1", Key: "arn"},
+			}},
+			expectedMessage: `spec.arnFrom: Invalid value: "": exactly one of configMapKeyRef and secretKeyRef must be set`,
+		},
+		"failure-arn-from-no-key": {
+			spec: api.AWSPCAIssuerSpec{ArnFrom: &api.AWSArnSource{
+				SecretKeyRef: &api.AWSKeySelector{Name: "ca", Namespace: "ns1"},
+			}},
+			expectedMessage: "spec.arnFrom.secretKeyRef.key: Required value",
+		},
+		"success-default-credential-chain": {
+			spec: api.AWSPCAIssuerSpec{Arn: caArn},
+		},
+		"success-custom-key-selectors": {
+			spec: api.AWSPCAIssuerSpec{Arn: caArn, Region: "us-east-1", SecretRef: api.AWSCredentialsSecretReference{
+				SecretReference:     secretRef.SecretReference,
+				AccessKeyIDSelector: v1.SecretKeySelector{Key: "id"},
+			}},
+		},
+		"success-us-gov": {
+			spec: api.AWSPCAIssuerSpec{
+				Arn:    "arn:aws-us-gov:acm-pca:us-gov-west-1:account:certificate-authority/12345678-1234-1234-1234-123456789012",
+				Region: "us-gov-west-1",
+			},
+		},
+		"success-failover-in-other-region": {
+			spec: api.AWSPCAIssuerSpec{
+				Arn:         caArn,
+				Region:      "us-east-1",
+				ArnFailover: []string{"arn:aws:acm-pca:us-west-2:account:certificate-authority/12345678-1234-1234-1234-123456789012"},
+			},
+		},
+		"success-regional-arns": {
+			spec: api.AWSPCAIssuerSpec{
+				Arn:          caArn,
+				RegionalArns: map[string]string{"eu-west-1": "arn:aws:acm-pca:eu-west-1:account:certificate-authority/12345678-1234-1234-1234-123456789012"},
+			},
+		},
+		"success-policy": {
+			spec: api.AWSPCAIssuerSpec{
+				Arn:               caArn,
+				AllowedDomains:    []string{"example.com", "*.Example.org."},
+				AllowedNamespaces: []string{"ns1"},
+			},
+		},
+		"success-api-passthrough": {
+			spec: api.AWSPCAIssuerSpec{Arn: caArn, APIPassthrough: &api.AWSPCAAPIPassthrough{
+				CertificatePolicies: []string{"2.23.140.1.2.1"},
+				CustomExtensions:    []api.AWSPCACustomExtension{{ObjectIdentifier: "1.3.6.1.4.1.99999.1", Value: "BQA=", Critical: true}},
+			}},
+		},
+		"failure-missing-arn": {
+			spec:            api.AWSPCAIssuerSpec{Region: "us-east-1"},
+			expectedMessage: "spec.arn: Required value: the ARN of the PCA certificate authority is required",
+		},
+		"failure-malformed-arn": {
+			spec:            api.AWSPCAIssuerSpec{Arn: "not-an-arn", Region: "us-east-1"},
+			expectedMessage: `spec.arn: Invalid value: "not-an-arn": arn: invalid prefix`,
+		},
+		"failure-not-a-ca-arn": {
+			spec:            api.AWSPCAIssuerSpec{Arn: "arn:aws:acm:us-east-1:account:certificate/12345678-1234-1234-1234-123456789012"},
+			expectedMessage: "is not the ARN of a PCA certificate authority",
+		},
+		"failure-region-mismatch": {
+			spec:            api.AWSPCAIssuerSpec{Arn: caArn, Region: "eu-west-1"},
+			expectedMessage: `spec.region: Invalid value: "eu-west-1": must match the region us-east-1 of the CA ARN`,
+		},
+		"failure-malformed-failover-arn": {
+			spec:            api.AWSPCAIssuerSpec{Arn: caArn, ArnFailover: []string{"not-an-arn"}},
+			expectedMessage: `spec.arnFailover[0]: Invalid value: "not-an-arn"`,
+		},
+		"failure-regional-arn-region-mismatch": {
+			spec: api.AWSPCAIssuerSpec{
+				Arn:          caArn,
+				RegionalArns: map[string]string{"eu-west-1": "arn:aws:acm-pca:us-west-2:account:certificate-authority/12345678-1234-1234-1234-123456789012"},
+			},
+			expectedMessage: `spec.regionalArns[eu-west-1]: Invalid value: "arn:aws:acm-pca:us-west-2:account:certificate-authority/12345678-1234-1234-1234-123456789012": must be a CA in region eu-west-1`,
+		},
+		"failure-invalid-allowed-domain": {
+			spec:            api.AWSPCAIssuerSpec{Arn: caArn, AllowedDomains: []string{"example.com", "bad_domain.com"}},
+			expectedMessage: `spec.allowedDomains[1]: Invalid value: "bad_domain.com"`,
+		},
+		"failure-invalid-allowed-namespace": {
+			spec:            api.AWSPCAIssuerSpec{Arn: caArn, AllowedNamespaces: []string{"NS1"}},
+			expectedMessage: `spec.allowedNamespaces[0]: Invalid value: "NS1"`,
+		},
+		"failure-path-length-out-of-range": {
+			spec:            api.AWSPCAIssuerSpec{Arn: caArn, PathLength: ptr.To[int32](4)},
+			expectedMessage: "spec.pathLength: Invalid value: 4: must be between 0 and 3",
+		},
+		"failure-invalid-certificate-policy": {
+			spec: api.AWSPCAIssuerSpec{Arn: caArn, APIPassthrough: &api.AWSPCAAPIPassthrough{
+				CertificatePolicies: []string{"2.23.140.1.2.1", "policy"},
+			}},
+			expectedMessage: `spec.apiPassthrough.certificatePolicies[1]: Invalid value: "policy"`,
+		},
+		"failure-duplicate-custom-extension": {
+			spec: api.AWSPCAIssuerSpec{Arn: caArn, APIPassthrough: &api.AWSPCAAPIPassthrough{
+				CustomExtensions: []api.AWSPCACustomExtension{
+					{ObjectIdentifier: "1.3.6.1.4.1.99999.1", Value: "BQA="},
+					{ObjectIdentifier: "1.3.6.1.4.1.99999.1", Value: "BQA="},
+				},
+			}},
+			expectedMessage: `spec.apiPassthrough.customExtensions[1].objectIdentifier: Duplicate value: "1.3.6.1.4.1.99999.1"`,
+		},
+		"failure-invalid-custom-extension-value": {
+			spec: api.AWSPCAIssuerSpec{Arn: caArn, APIPassthrough: &api.AWSPCAAPIPassthrough{
+				CustomExtensions: []api.AWSPCACustomExtension{{ObjectIdentifier: "1.3.6.1.4.1.99999.1", Value: "not base64!"}},
+			}},
+			expectedMessage: `spec.apiPassthrough.customExtensions[0].value: Invalid value: "not base64!": value is not base64 encoded`,
+		},
+		"failure-secret-without-namespace": {
+			spec: api.AWSPCAIssuerSpec{Arn: caArn, SecretRef: api.AWSCredentialsSecretReference{
+				SecretReference: v1.SecretReference{Name: "issuer1-credentials"},
+			}},
+			expectedMessage:    "spec.secretRef.namespace: Required value",
+			clusterIssuerValid: true,
+		},
+		"failure-secret-without-name": {
+			spec: api.AWSPCAIssuerSpec{Arn: caArn, SecretRef: api.AWSCredentialsSecretReference{
+				SecretReference: v1.SecretReference{Namespace: "ns1"},
+			}},
+			expectedMessage: "spec.secretRef.name: Required value",
+		},
+		"failure-key-selector-without-secret": {
+			spec: api.AWSPCAIssuerSpec{Arn: caArn, SecretRef: api.AWSCredentialsSecretReference{
+				SecretAccessKeySelector: v1.SecretKeySelector{Key: "secret"},
+			}},
+			expectedMessage: "spec.secretRef.secretAccessKeySelector: Forbidden: key selectors require a credentials Secret",
+		},
+		"success-roles-anywhere": {
+			spec: api.AWSPCAIssuerSpec{Arn: caArn, RolesAnywhere: &api.AWSRolesAnywhere{
+				SecretRef: v1.SecretReference{Name: "issuer1-client-certificate", Namespace: "ns1"},
+			}},
+		},
+		"failure-roles-anywhere-without-namespace": {
+			spec: api.AWSPCAIssuerSpec{Arn: caArn, RolesAnywhere: &api.AWSRolesAnywhere{
+				SecretRef: v1.SecretReference{Name: "issuer1-client-certificate"},
+			}},
+			expectedMessage:    "spec.rolesAnywhere.secretRef.namespace: Required value",
+			clusterIssuerValid: true,
+		},
+		"failure-roles-anywhere-with-secret-credentials": {
+			spec: api.AWSPCAIssuerSpec{Arn: caArn, SecretRef: secretRef, RolesAnywhere: &api.AWSRolesAnywhere{
+				SecretRef: v1.SecretReference{Name: "issuer1-client-certificate", Namespace: "ns1"},
+			}},
+			expectedMessage: "spec.rolesAnywhere: Forbidden: cannot be combined with the access key of secretRef",
+		},
+		"success-profile": {
+			spec: api.AWSPCAIssuerSpec{Arn: caArn, Profile: "dev"},
+		},
+		"failure-profile-with-secret-credentials": {
+			spec:            api.AWSPCAIssuerSpec{Arn: caArn, SecretRef: secretRef, Profile: "dev"},
+			expectedMessage: "spec.profile: Forbidden: cannot be combined with the access key of secretRef",
+		},
+		"failure-profile-with-roles-anywhere": {
+			spec: api.AWSPCAIssuerSpec{Arn: caArn, Profile: "dev", RolesAnywhere: &api.AWSRolesAnywhere{
+				SecretRef: v1.SecretReference{Name: "issuer1-client-certificate", Namespace: "ns1"},
+			}},
+			expectedMessage: "spec.profile: Forbidden: cannot be combined with rolesAnywhere",
+		},
+	}
+
+	validator := &IssuerValidator{}
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			issuers := map[string]runtime.Object{
+				"AWSPCAIssuer":        &api.AWSPCAIssuer{ObjectMeta: metav1.ObjectMeta{Name: "issuer1", Namespace: "ns1"}, Spec: tc.spec},
+				"AWSPCAClusterIssuer": &api.AWSPCAClusterIssuer{ObjectMeta: metav1.ObjectMeta{Name: "issuer1"}, Spec: tc.spec},
+			}
+			for kind, issuer := range issuers {
+				_, createErr := validator.ValidateCreate(context.TODO(), issuer)
+				_, updateErr := validator.ValidateUpdate(context.TODO(), issuer, issuer)
+				for _, err := range []error{createErr, updateErr} {
+					if tc.expectedMessage == "" || (tc.clusterIssuerValid && kind == "AWSPCAClusterIssuer") {
+						assert.NoError(t, err, kind)
+						continue
+					}
+					require.Error(t, err, kind)
+					assert.True(t, apierrors.IsInvalid(err), "expected an Invalid error for %s, got %v", kind, err)
+					assert.Contains(t, err.Error(), kind+`.awspca.cert-manager.io "issuer1" is invalid`)
+					assert.Contains(t, err.Error(), tc.expectedMessage)
+				}
+			}
+		})
+	}
+}
+
+func TestIssuerValidatorDelete(t *testing.T) {
+	_, err := (&IssuerValidator{}).ValidateDelete(context.TODO(), &api.AWSPCAIssuer{})
+	assert.NoError(t, err)
+}
+
+func TestIssuerValidatorUnexpectedObject(t *testing.T) {
+	_, err := (&IssuerValidator{}).ValidateCreate(context.TODO(), &v1.Secret{})
+	assert.EqualError(t, err, "expected an AWSPCAIssuer or AWSPCAClusterIssuer, got *v1.Secret")
+}
@@ -0,0 +1,138 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/serializer"
+	utilyaml "k8s.io/apimachinery/pkg/util/yaml"
+
+	awspcacertmanageriov1beta1 "github.com/cert-manager/aws-privateca-issuer/pkg/api/v1beta1"
+	"github.com/cert-manager/aws-privateca-issuer/pkg/webhooks"
+)
+
+// validateCommand is the subcommand that validates issuer manifests offline
+const validateCommand = "validate"
+
+// Exit codes of the validate subcommand
+const (
+	exitValid   = 0
+	exitInvalid = 1
+	exitUsage   = 2
+)
+
+// runValidate validates the AWSPCAIssuers and AWSPCAClusterIssuers in the
+// manifests given with -f like the admission webhook does, without contacting
+// Kubernetes or AWS. "-" reads a manifest from stdin. Results are reported to
+// stdout and problems to stderr, and the exit code is returned.
+func runValidate(args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet(validateCommand, flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	var files []string
+	fs.Func("f", "A manifest of AWSPCAIssuers or AWSPCAClusterIssuers to validate, or - for stdin. May be repeated.", func(file string) error {
+		files = append(files, file)
+		return nil
+	})
+	if err := fs.Parse(args); err != nil {
+		return exitUsage
+	}
+	if len(files) == 0 || fs.NArg() > 0 {
+		fmt.Fprintf(stderr, "usage: %s %s -f <manifest> [-f <manifest>...]\n", os.Args[0], validateCommand)
+		return exitUsage
+	}
+
+	scheme := runtime.NewScheme()
+	if err := awspcacertmanageriov1beta1.AddToScheme(scheme); err != nil {
+		fmt.Fprintln(stderr, err)
+		return exitUsage
+	}
+	decoder := serializer.NewCodecFactory(scheme, serializer.EnableStrict).UniversalDeserializer()
+
+	exitCode := exitValid
+	for _, file := range files {
+		if err := validateManifest(file, stdin, decoder, stdout, stderr); err != nil {
+			fmt.Fprintf(stderr, "%s: %v\n", file, err)
+			exitCode = exitInvalid
+		}
+	}
+	return exitCode
+}
+
+// validateManifest validates every document of the manifest file. An error is
+// returned if the file cannot be read or any document is invalid.
+func validateManifest(file string, stdin io.Reader, decoder runtime.Decoder, stdout, stderr io.Writer) error {
+	in := stdin
+	if file != "-" {
+		f, err := os.Open(file)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		in = f
+	}
+
+	var invalid bool
+	reader := utilyaml.NewYAMLReader(bufio.NewReader(in))
+	for i := 0; ; i++ {
+		doc, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return err
+		}
+		if len(bytes.TrimSpace(doc)) == 0 {
+			continue
+		}
+
+		// Unknown fields are reported, as the API server would drop them
+		obj, gvk, err := decoder.Decode(doc, nil, nil)
+		if err != nil {
+			fmt.Fprintf(stderr, "%s: document %d: %v\n", file, i, err)
+			invalid = true
+			continue
+		}
+		name := gvk.Kind
+		if accessor, err := meta.Accessor(obj); err == nil {
+			name = fmt.Sprintf("%s %s", gvk.Kind, accessor.GetName())
+			if accessor.GetNamespace() != "" {
+				name = fmt.Sprintf("%s %s/%s", gvk.Kind, accessor.GetNamespace(), accessor.GetName())
+			}
+		}
+
+		if err := webhooks.Validate(obj); err != nil {
+			fmt.Fprintf(stderr, "%s: %s: %v\n", file, name, err)
+			invalid = true
+			continue
+		}
+		fmt.Fprintf(stdout, "%s: %s is valid\n", file, name)
+	}
+
+	if invalid {
+		return errors.New("manifest contains invalid issuers")
+	}
+	return nil
+}
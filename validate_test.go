@@ -0,0 +1,165 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const validIssuerManifest = `apiVersion: awspca.cert-manager.io/v1beta1
+kind: AWSPCAIssuer
+metadata:
+  name: issuer1
+  namespace: ns1
+spec:
+  arn: arn:aws:acm-pca:us-east-1:account:certificate-authority/12345678-1234-1234-1234-123456789012
+  region: us-east-1
+---
+apiVersion: awspca.cert-manager.io/v1beta1
+kind: AWSPCAClusterIssuer
+metadata:
+  name: clusterissuer1
+spec:
+  arn: arn:aws:acm-pca:us-east-1:account:certificate-authority/12345678-1234-1234-1234-123456789012
+  secretRef:
+    name: issuer1-credentials
+    namespace: ns1
+`
+
+func TestRunValidate(t *testing.T) {
+	tests := map[string]struct {
+		manifest         string
+		stdin            string
+		args             []string
+		expectedExitCode int
+		expectedStdout   []string
+		expectedStderr   []string
+	}{
+		"valid": {
+			manifest:         validIssuerManifest,
+			expectedExitCode: exitValid,
+			expectedStdout: []string{
+				"AWSPCAIssuer ns1/issuer1 is valid",
+				"AWSPCAClusterIssuer clusterissuer1 is valid",
+			},
+		},
+		"valid-stdin": {
+			stdin:            validIssuerManifest,
+			args:             []string{"-f", "-"},
+			expectedExitCode: exitValid,
+			expectedStdout:   []string{"-: AWSPCAIssuer ns1/issuer1 is valid"},
+		},
+		"region-mismatch": {
+			manifest: `apiVersion: awspca.cert-manager.io/v1beta1
+kind: AWSPCAIssuer
+metadata:
+  name: issuer1
+  namespace: ns1
+spec:
+  arn: arn:aws:acm-pca:us-east-1:account:certificate-authority/12345678-1234-1234-1234-123456789012
+  region: eu-west-1
+`,
+			expectedExitCode: exitInvalid,
+			expectedStderr:   []string{`AWSPCAIssuer ns1/issuer1: AWSPCAIssuer.awspca.cert-manager.io "issuer1" is invalid: spec.region`},
+		},
+		"malformed-arn-and-partial-credentials": {
+			manifest: validIssuerManifest + `---
+apiVersion: awspca.cert-manager.io/v1beta1
+kind: AWSPCAIssuer
+metadata:
+  name: issuer2
+  namespace: ns1
+spec:
+  arn: not-an-arn
+  secretRef:
+    name: issuer2-credentials
+`,
+			expectedExitCode: exitInvalid,
+			expectedStdout:   []string{"AWSPCAIssuer ns1/issuer1 is valid"},
+			expectedStderr: []string{
+				`spec.arn: Invalid value: "not-an-arn"`,
+				"spec.secretRef.namespace: Required value",
+				"manifest contains invalid issuers",
+			},
+		},
+		"unknown-field": {
+			manifest: `apiVersion: awspca.cert-manager.io/v1beta1
+kind: AWSPCAIssuer
+metadata:
+  name: issuer1
+spec:
+  arn: arn:aws:acm-pca:us-east-1:account:certificate-authority/12345678-1234-1234-1234-123456789012
+  reigon: us-east-1
+`,
+			expectedExitCode: exitInvalid,
+			expectedStderr:   []string{`document 0: strict decoding error: unknown field "spec.reigon"`},
+		},
+		"other-kind": {
+			manifest: `apiVersion: v1
+kind: Secret
+metadata:
+  name: issuer1-credentials
+`,
+			expectedExitCode: exitInvalid,
+			expectedStderr:   []string{"document 0: no kind \"Secret\" is registered"},
+		},
+		"missing-file": {
+			args:             []string{"-f", "missing.yaml"},
+			expectedExitCode: exitInvalid,
+			expectedStderr:   []string{"missing.yaml: open missing.yaml: no such file or directory"},
+		},
+		"no-file": {
+			args:             []string{},
+			expectedExitCode: exitUsage,
+			expectedStderr:   []string{"usage:"},
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			args := tc.args
+			if tc.manifest != "" {
+				path := filepath.Join(t.TempDir(), "issuer.yaml")
+				require.NoError(t, os.WriteFile(path, []byte(tc.manifest), 0o600))
+				args = []string{"-f", path}
+			}
+			if args == nil {
+				args = []string{}
+			}
+
+			var stdout, stderr bytes.Buffer
+			exitCode := runValidate(args, strings.NewReader(tc.stdin), &stdout, &stderr)
+			assert.Equal(t, tc.expectedExitCode, exitCode, "stderr: %s", stderr.String())
+			for _, expected := range tc.expectedStdout {
+				assert.Contains(t, stdout.String(), expected)
+			}
+			for _, expected := range tc.expectedStderr {
+				assert.Contains(t, stderr.String(), expected)
+			}
+			if tc.expectedExitCode == exitValid {
+				assert.Empty(t, stderr.String())
+			}
+		})
+	}
+}